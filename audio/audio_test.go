@@ -0,0 +1,77 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func oggPage(granule uint64, payload []byte) []byte {
+	page := []byte("OggS\x00\x00")
+	page = binary.LittleEndian.AppendUint64(page, granule)
+	page = append(page, make([]byte, 12)...)
+	return append(page, payload...)
+}
+
+func TestDurationOpus(t *testing.T) {
+	head := []byte("OpusHead\x01\x01")
+	head = binary.LittleEndian.AppendUint16(head, 312)
+
+	var data []byte
+	data = append(data, oggPage(0, head)...)
+	data = append(data, oggPage(48000, []byte("audio"))...)
+	data = append(data, oggPage(312+3*48000, []byte("audio"))...)
+
+	d, ok := Duration(data)
+	if !ok || d != 3*time.Second {
+		t.Errorf("Duration = %v, %t, want 3s", d, ok)
+	}
+}
+
+func TestDurationWAV(t *testing.T) {
+	var data bytes.Buffer
+	data.WriteString("RIFF\x00\x00\x00\x00WAVE")
+	data.WriteString("fmt ")
+	binary.Write(&data, binary.LittleEndian, []uint32{16})
+	binary.Write(&data, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(&data, binary.LittleEndian, []uint32{8000, 16000})
+	binary.Write(&data, binary.LittleEndian, []uint16{2, 16})
+	data.WriteString("data")
+	binary.Write(&data, binary.LittleEndian, []uint32{32000})
+	data.Write(make([]byte, 32000))
+
+	d, ok := Duration(data.Bytes())
+	if !ok || d != 2*time.Second {
+		t.Errorf("Duration = %v, %t, want 2s", d, ok)
+	}
+}
+
+func TestDurationUnknownFormat(t *testing.T) {
+	if _, ok := Duration([]byte("ID3\x04 not parsed")); ok {
+		t.Error("Duration of an mp3 should be unknown")
+	}
+}
+
+func TestHTTPTranscriber(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" || r.FormValue("model") != "whisper-1" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if _, _, err := r.FormFile("file"); err != nil {
+			http.Error(w, "no file", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"text": "hello there"}`))
+	}))
+	defer srv.Close()
+
+	text, err := NewHTTPTranscriber(srv.URL, "key", "whisper-1").Transcribe(context.Background(), "voice-message.ogg", []byte("OggS"))
+	if err != nil || text != "hello there" {
+		t.Errorf("Transcribe = %q, %v", text, err)
+	}
+}
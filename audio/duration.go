@@ -0,0 +1,76 @@
+// Package audio inspects and transcribes audio attachments.
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+)
+
+// OPUS_GRANULE_RATE is the granule position rate of Ogg Opus streams, which
+// is 48 kHz whatever the input sample rate.
+const OPUS_GRANULE_RATE = 48000
+
+// Duration returns the playing time of an Ogg (Opus or Vorbis) or WAV file,
+// the formats of voice messages and common uploads. ok is false for other
+// formats and truncated files.
+func Duration(data []byte) (d time.Duration, ok bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return oggDuration(data)
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WAVE")):
+		return wavDuration(data)
+	}
+	return 0, false
+}
+
+// oggDuration reads the granule position of the last page, which counts the
+// samples played so far.
+func oggDuration(data []byte) (time.Duration, bool) {
+	last := bytes.LastIndex(data, []byte("OggS"))
+	if last < 0 || len(data) < last+14 {
+		return 0, false
+	}
+	granule := int64(binary.LittleEndian.Uint64(data[last+6 : last+14]))
+
+	var rate, skip int64
+	if i := bytes.Index(data, []byte("OpusHead")); i >= 0 && len(data) >= i+12 {
+		rate = OPUS_GRANULE_RATE
+		skip = int64(binary.LittleEndian.Uint16(data[i+10 : i+12]))
+	} else if i := bytes.Index(data, []byte("\x01vorbis")); i >= 0 && len(data) >= i+16 {
+		rate = int64(binary.LittleEndian.Uint32(data[i+12 : i+16]))
+	}
+	if rate == 0 || granule <= skip {
+		return 0, false
+	}
+
+	return time.Duration(granule-skip) * time.Second / time.Duration(rate), true
+}
+
+// wavDuration divides the size of the data chunk by the byte rate of the fmt chunk.
+func wavDuration(data []byte) (time.Duration, bool) {
+	var byteRate, dataSize int64
+	for off := 12; off+8 <= len(data); {
+		id := string(data[off : off+4])
+		size := int(binary.LittleEndian.Uint32(data[off+4 : off+8]))
+		body := off + 8
+
+		switch id {
+		case "fmt ":
+			if body+12 > len(data) {
+				return 0, false
+			}
+			byteRate = int64(binary.LittleEndian.Uint32(data[body+8 : body+12]))
+		case "data":
+			dataSize = int64(size)
+		}
+
+		// Chunks are padded to an even size.
+		off = body + size + size%2
+	}
+
+	if byteRate == 0 || dataSize == 0 {
+		return 0, false
+	}
+	return time.Duration(dataSize) * time.Second / time.Duration(byteRate), true
+}
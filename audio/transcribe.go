@@ -0,0 +1,84 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// TRANSCRIBE_TIMEOUT bounds a single transcription request.
+const TRANSCRIBE_TIMEOUT = 2 * time.Minute
+
+// Transcriber turns speech into text.
+type Transcriber interface {
+	Transcribe(ctx context.Context, filename string, data []byte) (string, error)
+}
+
+// HTTPTranscriber calls an OpenAI-compatible /audio/transcriptions endpoint,
+// e.g. OpenAI's Whisper API or a self-hosted whisper server.
+type HTTPTranscriber struct {
+	URL    string
+	APIKey string
+	Model  string
+	Client *http.Client
+}
+
+func NewHTTPTranscriber(url, apiKey, model string) *HTTPTranscriber {
+	return &HTTPTranscriber{
+		URL:    url,
+		APIKey: apiKey,
+		Model:  model,
+		Client: &http.Client{Timeout: TRANSCRIBE_TIMEOUT},
+	}
+}
+
+func (t *HTTPTranscriber) Transcribe(ctx context.Context, filename string, data []byte) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("model", t.Model); err != nil {
+		return "", err
+	}
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if t.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	}
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decoding transcription: %w", err)
+	}
+	return result.Text, nil
+}
@@ -0,0 +1,227 @@
+package bot
+
+import (
+	"slices"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/msglink"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+func (b *Bot) DMReactionAdd(r *discordgo.MessageReactionAdd) {
+	// This runs for every reaction the bot sees, so anything that isn't an
+	// action is dropped before looking anything up.
+	if r.UserID == b.state.User.ID || !slices.Contains(ACTION_EMOJIS, r.Emoji.Name) {
+		return
+	}
+	// Without a guild the reaction is in a DM. In guilds, actions only apply
+	// to the user's bookmarks posted to their archive channel.
+	if r.GuildID != "" && !b.isOwnBookmarkMessage(r.UserID, r.ChannelID, r.MessageID) {
+		return
+	}
+
+	// Instances sharing the store all receive the reaction; one acts on it.
+	release, ok := b.claim("action:"+r.ChannelID+":"+r.MessageID+":"+r.Emoji.Name, r.Emoji.Name+" reaction of user "+r.UserID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	unlock := b.lockBookmarkDM(r.UserID, r.ChannelID, r.MessageID)
+	defer unlock()
+
+	switch r.Emoji.Name {
+	case DELETE_EMOJI:
+		b.logger.Printf("Processing delete reaction from user %s in DM", r.UserID)
+		b.deleteBookmark(r.UserID, r.ChannelID, r.MessageID)
+	case PIN_EMOJI:
+		b.setBookmarkState(r.ChannelID, r.MessageID, r.UserID, func(bm *store.Bookmark) { bm.Pinned = true })
+	case ARCHIVE_EMOJI:
+		b.setBookmarkState(r.ChannelID, r.MessageID, r.UserID, func(bm *store.Bookmark) { bm.Status = store.StatusArchived })
+	case RESEND_EMOJI:
+		b.resendBookmark(r.ChannelID, r.MessageID, r.UserID)
+	}
+}
+
+// deleteBookmark removes a bookmark DM and the 🔖 reaction on its source.
+// Tracked bookmarks go to the trash and can be restored for UNDO_WINDOW. It
+// reports whether the bookmark message is gone.
+func (b *Bot) deleteBookmark(userID, dmChannelID, dmMessageID string) bool {
+	var err error
+	var channelID, messageID string
+
+	stored, tracked := b.store.ByDM(dmChannelID, dmMessageID)
+	if tracked {
+		channelID, messageID = stored.ChannelID, stored.MessageID
+	} else {
+		// Bookmarks delivered before the store existed are resolved from their embed.
+		msg, err := b.api.ChannelMessage(dmChannelID, dmMessageID)
+		if err != nil {
+			b.logger.Printf("Error getting DM message %s from channel %s: %v", dmMessageID, dmChannelID, err)
+			return false
+		}
+
+		if len(msg.Embeds) == 0 {
+			b.logger.Printf("Warning: User %s reacted to delete on a message with no embeds", userID)
+			return false
+		}
+
+		link, ok := embedSourceLink(msg.Embeds[0])
+		if !ok {
+			b.logger.Printf("Error: Could not extract message link from bookmark embed for user %s", userID)
+			return false
+		}
+		channelID, messageID = link.ChannelID, link.MessageID
+	}
+
+	if !tracked || !(stored.SourceDeleted || stored.Orphaned) {
+		b.removeTriggerReactions(channelID, messageID, userID)
+	}
+
+	err = b.api.ChannelMessageDelete(dmChannelID, dmMessageID)
+	if err != nil {
+		b.logger.Printf("Error deleting bookmark message from DM (channel: %s, message: %s): %v", dmChannelID, dmMessageID, err)
+		return false
+	}
+
+	if tracked {
+		deletedAt := time.Now()
+		_, trashed, err := b.store.Trash(dmChannelID, dmMessageID, deletedAt)
+		if err != nil {
+			b.logger.Printf("Error removing stored bookmark (channel: %s, message: %s): %v", dmChannelID, dmMessageID, err)
+			b.recordFailure(alertStore, "", err)
+		} else if trashed {
+			b.auditBookmark(store.AuditDelete, userID, stored)
+			b.offerUndo(userID, stored, deletedAt)
+		}
+	}
+
+	b.logger.Printf("Successfully processed bookmark deletion for user %s", userID)
+	return true
+}
+
+func (b *Bot) addActionReactions(dmChannelID, dmMessageID string) {
+	for _, emoji := range ACTION_EMOJIS {
+		err := withRetryErr(b, func() error {
+			return b.api.MessageReactionAdd(dmChannelID, dmMessageID, emoji)
+		})
+		if err != nil {
+			b.logger.Printf("Error adding %s reaction to bookmark message %s: %v", emoji, dmMessageID, err)
+		}
+	}
+}
+
+// isOwnBookmarkMessage reports whether a DM or archive channel message is a
+// stored bookmark of the user, so the bookmark actions apply to it.
+func (b *Bot) isOwnBookmarkMessage(userID, channelID, messageID string) bool {
+	bm, ok := b.store.ByDM(channelID, messageID)
+	return ok && bm.UserID == userID
+}
+
+// DMReactionRemove undoes the pin and archive actions when their reaction is removed.
+func (b *Bot) DMReactionRemove(r *discordgo.MessageReactionRemove) {
+	if r.UserID == b.state.User.ID || !slices.Contains(ACTION_EMOJIS, r.Emoji.Name) {
+		return
+	}
+	if r.GuildID != "" && !b.isOwnBookmarkMessage(r.UserID, r.ChannelID, r.MessageID) {
+		return
+	}
+
+	unlock := b.lockBookmarkDM(r.UserID, r.ChannelID, r.MessageID)
+	defer unlock()
+
+	switch r.Emoji.Name {
+	case PIN_EMOJI:
+		b.setBookmarkState(r.ChannelID, r.MessageID, r.UserID, func(bm *store.Bookmark) { bm.Pinned = false })
+	case ARCHIVE_EMOJI:
+		b.setBookmarkState(r.ChannelID, r.MessageID, r.UserID, func(bm *store.Bookmark) { bm.Status = store.StatusActive })
+	}
+}
+
+// setBookmarkState applies change to the bookmark delivered as the given DM message.
+func (b *Bot) setBookmarkState(dmChannelID, dmMessageID, userID string, change func(bm *store.Bookmark)) {
+	bm, ok := b.store.ByDM(dmChannelID, dmMessageID)
+	if !ok {
+		b.logger.Printf("Warning: User %s reacted to an untracked bookmark message %s", userID, dmMessageID)
+		return
+	}
+
+	change(&bm)
+	err := b.store.Update(bm)
+	if err != nil {
+		b.logger.Printf("Error updating stored bookmark for user %s: %v", userID, err)
+		b.recordFailure(alertStore, "", err)
+		return
+	}
+
+	b.logger.Printf("Updated bookmark %s for user %s (pinned: %t, status: %q)", dmMessageID, userID, bm.Pinned, bm.Status)
+}
+
+// resendBookmark re-sends a bookmark as a new DM, moving it to the bottom of
+// the conversation, and deletes the old message.
+func (b *Bot) resendBookmark(dmChannelID, dmMessageID, userID string) {
+	bm, ok := b.store.ByDM(dmChannelID, dmMessageID)
+	if !ok {
+		b.logger.Printf("Warning: User %s reacted to an untracked bookmark message %s", userID, dmMessageID)
+		return
+	}
+
+	dmMsg, err := b.api.ChannelMessage(dmChannelID, dmMessageID)
+	if err != nil {
+		b.logger.Printf("Error getting DM message %s from channel %s: %v", dmMessageID, dmChannelID, err)
+		return
+	}
+
+	// The old message's files, such as archived attachments, go with the
+	// new one; without all of them the old message is kept.
+	files, err := reuploadFiles(dmMsg)
+	if err != nil {
+		b.logger.Printf("Error copying files of bookmark message %s for user %s: %v", dmMessageID, userID, err)
+		return
+	}
+
+	sentMsg, err := b.api.ChannelMessageSendComplex(dmChannelID, &discordgo.MessageSend{Embeds: dmMsg.Embeds, Files: files, Components: bookmarkComponents(bm.Compact)})
+	if err != nil {
+		b.logger.Printf("Error re-sending bookmark to user %s: %v", userID, err)
+		return
+	}
+
+	bm.DMMessageID = sentMsg.ID
+	err = b.store.Replace(dmChannelID, dmMessageID, bm)
+	if err != nil {
+		b.logger.Printf("Error updating stored bookmark for user %s: %v", userID, err)
+		b.recordFailure(alertStore, "", err)
+		// The old message stays the tracked one, so the copy goes.
+		if err := b.api.ChannelMessageDelete(dmChannelID, sentMsg.ID); err != nil {
+			b.logger.Printf("Error deleting re-sent bookmark message %s of user %s: %v", sentMsg.ID, userID, err)
+		}
+		return
+	}
+	b.addActionReactions(dmChannelID, sentMsg.ID)
+
+	err = b.api.ChannelMessageDelete(dmChannelID, dmMessageID)
+	if err != nil {
+		b.logger.Printf("Error deleting old bookmark message from DM (channel: %s, message: %s): %v", dmChannelID, dmMessageID, err)
+	}
+
+	b.logger.Printf("Re-sent bookmark %s to user %s as %s", dmMessageID, userID, sentMsg.ID)
+}
+
+// embedSourceLink recovers the source message of a bookmark embed from its
+// URL, or from the "Source" field of embeds sent before the URL was set.
+func embedSourceLink(embed *discordgo.MessageEmbed) (msglink.Link, bool) {
+	if link, err := msglink.Parse(embed.URL); err == nil {
+		return link, true
+	}
+
+	for _, field := range embed.Fields {
+		if field.Name != "Source" {
+			continue
+		}
+		if links := msglink.Find(field.Value); len(links) > 0 {
+			return links[0], true
+		}
+	}
+	return msglink.Link{}, false
+}
@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// ALERT_FLUSH_INTERVAL is how often recorded failures are turned into alerts.
+	ALERT_FLUSH_INTERVAL = 5 * time.Minute
+	// ALERT_COOLDOWN is the minimum time between two alerts of the same kind.
+	ALERT_COOLDOWN         = time.Hour
+	ALERT_ERROR_MAX_LENGTH = 300
+)
+
+// alertKind is a category of operational failure reported to the owner.
+type alertKind string
+
+const (
+	alertDelivery alertKind = "delivery"
+	alertStore    alertKind = "store"
+	alertGateway  alertKind = "gateway"
+	alertAPI      alertKind = "api"
+)
+
+// alertThresholds is the number of failures of a kind within one flush
+// interval that makes an alert; fewer are only logged.
+var alertThresholds = map[alertKind]int{
+	alertDelivery: 5,
+	alertStore:    1,
+	alertGateway:  3,
+	alertAPI:      1,
+}
+
+var alertTitles = map[alertKind]string{
+	alertDelivery: "Bookmark deliveries failed",
+	alertStore:    "Store writes failed",
+	alertGateway:  "Gateway disconnected",
+	alertAPI:      "Unexpected Discord API errors",
+}
+
+// failureCount aggregates the failures of one kind since the last alert.
+type failureCount struct {
+	count int
+	since time.Time
+	// subjects are the distinct users affected, if any.
+	subjects map[string]bool
+	lastErr  string
+}
+
+// alerter aggregates failures so the owner gets one message per kind and
+// cooldown rather than one per failure.
+type alerter struct {
+	mu       sync.Mutex
+	pending  map[alertKind]*failureCount
+	lastSent map[alertKind]time.Time
+}
+
+func (a *alerter) record(kind alertKind, subject string, err error, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.pending == nil {
+		a.pending = map[alertKind]*failureCount{}
+		a.lastSent = map[alertKind]time.Time{}
+	}
+
+	c, ok := a.pending[kind]
+	if !ok {
+		c = &failureCount{since: now, subjects: map[string]bool{}}
+		a.pending[kind] = c
+	}
+	c.count++
+	if subject != "" {
+		c.subjects[subject] = true
+	}
+	if err != nil {
+		c.lastErr = err.Error()
+	}
+}
+
+// due returns the alerts to send at now, in a stable order. Counts below
+// their threshold start over, counts held back by the cooldown keep growing.
+func (a *alerter) due(now time.Time) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var alerts []string
+	for kind, c := range a.pending {
+		if c.count < alertThresholds[kind] {
+			delete(a.pending, kind)
+			continue
+		}
+		if now.Sub(a.lastSent[kind]) < ALERT_COOLDOWN {
+			continue
+		}
+
+		alerts = append(alerts, formatAlert(kind, c))
+		a.lastSent[kind] = now
+		delete(a.pending, kind)
+	}
+
+	sort.Strings(alerts)
+	return alerts
+}
+
+func formatAlert(kind alertKind, c *failureCount) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "⚠️ **%s** %d time(s)", alertTitles[kind], c.count)
+	if len(c.subjects) > 0 {
+		fmt.Fprintf(&sb, " for %d user(s)", len(c.subjects))
+	}
+	fmt.Fprintf(&sb, " since <t:%d:R>", c.since.Unix())
+	if c.lastErr != "" {
+		lastErr := c.lastErr
+		if runes := []rune(lastErr); len(runes) > ALERT_ERROR_MAX_LENGTH {
+			lastErr = string(runes[:ALERT_ERROR_MAX_LENGTH]) + "…"
+		}
+		fmt.Fprintf(&sb, "\nLast error: `%s`", strings.ReplaceAll(lastErr, "`", "'"))
+	}
+	return sb.String()
+}
+
+// recordFailure counts an operational failure towards the owner alerts.
+func (b *Bot) recordFailure(kind alertKind, subject string, err error) {
+	b.alerts.record(kind, subject, err, time.Now())
+	b.stats.failure(kind)
+}
+
+// startAlerts starts sending aggregated alerts once; later Ready events are no-ops.
+func (b *Bot) startAlerts() {
+	b.alertsOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(ALERT_FLUSH_INTERVAL)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-b.done:
+					return
+				case now := <-ticker.C:
+					b.sendDueAlerts(now)
+				}
+			}
+		}()
+	})
+}
+
+func (b *Bot) sendDueAlerts(now time.Time) {
+	for _, alert := range b.alerts.due(now) {
+		b.sendAlert(alert)
+	}
+}
+
+// sendAlert posts an alert to the alert channel, or the owner's DMs if none
+// is configured.
+func (b *Bot) sendAlert(content string) {
+	b.logger.Printf("Alert: %s", content)
+
+	if alertChannel := b.cfg().AlertChannel; alertChannel != "" {
+		_, err := b.send(alertChannel, &discordgo.MessageSend{Content: content})
+		if err != nil {
+			b.logger.Printf("Error sending alert to channel %s: %v", alertChannel, err)
+		}
+		return
+	}
+	owner := b.owner()
+	if owner == "" {
+		b.logger.Printf("No owner to alert")
+		return
+	}
+	b.notifyUser(owner, content)
+}
@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlerterAggregatesAboveThreshold(t *testing.T) {
+	var a alerter
+	now := time.Now()
+
+	for n := 0; n < alertThresholds[alertDelivery]-1; n++ {
+		a.record(alertDelivery, "user", errors.New("closed DMs"), now)
+	}
+	if alerts := a.due(now); len(alerts) != 0 {
+		t.Fatalf("due = %q below the threshold, want none", alerts)
+	}
+
+	// Counts below the threshold start over at every flush.
+	for n := 0; n < alertThresholds[alertDelivery]; n++ {
+		a.record(alertDelivery, fmt.Sprintf("user-%d", n%2), errors.New("closed DMs"), now)
+	}
+	alerts := a.due(now)
+	if len(alerts) != 1 {
+		t.Fatalf("due = %q, want one alert", alerts)
+	}
+	want := fmt.Sprintf("%d time(s) for 2 user(s)", alertThresholds[alertDelivery])
+	if !strings.Contains(alerts[0], want) || !strings.Contains(alerts[0], "closed DMs") {
+		t.Errorf("alert = %q, want %q and the last error", alerts[0], want)
+	}
+}
+
+func TestAlerterCooldown(t *testing.T) {
+	var a alerter
+	now := time.Now()
+
+	a.record(alertStore, "", errors.New("disk full"), now)
+	if alerts := a.due(now); len(alerts) != 1 {
+		t.Fatalf("due = %q, want one alert", alerts)
+	}
+
+	a.record(alertStore, "", errors.New("disk full"), now.Add(time.Minute))
+	a.record(alertStore, "", errors.New("disk full"), now.Add(2*time.Minute))
+	if alerts := a.due(now.Add(ALERT_FLUSH_INTERVAL)); len(alerts) != 0 {
+		t.Fatalf("due = %q during the cooldown, want none", alerts)
+	}
+
+	alerts := a.due(now.Add(ALERT_COOLDOWN))
+	if len(alerts) != 1 || !strings.Contains(alerts[0], "2 time(s)") {
+		t.Errorf("due = %q after the cooldown, want the failures held back", alerts)
+	}
+}
+
+func TestSendAlertToChannel(t *testing.T) {
+	b, api := newTestBot(t)
+	b.config.AlertChannel = "alerts"
+	b.setOwner("owner")
+
+	b.Disconnect(nil)
+	b.Disconnect(nil)
+	b.Disconnect(nil)
+	b.sendDueAlerts(time.Now())
+
+	if len(api.sent) != 1 || api.sent[0].ChannelID != "alerts" || !strings.Contains(api.sent[0].Message.Content, "Gateway disconnected") {
+		t.Fatalf("sent %+v, want a gateway alert in the alert channel", api.sent)
+	}
+}
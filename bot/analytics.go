@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/msglink"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// ANALYTICS_MIN_MEMBERS is how many members must have bookmarked in a
+	// channel or a message before it's shown, so no one's bookmarks can be
+	// singled out.
+	ANALYTICS_MIN_MEMBERS = 3
+	// ANALYTICS_DEFAULT_DAYS is the window without a "days" option.
+	ANALYTICS_DEFAULT_DAYS = 30
+)
+
+// guildAnalyticsCommand shows admins which channels and messages of their
+// guild get bookmarked most: anonymized counts, without who bookmarked them
+// or what the messages say.
+func (b *Bot) guildAnalyticsCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	if i.GuildID == "" {
+		b.respondEphemeral(i, "Use this command in your server.")
+		return
+	}
+	if b.store.GuildSettings(i.GuildID).AnalyticsDisabled {
+		b.respondEphemeral(i, "Analytics are turned off for this server. Turn them on with `/bookmarker analytics-privacy`.")
+		return
+	}
+
+	days := ANALYTICS_DEFAULT_DAYS
+	if opt, ok := opts["days"]; ok {
+		days = int(opt.IntValue())
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	var bookmarks []store.Bookmark
+	for _, bm := range b.store.All() {
+		if bm.GuildID == i.GuildID && !bm.CreatedAt.Before(since) {
+			bookmarks = append(bookmarks, bm)
+		}
+	}
+
+	b.logger.Printf("User %s viewed analytics of guild %s", interactionUser(i).ID, i.GuildID)
+	b.respondEmbed(i, analyticsEmbed(bookmarks, days), discordgo.MessageFlagsEphemeral)
+}
+
+func analyticsEmbed(bookmarks []store.Bookmark, days int) *discordgo.MessageEmbed {
+	analytics := &discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("Bookmarks of the last %d day(s)", days),
+		Color:  embed.DEFAULT_EMBED_COLOR,
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Only channels and messages bookmarked by at least %d members are shown.", ANALYTICS_MIN_MEMBERS)},
+	}
+
+	members := map[string]bool{}
+	perChannel := map[string]map[string]bool{}
+	perMessage := map[string]map[string]bool{}
+	for _, bm := range bookmarks {
+		members[bm.UserID] = true
+		addMember(perChannel, bm.ChannelID, bm.UserID)
+		addMember(perMessage, msglink.New(bm.GuildID, bm.ChannelID, bm.MessageID).String(), bm.UserID)
+	}
+	if len(members) < ANALYTICS_MIN_MEMBERS {
+		analytics.Description = "Not enough members bookmarked messages of this server yet to show anonymized analytics."
+		return analytics
+	}
+
+	analytics.Fields = []*discordgo.MessageEmbedField{
+		{Name: "Bookmarks", Value: fmt.Sprintf("%d", len(bookmarks)), Inline: true},
+		{Name: "Members bookmarking", Value: fmt.Sprintf("%d", len(members)), Inline: true},
+	}
+	if channels := anonymizedCounts(perChannel); len(channels) > 0 {
+		analytics.Fields = append(analytics.Fields, &discordgo.MessageEmbedField{
+			Name:  "Top channels",
+			Value: topCounts(channels, func(id string) string { return "<#" + id + ">" }),
+		})
+	}
+	if messages := anonymizedCounts(perMessage); len(messages) > 0 {
+		analytics.Fields = append(analytics.Fields, &discordgo.MessageEmbedField{
+			Name:  "Top messages",
+			Value: topCounts(messages, func(link string) string { return "[message](" + link + ")" }),
+		})
+	}
+	return analytics
+}
+
+func addMember(sets map[string]map[string]bool, key, userID string) {
+	if sets[key] == nil {
+		sets[key] = map[string]bool{}
+	}
+	sets[key][userID] = true
+}
+
+// anonymizedCounts counts the members of each set, leaving out sets of
+// fewer than ANALYTICS_MIN_MEMBERS.
+func anonymizedCounts(sets map[string]map[string]bool) map[string]int {
+	counts := map[string]int{}
+	for key, members := range sets {
+		if len(members) >= ANALYTICS_MIN_MEMBERS {
+			counts[key] = len(members)
+		}
+	}
+	return counts
+}
+
+// guildAnalyticsPrivacyCommand turns /bookmarker analytics on or off for the guild.
+func (b *Bot) guildAnalyticsPrivacyCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	if i.GuildID == "" {
+		b.respondEphemeral(i, "Use this command in your server.")
+		return
+	}
+
+	disabled := opts["analytics"].StringValue() == "off"
+	settings := b.store.GuildSettings(i.GuildID)
+	settings.AnalyticsDisabled = disabled
+	err := b.store.SetGuildSettings(i.GuildID, settings)
+	if err != nil {
+		b.logger.Printf("Error saving settings of guild %s: %v", i.GuildID, err)
+		b.recordFailure(alertStore, "", err)
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	b.audit(store.AuditEntry{Action: store.AuditGuild, ActorID: interactionUser(i).ID, GuildID: i.GuildID, Detail: fmt.Sprintf("analytics disabled: %t", disabled)})
+	b.logger.Printf("User %s set analytics of guild %s disabled: %t", interactionUser(i).ID, i.GuildID, disabled)
+	if disabled {
+		b.respondEphemeral(i, "Bookmark analytics are turned off for this server.")
+	} else {
+		b.respondEphemeral(i, "Admins can see anonymized bookmark counts of this server with `/bookmarker analytics`.")
+	}
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+)
+
+func addGuildBookmark(t *testing.T, b *Bot, userID, channelID, messageID, content string, createdAt time.Time) {
+	t.Helper()
+	err := b.store.Add(store.Bookmark{UserID: userID, GuildID: "guild", ChannelID: channelID, MessageID: messageID, Content: content, CreatedAt: createdAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnalyticsShowsOnlyAnonymizedCounts(t *testing.T) {
+	b, api := newTestBot(t)
+	now := time.Now()
+	for _, user := range []string{"member-ann", "member-ben", "member-cid"} {
+		addGuildBookmark(t, b, user, "popular", "hit", "secret recipe", now)
+	}
+	addGuildBookmark(t, b, "member-ann", "quiet", "niche", "private thoughts", now)
+	addGuildBookmark(t, b, "member-dan", "popular", "old", "old news", now.AddDate(0, 0, -60))
+
+	guildAdminCommand(b, "analytics")
+	analytics := api.responses[len(api.responses)-1].Data.Embeds[0]
+	fields := map[string]string{}
+	for _, field := range analytics.Fields {
+		fields[field.Name] = field.Value
+	}
+	if fields["Bookmarks"] != "4" || fields["Members bookmarking"] != "3" {
+		t.Errorf("counts = %q and %q, want 4 bookmarks by 3 members in the window", fields["Bookmarks"], fields["Members bookmarking"])
+	}
+	if fields["Top channels"] != "<#popular> — 3" {
+		t.Errorf("top channels = %q, want only the channel bookmarked by 3 members", fields["Top channels"])
+	}
+	if !strings.Contains(fields["Top messages"], "/guild/popular/hit) — 3") || strings.Contains(fields["Top messages"], "niche") {
+		t.Errorf("top messages = %q", fields["Top messages"])
+	}
+	for _, field := range analytics.Fields {
+		for _, secret := range []string{"recipe", "private", "member-"} {
+			if strings.Contains(field.Value, secret) {
+				t.Errorf("analytics expose %q: %q", secret, field.Value)
+			}
+		}
+	}
+}
+
+func TestAnalyticsNeedEnoughMembers(t *testing.T) {
+	b, api := newTestBot(t)
+	addGuildBookmark(t, b, "ann", "channel", "message", "", time.Now())
+
+	guildAdminCommand(b, "analytics")
+	analytics := api.responses[len(api.responses)-1].Data.Embeds[0]
+	if len(analytics.Fields) != 0 || !strings.HasPrefix(analytics.Description, "Not enough members") {
+		t.Errorf("analytics = %+v, want no counts", analytics)
+	}
+}
+
+func TestAnalyticsPrivacyToggle(t *testing.T) {
+	b, api := newTestBot(t)
+
+	guildAdminCommand(b, "analytics-privacy", stringOption("analytics", "off"))
+	if !b.store.GuildSettings("guild").AnalyticsDisabled {
+		t.Fatal("analytics weren't turned off")
+	}
+	guildAdminCommand(b, "analytics")
+	if resp := api.responses[len(api.responses)-1]; len(resp.Data.Embeds) != 0 || !strings.Contains(resp.Data.Content, "turned off") {
+		t.Errorf("response = %+v, want a refusal", resp.Data)
+	}
+
+	guildAdminCommand(b, "analytics-privacy", stringOption("analytics", "on"))
+	if !b.store.GuildSettings("guild").IsZero() {
+		t.Error("analytics weren't turned back on")
+	}
+}
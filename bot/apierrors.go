@@ -0,0 +1,182 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	API_MAX_ATTEMPTS = 4
+	API_BACKOFF_BASE = time.Second
+	API_BACKOFF_MAX  = 30 * time.Second
+)
+
+// apiErrorClass groups Discord API failures by how the bot should react.
+type apiErrorClass int
+
+const (
+	// apiErrorUnknown is anything unexpected, e.g. a bad request or an
+	// invalid token, and is reported to the owner.
+	apiErrorUnknown apiErrorClass = iota
+	// apiErrorRateLimited and apiErrorTransient are retried with backoff.
+	apiErrorRateLimited
+	apiErrorTransient
+	// apiErrorForbidden means the bot lacks permissions or the user closed
+	// their DMs; only the user can fix it.
+	apiErrorForbidden
+	// apiErrorNotFound means the target is gone and the work is dropped.
+	apiErrorNotFound
+)
+
+func (c apiErrorClass) String() string {
+	switch c {
+	case apiErrorRateLimited:
+		return "rate limited"
+	case apiErrorTransient:
+		return "transient"
+	case apiErrorForbidden:
+		return "forbidden"
+	case apiErrorNotFound:
+		return "not found"
+	}
+	return "unknown"
+}
+
+// classifyAPIError tells what kind of failure a discordgo REST call returned.
+func classifyAPIError(err error) apiErrorClass {
+	var rateLimit *discordgo.RateLimitError
+	if errors.As(err, &rateLimit) {
+		return apiErrorRateLimited
+	}
+
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) {
+		if restErr.Message != nil {
+			switch restErr.Message.Code {
+			case discordgo.ErrCodeMissingAccess, discordgo.ErrCodeMissingPermissions, discordgo.ErrCodeCannotSendMessagesToThisUser:
+				return apiErrorForbidden
+			case discordgo.ErrCodeUnknownChannel, discordgo.ErrCodeUnknownGuild, discordgo.ErrCodeUnknownMessage, discordgo.ErrCodeUnknownUser:
+				return apiErrorNotFound
+			}
+		}
+		if restErr.Response == nil {
+			return apiErrorUnknown
+		}
+		switch status := restErr.Response.StatusCode; {
+		case status == http.StatusTooManyRequests:
+			return apiErrorRateLimited
+		case status == http.StatusForbidden:
+			return apiErrorForbidden
+		case status == http.StatusNotFound:
+			return apiErrorNotFound
+		case status >= 500:
+			return apiErrorTransient
+		}
+		return apiErrorUnknown
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return apiErrorTransient
+	}
+	return apiErrorUnknown
+}
+
+// retryDelay returns how long to wait before the given retry (1-based),
+// honoring the delay Discord asks for on rate limits.
+func retryDelay(err error, attempt int) time.Duration {
+	var rateLimit *discordgo.RateLimitError
+	if errors.As(err, &rateLimit) && rateLimit.TooManyRequests != nil && rateLimit.RetryAfter > 0 {
+		return rateLimit.RetryAfter
+	}
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		if seconds, err := strconv.ParseFloat(restErr.Response.Header.Get("Retry-After"), 64); err == nil && seconds > 0 {
+			return time.Duration(seconds * float64(time.Second))
+		}
+	}
+
+	delay := API_BACKOFF_BASE << (attempt - 1)
+	if delay > API_BACKOFF_MAX || delay <= 0 {
+		delay = API_BACKOFF_MAX
+	}
+	return delay
+}
+
+// sleepFor waits between retries; tests replace it to run instantly.
+var sleepFor = func(done <-chan struct{}, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// withRetry runs a REST call, retrying rate limits and transient failures
+// with exponential backoff. Other errors are returned at once, as is the
+// last error when the attempts run out or the bot shuts down.
+func withRetry[T any](b *Bot, call func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := call()
+		if err == nil {
+			return result, nil
+		}
+
+		class := classifyAPIError(err)
+		if (class != apiErrorRateLimited && class != apiErrorTransient) || attempt == API_MAX_ATTEMPTS {
+			return result, err
+		}
+
+		delay := retryDelay(err, attempt)
+		b.logger.Printf("Discord API call failed (%s), retrying in %s: %v", class, delay, err)
+		if !sleepFor(b.done, delay) {
+			return result, err
+		}
+	}
+}
+
+// withRetryErr is withRetry for calls that only return an error.
+func withRetryErr(b *Bot, call func() error) error {
+	_, err := withRetry(b, func() (struct{}, error) { return struct{}{}, call() })
+	return err
+}
+
+// send posts a message, retrying transient failures. Uploaded files are
+// rewound before every attempt, since each one consumes them.
+func (b *Bot) send(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error) {
+	return withRetry(b, func() (*discordgo.Message, error) {
+		for _, f := range data.Files {
+			if seeker, ok := f.Reader.(io.Seeker); ok {
+				seeker.Seek(0, io.SeekStart)
+			}
+		}
+		return b.api.ChannelMessageSendComplex(channelID, data)
+	})
+}
+
+// reportDeliveryError logs a failed bookmark delivery and tells whoever can
+// fix it: the user about an archive channel the bot can't post to, the owner
+// about unexpected errors. Missing targets and exhausted retries are dropped.
+func (b *Bot) reportDeliveryError(user *discordgo.User, err error) {
+	class := classifyAPIError(err)
+	b.logger.Printf("Error delivering bookmark to user %s (%s), %s: %v", user.Username, user.ID, class, err)
+	b.recordFailure(alertDelivery, user.ID, err)
+
+	archiveChannelID := b.store.Settings(user.ID).ArchiveChannelID
+	switch {
+	case archiveChannelID != "" && (class == apiErrorForbidden || class == apiErrorNotFound):
+		b.notifyUser(user.ID, fmt.Sprintf("I couldn't post your bookmark in <#%s>. Check my permissions there, or run `/bookmarks archive` without a channel to get bookmarks in your DMs again.", archiveChannelID))
+	case class == apiErrorUnknown:
+		b.recordFailure(alertAPI, user.ID, err)
+	}
+}
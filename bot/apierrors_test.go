@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+func restError(status, code int) error {
+	err := &discordgo.RESTError{Response: &http.Response{StatusCode: status, Header: http.Header{}}}
+	if code != 0 {
+		err.Message = &discordgo.APIErrorMessage{Code: code}
+	}
+	return err
+}
+
+// noSleep makes retries run instantly for the duration of a test.
+func noSleep(t *testing.T) {
+	t.Helper()
+	orig := sleepFor
+	sleepFor = func(done <-chan struct{}, d time.Duration) bool { return true }
+	t.Cleanup(func() { sleepFor = orig })
+}
+
+func TestClassifyAPIError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want apiErrorClass
+	}{
+		{&discordgo.RateLimitError{RateLimit: &discordgo.RateLimit{TooManyRequests: &discordgo.TooManyRequests{}}}, apiErrorRateLimited},
+		{restError(http.StatusTooManyRequests, 0), apiErrorRateLimited},
+		{restError(http.StatusBadGateway, 0), apiErrorTransient},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, apiErrorTransient},
+		{restError(http.StatusForbidden, discordgo.ErrCodeCannotSendMessagesToThisUser), apiErrorForbidden},
+		{restError(http.StatusBadRequest, discordgo.ErrCodeMissingPermissions), apiErrorForbidden},
+		{restError(http.StatusNotFound, discordgo.ErrCodeUnknownMessage), apiErrorNotFound},
+		{restError(http.StatusUnauthorized, 0), apiErrorUnknown},
+		{errors.New("boom"), apiErrorUnknown},
+	}
+	for _, tt := range tests {
+		if got := classifyAPIError(tt.err); got != tt.want {
+			t.Errorf("classifyAPIError(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	limited := restError(http.StatusTooManyRequests, 0).(*discordgo.RESTError)
+	limited.Response.Header.Set("Retry-After", "2.5")
+	if d := retryDelay(limited, 1); d != 2500*time.Millisecond {
+		t.Errorf("retryDelay honoring Retry-After = %s, want 2.5s", d)
+	}
+	if d := retryDelay(restError(http.StatusBadGateway, 0), 3); d != 4*API_BACKOFF_BASE {
+		t.Errorf("retryDelay of third attempt = %s, want %s", d, 4*API_BACKOFF_BASE)
+	}
+	if d := retryDelay(restError(http.StatusBadGateway, 0), 20); d != API_BACKOFF_MAX {
+		t.Errorf("retryDelay = %s, want it capped at %s", d, API_BACKOFF_MAX)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	noSleep(t)
+	b, _ := newTestBot(t)
+
+	calls := 0
+	err := withRetryErr(b, func() error {
+		calls++
+		if calls < 3 {
+			return restError(http.StatusServiceUnavailable, 0)
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("transient failures: err = %v after %d calls, want success after 3", err, calls)
+	}
+
+	calls = 0
+	err = withRetryErr(b, func() error {
+		calls++
+		return restError(http.StatusNotFound, discordgo.ErrCodeUnknownChannel)
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("not found: err = %v after %d calls, want failure after 1", err, calls)
+	}
+
+	calls = 0
+	withRetryErr(b, func() error {
+		calls++
+		return restError(http.StatusBadGateway, 0)
+	})
+	if calls != API_MAX_ATTEMPTS {
+		t.Errorf("made %d attempts, want %d", calls, API_MAX_ATTEMPTS)
+	}
+}
+
+func TestSendRewindsFilesOnRetry(t *testing.T) {
+	noSleep(t)
+	b, api := newTestBot(t)
+	api.sendErrors["dm-user"] = []error{restError(http.StatusInternalServerError, 0)}
+
+	file := &discordgo.File{Name: "cat.png", Reader: strings.NewReader("meow")}
+	data := &discordgo.MessageSend{Files: []*discordgo.File{file}}
+	// The failed attempt consumes the file.
+	io.ReadAll(file.Reader)
+
+	if _, err := b.send("dm-user", data); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if content, _ := io.ReadAll(file.Reader); string(content) != "meow" {
+		t.Errorf("file content at second attempt = %q, want it rewound", content)
+	}
+}
+
+func TestReactionAddReportsForbiddenArchiveChannel(t *testing.T) {
+	b, api := newTestBot(t)
+	b.store.SetSettings("user", store.UserSettings{ArchiveChannelID: "archive"})
+	api.sendErrors["archive"] = []error{restError(http.StatusForbidden, discordgo.ErrCodeMissingPermissions)}
+	api.addMessage(sourceMessage())
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(api.sent) != 1 || api.sent[0].ChannelID != "dm-user" || !strings.Contains(api.sent[0].Message.Content, "<#archive>") {
+		t.Fatalf("sent %+v, want a DM about the archive channel", api.sent)
+	}
+	if len(b.store.ForUser("user")) != 0 {
+		t.Error("undelivered bookmark was stored")
+	}
+}
+
+func TestReactionAddAlertsOwnerOnUnexpectedError(t *testing.T) {
+	b, api := newTestBot(t)
+	b.setOwner("owner")
+	api.sendErrors["dm-user"] = []error{restError(http.StatusUnauthorized, 0)}
+	api.addMessage(sourceMessage())
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	b.sendDueAlerts(time.Now())
+
+	if len(api.sent) != 1 || api.sent[0].ChannelID != "dm-owner" || !strings.Contains(api.sent[0].Message.Content, "Unexpected Discord API errors") {
+		t.Fatalf("sent %+v, want an alert to the owner", api.sent)
+	}
+}
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// MESSAGE_MAX_FILES is Discord's limit on uploads per message.
+const MESSAGE_MAX_FILES = 10
+
+var archiveClient = &http.Client{Timeout: 30 * time.Second}
+
+// archiveAttachments downloads the message's attachments, up to maxBytes in
+// total, so they can be re-uploaded with the bookmark. Attachments that don't
+// fit or fail to download are left as links. names maps the IDs of the
+// archived attachments to their upload's name, unique within the message.
+func (b *Bot) archiveAttachments(msg *discordgo.Message, maxBytes int64) (files []*discordgo.File, names map[string]string) {
+	names = map[string]string{}
+	used := map[string]bool{}
+	var total int64
+
+	for _, a := range msg.Attachments {
+		if total+int64(a.Size) > maxBytes {
+			b.logger.Printf("Skipping archive of attachment %s (%d bytes): size cap of %d bytes reached", a.Filename, a.Size, maxBytes)
+			continue
+		}
+
+		data, err := downloadAttachment(a.URL, maxBytes-total)
+		if err != nil {
+			b.logger.Printf("Error archiving attachment %s of message %s: %v", a.Filename, msg.ID, err)
+			continue
+		}
+
+		name := a.Filename
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%d_%s", n, a.Filename)
+		}
+		used[name] = true
+		names[a.ID] = name
+
+		total += int64(len(data))
+		files = append(files, &discordgo.File{
+			Name:        name,
+			ContentType: a.ContentType,
+			Reader:      bytes.NewReader(data),
+		})
+	}
+
+	return files, names
+}
+
+func downloadAttachment(url string, limit int64) ([]byte, error) {
+	resp, err := archiveClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("attachment exceeds %d bytes", limit)
+	}
+
+	return data, nil
+}
+
+// useArchivedFiles points the embed at re-uploaded copies of its
+// attachments, named by attachment ID as archiveAttachments returns them.
+func useArchivedFiles(embed *discordgo.MessageEmbed, msg *discordgo.Message, names map[string]string) {
+	for _, a := range msg.Attachments {
+		name, ok := names[a.ID]
+		if !ok {
+			continue
+		}
+		if embed.Image != nil && embed.Image.URL == a.URL {
+			embed.Image.URL = "attachment://" + name
+		}
+		for _, field := range embed.Fields {
+			if strings.HasSuffix(field.Value, "("+a.URL+")") {
+				field.Value += " · archived copy attached"
+			}
+		}
+	}
+}
+
+// reuploadFiles downloads the files of a bookmark message for a copy of it,
+// and points its embeds at the copies' uploads.
+func reuploadFiles(msg *discordgo.Message) ([]*discordgo.File, error) {
+	var files []*discordgo.File
+	for _, a := range msg.Attachments {
+		data, err := downloadAttachment(a.URL, int64(a.Size))
+		if err != nil {
+			return nil, fmt.Errorf("downloading %s: %w", a.Filename, err)
+		}
+		files = append(files, &discordgo.File{
+			Name:        a.Filename,
+			ContentType: a.ContentType,
+			Reader:      bytes.NewReader(data),
+		})
+
+		for _, e := range msg.Embeds {
+			if e.Image != nil && e.Image.URL == a.URL {
+				e.Image.URL = "attachment://" + a.Filename
+			}
+		}
+	}
+	return files, nil
+}
+
+// contentFile holds the full text of a message too long for its bookmark embed.
+func contentFile(msg *discordgo.Message, spoiler bool) *discordgo.File {
+	name := fmt.Sprintf("message-%s.txt", msg.ID)
+	if spoiler {
+		name = "SPOILER_" + name
+	}
+	return &discordgo.File{
+		Name:        name,
+		ContentType: "text/plain; charset=utf-8",
+		Reader:      strings.NewReader(msg.Content),
+	}
+}
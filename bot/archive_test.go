@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestArchivedAttachmentsWithTheSameName(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	b, _ := newTestBot(t)
+	msg := sourceMessage()
+	msg.Attachments = []*discordgo.MessageAttachment{
+		{ID: "first", Filename: "image.png", URL: srv.URL + "/first", Size: 6},
+		{ID: "second", Filename: "image.png", URL: srv.URL + "/second", Size: 7},
+	}
+
+	files, names := b.archiveAttachments(msg, 1024)
+	if len(files) != 2 || files[0].Name == files[1].Name {
+		t.Fatalf("uploads = %v, want two with different names", names)
+	}
+	second, _ := io.ReadAll(files[1].Reader)
+	if files[1].Name != names["second"] || string(second) != "/second" {
+		t.Errorf("upload %s holds %q, want the second attachment", files[1].Name, second)
+	}
+
+	e := &discordgo.MessageEmbed{Image: &discordgo.MessageEmbedImage{URL: msg.Attachments[1].URL}}
+	useArchivedFiles(e, msg, names)
+	if e.Image.URL != "attachment://"+names["second"] {
+		t.Errorf("embed image = %q, want the second upload", e.Image.URL)
+	}
+}
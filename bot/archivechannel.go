@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// ARCHIVE_THREAD_DURATION is the auto-archive duration of archive
+	// threads, in minutes. Posting a bookmark reopens an archived thread.
+	ARCHIVE_THREAD_DURATION = 10080
+	THREAD_NAME_MAX_LENGTH  = 100
+)
+
+// sendBookmark posts a bookmark to the user's DMs or, if they configured
+// one, their archive channel or the matching thread of it. A thread that
+// can't be posted to anymore, e.g. because it was deleted, is replaced once.
+func (b *Bot) sendBookmark(user *discordgo.User, guild *discordgo.Guild, tags []string, send *discordgo.MessageSend) (*discordgo.Message, error) {
+	settings := b.store.Settings(user.ID)
+	if settings.ArchiveChannelID == "" {
+		dmChannel, err := b.dmChannel(user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("creating DM channel: %w", err)
+		}
+		return b.send(dmChannel.ID, send)
+	}
+
+	if settings.ArchiveThreads == store.ThreadsNone {
+		return b.send(settings.ArchiveChannelID, send)
+	}
+
+	key, name := archiveThreadKey(settings.ArchiveThreads, guild, tags)
+	threadID, cached := b.store.ArchiveThread(user.ID, key)
+	if cached {
+		sent, err := b.send(threadID, send)
+		if err == nil {
+			return sent, nil
+		}
+		b.logger.Printf("Error posting to archive thread %s of user %s, creating a new one: %v", threadID, user.ID, err)
+	}
+
+	thread, err := withRetry(b, func() (*discordgo.Channel, error) {
+		return b.api.ThreadStart(settings.ArchiveChannelID, name, discordgo.ChannelTypeGuildPublicThread, ARCHIVE_THREAD_DURATION)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating archive thread %q: %w", name, err)
+	}
+
+	err = b.store.SetArchiveThread(user.ID, key, thread.ID)
+	if err != nil {
+		b.logger.Printf("Error storing archive thread of user %s: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+	}
+
+	return b.send(thread.ID, send)
+}
+
+// archiveThreadKey returns the store key and the name of the archive thread
+// a bookmark belongs in. Untagged bookmarks share one thread in tag mode.
+func archiveThreadKey(mode store.ThreadMode, guild *discordgo.Guild, tags []string) (key, name string) {
+	switch {
+	case mode == store.ThreadsTag && len(tags) > 0:
+		key, name = "tag:"+tags[0], "#"+tags[0]
+	case mode == store.ThreadsTag:
+		key, name = "tag:", "Untagged"
+	default:
+		key, name = "guild:"+guild.ID, guild.Name
+	}
+
+	if runes := []rune(name); len(runes) > THREAD_NAME_MAX_LENGTH {
+		name = string(runes[:THREAD_NAME_MAX_LENGTH])
+	}
+	return key, name
+}
+
+func (b *Bot) archiveChannelCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+	settings := b.store.Settings(user.ID)
+
+	opt, ok := opts["channel"]
+	if !ok {
+		settings.ArchiveChannelID = ""
+		settings.ArchiveThreads = store.ThreadsNone
+		b.saveArchiveSettings(i, user.ID, settings, "New bookmarks will be sent to your DMs again.")
+		return
+	}
+
+	if i.GuildID == "" {
+		b.respondEphemeral(i, "Archive channels can only be set up from a server.")
+		return
+	}
+
+	channelID := opt.Value.(string)
+	required := int64(discordgo.PermissionViewChannel | discordgo.PermissionSendMessages)
+	if !b.memberHasPermissions(i.GuildID, i.Member, channelID, required) {
+		b.respondEphemeral(i, "You can't send messages in that channel.")
+		return
+	}
+
+	mode := store.ThreadsNone
+	if opt, ok := opts["threads"]; ok && opt.StringValue() != "none" {
+		mode = store.ThreadMode(opt.StringValue())
+	}
+
+	// Threads of a previous archive channel can't be reused.
+	if settings.ArchiveChannelID != channelID {
+		b.forgetArchiveThreads(user.ID)
+	}
+
+	settings.ArchiveChannelID = channelID
+	settings.ArchiveThreads = mode
+
+	reply := fmt.Sprintf("New bookmarks will be posted to <#%s>", channelID)
+	switch mode {
+	case store.ThreadsGuild:
+		reply += ", in one thread per server"
+	case store.ThreadsTag:
+		reply += ", in one thread per tag"
+	}
+	reply += ". Anyone who can read that channel will see them."
+	b.saveArchiveSettings(i, user.ID, settings, reply)
+}
+
+func (b *Bot) saveArchiveSettings(i *discordgo.InteractionCreate, userID string, settings store.UserSettings, reply string) {
+	err := b.store.SetSettings(userID, settings)
+	if err != nil {
+		b.logger.Printf("Error saving settings of user %s: %v", userID, err)
+		b.recordFailure(alertStore, "", err)
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+	b.respondEphemeral(i, reply)
+}
+
+// forgetArchiveThreads drops the remembered archive threads of the user's
+// bookmarked guilds and tags.
+func (b *Bot) forgetArchiveThreads(userID string) {
+	keys := map[string]bool{"tag:": true}
+	for _, bm := range b.store.ForUser(userID) {
+		keys["guild:"+bm.GuildID] = true
+		for _, tag := range bm.Tags {
+			keys["tag:"+tag] = true
+		}
+	}
+
+	for key := range keys {
+		if _, ok := b.store.ArchiveThread(userID, key); !ok {
+			continue
+		}
+		err := b.store.SetArchiveThread(userID, key, "")
+		if err != nil {
+			b.logger.Printf("Error forgetting archive thread of user %s: %v", userID, err)
+			b.recordFailure(alertStore, "", err)
+		}
+	}
+}
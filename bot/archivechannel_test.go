@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestArchiveChannelThreadPerGuild(t *testing.T) {
+	b, api := newTestBot(t)
+	err := b.store.SetSettings("user", store.UserSettings{ArchiveChannelID: "archive", ArchiveThreads: store.ThreadsGuild})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := sourceMessage()
+	second := sourceMessage()
+	second.ID = "message-2"
+	second.Content = "another message"
+	api.addMessage(first)
+	api.addMessage(second)
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	r := bookmarkReaction(BOOKMARK_EMOJI)
+	r.MessageID = second.ID
+	b.ReactionAdd(r)
+
+	if len(api.threads) != 1 || api.threads[0].Name != "Test Guild" || api.threads[0].ParentID != "archive" {
+		t.Fatalf("threads = %+v, want one for Test Guild in the archive channel", api.threads)
+	}
+	threadID := api.threads[0].ID
+	if len(api.sent) != 2 || api.sent[0].ChannelID != threadID || api.sent[1].ChannelID != threadID {
+		t.Fatalf("sent %+v, want both bookmarks in %s", api.sent, threadID)
+	}
+
+	bm, ok := b.store.Get("user", "channel", "message")
+	if !ok || bm.DMChannelID != threadID {
+		t.Fatalf("stored bookmark = %+v", bm)
+	}
+
+	// Actions work on bookmarks in the archive thread, for their owner only.
+	b.state.ChannelAdd(&discordgo.Channel{ID: threadID, GuildID: "guild", ParentID: "archive", Type: discordgo.ChannelTypeGuildPublicThread})
+	del := &discordgo.MessageReactionAdd{MessageReaction: &discordgo.MessageReaction{
+		UserID: "someone-else", ChannelID: threadID, MessageID: bm.DMMessageID, GuildID: "guild",
+		Emoji: discordgo.Emoji{Name: DELETE_EMOJI},
+	}}
+	b.DMReactionAdd(del)
+	if len(api.deleted) != 0 {
+		t.Fatalf("another user deleted the bookmark")
+	}
+
+	del.UserID = "user"
+	b.DMReactionAdd(del)
+	if len(api.deleted) != 1 || b.store.Has("user", "channel", "message") {
+		t.Errorf("bookmark not deleted: deleted %v", api.deleted)
+	}
+}
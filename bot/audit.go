@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/msglink"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// AUDIT_PAGE_SIZE is how many entries /bookmarks audit shows.
+const AUDIT_PAGE_SIZE = 25
+
+// audit records an action in the audit log.
+func (b *Bot) audit(e store.AuditEntry) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	if err := b.store.AddAudit(e); err != nil {
+		b.logger.Printf("Error recording %s action of user %s in the audit log: %v", e.Action, e.ActorID, err)
+		b.recordFailure(alertStore, "", err)
+	}
+}
+
+// auditBookmark records an action on a single bookmark.
+func (b *Bot) auditBookmark(action store.AuditAction, actorID string, bm store.Bookmark) {
+	b.audit(store.AuditEntry{
+		Action:    action,
+		ActorID:   actorID,
+		UserID:    bm.UserID,
+		GuildID:   bm.GuildID,
+		ChannelID: bm.ChannelID,
+		MessageID: bm.MessageID,
+	})
+}
+
+// auditCommand shows the owner the newest audit log entries matching the filters.
+func (b *Bot) auditCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	if !b.isOwner(interactionUser(i).ID) {
+		b.respondEphemeral(i, "Only the bot owner can view the audit log.")
+		return
+	}
+
+	f := store.AuditFilter{Limit: AUDIT_PAGE_SIZE + 1}
+	if opt, ok := opts["user_id"]; ok {
+		f.UserID = strings.TrimSpace(opt.StringValue())
+	}
+	if opt, ok := opts["guild"]; ok {
+		f.GuildID = strings.TrimSpace(opt.StringValue())
+	}
+	for name, dest := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+		opt, ok := opts[name]
+		if !ok {
+			continue
+		}
+		day, err := time.Parse(CLEAR_DATE_LAYOUT, strings.TrimSpace(opt.StringValue()))
+		if err != nil {
+			b.respondEphemeral(i, fmt.Sprintf("`%s` must be a date like 2024-01-31.", name))
+			return
+		}
+		*dest = day
+	}
+	if !f.Until.IsZero() {
+		// Until is inclusive of the whole day.
+		f.Until = f.Until.Add(24 * time.Hour)
+	}
+
+	entries, err := b.store.AuditLog(f)
+	if err != nil {
+		b.logger.Printf("Error querying the audit log: %v", err)
+		b.respondEphemeral(i, "I couldn't read the audit log, please check the logs.")
+		return
+	}
+
+	b.respondEmbed(i, auditEmbed(entries), discordgo.MessageFlagsEphemeral)
+}
+
+func auditEmbed(entries []store.AuditEntry) *discordgo.MessageEmbed {
+	e := &discordgo.MessageEmbed{Title: "Audit log", Color: embed.DEFAULT_EMBED_COLOR}
+	if len(entries) == 0 {
+		e.Description = "No matching entries."
+		return e
+	}
+	if len(entries) > AUDIT_PAGE_SIZE {
+		entries = entries[:AUDIT_PAGE_SIZE]
+		e.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Showing the newest %d entries; narrow the filters to see older ones.", AUDIT_PAGE_SIZE)}
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, auditLine(entry))
+	}
+	e.Description = joinLimited(lines, embed.DESCRIPTION_MAX_LENGTH)
+	return e
+}
+
+// auditLine formats an entry like "<time> **delete** by @owner for @user ·
+// server `123` · message".
+func auditLine(e store.AuditEntry) string {
+	line := fmt.Sprintf("<t:%d:f> **%s** by %s", e.At.Unix(), e.Action, auditActor(e.ActorID))
+	if e.UserID != "" && e.UserID != e.ActorID {
+		line += fmt.Sprintf(" for <@%s>", e.UserID)
+	}
+	if e.MessageID != "" {
+		line += fmt.Sprintf(" · [message](%s)", msglink.New(e.GuildID, e.ChannelID, e.MessageID))
+	} else if e.GuildID != "" {
+		line += fmt.Sprintf(" · server `%s`", e.GuildID)
+	}
+	if e.Detail != "" {
+		line += " · " + e.Detail
+	}
+	return line
+}
+
+// auditActor mentions a user; the command line tools aren't one.
+func auditActor(id string) string {
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return "`" + id + "`"
+	}
+	return "<@" + id + ">"
+}
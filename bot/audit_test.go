@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuditCommandListsBookmarkActions(t *testing.T) {
+	b, api := newTestBot(t)
+	b.setOwner("owner")
+	r := deliverTestBookmark(t, b, api)
+
+	clickComponent(b, "user", r.MessageID, "bookmark:confirm:"+r.MessageID)
+	if len(b.store.ForUser("user")) != 0 {
+		t.Fatal("bookmark not deleted")
+	}
+
+	userCommand(b, "owner", "audit", stringOption("user_id", "user"))
+
+	resp := api.responses[len(api.responses)-1]
+	if len(resp.Data.Embeds) != 1 {
+		t.Fatalf("response %+v, want one embed", resp.Data)
+	}
+	lines := strings.Split(resp.Data.Embeds[0].Description, "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "**delete**") || !strings.Contains(lines[1], "**create**") {
+		t.Errorf("audit log:\n%s\nwant the delete, then the create", resp.Data.Embeds[0].Description)
+	}
+}
+
+func TestAuditCommandIsOwnerOnly(t *testing.T) {
+	b, api := newTestBot(t)
+	b.setOwner("someone else")
+
+	userCommand(b, "owner", "audit")
+
+	if len(api.responses) != 1 || len(api.responses[0].Data.Embeds) != 0 {
+		t.Errorf("responses = %+v, want a refusal", api.responses)
+	}
+}
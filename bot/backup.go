@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// BACKUP_UPLOAD_MAX_BYTES is Discord's default upload limit.
+	BACKUP_UPLOAD_MAX_BYTES = 10 << 20
+	// BACKUP_MAX_BYTES caps the download of a backup to restore.
+	BACKUP_MAX_BYTES = 100 << 20
+)
+
+// backupCommand sends the owner a gzip-compressed copy of the whole store.
+func (b *Bot) backupCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	if !b.isOwner(interactionUser(i).ID) {
+		b.respondEphemeral(i, "Only the bot owner can make backups.")
+		return
+	}
+	if !b.deferEphemeral(i) {
+		return
+	}
+
+	snapshot, err := b.store.Snapshot()
+	if err != nil {
+		b.logger.Printf("Error reading store for backup: %v", err)
+		b.recordFailure(alertStore, "", err)
+		b.editResponse(i, "I couldn't read the store, please check the logs.")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := store.WriteSnapshot(&buf, snapshot); err != nil {
+		b.logger.Printf("Error writing backup: %v", err)
+		b.editResponse(i, "I couldn't write the backup, please check the logs.")
+		return
+	}
+	if buf.Len() > BACKUP_UPLOAD_MAX_BYTES {
+		b.editResponse(i, fmt.Sprintf("The backup is %d MiB, too large to upload. Run `discord-bookmarker backup` on the host instead.", buf.Len()>>20))
+		return
+	}
+
+	content := fmt.Sprintf("Backup of %d bookmark(s) from %d user(s).", len(snapshot.Bookmarks), countUsers(snapshot.Bookmarks))
+	_, err = b.api.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+		Files: []*discordgo.File{{
+			Name:        "bookmarks-" + snapshot.CreatedAt.UTC().Format("20060102-150405") + ".json.gz",
+			ContentType: "application/gzip",
+			Reader:      &buf,
+		}},
+	})
+	if err != nil {
+		b.logger.Printf("Error uploading backup for interaction %s: %v", i.ID, err)
+		return
+	}
+	b.audit(store.AuditEntry{Action: store.AuditExport, ActorID: interactionUser(i).ID, Detail: fmt.Sprintf("backup of %d bookmark(s)", len(snapshot.Bookmarks))})
+	b.logger.Printf("Sent backup of %d bookmark(s) to user %s", len(snapshot.Bookmarks), interactionUser(i).ID)
+}
+
+// restoreCommand replaces the whole store with an uploaded backup.
+func (b *Bot) restoreCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	if !b.isOwner(interactionUser(i).ID) {
+		b.respondEphemeral(i, "Only the bot owner can restore backups.")
+		return
+	}
+	if opt, ok := opts["replace"]; !ok || !opt.BoolValue() {
+		b.respondEphemeral(i, "Restoring replaces every stored bookmark; set `replace` to True to confirm.")
+		return
+	}
+
+	opt, ok := opts["file"]
+	if !ok {
+		b.respondEphemeral(i, "Please attach a backup file.")
+		return
+	}
+	attachment, ok := i.ApplicationCommandData().Resolved.Attachments[opt.Value.(string)]
+	if !ok {
+		b.respondEphemeral(i, "I couldn't find the attached file.")
+		return
+	}
+	if !b.deferEphemeral(i) {
+		return
+	}
+
+	data, err := downloadAttachment(attachment.URL, BACKUP_MAX_BYTES)
+	if err != nil {
+		b.logger.Printf("Error downloading backup %s: %v", attachment.Filename, err)
+		b.editResponse(i, "I couldn't download that file.")
+		return
+	}
+
+	snapshot, err := store.ReadSnapshot(bytes.NewReader(data))
+	if err != nil {
+		b.logger.Printf("Error reading backup %s: %v", attachment.Filename, err)
+		b.editResponse(i, "That file isn't a valid backup: "+err.Error())
+		return
+	}
+
+	if _, ok := b.store.(*store.EncryptedStore); !ok && snapshot.Encrypted() {
+		b.editResponse(i, "That backup is encrypted; set the `ENCRYPTION_KEY` it was made with to restore it.")
+		return
+	}
+	if err := b.store.Restore(snapshot); err != nil {
+		b.logger.Printf("Error restoring backup %s: %v", attachment.Filename, err)
+		b.recordFailure(alertStore, "", err)
+		b.editResponse(i, "Restoring the backup failed, please check the logs.")
+		return
+	}
+
+	b.audit(store.AuditEntry{Action: store.AuditImport, ActorID: interactionUser(i).ID, Detail: fmt.Sprintf("restored %d bookmark(s) from %s", len(snapshot.Bookmarks), attachment.Filename)})
+	b.logger.Printf("Restored backup from %s with %d bookmark(s), requested by user %s",
+		snapshot.CreatedAt.Format(time.RFC3339), len(snapshot.Bookmarks), interactionUser(i).ID)
+	b.editResponse(i, fmt.Sprintf("Restored %d bookmark(s) from the backup of <t:%d:f>.", len(snapshot.Bookmarks), snapshot.CreatedAt.Unix()))
+}
+
+func countUsers(bookmarks []store.Bookmark) int {
+	users := map[string]bool{}
+	for _, bm := range bookmarks {
+		users[bm.UserID] = true
+	}
+	return len(users)
+}
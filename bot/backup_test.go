@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// restoreBackup runs /bookmarks restore as the owner with the backup at url.
+func restoreBackup(b *Bot, url string) {
+	i := userCommandInteraction("owner", "restore",
+		&discordgo.ApplicationCommandInteractionDataOption{Type: discordgo.ApplicationCommandOptionAttachment, Name: "file", Value: "backup"},
+		&discordgo.ApplicationCommandInteractionDataOption{Type: discordgo.ApplicationCommandOptionBoolean, Name: "replace", Value: true},
+	)
+	data := i.ApplicationCommandData()
+	data.Resolved = &discordgo.ApplicationCommandInteractionDataResolved{
+		Attachments: map[string]*discordgo.MessageAttachment{
+			"backup": {ID: "backup", Filename: "bookmarks.json.gz", URL: url},
+		},
+	}
+	i.Data = data
+	b.InteractionCreate(i)
+}
+
+func TestBackupAndRestoreCommands(t *testing.T) {
+	b, api := newTestBot(t)
+	b.setOwner("owner")
+	api.addMessage(sourceMessage())
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	userCommand(b, "owner", "backup")
+
+	if len(api.responseEdits) != 1 || len(api.responseEdits[0].Files) != 1 {
+		t.Fatalf("response edits = %+v, want one with the backup file", api.responseEdits)
+	}
+	backup, err := io.ReadAll(api.responseEdits[0].Files[0].Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(backup)
+	}))
+	defer srv.Close()
+
+	// Bookmarks made after the backup are gone once it's restored.
+	b.store.Add(store.Bookmark{UserID: "other", ChannelID: "channel", MessageID: "later"})
+
+	restoreBackup(b, srv.URL)
+
+	if !b.store.Has("user", "channel", "message") {
+		t.Error("bookmark from the backup is missing")
+	}
+	if b.store.Has("other", "channel", "later") {
+		t.Error("bookmark made after the backup survived the restore")
+	}
+}
+
+func TestBackupCommandIsOwnerOnly(t *testing.T) {
+	b, api := newTestBot(t)
+	b.setOwner("someone-else")
+
+	userCommand(b, "owner", "backup")
+
+	if len(api.responseEdits) != 0 {
+		t.Errorf("sent a backup to a user who isn't the owner")
+	}
+	if len(api.responses) != 1 || api.responses[0].Data.Flags != discordgo.MessageFlagsEphemeral {
+		t.Errorf("responses = %+v, want an ephemeral refusal", api.responses)
+	}
+}
+
+func TestRestoreCommandRejectsInvalidFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"user_id": "user"}]`))
+	}))
+	defer srv.Close()
+
+	b, api := newTestBot(t)
+	b.setOwner("owner")
+	b.store.Add(store.Bookmark{UserID: "user", ChannelID: "channel", MessageID: "message"})
+
+	restoreBackup(b, srv.URL)
+
+	if !b.store.Has("user", "channel", "message") {
+		t.Error("store was changed by an invalid backup")
+	}
+	if len(api.responseEdits) != 1 || !strings.Contains(*api.responseEdits[0].Content, "isn't a valid backup") {
+		t.Errorf("response edits = %+v, want an invalid backup message", api.responseEdits)
+	}
+}
@@ -0,0 +1,534 @@
+// Package bot implements the bookmark bot's event and command handlers.
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/audio"
+	"github.com/anonmiraj/discord-bookmarker/config"
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/integration"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/anonmiraj/discord-bookmarker/telemetry"
+	"github.com/anonmiraj/discord-bookmarker/translate"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	BOOKMARK_EMOJI = "🔖"
+	DELETE_EMOJI   = "❌"
+	PIN_EMOJI      = "📌"
+	ARCHIVE_EMOJI  = "📥"
+	RESEND_EMOJI   = "🔁"
+	REFRESH_EMOJI  = "🔄"
+	EXPAND_EMOJI   = "🔽"
+)
+
+// ACTION_EMOJIS are added to every bookmark DM so users can click them.
+var ACTION_EMOJIS = []string{DELETE_EMOJI, PIN_EMOJI, ARCHIVE_EMOJI, RESEND_EMOJI}
+
+// DiscordAPI is the subset of *discordgo.Session REST calls the bot uses.
+type DiscordAPI interface {
+	Application(appID string) (*discordgo.Application, error)
+	Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	ChannelMessage(channelID, messageID string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessages(channelID string, limit int, beforeID, afterID, aroundID string, options ...discordgo.RequestOption) ([]*discordgo.Message, error)
+	ChannelMessagesPinned(channelID string, options ...discordgo.RequestOption) ([]*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEditEmbed(channelID, messageID string, embed *discordgo.MessageEmbed, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageDelete(channelID, messageID string, options ...discordgo.RequestOption) error
+	Guild(guildID string, options ...discordgo.RequestOption) (*discordgo.Guild, error)
+	GuildMember(guildID, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error)
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	MessageReactionAdd(channelID, messageID, emojiID string, options ...discordgo.RequestOption) error
+	MessageReactionRemove(channelID, messageID, emojiID, userID string, options ...discordgo.RequestOption) error
+	MessageReactions(channelID, messageID, emojiID string, limit int, beforeID, afterID string, options ...discordgo.RequestOption) ([]*discordgo.User, error)
+	// Request sends raw requests, for API fields discordgo doesn't model yet.
+	Request(method, urlStr string, data interface{}, options ...discordgo.RequestOption) ([]byte, error)
+	ThreadStart(channelID, name string, typ discordgo.ChannelType, archiveDuration int, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	User(userID string, options ...discordgo.RequestOption) (*discordgo.User, error)
+	UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+}
+
+// Bot holds the dependencies shared by all handlers.
+type Bot struct {
+	api    DiscordAPI
+	state  *discordgo.State
+	store  store.BookmarkStore
+	logger *log.Logger
+	// transcriber transcribes voice messages; nil disables transcription.
+	transcriber audio.Transcriber
+	// translator translates bookmarks into the user's language; nil disables translation.
+	translator translate.Translator
+
+	// reloadMu guards the settings Reload swaps; read them through cfg,
+	// embedTemplate and bookmarkTriggers.
+	reloadMu sync.RWMutex
+	config   config.Config
+	template *embed.Template
+	// triggers are the reactions that bookmark a message.
+	triggers emojiSet
+	// integrationClient posts to users' integrations; it refuses private addresses.
+	integrationClient *http.Client
+
+	users       *lruCache[*discordgo.User]
+	dmChannels  *lruCache[*discordgo.Channel]
+	guildColors *lruCache[guildColor]
+	// resolvedMessages keeps the targets of message commands, which can't be
+	// fetched again in servers the bot was only user-installed in.
+	resolvedMessages *lruCache[*discordgo.Message]
+	// userInstalls keeps by interaction ID whether only a user install
+	// authorized it, see userInstalled.
+	userInstalls *lruCache[bool]
+	// memberRoles keeps the roles of members by "guild:user", see currentRoles.
+	memberRoles *lruCache[memberRoles]
+
+	// ownerID is the bot owner's user ID, from OWNER_ID or the application
+	// info. It's set on every Ready while handlers read it, see owner.
+	ownerID atomic.Pointer[string]
+	// translations tracks the translations running in the background, see
+	// translateLater.
+	translations sync.WaitGroup
+	// ready reports whether the gateway connection is currently established.
+	ready atomic.Bool
+
+	// bookmarkLocks serializes handling of each bookmark, see bookmarkKey.
+	bookmarkLocks keyedMutex
+
+	// done is closed on Shutdown to stop background jobs.
+	done          chan struct{}
+	shutdownOnce  sync.Once
+	digestOnce    sync.Once
+	alertsOnce    sync.Once
+	retentionOnce sync.Once
+	trashOnce     sync.Once
+	presenceOnce  sync.Once
+	dmScanOnce    sync.Once
+	reminderOnce  sync.Once
+	jobsOnce      sync.Once
+
+	// jobs queues background work for the job worker, see enqueue.
+	jobs chan job
+
+	// alerts aggregates operational failures reported to the owner.
+	alerts alerter
+
+	stats *statsCollector
+	// heartbeatLatency reports the gateway latency once registered to a session.
+	heartbeatLatency func() time.Duration
+	// updateStatus sets the bot's "Watching" status once registered to a
+	// session; an empty name clears it.
+	updateStatus func(name string) error
+	// presenceShown reports whether the bookmark count is in the status.
+	presenceShown atomic.Bool
+}
+
+// New creates a bot that talks to Discord through api and reads cached
+// entities from state, which must have its User set once connected.
+func New(api DiscordAPI, state *discordgo.State, st store.BookmarkStore, cfg config.Config, tmpl *embed.Template, logger *log.Logger) *Bot {
+	b := &Bot{
+		api:         api,
+		state:       state,
+		store:       st,
+		config:      cfg,
+		template:    tmpl,
+		logger:      logger,
+		users:       newLRUCache[*discordgo.User](USER_CACHE_SIZE),
+		dmChannels:  newLRUCache[*discordgo.Channel](USER_CACHE_SIZE),
+		guildColors: newLRUCache[guildColor](GUILD_COLOR_CACHE_SIZE),
+		memberRoles: newLRUCache[memberRoles](USER_CACHE_SIZE),
+		done:        make(chan struct{}),
+		jobs:        make(chan job, JOB_QUEUE_SIZE),
+
+		resolvedMessages:  newLRUCache[*discordgo.Message](USER_CACHE_SIZE),
+		userInstalls:      newLRUCache[bool](USER_CACHE_SIZE),
+		integrationClient: integration.NewClient(),
+		stats:             newStatsCollector(time.Now()),
+	}
+	b.triggers = newTriggers(cfg.BookmarkEmojis)
+	if cfg.TranscribeURL != "" {
+		b.transcriber = audio.NewHTTPTranscriber(cfg.TranscribeURL, cfg.TranscribeAPIKey, cfg.TranscribeModel)
+	}
+	if cfg.TranslateProvider != "" {
+		translator, err := translate.New(cfg.TranslateProvider, cfg.TranslateURL, cfg.TranslateAPIKey)
+		if err != nil {
+			logger.Printf("Error setting up translation, bookmarks won't be translated: %v", err)
+		}
+		b.translator = translator
+	}
+	return b
+}
+
+// Register adds the bot's handlers to a session.
+func (b *Bot) Register(s *discordgo.Session) {
+	b.heartbeatLatency = s.HeartbeatLatency
+	b.updateStatus = func(name string) error { return s.UpdateWatchStatus(0, name) }
+	s.AddHandler(func(_ *discordgo.Session, e *discordgo.Event) { b.stats.event(e.Type) })
+	s.AddHandler(func(_ *discordgo.Session, r *discordgo.MessageReactionAdd) { b.ReactionAdd(r) })
+	s.AddHandler(func(_ *discordgo.Session, r *discordgo.MessageReactionAdd) { b.DMReactionAdd(r) })
+	s.AddHandler(func(_ *discordgo.Session, r *discordgo.MessageReactionRemove) { b.DMReactionRemove(r) })
+	s.AddHandler(func(_ *discordgo.Session, m *discordgo.MessageCreate) { b.DMMessageCreate(m) })
+	s.AddHandler(func(_ *discordgo.Session, m *discordgo.MessageUpdate) { b.MessageUpdate(m) })
+	s.AddHandler(func(_ *discordgo.Session, m *discordgo.MessageDelete) { b.MessageDelete(m) })
+	s.AddHandler(func(_ *discordgo.Session, m *discordgo.MessageDeleteBulk) { b.MessageDeleteBulk(m) })
+	// Interactions are handled from the raw event, as discordgo doesn't
+	// decode which installation authorized them.
+	s.AddHandler(func(_ *discordgo.Session, e *discordgo.Event) { b.InteractionEvent(e) })
+	s.AddHandler(func(_ *discordgo.Session, r *discordgo.Ready) { b.Ready(r) })
+	s.AddHandler(func(_ *discordgo.Session, r *discordgo.Resumed) { b.Resumed(r) })
+	s.AddHandler(func(_ *discordgo.Session, d *discordgo.Disconnect) { b.Disconnect(d) })
+	s.AddHandler(func(_ *discordgo.Session, g *discordgo.GuildDelete) { b.GuildDelete(g) })
+	s.AddHandler(func(_ *discordgo.Session, ban *discordgo.GuildBanAdd) { b.GuildBanAdd(ban) })
+}
+
+// Ready runs on every gateway (re)identify.
+func (b *Bot) Ready(r *discordgo.Ready) {
+	b.ready.Store(true)
+	b.registerCommands()
+	b.resolveOwner()
+	b.catchUp()
+	b.startDigests()
+	b.startAlerts()
+	b.startRetention()
+	b.startTrashPurge()
+	b.startPresence()
+	b.startDMScan()
+	b.startReminders()
+	b.startJobs()
+}
+
+func (b *Bot) ReactionAdd(r *discordgo.MessageReactionAdd) {
+	if r.UserID == b.state.User.ID {
+		return
+	}
+
+	// Only guild messages can be bookmarked. Reactions in DMs, including the
+	// bookmark actions DMReactionAdd handles, and group DMs are skipped, as
+	// are other emojis, all without looking anything up: this runs for every
+	// reaction in every guild.
+	if r.GuildID == "" {
+		return
+	}
+	// Super reactions arrive as regular reaction events and match the same way.
+	if !b.bookmarkTriggers().matches(&r.Emoji) {
+		return
+	}
+
+	release, ok := b.claimReaction(r.UserID, r.ChannelID, r.MessageID)
+	if !ok {
+		b.logger.Printf("Bookmark reaction from user %s in channel %s:%s is handled by another instance", r.UserID, r.ChannelID, r.MessageID)
+		return
+	}
+	defer release()
+
+	b.logger.Printf("Processing bookmark reaction from user %s in channel %s:%s", r.UserID, r.ChannelID, r.MessageID)
+	ctx, span := telemetry.Start(context.Background(), "bookmark.reaction",
+		"user.id", r.UserID, "guild.id", r.GuildID, "channel.id", r.ChannelID, "message.id", r.MessageID)
+	var err error
+	defer func() { span.End(err) }()
+
+	_, fetchSpan := telemetry.Start(ctx, "discord.fetch_message")
+	msg, err := withRetry(b, func() (*discordgo.Message, error) {
+		return b.api.ChannelMessage(r.ChannelID, r.MessageID)
+	})
+	fetchSpan.End(err)
+	if err != nil {
+		b.logger.Printf("Error getting message %s from channel %s: %v", r.MessageID, r.ChannelID, err)
+		return
+	}
+
+	// Bookmarks posted to archive channels aren't bookmarked again.
+	if msg.Author != nil && msg.Author.ID == b.state.User.ID {
+		return
+	}
+
+	if r.Member != nil && r.Member.User != nil {
+		b.users.Add(r.Member.User.ID, r.Member.User)
+		// The event carries the member's current roles; keep them for a
+		// while for role-gated guilds, and in the state for permissions.
+		b.rememberRoles(r.GuildID, r.Member)
+		member := *r.Member
+		member.GuildID = r.GuildID
+		b.state.MemberAdd(&member)
+	}
+
+	user, err := b.user(r.UserID)
+	if err != nil {
+		b.logger.Printf("Error getting user info for user %s: %v", r.UserID, err)
+		return
+	}
+
+	guild, err := b.guild(r.GuildID)
+	if err != nil {
+		b.logger.Printf("Error getting guild info for guild %s: %v", r.GuildID, err)
+		return
+	}
+
+	if b.offerRepostMerge(user, guild, msg) {
+		return
+	}
+
+	err = b.deliverBookmark(ctx, user, guild, msg)
+	if errors.Is(err, errNSFWBlocked) {
+		b.logger.Printf("Refused bookmark from age-restricted channel %s for user %s", r.ChannelID, user.ID)
+		b.notifyUser(user.ID, "Bookmarks from age-restricted channels are disabled on this bot.")
+		return
+	}
+	if errors.Is(err, errAlreadyBookmarked) {
+		b.logger.Printf("User %s already bookmarked message %s, ignoring repeated reaction", user.ID, r.MessageID)
+		return
+	}
+	if errors.Is(err, errGuildOptedOut) {
+		b.logger.Printf("Ignored bookmark reaction of user %s in opted out guild %s", user.ID, guild.ID)
+		b.notifyUser(user.ID, optedOutMessage(guild))
+		return
+	}
+	if errors.Is(err, errRoleNotAllowed) {
+		b.logger.Printf("Refused bookmark reaction of user %s without a bookmarking role in guild %s", user.ID, guild.ID)
+		b.notifyUser(user.ID, b.roleRefusalMessage(guild))
+		return
+	}
+	if err != nil {
+		b.reportDeliveryError(user, err)
+		return
+	}
+
+	err = b.store.MarkProcessed(user.ID, r.ChannelID, r.MessageID)
+	if err != nil {
+		b.logger.Printf("Error recording processed reaction for user %s: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+	}
+	b.unreactAfterDelivery(guild.ID, user.ID, r.ChannelID, r.MessageID, &r.Emoji)
+
+	b.logger.Printf("Successfully sent bookmark to user %s (%s) from guild %s", user.Username, user.ID, guild.Name)
+}
+
+// notifyUser sends a plain text DM to the user.
+func (b *Bot) notifyUser(userID, content string) {
+	dmChannel, err := b.dmChannel(userID)
+	if err != nil {
+		b.logger.Printf("Error creating DM channel with user %s: %v", userID, err)
+		return
+	}
+
+	_, err = b.send(dmChannel.ID, &discordgo.MessageSend{Content: content})
+	if err != nil {
+		b.logger.Printf("Error sending notice to user %s: %v", userID, err)
+	}
+}
+
+// errAlreadyBookmarked is returned by deliverBookmark for duplicate requests,
+// e.g. a 🔖 removed and added again.
+var errAlreadyBookmarked = errors.New("message already bookmarked")
+
+// deliverBookmark sends the bookmark embed for msg to the user's DMs and
+// records it in the store. For users in digest mode it is only recorded, to
+// be sent with their next digest. Its stages are traced as children of the
+// span in ctx. The bot is a member of the guild, as reactions and links only
+// come from those.
+func (b *Bot) deliverBookmark(ctx context.Context, user *discordgo.User, guild *discordgo.Guild, msg *discordgo.Message) error {
+	return b.deliverAnnotatedBookmark(ctx, user, guild, msg, "", nil, false)
+}
+
+// deliverAnnotatedBookmark is deliverBookmark with the user's note and tags,
+// and whether it's made through a user install rather than in a server the
+// bot is a member of.
+func (b *Bot) deliverAnnotatedBookmark(ctx context.Context, user *discordgo.User, guild *discordgo.Guild, msg *discordgo.Message, note string, tags []string, userInstall bool) error {
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(user.ID, msg.ChannelID, msg.ID))
+	defer unlock()
+
+	if b.store.Has(user.ID, msg.ChannelID, msg.ID) {
+		return errAlreadyBookmarked
+	}
+	spoiler, err := b.checkBookmarkable(user.ID, guild, msg.ChannelID)
+	if err != nil {
+		return err
+	}
+	cfg := b.cfg()
+
+	var voice audioDetails
+	if hasAudio(msg) {
+		voice = b.describeAudio(msg)
+	}
+
+	settings := b.store.Settings(user.ID)
+	if settings.Delivery.Interval() > 0 {
+		_, storeSpan := telemetry.Start(ctx, "store.write")
+		err := b.store.Add(store.Bookmark{
+			UserID:      user.ID,
+			GuildID:     guild.ID,
+			UserInstall: userInstall,
+			ChannelID:   msg.ChannelID,
+			MessageID:   msg.ID,
+			Content:     msg.Content,
+			ContentHash: contentHash(msg),
+			Transcript:  voice.transcript,
+			Note:        note,
+			Tags:        tags,
+			CreatedAt:   time.Now(),
+			Pending:     true,
+		})
+		storeSpan.End(err)
+		if err == nil {
+			b.audit(store.AuditEntry{Action: store.AuditCreate, ActorID: user.ID, UserID: user.ID, GuildID: guild.ID, ChannelID: msg.ChannelID, MessageID: msg.ID, Detail: "awaiting digest"})
+			b.forwardBookmark(settings, user, guild, msg, note, tags)
+			b.deliverToCollections(user, guild, msg, tags)
+			b.translateLater(user.ID, msg, settings.Language, spoiler)
+		}
+		return err
+	}
+
+	_, buildSpan := telemetry.Start(ctx, "embed.build")
+	shortID := b.store.NewShortID(user.ID)
+	link, origin := b.messageOrigin(guild, msg)
+	compact := settings.Verbosity == store.VerbosityCompact
+	var bookmarkEmbed *discordgo.MessageEmbed
+	if compact {
+		bookmarkEmbed = b.buildCompactEmbed(msg, origin, link.String())
+	} else {
+		bookmarkEmbed = b.buildEmbed(msg, origin, link.String(), spoiler)
+		applyAudio(bookmarkEmbed, msg, voice, spoiler)
+		embed.AddLinkPreview(bookmarkEmbed, b.linkPreview(msg, spoiler))
+	}
+	embed.AddNote(bookmarkEmbed, note, tags)
+	addShortID(bookmarkEmbed, shortID)
+
+	send := &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{bookmarkEmbed}, Components: bookmarkComponents(compact)}
+	// Compact bookmarks leave attachments out; expanding links to them.
+	if !compact && len(msg.Attachments) > 0 && (spoiler || cfg.ArchiveAttachments) {
+		var names map[string]string
+		send.Files, names = b.archiveAttachments(msg, cfg.ArchiveMaxBytes)
+		if spoiler {
+			markSpoilerFiles(send.Files)
+		} else {
+			useArchivedFiles(bookmarkEmbed, msg, names)
+		}
+	}
+	archived := len(send.Files) > 0
+	if embed.Overflows(bookmarkEmbed) {
+		notice := embed.TRUNCATED_LINK_NOTICE
+		if len(send.Files) < MESSAGE_MAX_FILES {
+			send.Files = append(send.Files, contentFile(msg, spoiler))
+			notice = embed.TRUNCATED_FILE_NOTICE
+		}
+		embed.Fit(bookmarkEmbed, notice)
+	}
+	buildSpan.End(nil)
+
+	_, sendSpan := telemetry.Start(ctx, "discord.send_dm")
+	sentMsg, err := b.sendBookmark(user, guild, tags, send)
+	sendSpan.End(err)
+	if err != nil {
+		return fmt.Errorf("sending bookmark embed: %w", err)
+	}
+
+	b.addActionReactions(sentMsg.ChannelID, sentMsg.ID)
+
+	_, storeSpan := telemetry.Start(ctx, "store.write")
+	err = b.store.Add(store.Bookmark{
+		UserID:      user.ID,
+		GuildID:     guild.ID,
+		UserInstall: userInstall,
+		ChannelID:   msg.ChannelID,
+		MessageID:   msg.ID,
+		DMChannelID: sentMsg.ChannelID,
+		DMMessageID: sentMsg.ID,
+		Content:     msg.Content,
+		ContentHash: contentHash(msg),
+		Transcript:  voice.transcript,
+		Note:        note,
+		Tags:        tags,
+		CreatedAt:   time.Now(),
+		Archived:    archived,
+		ShortID:     shortID,
+		Compact:     compact,
+	})
+	storeSpan.End(err)
+	if err != nil {
+		b.recordFailure(alertStore, "", err)
+		// Without a record the DM's actions can't work and a retry would
+		// send it again.
+		if err := b.api.ChannelMessageDelete(sentMsg.ChannelID, sentMsg.ID); err != nil {
+			b.logger.Printf("Error deleting unstored bookmark DM for user %s: %v", user.ID, err)
+		}
+		return fmt.Errorf("storing bookmark: %w", err)
+	}
+
+	b.audit(store.AuditEntry{Action: store.AuditCreate, ActorID: user.ID, UserID: user.ID, GuildID: guild.ID, ChannelID: msg.ChannelID, MessageID: msg.ID})
+	b.forwardBookmark(settings, user, guild, msg, note, tags)
+	b.deliverToCollections(user, guild, msg, tags)
+	b.translateLater(user.ID, msg, settings.Language, spoiler)
+	return nil
+}
+
+// checkBookmarkable returns why the user can't bookmark messages of the
+// channel, if anything, and whether their media must be spoilered.
+func (b *Bot) checkBookmarkable(userID string, guild *discordgo.Guild, channelID string) (spoiler bool, err error) {
+	guildSettings := b.store.GuildSettings(guild.ID)
+	if guildSettings.BookmarksDisabled {
+		return false, errGuildOptedOut
+	}
+	if !b.memberAllowed(guild.ID, userID, guildSettings) {
+		return false, errRoleNotAllowed
+	}
+
+	cfg := b.cfg()
+	nsfw := b.isNSFWChannel(channelID)
+	if nsfw && cfg.NSFWPolicy == config.NSFW_BLOCK {
+		return false, errNSFWBlocked
+	}
+	return nsfw && cfg.NSFWPolicy == config.NSFW_SPOILER, nil
+}
+
+// buildEmbed renders the bookmark embed with the configured template, in the
+// source guild's color.
+func (b *Bot) buildEmbed(msg *discordgo.Message, guild *discordgo.Guild, messageLink string, spoiler bool) *discordgo.MessageEmbed {
+	// Only the embed shows resolved names; the stored content stays as sent.
+	resolved := *msg
+	resolved.Content = embed.ResolveMentions(msg.Content, b.mentionNames(msg, guild))
+
+	bookmarkEmbed, err := b.embedTemplate().Build(&resolved, guild.Name, messageLink, spoiler)
+	if err != nil {
+		b.logger.Printf("Error rendering embed template for message %s: %v", msg.ID, err)
+	}
+	if contentUnavailable(msg) {
+		b.logger.Printf("Warning: message %s in channel %s came back blank, is the Message Content intent enabled?", msg.ID, msg.ChannelID)
+		bookmarkEmbed.Description = CONTENT_UNAVAILABLE_NOTICE
+	}
+	bookmarkEmbed.Color = b.embedColor(guild)
+	return bookmarkEmbed
+}
+
+// mentionNames looks up the names of the users, roles and channels msg
+// mentions, for rendering it outside its guild.
+func (b *Bot) mentionNames(msg *discordgo.Message, guild *discordgo.Guild) embed.Names {
+	names := embed.Names{Users: map[string]string{}, Roles: map[string]string{}, Channels: map[string]string{}}
+
+	for _, u := range msg.Mentions {
+		names.Users[u.ID] = u.Username
+		if member, err := b.state.Member(guild.ID, u.ID); err == nil && member.Nick != "" {
+			names.Users[u.ID] = member.Nick
+		} else if u.GlobalName != "" {
+			names.Users[u.ID] = u.GlobalName
+		}
+	}
+	for _, role := range guild.Roles {
+		names.Roles[role.ID] = role.Name
+	}
+	for _, channel := range append(guild.Channels, guild.Threads...) {
+		names.Channels[channel.ID] = channel.Name
+	}
+	for _, channel := range msg.MentionChannels {
+		names.Channels[channel.ID] = channel.Name
+	}
+
+	return names
+}
@@ -0,0 +1,426 @@
+package bot
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+func sourceMessage() *discordgo.Message {
+	return &discordgo.Message{
+		ID:        "message",
+		ChannelID: "channel",
+		Content:   "hello world",
+		Author:    &discordgo.User{ID: "author", Username: "bob"},
+		Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Reactions: []*discordgo.MessageReactions{
+			{Count: 1, Emoji: &discordgo.Emoji{Name: BOOKMARK_EMOJI}},
+		},
+	}
+}
+
+func bookmarkReaction(emoji string) *discordgo.MessageReactionAdd {
+	return &discordgo.MessageReactionAdd{
+		MessageReaction: &discordgo.MessageReaction{
+			UserID:    "user",
+			MessageID: "message",
+			ChannelID: "channel",
+			GuildID:   "guild",
+			Emoji:     discordgo.Emoji{Name: emoji},
+		},
+	}
+}
+
+func TestReactionAddDeliversBookmark(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(api.sent))
+	}
+	sent := api.sent[0]
+	if sent.ChannelID != "dm-user" {
+		t.Errorf("sent to channel %q, want dm-user", sent.ChannelID)
+	}
+	embed := sent.Message.Embeds[0]
+	if embed.Title != "Bookmark from Test Guild" {
+		t.Errorf("embed title = %q", embed.Title)
+	}
+	if embed.Description != "hello world" {
+		t.Errorf("embed description = %q", embed.Description)
+	}
+	if len(api.reactionsAdded) != len(ACTION_EMOJIS) {
+		t.Errorf("added %d reactions, want %d", len(api.reactionsAdded), len(ACTION_EMOJIS))
+	}
+
+	bookmarks := b.store.ForUser("user")
+	if len(bookmarks) != 1 {
+		t.Fatalf("stored %d bookmarks, want 1", len(bookmarks))
+	}
+	if bm := bookmarks[0]; bm.GuildID != "guild" || bm.ChannelID != "channel" || bm.MessageID != "message" || bm.DMChannelID != "dm-user" {
+		t.Errorf("stored bookmark = %+v", bm)
+	}
+	if !b.store.Processed("user", "channel", "message") {
+		t.Error("reaction was not marked as processed")
+	}
+}
+
+func TestReactionAddDeliversOncePerMessage(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	var wg sync.WaitGroup
+	for n := 0; n < 5; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+		}()
+	}
+	wg.Wait()
+
+	if len(api.sent) != 1 {
+		t.Errorf("sent %d messages for repeated reactions, want 1", len(api.sent))
+	}
+	if n := len(b.store.ForUser("user")); n != 1 {
+		t.Errorf("stored %d bookmarks, want 1", n)
+	}
+}
+
+// failingAddStore is a store whose bookmark writes fail.
+type failingAddStore struct {
+	store.BookmarkStore
+}
+
+func (failingAddStore) Add(store.Bookmark) error {
+	return errors.New("disk full")
+}
+
+func TestReactionAddStopsWhenStoringFails(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+	b.store = failingAddStore{b.store}
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(api.sent) != 1 || len(api.deleted) != 1 {
+		t.Fatalf("sent %d and deleted %d DMs, want the unstored DM deleted", len(api.sent), len(api.deleted))
+	}
+	entries, err := b.store.AuditLog(store.AuditFilter{UserID: "user"})
+	if err != nil || len(entries) != 0 {
+		t.Errorf("audit log = %+v, %v, want no entry for the unstored bookmark", entries, err)
+	}
+	if b.store.Processed("user", "channel", "message") {
+		t.Error("reaction marked processed although the bookmark wasn't stored")
+	}
+}
+
+func TestReactionAddIgnoresOtherEmoji(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	b.ReactionAdd(bookmarkReaction("👍"))
+
+	if len(api.sent) != 0 {
+		t.Errorf("sent %d messages, want 0", len(api.sent))
+	}
+}
+
+func TestReactionAddIgnoresOwnReactions(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	r := bookmarkReaction(BOOKMARK_EMOJI)
+	r.UserID = "bot"
+	b.ReactionAdd(r)
+
+	if len(api.sent) != 0 {
+		t.Errorf("sent %d messages, want 0", len(api.sent))
+	}
+}
+
+func TestReactionAddIgnoresGroupDMs(t *testing.T) {
+	b, api := newTestBot(t)
+	msg := sourceMessage()
+	msg.ChannelID = "group-dm"
+	api.addMessage(msg)
+
+	r := bookmarkReaction(BOOKMARK_EMOJI)
+	r.ChannelID, r.GuildID = "group-dm", ""
+	b.ReactionAdd(r)
+
+	if len(api.sent) != 0 || len(b.store.ForUser("user")) != 0 {
+		t.Errorf("sent %d messages for a group DM reaction, want 0", len(api.sent))
+	}
+}
+
+func TestReactionAddBlocksNSFW(t *testing.T) {
+	b, api := newTestBot(t)
+	b.config.NSFWPolicy = "block"
+	channel, _ := b.state.Channel("channel")
+	channel.NSFW = true
+	api.addMessage(sourceMessage())
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(api.sent) != 1 || len(api.sent[0].Message.Embeds) != 0 {
+		t.Fatalf("sent %+v, want a single plain notice", api.sent)
+	}
+	if len(b.store.ForUser("user")) != 0 {
+		t.Error("blocked bookmark was stored")
+	}
+}
+
+// deliverTestBookmark bookmarks the source message and returns the DM reaction
+// event template for it.
+func deliverTestBookmark(t *testing.T, b *Bot, api *fakeAPI) *discordgo.MessageReactionAdd {
+	t.Helper()
+
+	api.addMessage(sourceMessage())
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	b.state.ChannelAdd(&discordgo.Channel{ID: "dm-user", Type: discordgo.ChannelTypeDM})
+
+	bookmarks := b.store.ForUser("user")
+	if len(bookmarks) != 1 {
+		t.Fatalf("stored %d bookmarks, want 1", len(bookmarks))
+	}
+
+	return &discordgo.MessageReactionAdd{
+		MessageReaction: &discordgo.MessageReaction{
+			UserID:    "user",
+			MessageID: bookmarks[0].DMMessageID,
+			ChannelID: "dm-user",
+		},
+	}
+}
+
+func TestDMReactionAddDeletesBookmark(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+
+	r.Emoji.Name = DELETE_EMOJI
+	b.DMReactionAdd(r)
+
+	if want := "channel/message/" + BOOKMARK_EMOJI + "/user"; len(api.reactionsRemoved) != 1 || api.reactionsRemoved[0] != want {
+		t.Errorf("removed reactions %v, want [%s]", api.reactionsRemoved, want)
+	}
+	if want := "dm-user/" + r.MessageID; len(api.deleted) != 1 || api.deleted[0] != want {
+		t.Errorf("deleted %v, want [%s]", api.deleted, want)
+	}
+	if len(b.store.ForUser("user")) != 0 {
+		t.Error("bookmark is still stored")
+	}
+}
+
+func TestDMReactionAddFallsBackToEmbedLink(t *testing.T) {
+	b, api := newTestBot(t)
+	b.state.ChannelAdd(&discordgo.Channel{ID: "dm-user", Type: discordgo.ChannelTypeDM})
+	source := sourceMessage()
+	source.ChannelID, source.ID = "222222222222222222", "333333333333333333"
+	api.addMessage(source)
+	api.addMessage(&discordgo.Message{
+		ID:        "legacy",
+		ChannelID: "dm-user",
+		Embeds: []*discordgo.MessageEmbed{{
+			Fields: []*discordgo.MessageEmbedField{
+				{Name: "Source", Value: "[Jump to message](https://discord.com/channels/111111111111111111/222222222222222222/333333333333333333)"},
+			},
+		}},
+	})
+
+	r := &discordgo.MessageReactionAdd{
+		MessageReaction: &discordgo.MessageReaction{
+			UserID:    "user",
+			MessageID: "legacy",
+			ChannelID: "dm-user",
+			Emoji:     discordgo.Emoji{Name: DELETE_EMOJI},
+		},
+	}
+	b.DMReactionAdd(r)
+
+	if len(api.reactionsRemoved) != 1 || !strings.HasPrefix(api.reactionsRemoved[0], "222222222222222222/333333333333333333/") {
+		t.Errorf("removed reactions %v", api.reactionsRemoved)
+	}
+	if len(api.deleted) != 1 {
+		t.Errorf("deleted %v, want the legacy bookmark", api.deleted)
+	}
+}
+
+func TestDMReactionAddPinsAndArchives(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+
+	r.Emoji.Name = PIN_EMOJI
+	b.DMReactionAdd(r)
+	r.Emoji.Name = ARCHIVE_EMOJI
+	b.DMReactionAdd(r)
+
+	bm, _ := b.store.ByDM("dm-user", r.MessageID)
+	if !bm.Pinned || bm.Status != "archived" {
+		t.Errorf("bookmark pinned=%t status=%q, want pinned and archived", bm.Pinned, bm.Status)
+	}
+
+	b.DMReactionRemove(&discordgo.MessageReactionRemove{MessageReaction: &discordgo.MessageReaction{
+		UserID:    "user",
+		MessageID: r.MessageID,
+		ChannelID: "dm-user",
+		Emoji:     discordgo.Emoji{Name: PIN_EMOJI},
+	}})
+
+	bm, _ = b.store.ByDM("dm-user", r.MessageID)
+	if bm.Pinned {
+		t.Error("bookmark is still pinned after removing the reaction")
+	}
+}
+
+func TestDMReactionAddResendsFiles(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "image")
+	}))
+	defer srv.Close()
+
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+	dmMsg, _ := api.ChannelMessage("dm-user", r.MessageID)
+	dmMsg.Attachments = []*discordgo.MessageAttachment{{ID: "a", Filename: "image.png", URL: srv.URL + "/image.png", Size: 5}}
+	dmMsg.Embeds[0].Image = &discordgo.MessageEmbedImage{URL: srv.URL + "/image.png"}
+
+	r.Emoji.Name = RESEND_EMOJI
+	b.DMReactionAdd(r)
+
+	resent := api.sent[len(api.sent)-1].Message
+	if len(resent.Files) != 1 || resent.Files[0].Name != "image.png" || resent.Embeds[0].Image.URL != "attachment://image.png" {
+		t.Errorf("re-sent files %v, image %+v, want the archived copy", resent.Files, resent.Embeds[0].Image)
+	}
+	if _, ok := b.store.ByDM("dm-user", r.MessageID); ok {
+		t.Error("bookmark still tracked as the old message")
+	}
+}
+
+// failingReplaceStore is a store whose bookmark replacements fail.
+type failingReplaceStore struct {
+	store.BookmarkStore
+}
+
+func (failingReplaceStore) Replace(string, string, store.Bookmark) error {
+	return errors.New("disk full")
+}
+
+func TestDMReactionAddKeepsOldMessageWhenResendFails(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+	b.store = failingReplaceStore{b.store}
+
+	r.Emoji.Name = RESEND_EMOJI
+	b.DMReactionAdd(r)
+
+	if _, ok := b.store.ByDM("dm-user", r.MessageID); !ok {
+		t.Error("bookmark no longer tracked as the old message")
+	}
+	if want := "dm-user/sent-2"; len(api.deleted) != 1 || api.deleted[0] != want {
+		t.Errorf("deleted %v, want only the copy %s", api.deleted, want)
+	}
+}
+
+func TestDMReactionAddIgnoresGuildChannels(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	b.DMReactionAdd(bookmarkReaction(DELETE_EMOJI))
+
+	if len(api.deleted) != 0 || len(api.reactionsRemoved) != 0 {
+		t.Errorf("acted on a guild reaction: deleted %v, removed %v", api.deleted, api.reactionsRemoved)
+	}
+}
+
+func TestReactionAddUsesGuildColor(t *testing.T) {
+	b, api := newTestBot(t)
+	b.config.GuildColors = map[string]int{"guild": 0xe67e22}
+	api.addMessage(sourceMessage())
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(api.sent))
+	}
+	if color := api.sent[0].Message.Embeds[0].Color; color != 0xe67e22 {
+		t.Errorf("embed color = %06x, want e67e22", color)
+	}
+}
+
+func TestLongMessageAttachesFullText(t *testing.T) {
+	b, api := newTestBot(t)
+	msg := sourceMessage()
+	msg.Content = strings.Repeat("a long message ", 300)
+	api.addMessage(msg)
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(api.sent))
+	}
+	sent := api.sent[0].Message
+	if !strings.HasSuffix(sent.Embeds[0].Description, embed.TRUNCATED_FILE_NOTICE) {
+		t.Errorf("description doesn't mention the attachment: %q", sent.Embeds[0].Description)
+	}
+	if len(sent.Files) != 1 || sent.Files[0].Name != "message-message.txt" {
+		t.Fatalf("files = %+v, want the full text", sent.Files)
+	}
+	data, _ := io.ReadAll(sent.Files[0].Reader)
+	if string(data) != msg.Content {
+		t.Errorf("attached %d characters, want %d", len(data), len(msg.Content))
+	}
+	if bm, _ := b.store.Get("user", "channel", "message"); bm.Archived {
+		t.Error("full text attachment marked the bookmark as archived")
+	}
+}
+
+func TestBookmarkResolvesMentions(t *testing.T) {
+	b, api := newTestBot(t)
+	msg := sourceMessage()
+	msg.Content = "ask <@42> <:ok:123>"
+	msg.Mentions = []*discordgo.User{{ID: "42", Username: "carol"}}
+	api.addMessage(msg)
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(api.sent))
+	}
+	if got := api.sent[0].Message.Embeds[0].Description; got != "ask @carol [:ok:](https://cdn.discordapp.com/emojis/123.webp)" {
+		t.Errorf("description = %q", got)
+	}
+	if bm, _ := b.store.Get("user", "channel", "message"); bm.Content != msg.Content {
+		t.Errorf("stored content = %q, want the original", bm.Content)
+	}
+}
+
+func TestOwnerResolvedWhileHandlersRun(t *testing.T) {
+	b, _ := newTestBot(t)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			b.resolveOwner()
+		}
+	}()
+	for range 100 {
+		b.isOwner("owner")
+	}
+	<-done
+	if !b.isOwner("owner") || b.isOwner("user") {
+		t.Errorf("owner = %q, want the application owner", b.owner())
+	}
+}
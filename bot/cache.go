@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const USER_CACHE_SIZE = 1024
+
+// lruCache is a small fixed-size, concurrency-safe LRU keyed by snowflake.
+type lruCache[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+}
+
+func newLRUCache[V any](capacity int) *lruCache[V] {
+	return &lruCache[V]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*lruEntry[V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+func (c *lruCache[V]) Add(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry[V]).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[V]).key)
+	}
+}
+
+// channel returns a channel from the gateway state, falling back to REST
+// and caching the result in the state for subsequent lookups.
+func (b *Bot) channel(channelID string) (*discordgo.Channel, error) {
+	if channel, err := b.state.Channel(channelID); err == nil {
+		return channel, nil
+	}
+
+	channel, err := b.api.Channel(channelID)
+	if err != nil {
+		return nil, err
+	}
+	// ChannelAdd fails for guild channels whose guild isn't tracked; that's fine.
+	b.state.ChannelAdd(channel)
+
+	return channel, nil
+}
+
+// guild returns a guild from the gateway state, falling back to REST.
+func (b *Bot) guild(guildID string) (*discordgo.Guild, error) {
+	if guild, err := b.state.Guild(guildID); err == nil {
+		return guild, nil
+	}
+
+	return b.api.Guild(guildID)
+}
+
+// user returns a user from the LRU cache, falling back to REST.
+func (b *Bot) user(userID string) (*discordgo.User, error) {
+	if user, ok := b.users.Get(userID); ok {
+		return user, nil
+	}
+
+	user, err := b.api.User(userID)
+	if err != nil {
+		return nil, err
+	}
+	b.users.Add(userID, user)
+
+	return user, nil
+}
+
+// dmChannel returns the DM channel with a user, creating it only on a cache miss.
+func (b *Bot) dmChannel(userID string) (*discordgo.Channel, error) {
+	if channel, ok := b.dmChannels.Get(userID); ok {
+		return channel, nil
+	}
+
+	channel, err := withRetry(b, func() (*discordgo.Channel, error) {
+		return b.api.UserChannelCreate(userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.dmChannels.Add(userID, channel)
+
+	return channel, nil
+}
+
+// member returns a guild member from the gateway state, falling back to REST.
+func (b *Bot) member(guildID, userID string) (*discordgo.Member, error) {
+	if member, err := b.state.Member(guildID, userID); err == nil {
+		return member, nil
+	}
+
+	member, err := b.api.GuildMember(guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	member.GuildID = guildID
+
+	return member, nil
+}
@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	"github.com/anonmiraj/discord-bookmarker/telemetry"
+	"github.com/bwmarrin/discordgo"
+)
+
+// catchUp runs on every (re)identify. A Ready, unlike a Resumed, means
+// Discord did not replay the events missed while disconnected, so recent
+// messages in the configured channels are rescanned for unprocessed 🔖
+// reactions.
+func (b *Bot) catchUp() {
+	channels := b.cfg().CatchUpChannels
+	if len(channels) == 0 {
+		return
+	}
+
+	go func() {
+		b.logger.Printf("Catching up on %d channel(s)", len(channels))
+
+		delivered := 0
+		for _, channelID := range channels {
+			delivered += b.catchUpChannel(channelID)
+		}
+
+		b.logger.Printf("Catch-up finished, delivered %d missed bookmark(s)", delivered)
+	}()
+}
+
+func (b *Bot) catchUpChannel(channelID string) int {
+	channel, err := b.channel(channelID)
+	if err != nil {
+		b.logger.Printf("Error getting channel info for catch-up channel %s: %v", channelID, err)
+		return 0
+	}
+
+	guild, err := b.guild(channel.GuildID)
+	if err != nil {
+		b.logger.Printf("Error getting guild info for guild %s: %v", channel.GuildID, err)
+		return 0
+	}
+
+	messages, err := b.api.ChannelMessages(channelID, b.cfg().CatchUpLimit, "", "", "")
+	if err != nil {
+		b.logger.Printf("Error getting recent messages of catch-up channel %s: %v", channelID, err)
+		return 0
+	}
+
+	delivered := 0
+	for _, msg := range messages {
+		for _, user := range b.triggerUsers(msg) {
+			if user.Bot || b.store.Processed(user.ID, channelID, msg.ID) || b.store.Has(user.ID, channelID, msg.ID) {
+				continue
+			}
+			if b.catchUpReaction(user, guild, msg) {
+				delivered++
+			}
+		}
+	}
+
+	return delivered
+}
+
+// catchUpReaction delivers a missed bookmark reaction unless another
+// instance is handling it, and reports whether it was delivered.
+func (b *Bot) catchUpReaction(user *discordgo.User, guild *discordgo.Guild, msg *discordgo.Message) bool {
+	release, ok := b.claimReaction(user.ID, msg.ChannelID, msg.ID)
+	if !ok {
+		return false
+	}
+	defer release()
+
+	b.logger.Printf("Catching up on missed bookmark reaction from user %s in channel %s:%s", user.ID, msg.ChannelID, msg.ID)
+	ctx, span := telemetry.Start(context.Background(), "bookmark.catch_up",
+		"user.id", user.ID, "guild.id", guild.ID, "channel.id", msg.ChannelID, "message.id", msg.ID)
+
+	err := b.deliverBookmark(ctx, user, guild, msg)
+	span.End(err)
+	if errors.Is(err, errAlreadyBookmarked) || errors.Is(err, errGuildOptedOut) || errors.Is(err, errRoleNotAllowed) {
+		return false
+	}
+	if err != nil {
+		b.logger.Printf("Error delivering bookmark to user %s (%s): %v", user.Username, user.ID, err)
+		return false
+	}
+
+	err = b.store.MarkProcessed(user.ID, msg.ChannelID, msg.ID)
+	if err != nil {
+		b.logger.Printf("Error recording processed reaction for user %s: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+	}
+	b.unreactAfterDelivery(guild.ID, user.ID, msg.ChannelID, msg.ID, nil)
+	return true
+}
+
+// triggerUsers returns every user who reacted to msg with a trigger emoji,
+// including super reactions, without duplicates.
+func (b *Bot) triggerUsers(msg *discordgo.Message) []*discordgo.User {
+	var users []*discordgo.User
+	seen := map[string]bool{}
+	for _, reaction := range msg.Reactions {
+		if !b.bookmarkTriggers().matches(reaction.Emoji) {
+			continue
+		}
+
+		emoji := reaction.Emoji.APIName()
+		reacted := b.reactionUsers(msg.ChannelID, msg.ID, emoji)
+		reacted = append(reacted, b.reactionUsers(msg.ChannelID, msg.ID, emoji, burstReactions)...)
+		for _, user := range reacted {
+			if !seen[user.ID] {
+				seen[user.ID] = true
+				users = append(users, user)
+			}
+		}
+	}
+	return users
+}
+
+// reactionUsers returns every user who reacted to a message with emoji.
+func (b *Bot) reactionUsers(channelID, messageID, emoji string, options ...discordgo.RequestOption) []*discordgo.User {
+	var users []*discordgo.User
+	after := ""
+
+	for {
+		page, err := b.api.MessageReactions(channelID, messageID, emoji, 100, "", after, options...)
+		if err != nil {
+			b.logger.Printf("Error getting %s reactions of message %s: %v", emoji, messageID, err)
+			return users
+		}
+
+		users = append(users, page...)
+		if len(page) < 100 {
+			return users
+		}
+		after = page[len(page)-1].ID
+	}
+}
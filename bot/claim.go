@@ -0,0 +1,37 @@
+package bot
+
+import "time"
+
+// claimReaction reports whether this instance should handle a user's
+// bookmark reaction and returns the function releasing the claim once it
+// was handled. Several instances can share a SQL store for high
+// availability; all of them receive every reaction and only the one whose
+// claim lands first handles it, while the others skip it. Once released,
+// the stored bookmark keeps a late duplicate from being delivered again.
+func (b *Bot) claimReaction(userID, channelID, messageID string) (release func(), ok bool) {
+	return b.claim("reaction:"+userID+":"+channelID+":"+messageID, "bookmark reaction of user "+userID)
+}
+
+// claim is claimReaction for any event shared instances all see, identified
+// by key, such as a DM or a scheduler run; what names it in logs.
+//
+// Store errors let the event through, so a single instance keeps working
+// while the database hiccups.
+func (b *Bot) claim(key, what string) (release func(), ok bool) {
+	claimed, err := b.store.Claim(key, time.Now())
+	if err != nil {
+		b.logger.Printf("Error claiming %s: %v", what, err)
+		b.recordFailure(alertStore, "", err)
+		return func() {}, true
+	}
+	if !claimed {
+		return nil, false
+	}
+
+	return func() {
+		if err := b.store.Release(key); err != nil {
+			b.logger.Printf("Error releasing claim of %s: %v", what, err)
+			b.recordFailure(alertStore, "", err)
+		}
+	}, true
+}
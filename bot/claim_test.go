@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+)
+
+func TestReactionClaimedByAnotherInstance(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	// Another instance sharing the store is handling the reaction.
+	key := "reaction:user:channel:message"
+	if ok, err := b.store.Claim(key, time.Now()); err != nil || !ok {
+		t.Fatalf("Claim = %t, %v", ok, err)
+	}
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	if len(api.sent) != 0 {
+		t.Fatalf("sent %d messages for a claimed reaction, want 0", len(api.sent))
+	}
+
+	if err := b.store.Release(key); err != nil {
+		t.Fatal(err)
+	}
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	if len(b.store.ForUser("user")) != 1 {
+		t.Fatal("reaction not handled once the claim was released")
+	}
+
+	// The claim is released once handled.
+	if ok, _ := b.store.Claim(key, time.Now()); !ok {
+		t.Error("claim still held after handling the reaction")
+	}
+}
+
+func TestBookmarkActionClaimedByAnotherInstance(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+
+	key := "action:dm-user:" + r.MessageID + ":" + RESEND_EMOJI
+	if ok, err := b.store.Claim(key, time.Now()); err != nil || !ok {
+		t.Fatalf("Claim = %t, %v", ok, err)
+	}
+	r.Emoji.Name = RESEND_EMOJI
+	b.DMReactionAdd(r)
+	if len(api.sent) != 1 || len(api.deleted) != 0 {
+		t.Fatalf("re-sent a bookmark for a claimed reaction: sent %d, deleted %v", len(api.sent), api.deleted)
+	}
+
+	b.store.Release(key)
+	b.DMReactionAdd(r)
+	if len(api.sent) != 2 {
+		t.Error("reaction not handled once the claim was released")
+	}
+}
+
+func TestDigestClaimedByAnotherInstance(t *testing.T) {
+	b, api := newTestBot(t)
+	start := time.Now().Add(-25 * time.Hour)
+	b.store.SetSettings("user", store.UserSettings{Delivery: store.DeliveryDaily, LastDigest: start})
+	b.store.Add(store.Bookmark{UserID: "user", GuildID: "guild", ChannelID: "channel", MessageID: "message", CreatedAt: start.Add(time.Hour), Pending: true})
+
+	// Another instance sharing the store is sending this period's digest.
+	key := fmt.Sprintf("digest:user:%d", start.Unix())
+	if ok, err := b.store.Claim(key, time.Now()); err != nil || !ok {
+		t.Fatalf("Claim = %t, %v", ok, err)
+	}
+	b.sendDueDigests(time.Now())
+	if len(api.sent) != 0 || len(b.store.Pending("user")) != 1 {
+		t.Fatalf("sent %d messages for a claimed digest, want 0", len(api.sent))
+	}
+}
+
+func TestReminderClaimedByAnotherInstance(t *testing.T) {
+	b, api := newTestBot(t)
+	deliverTestBookmark(t, b, api)
+	bm := b.store.ForUser("user")[0]
+	due := time.Now().Add(-time.Minute).Truncate(time.Second)
+	bm.RemindAt = &due
+	b.store.Update(bm)
+	sent := len(api.sent)
+
+	key := fmt.Sprintf("reminder:user:%s:%s:%d", bm.ChannelID, bm.MessageID, due.Unix())
+	if ok, err := b.store.Claim(key, time.Now()); err != nil || !ok {
+		t.Fatalf("Claim = %t, %v", ok, err)
+	}
+	b.sendDueReminders(time.Now())
+	if len(api.sent) != sent {
+		t.Fatal("sent a claimed reminder")
+	}
+
+	b.store.Release(key)
+	b.sendDueReminders(time.Now())
+	if len(api.sent) != sent+1 {
+		t.Error("reminder not sent once the claim was released")
+	}
+}
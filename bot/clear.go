@@ -0,0 +1,221 @@
+package bot
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	CLEAR_DATE_LAYOUT    = "2006-01-02"
+	CLEAR_TAG_MAX_LENGTH = 32
+	// CUSTOM_ID_MAX_LENGTH is Discord's limit on component custom IDs.
+	CUSTOM_ID_MAX_LENGTH = 100
+)
+
+// clearFilter selects the bookmarks removed by /bookmarks clear. Zero fields
+// match everything.
+type clearFilter struct {
+	GuildID string
+	Before  time.Time
+	Tag     string
+}
+
+func (f clearFilter) match(userID string, bm store.Bookmark) bool {
+	if bm.UserID != userID {
+		return false
+	}
+	if f.GuildID != "" && bm.GuildID != f.GuildID {
+		return false
+	}
+	if !f.Before.IsZero() && !bm.CreatedAt.Before(f.Before) {
+		return false
+	}
+	if f.Tag != "" && !hasTag(bm, f.Tag) {
+		return false
+	}
+	return true
+}
+
+func hasTag(bm store.Bookmark, tag string) bool {
+	for _, t := range bm.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// encode serializes the filter into the confirm button's custom ID, so the
+// confirmation survives restarts without keeping pending state.
+func (f clearFilter) encode() string {
+	v := url.Values{}
+	if f.GuildID != "" {
+		v.Set("g", f.GuildID)
+	}
+	if !f.Before.IsZero() {
+		v.Set("b", f.Before.Format(CLEAR_DATE_LAYOUT))
+	}
+	if f.Tag != "" {
+		v.Set("t", f.Tag)
+	}
+	return v.Encode()
+}
+
+func decodeClearFilter(s string) (clearFilter, error) {
+	v, err := url.ParseQuery(s)
+	if err != nil {
+		return clearFilter{}, err
+	}
+
+	f := clearFilter{GuildID: v.Get("g"), Tag: v.Get("t")}
+	if before := v.Get("b"); before != "" {
+		f.Before, err = time.Parse(CLEAR_DATE_LAYOUT, before)
+		if err != nil {
+			return clearFilter{}, err
+		}
+	}
+	return f, nil
+}
+
+func (f clearFilter) String() string {
+	var parts []string
+	if f.GuildID != "" {
+		parts = append(parts, fmt.Sprintf("from server `%s`", f.GuildID))
+	}
+	if !f.Before.IsZero() {
+		parts = append(parts, fmt.Sprintf("saved before %s", f.Before.Format(CLEAR_DATE_LAYOUT)))
+	}
+	if f.Tag != "" {
+		parts = append(parts, fmt.Sprintf("tagged `%s`", f.Tag))
+	}
+	if len(parts) == 0 {
+		return "all your bookmarks"
+	}
+	return "bookmarks " + strings.Join(parts, ", ")
+}
+
+func (b *Bot) clearCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+
+	var f clearFilter
+	if opt, ok := opts["guild"]; ok {
+		f.GuildID = strings.TrimSpace(opt.StringValue())
+	}
+	if opt, ok := opts["before"]; ok {
+		before, err := time.Parse(CLEAR_DATE_LAYOUT, strings.TrimSpace(opt.StringValue()))
+		if err != nil {
+			b.respondEphemeral(i, "`before` must be a date like 2024-01-31.")
+			return
+		}
+		f.Before = before
+	}
+	if opt, ok := opts["tag"]; ok {
+		f.Tag = strings.TrimSpace(opt.StringValue())
+	}
+
+	count := 0
+	for _, bm := range b.store.ForUser(user.ID) {
+		if f.match(user.ID, bm) {
+			count++
+		}
+	}
+	if count == 0 {
+		b.respondEphemeral(i, fmt.Sprintf("No %s to delete.", f))
+		return
+	}
+
+	confirmID := "clear:confirm:" + f.encode()
+	if len(confirmID) > CUSTOM_ID_MAX_LENGTH {
+		b.respondEphemeral(i, "Those filters are too long, try a shorter tag.")
+		return
+	}
+
+	err := b.api.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("This will delete %d bookmark(s): %s. This can't be undone.", count, f),
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    fmt.Sprintf("Delete %d bookmark(s)", count),
+						Style:    discordgo.DangerButton,
+						CustomID: confirmID,
+					},
+					discordgo.Button{
+						Label:    "Cancel",
+						Style:    discordgo.SecondaryButton,
+						CustomID: "clear:cancel",
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error responding to interaction %s: %v", i.ID, err)
+	}
+}
+
+// clearComponent handles the confirm and cancel buttons of /bookmarks clear.
+func (b *Bot) clearComponent(i *discordgo.InteractionCreate, args string) {
+	action, encoded, _ := strings.Cut(args, ":")
+	if action != "confirm" {
+		b.updateComponentMessage(i, "Cancelled, no bookmarks were deleted.")
+		return
+	}
+
+	f, err := decodeClearFilter(encoded)
+	if err != nil {
+		b.logger.Printf("Error decoding clear filter %q: %v", encoded, err)
+		b.updateComponentMessage(i, "Something went wrong, please run the command again.")
+		return
+	}
+
+	user := interactionUser(i)
+
+	// The filter is evaluated again, so bookmarks added since the prompt
+	// are included and ones already removed are not deleted twice.
+	removed, err := b.store.RemoveIf(func(bm store.Bookmark) bool { return f.match(user.ID, bm) })
+	if err != nil {
+		b.logger.Printf("Error removing bookmarks of user %s from store: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+		b.updateComponentMessage(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	for _, bm := range removed {
+		if !bm.HasDM() {
+			continue
+		}
+		err := b.api.ChannelMessageDelete(bm.DMChannelID, bm.DMMessageID)
+		if err != nil {
+			b.logger.Printf("Error deleting bookmark DM %s for user %s: %v", bm.DMMessageID, user.ID, err)
+		}
+	}
+
+	if len(removed) > 0 {
+		b.audit(store.AuditEntry{Action: store.AuditDelete, ActorID: user.ID, UserID: user.ID, GuildID: f.GuildID, Detail: fmt.Sprintf("cleared %d bookmark(s): %s", len(removed), f)})
+	}
+	b.logger.Printf("Cleared %d bookmark(s) for user %s (%s)", len(removed), user.ID, f)
+	b.updateComponentMessage(i, fmt.Sprintf("Deleted %d bookmark(s).", len(removed)))
+}
+
+// updateComponentMessage replaces the message holding the clicked component,
+// removing its buttons.
+func (b *Bot) updateComponentMessage(i *discordgo.InteractionCreate, content string) {
+	err := b.api.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error responding to interaction %s: %v", i.ID, err)
+	}
+}
@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestClearFilterRoundTrip(t *testing.T) {
+	f := clearFilter{GuildID: "guild", Before: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), Tag: "to read"}
+
+	got, err := decodeClearFilter(f.encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != f {
+		t.Errorf("decoded %+v, want %+v", got, f)
+	}
+}
+
+func TestClearConfirmDeletesMatching(t *testing.T) {
+	b, api := newTestBot(t)
+
+	old := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, bm := range []store.Bookmark{
+		{UserID: "user", GuildID: "guild", DMChannelID: "dm-user", DMMessageID: "old", CreatedAt: old},
+		{UserID: "user", GuildID: "guild", DMChannelID: "dm-user", DMMessageID: "new", CreatedAt: time.Now()},
+		{UserID: "other", GuildID: "guild", DMChannelID: "dm-other", DMMessageID: "theirs", CreatedAt: old},
+	} {
+		if err := b.store.Add(bm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := clearFilter{Before: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	clickComponent(b, "user", "prompt", "clear:confirm:"+f.encode())
+
+	if len(api.deleted) != 1 || api.deleted[0] != "dm-user/old" {
+		t.Errorf("deleted %v, want [dm-user/old]", api.deleted)
+	}
+	if n := len(b.store.All()); n != 2 {
+		t.Errorf("%d bookmarks left, want 2", n)
+	}
+	if len(api.responses) != 1 || api.responses[0].Type != discordgo.InteractionResponseUpdateMessage {
+		t.Errorf("responses %+v, want one message update", api.responses)
+	}
+}
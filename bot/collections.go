@@ -0,0 +1,344 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/msglink"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// COLLECTION_EMOJI marks collection messages.
+const COLLECTION_EMOJI = "📚"
+
+// collectionName parses a collection name the way tags are, so bookmarks
+// tagged with it match. It reports false unless the name is one tag.
+func collectionName(opts optionMap) (string, bool) {
+	tags := parseTags(opts["name"].StringValue())
+	if len(tags) != 1 {
+		return "", false
+	}
+	return tags[0], true
+}
+
+// collectionCommand runs the collection subcommands, which all take a name.
+func (b *Bot) collectionCommand(i *discordgo.InteractionCreate, opts optionMap, run func(user *discordgo.User, name string)) {
+	name, ok := collectionName(opts)
+	if !ok {
+		b.respondEphemeral(i, "A collection name is one word, like a tag.")
+		return
+	}
+	run(interactionUser(i), name)
+}
+
+// collectionCreateCommand creates a collection the user owns and subscribes to.
+func (b *Bot) collectionCreateCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	b.collectionCommand(i, opts, func(user *discordgo.User, name string) {
+		if _, exists := b.store.Collection(user.ID, name); exists {
+			b.respondEphemeral(i, fmt.Sprintf("You already have a collection named **%s**, please pick another name.", name))
+			return
+		}
+
+		err := b.store.SetCollection(store.Collection{
+			Name:        name,
+			OwnerID:     user.ID,
+			Subscribers: []string{user.ID},
+			CreatedAt:   time.Now(),
+		})
+		if err != nil {
+			b.logger.Printf("Error creating collection %s for user %s: %v", name, user.ID, err)
+			b.recordFailure(alertStore, "", err)
+			b.respondEphemeral(i, "Something went wrong, please try again later.")
+			return
+		}
+
+		b.logger.Printf("User %s created collection %s", user.ID, name)
+		b.respondEphemeral(i, fmt.Sprintf("%s Created collection **%s**. Share it with `/bookmarks collection share`, then bookmarks tagged `%s` reach everyone who subscribed.", COLLECTION_EMOJI, name, name))
+	})
+}
+
+// collectionShareCommand lets another user subscribe to a collection and
+// DMs them a button to do so.
+func (b *Bot) collectionShareCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	b.collectionCommand(i, opts, func(user *discordgo.User, name string) {
+		c, ok := b.store.Collection(user.ID, name)
+		if !ok {
+			b.respondEphemeral(i, fmt.Sprintf("You don't own a collection named **%s**.", name))
+			return
+		}
+		member, err := b.optionUser(i, opts["user"])
+		if err != nil {
+			b.logger.Printf("Error getting user %s to share collection %s with: %v", opts["user"].UserValue(nil).ID, name, err)
+			b.respondEphemeral(i, "Something went wrong, please try again later.")
+			return
+		}
+		if member.ID == user.ID || member.Bot {
+			b.respondEphemeral(i, "Share the collection with another member of your team.")
+			return
+		}
+
+		if !c.CanSubscribe(member.ID) {
+			c.Members = append(c.Members, member.ID)
+			if err := b.store.SetCollection(c); err != nil {
+				b.logger.Printf("Error sharing collection %s with user %s: %v", name, member.ID, err)
+				b.recordFailure(alertStore, "", err)
+				b.respondEphemeral(i, "Something went wrong, please try again later.")
+				return
+			}
+		}
+
+		b.logger.Printf("User %s shared collection %s with user %s", user.ID, name, member.ID)
+		reply := fmt.Sprintf("Shared **%s** with <@%s>, who can now subscribe to it.", name, member.ID)
+		if err := b.offerSubscription(user, member.ID, c); err != nil {
+			b.logger.Printf("Error offering collection %s to user %s: %v", name, member.ID, err)
+			reply += fmt.Sprintf(" I couldn't DM them, so tell them to use `/bookmarks collection subscribe %s owner:@%s`.", name, user.Username)
+		}
+		b.respondEphemeral(i, reply)
+	})
+}
+
+// collectionUnshareCommand removes a user from a collection's members and
+// subscribers, so they no longer get its bookmarks.
+func (b *Bot) collectionUnshareCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	b.collectionCommand(i, opts, func(user *discordgo.User, name string) {
+		c, ok := b.store.Collection(user.ID, name)
+		if !ok {
+			b.respondEphemeral(i, fmt.Sprintf("You don't own a collection named **%s**.", name))
+			return
+		}
+		memberID := opts["user"].UserValue(nil).ID
+		if memberID == user.ID {
+			b.respondEphemeral(i, fmt.Sprintf("You own **%s**; use `/bookmarks collection delete` to stop it.", name))
+			return
+		}
+		if !c.CanSubscribe(memberID) && !c.Subscribed(memberID) {
+			b.respondEphemeral(i, fmt.Sprintf("**%s** isn't shared with <@%s>.", name, memberID))
+			return
+		}
+
+		if err := b.store.SetCollection(c.WithoutUser(memberID)); err != nil {
+			b.logger.Printf("Error unsharing collection %s with user %s: %v", name, memberID, err)
+			b.recordFailure(alertStore, "", err)
+			b.respondEphemeral(i, "Something went wrong, please try again later.")
+			return
+		}
+
+		b.logger.Printf("User %s stopped sharing collection %s with user %s", user.ID, name, memberID)
+		b.respondEphemeral(i, fmt.Sprintf("Stopped sharing **%s** with <@%s>, who no longer gets its bookmarks.", name, memberID))
+	})
+}
+
+// collectionDeleteCommand deletes a collection the user owns. Bookmarks
+// already delivered through it stay with their users.
+func (b *Bot) collectionDeleteCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	b.collectionCommand(i, opts, func(user *discordgo.User, name string) {
+		if _, ok := b.store.Collection(user.ID, name); !ok {
+			b.respondEphemeral(i, fmt.Sprintf("You don't own a collection named **%s**.", name))
+			return
+		}
+
+		if err := b.store.DeleteCollection(user.ID, name); err != nil {
+			b.logger.Printf("Error deleting collection %s of user %s: %v", name, user.ID, err)
+			b.recordFailure(alertStore, "", err)
+			b.respondEphemeral(i, "Something went wrong, please try again later.")
+			return
+		}
+
+		b.logger.Printf("User %s deleted collection %s", user.ID, name)
+		b.respondEphemeral(i, fmt.Sprintf("Deleted collection **%s**. Bookmarks tagged `%s` stay private from now on.", name, name))
+	})
+}
+
+// offerSubscription DMs a user a button to subscribe to a shared collection.
+func (b *Bot) offerSubscription(owner *discordgo.User, userID string, c store.Collection) error {
+	dmChannel, err := b.dmChannel(userID)
+	if err != nil {
+		return err
+	}
+	_, err = b.send(dmChannel.ID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("%s %s shared the bookmark collection **%s** with you. Subscribe to get the bookmarks tagged `%s`.", COLLECTION_EMOJI, owner.Username, c.Name, c.Name),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Subscribe",
+					Style:    discordgo.PrimaryButton,
+					CustomID: "collection:subscribe:" + c.OwnerID + ":" + c.Name,
+				},
+			}},
+		},
+	})
+	return err
+}
+
+// collectionSubscribeCommand subscribes the user to a collection shared with them.
+func (b *Bot) collectionSubscribeCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	b.collectionCommand(i, opts, func(user *discordgo.User, name string) {
+		b.respondEphemeral(i, b.setSubscribed(user.ID, collectionOwner(opts), name, true))
+	})
+}
+
+// collectionUnsubscribeCommand stops delivering a collection's bookmarks to the user.
+func (b *Bot) collectionUnsubscribeCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	b.collectionCommand(i, opts, func(user *discordgo.User, name string) {
+		b.respondEphemeral(i, b.setSubscribed(user.ID, collectionOwner(opts), name, false))
+	})
+}
+
+// collectionOwner is the ID of the owner option, empty if it's not given.
+func collectionOwner(opts optionMap) string {
+	if opt, ok := opts["owner"]; ok {
+		return opt.UserValue(nil).ID
+	}
+	return ""
+}
+
+// collectionComponent handles the Subscribe button of a shared collection.
+// Buttons sent before names were unique per owner carry only the name.
+func (b *Bot) collectionComponent(i *discordgo.InteractionCreate, args string) {
+	action, rest, _ := strings.Cut(args, ":")
+	if action != "subscribe" {
+		b.logger.Printf("Warning: Unknown collection action %q", action)
+		return
+	}
+	ownerID, name, ok := strings.Cut(rest, ":")
+	if !ok {
+		ownerID, name = "", rest
+	}
+	b.updateComponentMessage(i, b.setSubscribed(interactionUser(i).ID, ownerID, name, true))
+}
+
+// sharedCollection finds the collection named name that userID may
+// subscribe to, owned by ownerID unless it's empty. It returns the reply
+// when there is none or several.
+func (b *Bot) sharedCollection(userID, ownerID, name string) (store.Collection, string, bool) {
+	name = store.NormalizeCollectionName(name)
+	var found []store.Collection
+	for _, c := range b.store.Collections(userID) {
+		if c.Name == name && (ownerID == "" || c.OwnerID == ownerID) && c.CanSubscribe(userID) {
+			found = append(found, c)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return store.Collection{}, fmt.Sprintf("No collection named **%s** was shared with you.", name), false
+	case 1:
+		return found[0], "", true
+	default:
+		return store.Collection{}, fmt.Sprintf("Several collections named **%s** were shared with you, pick one with the `owner` option.", name), false
+	}
+}
+
+// setSubscribed subscribes or unsubscribes a user and returns the reply.
+func (b *Bot) setSubscribed(userID, ownerID, name string, subscribed bool) string {
+	c, reply, ok := b.sharedCollection(userID, ownerID, name)
+	if !ok {
+		return reply
+	}
+	name = c.Name
+	if c.Subscribed(userID) == subscribed {
+		if subscribed {
+			return fmt.Sprintf("You're already subscribed to **%s**.", name)
+		}
+		return fmt.Sprintf("You aren't subscribed to **%s**.", name)
+	}
+
+	if subscribed {
+		c.Subscribers = append(c.Subscribers, userID)
+	} else {
+		c.Subscribers = slices.DeleteFunc(c.Subscribers, func(id string) bool { return id == userID })
+	}
+	if err := b.store.SetCollection(c); err != nil {
+		b.logger.Printf("Error updating subscribers of collection %s of user %s: %v", name, c.OwnerID, err)
+		b.recordFailure(alertStore, "", err)
+		return "Something went wrong, please try again later."
+	}
+
+	b.logger.Printf("User %s subscribed to collection %s of user %s: %t", userID, name, c.OwnerID, subscribed)
+	if subscribed {
+		return fmt.Sprintf("%s Subscribed to **%s**. Bookmarks tagged `%s` by its members will be sent to you.", COLLECTION_EMOJI, name, name)
+	}
+	return fmt.Sprintf("Unsubscribed from **%s**.", name)
+}
+
+// collectionListCommand lists the collections the user owns, was shared or subscribes to.
+func (b *Bot) collectionListCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+	collections := b.store.Collections(user.ID)
+	if len(collections) == 0 {
+		b.respondEphemeral(i, "You have no collections. Create one with `/bookmarks collection create`.")
+		return
+	}
+
+	lines := make([]string, len(collections))
+	for j, c := range collections {
+		status := "shared with you"
+		if c.Subscribed(user.ID) {
+			status = "subscribed"
+		}
+		if c.OwnerID == user.ID {
+			status += ", yours"
+		} else {
+			status += fmt.Sprintf(", by <@%s>", c.OwnerID)
+		}
+		lines[j] = fmt.Sprintf("**%s** · %d subscriber(s) · %s", c.Name, len(c.Subscribers), status)
+	}
+	b.respondEmbed(i, &discordgo.MessageEmbed{
+		Title:       COLLECTION_EMOJI + " Collections",
+		Description: strings.Join(lines, "\n"),
+		Color:       embed.DEFAULT_EMBED_COLOR,
+	}, discordgo.MessageFlagsEphemeral)
+}
+
+// deliverToCollections sends a new bookmark tagged with collections the
+// user subscribes to to the collections' other subscribers, as a card. Only
+// the user stores the bookmark, and their note and other tags stay private.
+// The quote is left out where not everyone can read the source, like shared
+// bookmarks.
+func (b *Bot) deliverToCollections(user *discordgo.User, guild *discordgo.Guild, msg *discordgo.Message, tags []string) {
+	var names []string
+	recipients := map[string]bool{}
+	for _, c := range b.store.Collections(user.ID) {
+		if !c.Subscribed(user.ID) || !slices.Contains(tags, c.Name) {
+			continue
+		}
+		if !slices.Contains(names, c.Name) {
+			names = append(names, c.Name)
+		}
+		for _, subscriber := range c.Subscribers {
+			if subscriber != user.ID && !b.store.Has(subscriber, msg.ChannelID, msg.ID) {
+				recipients[subscriber] = true
+			}
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	author := embed.AuthorOf(msg)
+	card := embed.BuildCard(embed.Card{
+		GuildName:   guild.Name,
+		ChannelID:   msg.ChannelID,
+		MessageLink: msglink.New(guild.ID, msg.ChannelID, msg.ID).String(),
+		Author:      &author,
+		Content:     msg.Content,
+		Timestamp:   msg.Timestamp,
+		SharedBy:    fmt.Sprintf("%s to %s", user.Username, strings.Join(names, ", ")),
+		Restricted:  b.isNSFWChannel(msg.ChannelID) || !b.everyoneCanRead(guild.ID, msg.ChannelID),
+	})
+
+	for subscriber := range recipients {
+		dmChannel, err := b.dmChannel(subscriber)
+		if err != nil {
+			b.logger.Printf("Error creating DM channel with user %s: %v", subscriber, err)
+			continue
+		}
+		if _, err := b.send(dmChannel.ID, &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{card}}); err != nil {
+			b.logger.Printf("Error sending collection bookmark of message %s to user %s: %v", msg.ID, subscriber, err)
+		}
+	}
+	b.logger.Printf("Sent bookmark of message %s by user %s to %d subscriber(s) of %s", msg.ID, user.ID, len(recipients), strings.Join(names, ", "))
+}
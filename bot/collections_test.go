@@ -0,0 +1,222 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/bwmarrin/discordgo"
+)
+
+// runCollectionCommand runs a /bookmarks collection subcommand as userID.
+// User options are resolved like Discord does, to the fake API's users.
+func runCollectionCommand(b *Bot, userID, sub string, options ...*discordgo.ApplicationCommandInteractionDataOption) {
+	resolved := &discordgo.ApplicationCommandInteractionDataResolved{Users: map[string]*discordgo.User{}}
+	for _, opt := range options {
+		if opt.Type != discordgo.ApplicationCommandOptionUser {
+			continue
+		}
+		id := opt.Value.(string)
+		if user, ok := b.api.(*fakeAPI).users[id]; ok {
+			resolved.Users[id] = user
+		} else {
+			resolved.Users[id] = &discordgo.User{ID: id, Username: id}
+		}
+	}
+
+	b.InteractionCreate(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommand,
+		User: &discordgo.User{ID: userID, Username: userID},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:     "bookmarks",
+			Resolved: resolved,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+				Type: discordgo.ApplicationCommandOptionSubCommandGroup,
+				Name: "collection",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+					Type:    discordgo.ApplicationCommandOptionSubCommand,
+					Name:    sub,
+					Options: options,
+				}},
+			}},
+		},
+	}})
+}
+
+func userOption(name, userID string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Type: discordgo.ApplicationCommandOptionUser, Name: name, Value: userID}
+}
+
+// bookmarkWithTags bookmarks the source message as "user" through the note modal.
+func bookmarkWithTags(b *Bot, tags string) {
+	b.InteractionCreate(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:    discordgo.InteractionModalSubmit,
+		GuildID: "guild",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "user", Username: "alice"}},
+		Data: discordgo.ModalSubmitInteractionData{
+			CustomID: "note:channel:message",
+			Components: []discordgo.MessageComponent{
+				&discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					&discordgo.TextInput{CustomID: "note", Value: "worth a read"},
+				}},
+				&discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					&discordgo.TextInput{CustomID: "tags", Value: tags},
+				}},
+			},
+		},
+	}})
+}
+
+func lastReply(api *fakeAPI) string {
+	return api.responses[len(api.responses)-1].Data.Content
+}
+
+func TestCollectionBookmarksReachSubscribers(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	runCollectionCommand(b, "user", "create", stringOption("name", "Research"))
+	runCollectionCommand(b, "user", "share", stringOption("name", "research"), userOption("user", "teammate"))
+	if len(api.sent) != 1 || api.sent[0].ChannelID != "dm-teammate" {
+		t.Fatalf("sent %+v, want the offer to the teammate", api.sent)
+	}
+	button := api.sent[0].Message.Components[0].(discordgo.ActionsRow).Components[0].(discordgo.Button)
+
+	clickComponent(b, "teammate", "offer", button.CustomID)
+	if c, _ := b.store.Collection("user", "research"); !c.Subscribed("teammate") {
+		t.Fatalf("teammate not subscribed: %+v", c)
+	}
+
+	bookmarkWithTags(b, "research")
+	if len(api.sent) != 3 {
+		t.Fatalf("sent %d messages, want the bookmark and the teammate's copy", len(api.sent))
+	}
+	shared := api.sent[2]
+	if shared.ChannelID != "dm-teammate" || !strings.Contains(shared.Message.Embeds[0].Footer.Text, "research") {
+		t.Errorf("shared copy = %+v", shared)
+	}
+	for _, field := range shared.Message.Embeds[0].Fields {
+		if field.Name == embed.NOTE_FIELD_NAME || strings.Contains(field.Value, "worth a read") {
+			t.Errorf("shared copy has the private note: %+v", field)
+		}
+	}
+	if b.store.Count() != 1 {
+		t.Errorf("stored %d bookmarks, want only the canonical one", b.store.Count())
+	}
+}
+
+func TestCollectionSubscribeNeedsShare(t *testing.T) {
+	b, api := newTestBot(t)
+
+	runCollectionCommand(b, "user", "create", stringOption("name", "research"))
+	runCollectionCommand(b, "stranger", "subscribe", stringOption("name", "research"))
+	if reply := lastReply(api); !strings.Contains(reply, "No collection named") {
+		t.Errorf("reply = %q, want a refusal", reply)
+	}
+
+	runCollectionCommand(b, "user", "create", stringOption("name", "Research"))
+	if reply := lastReply(api); !strings.Contains(reply, "already have a collection") {
+		t.Errorf("reply = %q, want the name taken", reply)
+	}
+	runCollectionCommand(b, "other", "create", stringOption("name", "two words"))
+	if reply := lastReply(api); !strings.Contains(reply, "one word") {
+		t.Errorf("reply = %q, want the name rejected", reply)
+	}
+}
+
+func TestUntaggedBookmarkStaysPrivate(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+	runCollectionCommand(b, "user", "create", stringOption("name", "research"))
+	runCollectionCommand(b, "user", "share", stringOption("name", "research"), userOption("user", "teammate"))
+	runCollectionCommand(b, "teammate", "subscribe", stringOption("name", "research"))
+
+	bookmarkWithTags(b, "recipes")
+	for _, sent := range api.sent[1:] {
+		if sent.ChannelID == "dm-teammate" {
+			t.Errorf("teammate got %+v", sent.Message)
+		}
+	}
+}
+
+func TestCollectionNamesArePerOwner(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	runCollectionCommand(b, "user", "create", stringOption("name", "research"))
+	runCollectionCommand(b, "other", "create", stringOption("name", "research"))
+	if reply := lastReply(api); !strings.Contains(reply, "Created collection") {
+		t.Fatalf("reply = %q, want the second owner's collection created", reply)
+	}
+	runCollectionCommand(b, "user", "share", stringOption("name", "research"), userOption("user", "teammate"))
+	runCollectionCommand(b, "other", "share", stringOption("name", "research"), userOption("user", "teammate"))
+
+	runCollectionCommand(b, "teammate", "subscribe", stringOption("name", "research"))
+	if reply := lastReply(api); !strings.Contains(reply, "Several collections") {
+		t.Errorf("reply = %q, want the owner asked for", reply)
+	}
+	runCollectionCommand(b, "teammate", "subscribe", stringOption("name", "research"), userOption("owner", "other"))
+	if c, _ := b.store.Collection("other", "research"); !c.Subscribed("teammate") {
+		t.Fatalf("teammate not subscribed to other's collection: %+v", c)
+	}
+	if c, _ := b.store.Collection("user", "research"); c.Subscribed("teammate") {
+		t.Errorf("teammate subscribed to user's collection too: %+v", c)
+	}
+
+	// user subscribes to their own research, not other's, so the teammate gets nothing.
+	sent := len(api.sent)
+	bookmarkWithTags(b, "research")
+	for _, m := range api.sent[sent:] {
+		if m.ChannelID == "dm-teammate" {
+			t.Errorf("teammate got %+v from a collection they don't subscribe to", m.Message)
+		}
+	}
+}
+
+func TestCollectionCannotBeSharedWithBots(t *testing.T) {
+	b, api := newTestBot(t)
+	api.users["helper"] = &discordgo.User{ID: "helper", Username: "helper", Bot: true}
+
+	runCollectionCommand(b, "user", "create", stringOption("name", "research"))
+	runCollectionCommand(b, "user", "share", stringOption("name", "research"), userOption("user", "helper"))
+
+	if c, _ := b.store.Collection("user", "research"); c.CanSubscribe("helper") {
+		t.Error("collection shared with a bot")
+	}
+	if len(api.sent) != 0 || !strings.Contains(lastReply(api), "another member") {
+		t.Errorf("sent %+v and replied %q, want the share refused", api.sent, lastReply(api))
+	}
+}
+
+func TestCollectionUnshareAndDeleteStopDelivery(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	runCollectionCommand(b, "user", "create", stringOption("name", "research"))
+	runCollectionCommand(b, "user", "share", stringOption("name", "research"), userOption("user", "teammate"))
+	runCollectionCommand(b, "teammate", "subscribe", stringOption("name", "research"))
+
+	runCollectionCommand(b, "stranger", "unshare", stringOption("name", "research"), userOption("user", "teammate"))
+	if reply := lastReply(api); !strings.Contains(reply, "don't own") {
+		t.Errorf("reply = %q, want only the owner to unshare", reply)
+	}
+	runCollectionCommand(b, "user", "unshare", stringOption("name", "research"), userOption("user", "teammate"))
+	if c, _ := b.store.Collection("user", "research"); c.CanSubscribe("teammate") || c.Subscribed("teammate") {
+		t.Fatalf("teammate still in %+v", c)
+	}
+	runCollectionCommand(b, "teammate", "subscribe", stringOption("name", "research"))
+	if reply := lastReply(api); !strings.Contains(reply, "No collection named") {
+		t.Errorf("reply = %q, want the unshared collection refused", reply)
+	}
+
+	sent := len(api.sent)
+	bookmarkWithTags(b, "research")
+	if len(api.sent) != sent+1 {
+		t.Errorf("sent %d messages, want only the bookmark", len(api.sent)-sent)
+	}
+
+	runCollectionCommand(b, "user", "delete", stringOption("name", "research"))
+	if _, ok := b.store.Collection("user", "research"); ok {
+		t.Error("collection not deleted")
+	}
+}
@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	GUILD_COLOR_CACHE_SIZE = 256
+	GUILD_ICON_MAX_BYTES   = 1 << 20
+)
+
+// guildColor is a cached embed color; ok is false when the icon yielded none.
+type guildColor struct {
+	color int
+	ok    bool
+}
+
+// embedColor returns the embed color of bookmarks from a guild: the configured
+// one, else the dominant color of its icon, else the template's.
+func (b *Bot) embedColor(guild *discordgo.Guild) int {
+	cfg := b.cfg()
+	if color, ok := cfg.GuildColors[guild.ID]; ok {
+		return color
+	}
+	if !cfg.GuildIconColors || guild.Icon == "" {
+		return b.embedTemplate().Color
+	}
+
+	// Keyed by icon hash too, so a new icon is picked up.
+	key := guild.ID + ":" + guild.Icon
+	cached, found := b.guildColors.Get(key)
+	if !found {
+		cached.color, cached.ok = b.iconColor(guild)
+		b.guildColors.Add(key, cached)
+	}
+	if !cached.ok {
+		return b.embedTemplate().Color
+	}
+	return cached.color
+}
+
+func (b *Bot) iconColor(guild *discordgo.Guild) (int, bool) {
+	data, err := downloadAttachment(guild.IconURL("64"), GUILD_ICON_MAX_BYTES)
+	if err != nil {
+		b.logger.Printf("Error downloading icon of guild %s: %v", guild.ID, err)
+		return 0, false
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		b.logger.Printf("Error decoding icon of guild %s: %v", guild.ID, err)
+		return 0, false
+	}
+
+	return embed.DominantColor(img)
+}
@@ -0,0 +1,854 @@
+package bot
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	// minPage is the smallest page number of paged listings.
+	minPage = 1.0
+	// minDays is the shortest time window in days.
+	minDays = 1.0
+	// manageGuild limits server settings to admins unless a server changes it.
+	manageGuild int64 = discordgo.PermissionManageServer
+)
+
+var commands = []*discordgo.ApplicationCommand{
+	{
+		Name:        "bookmarks",
+		Description: "Manage your bookmarks",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Name:        "import",
+				Description: "Import existing messages as bookmarks",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "pins",
+						Description: "Bookmark every pinned message of a channel",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionChannel,
+								Name:        "channel",
+								Description: "Channel to import pins from",
+								Required:    true,
+								ChannelTypes: []discordgo.ChannelType{
+									discordgo.ChannelTypeGuildText,
+									discordgo.ChannelTypeGuildNews,
+									discordgo.ChannelTypeGuildPublicThread,
+									discordgo.ChannelTypeGuildPrivateThread,
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "archive",
+				Description: "Post new bookmarks to a channel instead of your DMs",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "Your archive channel; leave empty to go back to DMs",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "threads",
+						Description: "Organize bookmarks into threads",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "No threads", Value: "none"},
+							{Name: "One thread per server", Value: string(store.ThreadsGuild)},
+							{Name: "One thread per tag", Value: string(store.ThreadsTag)},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "clear",
+				Description: "Delete every bookmark matching the filters",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "guild",
+						Description: "Only bookmarks from this server ID",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "before",
+						Description: "Only bookmarks saved before this date (YYYY-MM-DD)",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "tag",
+						Description: "Only bookmarks with this tag",
+						MaxLength:   CLEAR_TAG_MAX_LENGTH,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "delivery",
+				Description: "Choose whether bookmarks are sent right away or in a digest",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "mode",
+						Description: "How new bookmarks are delivered",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Instant DM per bookmark", Value: "instant"},
+							{Name: "Daily digest", Value: string(store.DeliveryDaily)},
+							{Name: "Weekly digest", Value: string(store.DeliveryWeekly)},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "settings",
+				Description: "Change how your bookmarks are delivered and shown",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "share",
+				Description: "Share one of your bookmarks as a card",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "bookmark",
+						Description: "Bookmark ID like bk-7F3K, or the link of the bookmarked message",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionChannel,
+						Name:        "channel",
+						Description: "Channel to post the card in (defaults to this one)",
+						ChannelTypes: []discordgo.ChannelType{
+							discordgo.ChannelTypeGuildText,
+							discordgo.ChannelTypeGuildNews,
+							discordgo.ChannelTypeGuildPublicThread,
+							discordgo.ChannelTypeGuildPrivateThread,
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "stats",
+				Description: "Show statistics about your bookmarks",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "global",
+						Description: "Show statistics for all users (bot owner only)",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List your bookmarks",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "sort",
+						Description: "Order of the bookmarks (default: pinned first)",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Pinned first", Value: SORT_PINNED},
+							{Name: "Newest first", Value: SORT_NEWEST},
+							{Name: "Oldest first", Value: SORT_OLDEST},
+							{Name: "By server", Value: SORT_GUILD},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "page",
+						Description: "Page to show",
+						MinValue:    &minPage,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "archived",
+						Description: "List archived bookmarks instead",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "pin",
+				Description: "Pin a bookmark to the top of your list",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "bookmark",
+						Description: "Bookmark ID like bk-7F3K, or the link of the bookmarked message",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "pinned",
+						Description: "Set to False to unpin",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "note",
+				Description: "Change the note of a bookmark",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "bookmark",
+						Description: "Bookmark ID like bk-7F3K, or the link of the bookmarked message",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "note",
+						Description: "New note; leave out to remove it",
+						MaxLength:   NOTE_INPUT_MAX_LENGTH,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remind",
+				Description: "Get a DM about a bookmark later",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "bookmark",
+						Description: "Bookmark ID like bk-7F3K, or the link of the bookmarked message",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "when",
+						Description: "When to remind you",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "In 1 hour", Value: "1h"},
+							{Name: "In 3 hours", Value: "3h"},
+							{Name: "Tomorrow", Value: "24h"},
+							{Name: "In a week", Value: "168h"},
+							{Name: "Cancel the reminder", Value: REMINDER_OFF},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reminders",
+				Description: "List your upcoming bookmark reminders",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "history",
+				Description: "Show the earlier versions of a bookmark's message and note",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "bookmark",
+						Description: "Bookmark ID like bk-7F3K, or the link of the bookmarked message",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Name:        "collection",
+				Description: "Share bookmarks with your team through named collections",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "create",
+						Description: "Create a collection; bookmarks you tag with its name reach its subscribers",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "name",
+								Description: "Name of the collection, used as a tag",
+								Required:    true,
+								MaxLength:   TAG_MAX_LENGTH,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "share",
+						Description: "Let another user subscribe to your collection",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "name",
+								Description: "Name of your collection",
+								Required:    true,
+								MaxLength:   TAG_MAX_LENGTH,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "user",
+								Description: "User to share the collection with",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "unshare",
+						Description: "Stop sharing your collection with a user, who no longer gets its bookmarks",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "name",
+								Description: "Name of your collection",
+								Required:    true,
+								MaxLength:   TAG_MAX_LENGTH,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "user",
+								Description: "User to stop sharing the collection with",
+								Required:    true,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "delete",
+						Description: "Delete your collection; its subscribers no longer get its bookmarks",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "name",
+								Description: "Name of your collection",
+								Required:    true,
+								MaxLength:   TAG_MAX_LENGTH,
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "subscribe",
+						Description: "Get the bookmarks of a collection shared with you",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "name",
+								Description: "Name of the collection",
+								Required:    true,
+								MaxLength:   TAG_MAX_LENGTH,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "owner",
+								Description: "Owner of the collection, if several shared with you have this name",
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "unsubscribe",
+						Description: "Stop getting the bookmarks of a collection",
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Type:        discordgo.ApplicationCommandOptionString,
+								Name:        "name",
+								Description: "Name of the collection",
+								Required:    true,
+								MaxLength:   TAG_MAX_LENGTH,
+							},
+							{
+								Type:        discordgo.ApplicationCommandOptionUser,
+								Name:        "owner",
+								Description: "Owner of the collection, if several shared with you have this name",
+							},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "list",
+						Description: "List your collections",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "rerender",
+				Description: "Update your bookmark messages to the current format",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "all",
+						Description: "Re-render every user's bookmarks (bot owner only)",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "delete",
+				Description: "Delete a bookmark",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "bookmark",
+						Description: "Bookmark ID like bk-7F3K, or the link of the bookmarked message",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "integration",
+				Description: "Also send new bookmarks to a webhook or a Notion database",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "webhook",
+						Description: "HTTPS URL receiving each new bookmark as JSON",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "notion_token",
+						Description: "Secret of your Notion integration",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "notion_database",
+						Description: "ID or link of the Notion database to add pages to",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "disable",
+						Description: "Remove your integrations",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "forget-me",
+				Description: "Delete everything the bot stores about you",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "delete_messages",
+						Description: "Also delete the bookmark messages",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "forget-user",
+				Description: "Delete everything stored about a user (bot owner only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "user_id",
+						Description: "ID of the user",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "delete_messages",
+						Description: "Also delete their bookmark messages",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "backup",
+				Description: "Download a backup of every stored bookmark (bot owner only)",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "restore",
+				Description: "Replace every stored bookmark with a backup (bot owner only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionAttachment,
+						Name:        "file",
+						Description: "Backup made with /bookmarks backup",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "replace",
+						Description: "Confirm that the current bookmarks are replaced",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "audit",
+				Description: "Show who created, deleted, exported or imported bookmarks (bot owner only)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "user_id",
+						Description: "Only actions by or affecting this user ID",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "guild",
+						Description: "Only actions in this server ID",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "since",
+						Description: "Only actions on or after this date (YYYY-MM-DD)",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "until",
+						Description: "Only actions on or before this date (YYYY-MM-DD)",
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:                     "bookmarker",
+		Description:              "Server settings of the bookmark bot",
+		DefaultMemberPermissions: &manageGuild,
+		DMPermission:             new(bool),
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "disable",
+				Description: "Stop members from bookmarking messages of this server",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "enable",
+				Description: "Allow bookmarking messages of this server again",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "allow-role",
+				Description: "Only let members with this role, or another allowed one, bookmark messages",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "role",
+						Description: "Role allowed to bookmark",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove-role",
+				Description: "Stop letting members bookmark because of this role",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "role",
+						Description: "Role to remove",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "reactions",
+				Description: "Choose whether 🔖 reactions are removed from messages once bookmarked",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "removal",
+						Description: "When reactions are removed",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "As each member chose", Value: "members"},
+							{Name: "Always", Value: string(store.ReactionRemovalAlways)},
+							{Name: "Never", Value: string(store.ReactionRemovalNever)},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "analytics",
+				Description: "See which channels and messages get bookmarked most, without who bookmarked them",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "days",
+						Description: "How many days back to count bookmarks (default 30)",
+						MinValue:    &minDays,
+						MaxValue:    365,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "analytics-privacy",
+				Description: "Turn bookmark analytics of this server on or off",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "analytics",
+						Description: "Whether admins can see anonymized bookmark counts",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "On", Value: "on"},
+							{Name: "Off", Value: "off"},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "status",
+				Description: "Show uptime, gateway latency, event and error counts (bot owner only)",
+			},
+		},
+	},
+	{
+		Type:         discordgo.MessageApplicationCommand,
+		Name:         BOOKMARK_COMMAND,
+		DMPermission: new(bool),
+	},
+	{
+		Type:         discordgo.MessageApplicationCommand,
+		Name:         BOOKMARK_NOTE_COMMAND,
+		DMPermission: new(bool),
+	},
+}
+
+// commandHandlers maps a full command path (e.g. "bookmarks import pins") to its handler.
+var commandHandlers = map[string]func(b *Bot, i *discordgo.InteractionCreate, opts optionMap){
+	"bookmarks import pins":            (*Bot).importPinsCommand,
+	"bookmarks archive":                (*Bot).archiveChannelCommand,
+	"bookmarks clear":                  (*Bot).clearCommand,
+	"bookmarks delivery":               (*Bot).deliveryCommand,
+	"bookmarks settings":               (*Bot).settingsCommand,
+	"bookmarks share":                  (*Bot).shareCommand,
+	"bookmarks stats":                  (*Bot).statsCommand,
+	"bookmarks list":                   (*Bot).listCommand,
+	"bookmarks pin":                    (*Bot).pinCommand,
+	"bookmarks note":                   (*Bot).noteCommand,
+	"bookmarks remind":                 (*Bot).remindCommand,
+	"bookmarks reminders":              (*Bot).remindersCommand,
+	"bookmarks history":                (*Bot).historyCommand,
+	"bookmarks rerender":               (*Bot).rerenderCommand,
+	"bookmarks collection create":      (*Bot).collectionCreateCommand,
+	"bookmarks collection share":       (*Bot).collectionShareCommand,
+	"bookmarks collection unshare":     (*Bot).collectionUnshareCommand,
+	"bookmarks collection delete":      (*Bot).collectionDeleteCommand,
+	"bookmarks collection subscribe":   (*Bot).collectionSubscribeCommand,
+	"bookmarks collection unsubscribe": (*Bot).collectionUnsubscribeCommand,
+	"bookmarks collection list":        (*Bot).collectionListCommand,
+	"bookmarks delete":                 (*Bot).deleteCommand,
+	"bookmarks integration":            (*Bot).integrationCommand,
+	"bookmarks forget-me":              (*Bot).forgetMeCommand,
+	"bookmarks forget-user":            (*Bot).forgetUserCommand,
+	"bookmarks backup":                 (*Bot).backupCommand,
+	"bookmarks restore":                (*Bot).restoreCommand,
+	"bookmarks audit":                  (*Bot).auditCommand,
+	"bookmarker disable":               (*Bot).guildDisableCommand,
+	"bookmarker enable":                (*Bot).guildEnableCommand,
+	"bookmarker allow-role":            (*Bot).guildAllowRoleCommand,
+	"bookmarker remove-role":           (*Bot).guildRemoveRoleCommand,
+	"bookmarker reactions":             (*Bot).guildReactionsCommand,
+	"bookmarker analytics":             (*Bot).guildAnalyticsCommand,
+	"bookmarker analytics-privacy":     (*Bot).guildAnalyticsPrivacyCommand,
+	"bookmarker status":                (*Bot).statusCommand,
+	BOOKMARK_COMMAND:                   (*Bot).bookmarkMessageCommand,
+	BOOKMARK_NOTE_COMMAND:              (*Bot).bookmarkWithNoteCommand,
+}
+
+// componentHandlers maps the prefix of a component custom ID, up to the first
+// ":", to its handler, which receives the rest of the ID.
+var componentHandlers = map[string]func(b *Bot, i *discordgo.InteractionCreate, args string){
+	"bookmark":   (*Bot).bookmarkComponent,
+	"clear":      (*Bot).clearComponent,
+	"collection": (*Bot).collectionComponent,
+	"forget":     (*Bot).forgetComponent,
+	"reminder":   (*Bot).reminderComponent,
+	"repost":     (*Bot).repostComponent,
+	"settings":   (*Bot).settingsComponent,
+	"undo":       (*Bot).undoComponent,
+}
+
+// modalHandlers maps the prefix of a modal custom ID like componentHandlers.
+var modalHandlers = map[string]func(b *Bot, i *discordgo.InteractionCreate, args string){
+	"note": (*Bot).noteModal,
+}
+
+type optionMap map[string]*discordgo.ApplicationCommandInteractionDataOption
+
+func (b *Bot) registerCommands() {
+	n, err := RegisterCommands(b.api, b.state.User.ID)
+	if err != nil {
+		b.logger.Printf("Error registering application commands: %v", err)
+		return
+	}
+	b.logger.Printf("Registered %d application command(s)", n)
+}
+
+// RegisterCommands replaces the global commands of the application with the
+// bot's commands and returns how many were registered.
+func RegisterCommands(api DiscordAPI, appID string) (int, error) {
+	installables := make([]installableCommand, len(commands))
+	for j, cmd := range commands {
+		installables[j] = installable(cmd)
+	}
+	_, err := api.Request(http.MethodPut, discordgo.EndpointApplicationGlobalCommands(appID), installables)
+	if err != nil {
+		return 0, err
+	}
+	return len(commands), nil
+}
+
+func (b *Bot) InteractionCreate(i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		b.handleCommand(i)
+	case discordgo.InteractionMessageComponent:
+		b.handleComponent(i)
+	case discordgo.InteractionModalSubmit:
+		b.handleModal(i)
+	}
+}
+
+func (b *Bot) handleCommand(i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	path, opts := commandPath(data.Name, data.Options)
+
+	handler, ok := commandHandlers[path]
+	if !ok {
+		b.logger.Printf("Warning: No handler for command %q", path)
+		return
+	}
+
+	b.logger.Printf("Processing command %q from user %s", path, interactionUser(i).ID)
+	handler(b, i, opts)
+}
+
+func (b *Bot) handleComponent(i *discordgo.InteractionCreate) {
+	customID := i.MessageComponentData().CustomID
+	prefix, args, _ := strings.Cut(customID, ":")
+
+	handler, ok := componentHandlers[prefix]
+	if !ok {
+		b.logger.Printf("Warning: No handler for component %q", customID)
+		return
+	}
+
+	b.logger.Printf("Processing component %q from user %s", customID, interactionUser(i).ID)
+	handler(b, i, args)
+}
+
+func (b *Bot) handleModal(i *discordgo.InteractionCreate) {
+	customID := i.ModalSubmitData().CustomID
+	prefix, args, _ := strings.Cut(customID, ":")
+
+	handler, ok := modalHandlers[prefix]
+	if !ok {
+		b.logger.Printf("Warning: No handler for modal %q", customID)
+		return
+	}
+
+	b.logger.Printf("Processing modal %q from user %s", customID, interactionUser(i).ID)
+	handler(b, i, args)
+}
+
+// commandPath walks sub command groups and sub commands, returning the full
+// command path and the options of the innermost sub command.
+func commandPath(name string, options []*discordgo.ApplicationCommandInteractionDataOption) (string, optionMap) {
+	path := []string{name}
+	for len(options) == 1 && (options[0].Type == discordgo.ApplicationCommandOptionSubCommandGroup ||
+		options[0].Type == discordgo.ApplicationCommandOptionSubCommand) {
+		path = append(path, options[0].Name)
+		options = options[0].Options
+	}
+
+	opts := make(optionMap, len(options))
+	for _, opt := range options {
+		opts[opt.Name] = opt
+	}
+
+	return strings.Join(path, " "), opts
+}
+
+// interactionUser returns the invoking user in both guild and DM contexts.
+func interactionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// optionUser returns the user picked in a user option. Discord resolves it
+// with the command; the option alone only carries the ID.
+func (b *Bot) optionUser(i *discordgo.InteractionCreate, opt *discordgo.ApplicationCommandInteractionDataOption) (*discordgo.User, error) {
+	id := opt.UserValue(nil).ID
+	if resolved := i.ApplicationCommandData().Resolved; resolved != nil {
+		if user, ok := resolved.Users[id]; ok {
+			return user, nil
+		}
+	}
+	return b.user(id)
+}
+
+// respondEmbed answers an interaction with an embed.
+func (b *Bot) respondEmbed(i *discordgo.InteractionCreate, e *discordgo.MessageEmbed, flags discordgo.MessageFlags) {
+	err := b.api.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{e},
+			Flags:  flags,
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error responding to interaction %s: %v", i.ID, err)
+	}
+}
+
+func (b *Bot) respondEphemeral(i *discordgo.InteractionCreate, content string) {
+	err := b.api.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error responding to interaction %s: %v", i.ID, err)
+	}
+}
+
+// deferEphemeral acknowledges a slow command; finish it with editResponse.
+func (b *Bot) deferEphemeral(i *discordgo.InteractionCreate) bool {
+	err := b.api.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error deferring interaction %s: %v", i.ID, err)
+		return false
+	}
+	return true
+}
+
+func (b *Bot) editResponse(i *discordgo.InteractionCreate, content string) {
+	_, err := b.api.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+	if err != nil {
+		b.logger.Printf("Error editing response to interaction %s: %v", i.ID, err)
+	}
+}
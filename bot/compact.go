@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/bwmarrin/discordgo"
+)
+
+// buildCompactEmbed renders the compact bookmark embed of users who chose
+// compact embeds, in the source guild's color.
+func (b *Bot) buildCompactEmbed(msg *discordgo.Message, guild *discordgo.Guild, messageLink string) *discordgo.MessageEmbed {
+	resolved := *msg
+	resolved.Content = embed.ResolveMentions(msg.Content, b.mentionNames(msg, guild))
+	if contentUnavailable(msg) {
+		resolved.Content = CONTENT_UNAVAILABLE_NOTICE
+	}
+	return embed.BuildCompact(&resolved, guild.Name, messageLink, b.embedColor(guild))
+}
+
+// expandBookmark replaces a compact bookmark embed with the full one.
+func (b *Bot) expandBookmark(i *discordgo.InteractionCreate) {
+	user := interactionUser(i)
+	unlock := b.lockBookmarkDM(user.ID, i.ChannelID, i.Message.ID)
+	defer unlock()
+
+	bm, ok := b.store.ByDM(i.ChannelID, i.Message.ID)
+	if !ok || bm.UserID != user.ID {
+		b.respondEphemeral(i, "This isn't your bookmark.")
+		return
+	}
+
+	full, _ := b.rebuildEmbed(bm)
+	embed.SetSeenIn(full, sourceLinks(bm))
+	err := b.api.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{full},
+			Components: bookmarkComponents(false),
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error responding to interaction %s: %v", i.ID, err)
+		return
+	}
+
+	bm.Compact = false
+	err = b.store.Update(bm)
+	if err != nil {
+		b.logger.Printf("Error updating stored bookmark for user %s: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+	}
+}
@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCompactBookmarkExpands(t *testing.T) {
+	b, api := newTestBot(t)
+	b.store.SetSettings("user", store.UserSettings{Verbosity: store.VerbosityCompact})
+	src := sourceMessage()
+	src.Content = "first line\nsecond line"
+	api.addMessage(src)
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want the bookmark", len(api.sent))
+	}
+	sent := api.sent[0].Message
+	compact := sent.Embeds[0]
+	if !strings.HasPrefix(compact.Description, "first line · [Jump](") || len(compact.Fields) != 0 {
+		t.Errorf("compact embed = %q with %d fields", compact.Description, len(compact.Fields))
+	}
+	row := sent.Components[0].(discordgo.ActionsRow)
+	expand := row.Components[len(row.Components)-1].(discordgo.Button)
+	if expand.CustomID != "bookmark:expand" {
+		t.Fatalf("last button = %q, want Expand", expand.CustomID)
+	}
+	bm := b.store.ForUser("user")[0]
+	if !bm.Compact {
+		t.Fatal("bookmark isn't marked compact")
+	}
+
+	clickComponent(b, "user", bm.DMMessageID, expand.CustomID)
+	resp := api.responses[len(api.responses)-1]
+	if resp.Type != discordgo.InteractionResponseUpdateMessage || !strings.Contains(resp.Data.Embeds[0].Description, "second line") {
+		t.Fatalf("response = %+v, want the full embed", resp.Data)
+	}
+	for _, c := range resp.Data.Components[0].(discordgo.ActionsRow).Components {
+		if c.(discordgo.Button).CustomID == "bookmark:expand" {
+			t.Error("expanded bookmark still offers Expand")
+		}
+	}
+	if b.store.ForUser("user")[0].Compact {
+		t.Error("expanded bookmark is still marked compact")
+	}
+}
+
+func TestExpandRefusesOtherUsers(t *testing.T) {
+	b, api := newTestBot(t)
+	b.store.SetSettings("user", store.UserSettings{Verbosity: store.VerbosityCompact})
+	deliverTestBookmark(t, b, api)
+	bm := b.store.ForUser("user")[0]
+
+	clickComponent(b, "someone-else", bm.DMMessageID, "bookmark:expand")
+	if resp := api.responses[len(api.responses)-1]; resp.Data.Content != "This isn't your bookmark." {
+		t.Errorf("response = %q", resp.Data.Content)
+	}
+	if !b.store.ForUser("user")[0].Compact {
+		t.Error("bookmark expanded by another user")
+	}
+}
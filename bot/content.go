@@ -0,0 +1,21 @@
+package bot
+
+import "github.com/bwmarrin/discordgo"
+
+// CONTENT_UNAVAILABLE_NOTICE is the description of bookmarks whose message
+// came back blank.
+const CONTENT_UNAVAILABLE_NOTICE = "*Content unavailable: the bot lacks the Message Content intent or permission to read this message.*"
+
+// contentUnavailable reports whether msg came back with nothing to show, as
+// Discord returns messages when the bot lacks the Message Content intent.
+// Only regular messages and replies count; they can't be empty otherwise.
+func contentUnavailable(msg *discordgo.Message) bool {
+	if msg.Type != discordgo.MessageTypeDefault && msg.Type != discordgo.MessageTypeReply {
+		return false
+	}
+	return msg.Content == "" &&
+		len(msg.Attachments) == 0 &&
+		len(msg.Embeds) == 0 &&
+		len(msg.StickerItems) == 0 &&
+		len(msg.Components) == 0
+}
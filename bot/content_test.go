@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestBlankMessageShowsContentUnavailable(t *testing.T) {
+	b, api := newTestBot(t)
+	msg := sourceMessage()
+	msg.Content = ""
+	api.addMessage(msg)
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want the bookmark", len(api.sent))
+	}
+	if got := api.sent[0].Message.Embeds[0].Description; got != CONTENT_UNAVAILABLE_NOTICE {
+		t.Errorf("description = %q, want the unavailable notice", got)
+	}
+}
+
+func TestContentUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *discordgo.Message
+		want bool
+	}{
+		{"blank", &discordgo.Message{}, true},
+		{"text", &discordgo.Message{Content: "hi"}, false},
+		{"attachment only", &discordgo.Message{Attachments: []*discordgo.MessageAttachment{{ID: "a"}}}, false},
+		{"sticker only", &discordgo.Message{StickerItems: []*discordgo.StickerItem{{ID: "s"}}}, false},
+		{"system message", &discordgo.Message{Type: discordgo.MessageTypeGuildMemberJoin}, false},
+	}
+	for _, tt := range tests {
+		if got := contentUnavailable(tt.msg); got != tt.want {
+			t.Errorf("%s: contentUnavailable = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
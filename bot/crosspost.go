@@ -0,0 +1,33 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/msglink"
+	"github.com/bwmarrin/discordgo"
+)
+
+// messageOrigin returns the link and guild a bookmark of msg credits. For a
+// message crossposted from an announcement channel, that's the original post
+// rather than the copy in the following channel.
+func (b *Bot) messageOrigin(guild *discordgo.Guild, msg *discordgo.Message) (msglink.Link, *discordgo.Guild) {
+	ref := msg.MessageReference
+	if msg.Flags&discordgo.MessageFlagsIsCrossPosted == 0 || ref == nil || ref.GuildID == "" || ref.MessageID == "" {
+		return msglink.New(guild.ID, msg.ChannelID, msg.ID), guild
+	}
+
+	link := msglink.New(ref.GuildID, ref.ChannelID, ref.MessageID)
+	if origin, err := b.state.Guild(ref.GuildID); err == nil {
+		return link, origin
+	}
+
+	// The bot usually isn't in the source guild, but the copy is posted by a
+	// webhook named "<guild> #<channel>".
+	origin := &discordgo.Guild{ID: ref.GuildID, Name: guild.Name}
+	if msg.Author != nil {
+		if i := strings.LastIndex(msg.Author.Username, " #"); i > 0 {
+			origin.Name = msg.Author.Username[:i]
+		}
+	}
+	return link, origin
+}
@@ -0,0 +1,33 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCrosspostLinksOriginalAnnouncement(t *testing.T) {
+	b, api := newTestBot(t)
+	msg := sourceMessage()
+	msg.Flags = discordgo.MessageFlagsIsCrossPosted
+	msg.Author = &discordgo.User{ID: "webhook", Username: "News Server #announcements", Bot: true}
+	msg.MessageReference = &discordgo.MessageReference{GuildID: "origin-guild", ChannelID: "origin-channel", MessageID: "origin-message"}
+	api.addMessage(msg)
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(api.sent))
+	}
+	embed := api.sent[0].Message.Embeds[0]
+	if want := "https://discord.com/channels/origin-guild/origin-channel/origin-message"; embed.URL != want {
+		t.Errorf("embed links %q, want %q", embed.URL, want)
+	}
+	if embed.Title != "Bookmark from News Server" {
+		t.Errorf("embed title = %q", embed.Title)
+	}
+	// The copy is what the user reacted to, so it stays the stored key.
+	if !b.store.Has("user", "channel", "message") {
+		t.Error("bookmark not stored under the crossposted copy")
+	}
+}
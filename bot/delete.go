@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// bookmarkComponents are the buttons under a delivered bookmark. Unlike the
+// ❌ reaction they don't depend on DM reaction events reaching the bot.
+// Compact bookmarks can be expanded to the full embed.
+func bookmarkComponents(compact bool) []discordgo.MessageComponent {
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{
+			Label:    "Delete bookmark",
+			Style:    discordgo.DangerButton,
+			Emoji:    &discordgo.ComponentEmoji{Name: DELETE_EMOJI},
+			CustomID: "bookmark:delete",
+		},
+		discordgo.Button{
+			Label:    "Refresh reactions",
+			Style:    discordgo.SecondaryButton,
+			Emoji:    &discordgo.ComponentEmoji{Name: REFRESH_EMOJI},
+			CustomID: "bookmark:refresh",
+		},
+	}
+	if compact {
+		buttons = append(buttons, discordgo.Button{
+			Label:    "Expand",
+			Style:    discordgo.PrimaryButton,
+			Emoji:    &discordgo.ComponentEmoji{Name: EXPAND_EMOJI},
+			CustomID: "bookmark:expand",
+		})
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+// bookmarkComponent handles the buttons of a bookmark message. Deleting asks
+// for confirmation in an ephemeral reply that carries the bookmark message.
+func (b *Bot) bookmarkComponent(i *discordgo.InteractionCreate, args string) {
+	user := interactionUser(i)
+
+	switch action, dmMessageID, _ := strings.Cut(args, ":"); action {
+	case "delete":
+		if !b.isOwnBookmarkMessage(user.ID, i.ChannelID, i.Message.ID) {
+			b.respondEphemeral(i, "This isn't your bookmark.")
+			return
+		}
+		b.confirmDelete(i, i.Message.ID)
+	case "refresh":
+		b.refreshReactions(i)
+	case "expand":
+		b.expandBookmark(i)
+	case "confirm":
+		if !b.isOwnBookmarkMessage(user.ID, i.ChannelID, dmMessageID) {
+			b.updateComponentMessage(i, "This bookmark was already deleted.")
+			return
+		}
+		unlock := b.lockBookmarkDM(user.ID, i.ChannelID, dmMessageID)
+		defer unlock()
+		if !b.deleteBookmark(user.ID, i.ChannelID, dmMessageID) {
+			b.updateComponentMessage(i, "Something went wrong, please try again later.")
+			return
+		}
+		b.updateComponentMessage(i, "Bookmark deleted.")
+	default:
+		b.updateComponentMessage(i, "Kept the bookmark.")
+	}
+}
+
+func (b *Bot) confirmDelete(i *discordgo.InteractionCreate, dmMessageID string) {
+	err := b.api.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "Delete this bookmark?",
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Delete",
+						Style:    discordgo.DangerButton,
+						CustomID: "bookmark:confirm:" + dmMessageID,
+					},
+					discordgo.Button{
+						Label:    "Keep",
+						Style:    discordgo.SecondaryButton,
+						CustomID: "bookmark:cancel",
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error responding to interaction %s: %v", i.ID, err)
+	}
+}
+
+// deleteCommand deletes a bookmark by its short ID or the link of its
+// message. Unlike the button it doesn't ask for confirmation: the bookmark is
+// named explicitly, and Undo is offered all the same.
+func (b *Bot) deleteCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+
+	bm, reply, ok := b.findBookmark(user.ID, opts["bookmark"].StringValue())
+	if !ok {
+		b.respondEphemeral(i, reply)
+		return
+	}
+
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(user.ID, bm.ChannelID, bm.MessageID))
+	defer unlock()
+
+	if !bm.HasDM() {
+		// Waiting for a digest, there is no message to delete.
+		removed, err := b.store.RemoveIf(func(other store.Bookmark) bool {
+			return other.UserID == user.ID && other.ChannelID == bm.ChannelID && other.MessageID == bm.MessageID
+		})
+		if err != nil {
+			b.logger.Printf("Error removing stored bookmark of user %s: %v", user.ID, err)
+			b.recordFailure(alertStore, "", err)
+			b.respondEphemeral(i, "Something went wrong, please try again later.")
+			return
+		}
+		if len(removed) > 0 {
+			b.auditBookmark(store.AuditDelete, user.ID, bm)
+		}
+		b.respondEphemeral(i, fmt.Sprintf("Deleted `%s`, it won't be in your next digest.", bm.ShortID))
+		return
+	}
+
+	if !b.deleteBookmark(user.ID, bm.DMChannelID, bm.DMMessageID) {
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+	b.respondEphemeral(i, fmt.Sprintf("Deleted `%s`.", bm.ShortID))
+}
@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDeleteButtonConfirmsAndDeletes(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+
+	row := api.sent[0].Message.Components[0].(discordgo.ActionsRow)
+	deleteID := row.Components[0].(discordgo.Button).CustomID
+
+	clickComponent(b, "user", r.MessageID, deleteID)
+	if len(api.deleted) != 0 {
+		t.Fatal("bookmark deleted without confirmation")
+	}
+	prompt := api.responses[len(api.responses)-1]
+	if prompt.Data.Flags != discordgo.MessageFlagsEphemeral {
+		t.Error("confirmation isn't ephemeral")
+	}
+	confirmID := prompt.Data.Components[0].(discordgo.ActionsRow).Components[0].(discordgo.Button).CustomID
+
+	// The ephemeral prompt is its own message; the ID carries the bookmark.
+	clickComponent(b, "user", "ephemeral", confirmID)
+
+	if len(api.deleted) != 1 || api.deleted[0] != "dm-user/"+r.MessageID {
+		t.Errorf("deleted = %v, want the bookmark message", api.deleted)
+	}
+	if len(b.store.ForUser("user")) != 0 {
+		t.Error("bookmark still stored")
+	}
+	if len(api.reactionsRemoved) != 1 {
+		t.Errorf("removed %d reactions from the source, want 1", len(api.reactionsRemoved))
+	}
+}
+
+func TestDeleteButtonRejectsOtherUsers(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+
+	clickComponent(b, "intruder", r.MessageID, "bookmark:confirm:"+r.MessageID)
+
+	if len(api.deleted) != 0 || len(b.store.ForUser("user")) != 1 {
+		t.Error("another user deleted the bookmark")
+	}
+}
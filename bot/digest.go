@@ -0,0 +1,227 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/msglink"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// DIGEST_CHECK_INTERVAL is how often due digests are looked for.
+	DIGEST_CHECK_INTERVAL = 10 * time.Minute
+	DIGEST_SNIPPET_LENGTH = 80
+	// Discord limits embeds to 25 fields of 1024 characters.
+	DIGEST_MAX_FIELDS      = 25
+	DIGEST_MAX_FIELD_CHARS = 1024
+)
+
+// startDigests starts the digest scheduler once; later Ready events are no-ops.
+func (b *Bot) startDigests() {
+	b.digestOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(DIGEST_CHECK_INTERVAL)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-b.done:
+					return
+				case now := <-ticker.C:
+					b.sendDueDigests(now)
+				}
+			}
+		}()
+	})
+}
+
+// sendDueDigests sends the digest of every user whose period has elapsed.
+func (b *Bot) sendDueDigests(now time.Time) {
+	for userID, settings := range b.store.AllSettings() {
+		interval := settings.Delivery.Interval()
+		if interval == 0 || now.Sub(settings.LastDigest) < interval {
+			continue
+		}
+		// Instances sharing the store all see the digest due. The claim is
+		// kept rather than released, so an instance that read the settings
+		// before the digest was recorded doesn't send it again.
+		key := fmt.Sprintf("digest:%s:%d", userID, settings.LastDigest.Unix())
+		if _, ok := b.claim(key, "digest of user "+userID); !ok {
+			continue
+		}
+		b.sendDigest(userID, settings.Delivery, now)
+	}
+}
+
+// sendDigest DMs the user one summary of their bookmarks pending at now. The
+// digest period restarts at now even if nothing was pending. Bookmarks made
+// after now are left for the next digest, as MarkDigested leaves them pending.
+func (b *Bot) sendDigest(userID string, delivery store.Delivery, now time.Time) {
+	pending := slices.DeleteFunc(b.store.Pending(userID), func(bm store.Bookmark) bool { return bm.CreatedAt.After(now) })
+	if len(pending) > 0 {
+		dmChannel, err := b.dmChannel(userID)
+		if err != nil {
+			b.logger.Printf("Error creating DM channel with user %s: %v", userID, err)
+			return
+		}
+
+		_, err = b.send(dmChannel.ID, &discordgo.MessageSend{
+			Embeds: []*discordgo.MessageEmbed{b.digestEmbed(delivery, pending)},
+		})
+		if err != nil {
+			b.logger.Printf("Error sending digest to user %s: %v", userID, err)
+			return
+		}
+
+		b.logger.Printf("Sent digest of %d bookmark(s) to user %s", len(pending), userID)
+	}
+
+	err := b.store.MarkDigested(userID, now)
+	if err != nil {
+		b.logger.Printf("Error recording digest for user %s: %v", userID, err)
+		b.recordFailure(alertStore, "", err)
+	}
+}
+
+// digestEmbed lists bookmarks grouped by server, one field per server.
+func (b *Bot) digestEmbed(delivery store.Delivery, bookmarks []store.Bookmark) *discordgo.MessageEmbed {
+	title := "Your bookmarks digest"
+	switch delivery {
+	case store.DeliveryDaily:
+		title = "Your daily bookmarks digest"
+	case store.DeliveryWeekly:
+		title = "Your weekly bookmarks digest"
+	}
+
+	digest := &discordgo.MessageEmbed{
+		Title:       title,
+		Description: fmt.Sprintf("%d new bookmark(s)", len(bookmarks)),
+		Color:       embed.DEFAULT_EMBED_COLOR,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+
+	byGuild := map[string][]store.Bookmark{}
+	for _, bm := range bookmarks {
+		byGuild[bm.GuildID] = append(byGuild[bm.GuildID], bm)
+	}
+
+	guildIDs := make([]string, 0, len(byGuild))
+	for guildID := range byGuild {
+		guildIDs = append(guildIDs, guildID)
+	}
+	sort.Strings(guildIDs)
+
+	for j, guildID := range guildIDs {
+		if j == DIGEST_MAX_FIELDS {
+			digest.Footer = &discordgo.MessageEmbedFooter{
+				Text: fmt.Sprintf("…and bookmarks from %d more server(s)", len(guildIDs)-DIGEST_MAX_FIELDS),
+			}
+			break
+		}
+
+		var lines []string
+		for _, bm := range byGuild[guildID] {
+			lines = append(lines, b.digestLine(bm))
+		}
+		digest.Fields = append(digest.Fields, &discordgo.MessageEmbedField{
+			Name:  b.guildName(guildID),
+			Value: joinLimited(lines, DIGEST_MAX_FIELD_CHARS),
+		})
+	}
+
+	return digest
+}
+
+func (b *Bot) digestLine(bm store.Bookmark) string {
+	return "• " + b.bookmarkLine(bm)
+}
+
+// bookmarkLine links a snippet of the bookmark to its source message, after
+// its short ID.
+func (b *Bot) bookmarkLine(bm store.Bookmark) string {
+	snippet := strings.TrimSpace(strings.SplitN(bm.Content, "\n", 2)[0])
+	if runes := []rune(snippet); len(runes) > DIGEST_SNIPPET_LENGTH {
+		snippet = string(runes[:DIGEST_SNIPPET_LENGTH]) + "…"
+	}
+	if snippet == "" {
+		snippet = "(no text)"
+	}
+	snippet = strings.NewReplacer("[", "(", "]", ")").Replace(snippet)
+	if b.spoilerMedia(bm.ChannelID) {
+		snippet = "||" + snippet + "||"
+	}
+
+	line := fmt.Sprintf("[%s](%s) in <#%s>", snippet, msglink.New(bm.GuildID, bm.ChannelID, bm.MessageID), bm.ChannelID)
+	if bm.ShortID != "" {
+		line = "`" + bm.ShortID + "` " + line
+	}
+	return line
+}
+
+// joinLimited joins lines with newlines, replacing the lines that don't fit
+// in limit bytes with a count.
+func joinLimited(lines []string, limit int) string {
+	var sb strings.Builder
+	for j, line := range lines {
+		if sb.Len()+len(line)+len("\n…and 9999 more") > limit {
+			fmt.Fprintf(&sb, "…and %d more", len(lines)-j)
+			break
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (b *Bot) deliveryCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+	delivery := store.Delivery(opts["mode"].StringValue())
+	if delivery == "instant" {
+		delivery = store.DeliveryInstant
+	}
+
+	settings := b.store.Settings(user.ID)
+	if delivery == settings.Delivery {
+		b.respondEphemeral(i, "That's already your delivery mode.")
+		return
+	}
+
+	settings = b.switchDelivery(user.ID, settings, delivery)
+	err := b.store.SetSettings(user.ID, settings)
+	if err != nil {
+		b.logger.Printf("Error saving settings of user %s: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	switch delivery {
+	case store.DeliveryInstant:
+		b.respondEphemeral(i, "Bookmarks will be sent to your DMs right away.")
+	default:
+		b.respondEphemeral(i, fmt.Sprintf("Bookmarks will be collected and sent to you in a %s digest.", delivery))
+	}
+}
+
+// switchDelivery returns the user's settings with delivery set, for the
+// caller to save. Bookmarks collected so far are sent right away when
+// leaving digest mode.
+func (b *Bot) switchDelivery(userID string, settings store.UserSettings, delivery store.Delivery) store.UserSettings {
+	if previous := settings.Delivery; previous.Interval() > 0 {
+		b.sendDigest(userID, previous, time.Now())
+		settings = b.store.Settings(userID)
+	}
+
+	settings.Delivery = delivery
+	settings.LastDigest = time.Now()
+	if delivery == store.DeliveryInstant {
+		settings.LastDigest = time.Time{}
+	}
+	return settings
+}
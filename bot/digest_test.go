@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+)
+
+func TestDigestCollectsAndSends(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	start := time.Now()
+	err := b.store.SetSettings("user", store.UserSettings{Delivery: store.DeliveryDaily, LastDigest: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(api.sent) != 0 {
+		t.Fatalf("sent %d messages before the digest, want 0", len(api.sent))
+	}
+	if n := len(b.store.Pending("user")); n != 1 {
+		t.Fatalf("%d pending bookmarks, want 1", n)
+	}
+
+	b.sendDueDigests(start.Add(time.Hour))
+	if len(api.sent) != 0 {
+		t.Fatal("digest sent before the period elapsed")
+	}
+
+	b.sendDueDigests(start.Add(25 * time.Hour))
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want the digest", len(api.sent))
+	}
+	digest := api.sent[0].Message.Embeds[0]
+	if len(digest.Fields) != 1 || digest.Fields[0].Name != "Test Guild" || !strings.Contains(digest.Fields[0].Value, "hello world") {
+		t.Errorf("digest fields = %+v", digest.Fields)
+	}
+	if n := len(b.store.Pending("user")); n != 0 {
+		t.Errorf("%d bookmarks still pending after the digest", n)
+	}
+}
+
+func TestDigestLeavesLaterBookmarksPending(t *testing.T) {
+	b, api := newTestBot(t)
+	now := time.Now()
+	b.store.SetSettings("user", store.UserSettings{Delivery: store.DeliveryDaily, LastDigest: now.Add(-25 * time.Hour)})
+	b.store.Add(store.Bookmark{UserID: "user", GuildID: "guild", ChannelID: "channel", MessageID: "before", Content: "before", CreatedAt: now.Add(-time.Minute), Pending: true})
+	// Made between the tick and reading the pending bookmarks.
+	b.store.Add(store.Bookmark{UserID: "user", GuildID: "guild", ChannelID: "channel", MessageID: "after", Content: "after", CreatedAt: now.Add(time.Second), Pending: true})
+
+	b.sendDueDigests(now)
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want the digest", len(api.sent))
+	}
+	if digest := api.sent[0].Message.Embeds[0]; strings.Contains(digest.Fields[0].Value, "after") {
+		t.Errorf("digest %+v includes the later bookmark", digest.Fields)
+	}
+	if pending := b.store.Pending("user"); len(pending) != 1 || pending[0].MessageID != "after" {
+		t.Errorf("pending %+v, want the later bookmark only", pending)
+	}
+}
+
+func TestJoinLimited(t *testing.T) {
+	lines := []string{strings.Repeat("a", 40), strings.Repeat("b", 40), strings.Repeat("c", 40)}
+
+	got := joinLimited(lines, 100)
+	if len(got) > 100 || !strings.HasSuffix(got, "…and 1 more") {
+		t.Errorf("joinLimited = %q", got)
+	}
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// DM_SCAN_INTERVAL is how often bookmark messages are checked for having
+// been deleted outside the bot, when DMCleanup is enabled.
+const DM_SCAN_INTERVAL = 24 * time.Hour
+
+// startDMScan starts the periodic bookmark message check once; later Ready
+// events are no-ops.
+func (b *Bot) startDMScan() {
+	b.dmScanOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(DM_SCAN_INTERVAL)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-b.done:
+					return
+				case <-ticker.C:
+					b.scanBookmarkMessages()
+				}
+			}
+		}()
+	})
+}
+
+// scanBookmarkMessages removes the bookmarks whose message is gone, e.g.
+// deleted by the user from their archive channel, so the store doesn't
+// keep bookmarks the user can no longer see. Messages that can't be read
+// for other reasons, like closed DMs, are kept.
+func (b *Bot) scanBookmarkMessages() {
+	if !b.cfg().DMCleanup || !b.ready.Load() {
+		return
+	}
+
+	removed := 0
+	for _, bm := range b.store.All() {
+		if !bm.HasDM() {
+			continue
+		}
+		select {
+		case <-b.done:
+			return
+		default:
+		}
+
+		_, err := withRetry(b, func() (*discordgo.Message, error) {
+			return b.api.ChannelMessage(bm.DMChannelID, bm.DMMessageID)
+		})
+		if err == nil {
+			continue
+		}
+		if class := classifyAPIError(err); class != apiErrorNotFound {
+			b.logger.Printf("Error checking bookmark message (channel: %s, message: %s), %s: %v", bm.DMChannelID, bm.DMMessageID, class, err)
+			continue
+		}
+
+		if b.removeDeletedBookmark(bm) {
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		b.logger.Printf("Removed %d bookmark(s) whose message was deleted", removed)
+	}
+}
+
+// removeDeletedBookmark removes a bookmark whose message is gone and, if
+// DMCleanupReactions is set, the user's bookmark reactions on the source.
+func (b *Bot) removeDeletedBookmark(bm store.Bookmark) bool {
+	unlock := b.lockBookmarkDM(bm.UserID, bm.DMChannelID, bm.DMMessageID)
+	defer unlock()
+
+	// An action may have redelivered or removed the bookmark meanwhile.
+	current, ok := b.store.ByDM(bm.DMChannelID, bm.DMMessageID)
+	if !ok || current.UserID != bm.UserID {
+		return false
+	}
+
+	if err := b.store.Remove(bm.DMChannelID, bm.DMMessageID); err != nil {
+		b.logger.Printf("Error removing bookmark with deleted message for user %s: %v", bm.UserID, err)
+		b.recordFailure(alertStore, "", err)
+		return false
+	}
+	b.logger.Printf("Removed bookmark of user %s on message %s, its message (channel: %s, message: %s) was deleted", bm.UserID, bm.MessageID, bm.DMChannelID, bm.DMMessageID)
+	b.audit(store.AuditEntry{Action: store.AuditDelete, ActorID: b.state.User.ID, UserID: bm.UserID, GuildID: bm.GuildID,
+		ChannelID: bm.ChannelID, MessageID: bm.MessageID, Detail: "bookmark message was deleted"})
+
+	if b.cfg().DMCleanupReactions {
+		b.removeBookmarkReactions(bm)
+	}
+	return true
+}
+
+// removeBookmarkReactions removes the user's trigger reactions from the
+// source message. It needs the Manage Messages permission in the channel.
+func (b *Bot) removeBookmarkReactions(bm store.Bookmark) {
+	msg, err := withRetry(b, func() (*discordgo.Message, error) {
+		return b.api.ChannelMessage(bm.ChannelID, bm.MessageID)
+	})
+	if err != nil {
+		b.logger.Printf("Error getting message %s from channel %s to remove bookmark reactions: %v", bm.MessageID, bm.ChannelID, err)
+		return
+	}
+
+	for _, r := range msg.Reactions {
+		if !b.bookmarkTriggers().matches(r.Emoji) {
+			continue
+		}
+		err := withRetryErr(b, func() error {
+			return b.api.MessageReactionRemove(bm.ChannelID, bm.MessageID, r.Emoji.APIName(), bm.UserID)
+		})
+		if err != nil {
+			b.logger.Printf("Error removing bookmark reaction of user %s from message %s: %v", bm.UserID, bm.MessageID, err)
+		}
+	}
+}
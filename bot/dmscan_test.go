@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestScanRemovesBookmarksWithDeletedMessages(t *testing.T) {
+	b, api := newTestBot(t)
+	b.config.DMCleanup = true
+	b.config.DMCleanupReactions = true
+	b.ready.Store(true)
+	r := deliverTestBookmark(t, b, api)
+
+	// Unreadable but existing messages are kept.
+	api.fetchErrors = map[string]error{"dm-user/" + r.MessageID: restError(http.StatusForbidden, discordgo.ErrCodeMissingAccess)}
+	b.scanBookmarkMessages()
+	if len(b.store.ForUser("user")) != 1 {
+		t.Fatal("bookmark removed although its message may still exist")
+	}
+
+	api.fetchErrors["dm-user/"+r.MessageID] = restError(http.StatusNotFound, discordgo.ErrCodeUnknownMessage)
+	b.scanBookmarkMessages()
+	if n := len(b.store.ForUser("user")); n != 0 {
+		t.Fatalf("%d bookmark(s) left, want the one with a deleted message removed", n)
+	}
+	if len(api.reactionsRemoved) != 1 || api.reactionsRemoved[0] != "channel/message/"+BOOKMARK_EMOJI+"/user" {
+		t.Errorf("removed reactions = %q", api.reactionsRemoved)
+	}
+}
+
+func TestScanDisabledByDefault(t *testing.T) {
+	b, api := newTestBot(t)
+	b.ready.Store(true)
+	r := deliverTestBookmark(t, b, api)
+
+	api.fetchErrors = map[string]error{"dm-user/" + r.MessageID: restError(http.StatusNotFound, discordgo.ErrCodeUnknownMessage)}
+	b.scanBookmarkMessages()
+	if len(b.store.ForUser("user")) != 1 {
+		t.Error("bookmark removed with the cleanup disabled")
+	}
+}
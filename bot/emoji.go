@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	// customEmojiPattern matches custom emoji mentions like <:name:id> or <a:name:id>.
+	customEmojiPattern = regexp.MustCompile(`^<a?:(\w+):([0-9]+)>$`)
+	emojiIDPattern     = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// emojiSet holds the trigger emojis. Unicode emojis and name aliases of
+// custom emojis match by name, custom emojis given by ID or mention by ID.
+type emojiSet struct {
+	names map[string]bool
+	ids   map[string]bool
+}
+
+// newEmojiSet parses trigger entries such as "🔖", "<:bookmark:123>",
+// "123" or ":bookmark:".
+func newEmojiSet(entries []string) emojiSet {
+	s := emojiSet{names: map[string]bool{}, ids: map[string]bool{}}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		switch m := customEmojiPattern.FindStringSubmatch(entry); {
+		case m != nil:
+			s.ids[m[2]] = true
+		case emojiIDPattern.MatchString(entry):
+			s.ids[entry] = true
+		case entry != "":
+			s.names[strings.Trim(entry, ":")] = true
+		}
+	}
+	return s
+}
+
+func (s emojiSet) matches(e *discordgo.Emoji) bool {
+	if e == nil {
+		return false
+	}
+	if e.ID != "" && s.ids[e.ID] {
+		return true
+	}
+	return e.Name != "" && s.names[e.Name]
+}
+
+// burstReactions makes MessageReactions list super reactions, which Discord
+// leaves out by default.
+func burstReactions(cfg *discordgo.RequestConfig) {
+	q := cfg.Request.URL.Query()
+	q.Set("type", "1")
+	cfg.Request.URL.RawQuery = q.Encode()
+}
+
+// removeTriggerReactions removes the user's trigger reactions from a message.
+// Which trigger they used isn't recorded, so every one present is removed.
+func (b *Bot) removeTriggerReactions(channelID, messageID, userID string) {
+	msg, err := b.api.ChannelMessage(channelID, messageID)
+	if err != nil {
+		b.logger.Printf("Error getting message %s from channel %s: %v", messageID, channelID, err)
+		return
+	}
+
+	for _, reaction := range msg.Reactions {
+		if !b.bookmarkTriggers().matches(reaction.Emoji) {
+			continue
+		}
+		err := b.api.MessageReactionRemove(channelID, messageID, reaction.Emoji.APIName(), userID)
+		if err != nil {
+			b.logger.Printf("Error removing bookmark reaction from original message (channel: %s, message: %s, user: %s): %v", channelID, messageID, userID, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestEmojiSetMatches(t *testing.T) {
+	s := newEmojiSet([]string{"🔖", "<:pin:111>", "222", ":bookmark:"})
+
+	tests := []struct {
+		emoji discordgo.Emoji
+		want  bool
+	}{
+		{discordgo.Emoji{Name: "🔖"}, true},
+		{discordgo.Emoji{Name: "pin", ID: "111"}, true},
+		{discordgo.Emoji{Name: "renamed", ID: "222"}, true},
+		{discordgo.Emoji{Name: "bookmark", ID: "333"}, true},
+		{discordgo.Emoji{Name: "pin", ID: "444"}, false},
+		{discordgo.Emoji{Name: "👍"}, false},
+	}
+	for _, tt := range tests {
+		if got := s.matches(&tt.emoji); got != tt.want {
+			t.Errorf("matches(%+v) = %t, want %t", tt.emoji, got, tt.want)
+		}
+	}
+}
+
+func TestReactionAddCustomTrigger(t *testing.T) {
+	b, api := newTestBot(t)
+	b.triggers = newEmojiSet([]string{"<:save:111>"})
+	api.addMessage(sourceMessage())
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	if len(api.sent) != 0 {
+		t.Fatalf("sent %d messages for an unconfigured emoji, want 0", len(api.sent))
+	}
+
+	r := bookmarkReaction("save")
+	r.Emoji.ID = "111"
+	b.ReactionAdd(r)
+	if len(api.sent) != 1 {
+		t.Errorf("sent %d messages for the custom trigger, want 1", len(api.sent))
+	}
+}
+
+func TestTriggerUsersIncludesSuperReactions(t *testing.T) {
+	b, api := newTestBot(t)
+	alice := &discordgo.User{ID: "alice"}
+	bob := &discordgo.User{ID: "bob"}
+	api.reactions["channel/message/"+BOOKMARK_EMOJI] = []*discordgo.User{alice}
+	api.reactions["channel/message/"+BOOKMARK_EMOJI+"/burst"] = []*discordgo.User{bob, alice}
+
+	users := b.triggerUsers(sourceMessage())
+
+	if len(users) != 2 || users[0].ID != "alice" || users[1].ID != "bob" {
+		t.Errorf("triggerUsers = %v, want alice and bob once each", users)
+	}
+}
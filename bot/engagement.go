@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/bwmarrin/discordgo"
+)
+
+// refreshReactions takes a new snapshot of the source message's reactions
+// and thread replies for the bookmark whose Refresh button was clicked.
+func (b *Bot) refreshReactions(i *discordgo.InteractionCreate) {
+	user := interactionUser(i)
+	unlock := b.lockBookmarkDM(user.ID, i.ChannelID, i.Message.ID)
+	defer unlock()
+
+	bm, ok := b.store.ByDM(i.ChannelID, i.Message.ID)
+	if !ok || bm.UserID != user.ID {
+		b.respondEphemeral(i, "This isn't your bookmark.")
+		return
+	}
+	if len(i.Message.Embeds) == 0 {
+		b.respondEphemeral(i, "This bookmark has no embed to update.")
+		return
+	}
+
+	msg, err := withRetry(b, func() (*discordgo.Message, error) {
+		return b.api.ChannelMessage(bm.ChannelID, bm.MessageID)
+	})
+	if err != nil {
+		b.logger.Printf("Error getting message %s from channel %s to refresh reactions: %v", bm.MessageID, bm.ChannelID, err)
+		b.respondEphemeral(i, "Couldn't read the original message, it may have been deleted.")
+		return
+	}
+
+	refreshed := *i.Message.Embeds[0]
+	refreshed.Fields = append([]*discordgo.MessageEmbedField(nil), refreshed.Fields...)
+	embed.SetReactions(&refreshed, msg, time.Now())
+
+	err = b.api.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{&refreshed}},
+	})
+	if err != nil {
+		b.logger.Printf("Error responding to interaction %s: %v", i.ID, err)
+	}
+}
@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/bwmarrin/discordgo"
+)
+
+func reactionsField(e *discordgo.MessageEmbed) string {
+	for _, field := range e.Fields {
+		if field.Name == embed.REACTIONS_FIELD_NAME {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+func TestRefreshReactionsButton(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+
+	sent := api.sent[0].Message
+	if v := reactionsField(sent.Embeds[0]); !strings.HasPrefix(v, BOOKMARK_EMOJI+" 1\n") {
+		t.Fatalf("reactions field = %q", v)
+	}
+
+	msg := sourceMessage()
+	msg.Reactions = append(msg.Reactions, &discordgo.MessageReactions{Count: 7, Emoji: &discordgo.Emoji{Name: "👍"}})
+	msg.Thread = &discordgo.Channel{ID: "thread", MessageCount: 3}
+	api.addMessage(msg)
+
+	b.InteractionCreate(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:      discordgo.InteractionMessageComponent,
+		User:      &discordgo.User{ID: "user"},
+		ChannelID: "dm-user",
+		Message:   &discordgo.Message{ID: r.MessageID, ChannelID: "dm-user", Embeds: sent.Embeds},
+		Data:      discordgo.MessageComponentInteractionData{CustomID: "bookmark:refresh"},
+	}})
+
+	resp := api.responses[len(api.responses)-1]
+	if resp.Type != discordgo.InteractionResponseUpdateMessage {
+		t.Fatalf("response type = %v, want an update of the bookmark", resp.Type)
+	}
+	if v := reactionsField(resp.Data.Embeds[0]); !strings.HasPrefix(v, "👍 7 · "+BOOKMARK_EMOJI+" 1\n💬 3 replies in thread\n") {
+		t.Errorf("refreshed reactions field = %q", v)
+	}
+	if v := reactionsField(sent.Embeds[0]); strings.Contains(v, "👍") {
+		t.Error("refresh changed the original embed in place")
+	}
+}
+
+func TestRefreshReactionsOfOthersBookmark(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+
+	clickComponent(b, "other", r.MessageID, "bookmark:refresh")
+
+	resp := api.responses[len(api.responses)-1]
+	if resp.Data.Content != "This isn't your bookmark." {
+		t.Errorf("response = %q", resp.Data.Content)
+	}
+}
@@ -0,0 +1,51 @@
+package bot
+
+import "github.com/bwmarrin/discordgo"
+
+const (
+	// EPHEMERAL_NOTICE explains why "Only you can see this" responses of
+	// bots can't be bookmarked: nobody else, including this bot, can read them.
+	EPHEMERAL_NOTICE = "That's a private bot response (*Only you can see this*). Discord doesn't let other bots read those, so it can't be bookmarked. Copy what you need, or ask the bot that sent it for a public response."
+	// UNREADABLE_NOTICE answers bookmark attempts on messages the bot can't fetch.
+	UNREADABLE_NOTICE = "I couldn't read that message: it may have been deleted, or be a private bot response (*Only you can see this*), which can't be bookmarked."
+)
+
+// isEphemeral reports whether msg is an interaction response only its
+// invoker can see. Such messages can't be fetched or reacted to.
+func isEphemeral(msg *discordgo.Message) bool {
+	return msg.Flags&discordgo.MessageFlagsEphemeral != 0
+}
+
+// isInteractionResponse reports whether msg answers a command or other
+// interaction, which is where ephemeral messages come from.
+func isInteractionResponse(msg *discordgo.Message) bool {
+	return msg.Interaction != nil || msg.Type == discordgo.MessageTypeChatInputCommand || msg.Type == discordgo.MessageTypeContextMenuCommand
+}
+
+// unreadableNotice explains why a message the bot couldn't fetch wasn't
+// bookmarked, blaming ephemerality if a message command targeted an
+// interaction response.
+func unreadableNotice(i *discordgo.InteractionCreate) string {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return UNREADABLE_NOTICE
+	}
+	data := i.ApplicationCommandData()
+	if data.Resolved == nil {
+		return UNREADABLE_NOTICE
+	}
+	if msg, ok := data.Resolved.Messages[data.TargetID]; ok && isInteractionResponse(msg) {
+		return EPHEMERAL_NOTICE
+	}
+	return UNREADABLE_NOTICE
+}
+
+// checkResolvedMessage answers a message command whose target can't be
+// bookmarked and reports whether it can.
+func (b *Bot) checkResolvedMessage(i *discordgo.InteractionCreate, msg *discordgo.Message) bool {
+	if isEphemeral(msg) {
+		b.logger.Printf("User %s tried to bookmark ephemeral message %s", interactionUser(i).ID, msg.ID)
+		b.respondEphemeral(i, EPHEMERAL_NOTICE)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func bookmarkMessageCommand(msg *discordgo.Message) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "user"}},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:        BOOKMARK_COMMAND,
+			CommandType: discordgo.MessageApplicationCommand,
+			TargetID:    msg.ID,
+			Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+				Messages: map[string]*discordgo.Message{msg.ID: msg},
+			},
+		},
+	}}
+}
+
+func TestBookmarkCommandExplainsEphemeralMessages(t *testing.T) {
+	b, api := newTestBot(t)
+	msg := sourceMessage()
+	msg.Flags = discordgo.MessageFlagsEphemeral
+
+	b.InteractionCreate(bookmarkMessageCommand(msg))
+	if len(api.responses) != 1 || api.responses[0].Data.Content != EPHEMERAL_NOTICE {
+		t.Fatalf("responses = %+v, want the ephemeral notice", api.responses)
+	}
+	if len(api.sent) != 0 {
+		t.Errorf("sent %d messages, want none", len(api.sent))
+	}
+}
+
+func TestBookmarkCommandExplainsUnreadableInteractionResponses(t *testing.T) {
+	noSleep(t)
+	b, api := newTestBot(t)
+	msg := sourceMessage()
+	msg.Type = discordgo.MessageTypeChatInputCommand
+	api.fetchErrors = map[string]error{"channel/message": restError(404, discordgo.ErrCodeUnknownMessage)}
+
+	b.InteractionCreate(bookmarkMessageCommand(msg))
+	if len(api.responseEdits) != 1 || *api.responseEdits[0].Content != EPHEMERAL_NOTICE {
+		t.Fatalf("response edits = %+v, want the ephemeral notice", api.responseEdits)
+	}
+}
+
+func TestBookmarkWebhookMessageWithoutAuthor(t *testing.T) {
+	b, api := newTestBot(t)
+	msg := sourceMessage()
+	msg.Author = nil
+	msg.WebhookID = "webhook"
+	api.addMessage(msg)
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want the bookmark", len(api.sent))
+	}
+}
@@ -0,0 +1,343 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/config"
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+var errNotFound = errors.New("fake: not found")
+
+// fakeAPI is an in-memory DiscordAPI that records every write.
+type fakeAPI struct {
+	mu sync.Mutex
+
+	messages map[string]*discordgo.Message
+	users    map[string]*discordgo.User
+	// members holds guild members by "guild/user".
+	members map[string]*discordgo.Member
+	nextID  int
+
+	sent             []sentMessage
+	edited           []string
+	deleted          []string
+	reactionsAdded   []string
+	reactionsRemoved []string
+	responses        []*discordgo.InteractionResponse
+	responseEdits    []*discordgo.WebhookEdit
+	threads          []*discordgo.Channel
+	channelLookups   int
+	memberLookups    int
+	// requests holds the JSON bodies of raw requests by "METHOD url".
+	requests map[string][]byte
+
+	// sendErrors holds errors returned by the next sends to a channel.
+	sendErrors map[string][]error
+	// fetchErrors holds errors returned when fetching a "channel/message".
+	fetchErrors map[string]error
+	// reactions lists reacting users by "channel/message/emoji", with a
+	// "/burst" suffix for super reactions.
+	reactions map[string][]*discordgo.User
+}
+
+type sentMessage struct {
+	ChannelID string
+	Message   *discordgo.MessageSend
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{
+		messages:   map[string]*discordgo.Message{},
+		users:      map[string]*discordgo.User{},
+		members:    map[string]*discordgo.Member{},
+		sendErrors: map[string][]error{},
+		reactions:  map[string][]*discordgo.User{},
+		requests:   map[string][]byte{},
+	}
+}
+
+func (f *fakeAPI) addMessage(msg *discordgo.Message) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages[msg.ChannelID+"/"+msg.ID] = msg
+}
+
+func (f *fakeAPI) Application(appID string) (*discordgo.Application, error) {
+	return &discordgo.Application{ID: appID, Owner: &discordgo.User{ID: "owner"}}, nil
+}
+
+func (f *fakeAPI) Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.channelLookups++
+	return nil, errNotFound
+}
+
+func (f *fakeAPI) ChannelMessage(channelID, messageID string, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fetchErrors[channelID+"/"+messageID]; err != nil {
+		return nil, err
+	}
+	if msg, ok := f.messages[channelID+"/"+messageID]; ok {
+		return msg, nil
+	}
+	return nil, errNotFound
+}
+
+func (f *fakeAPI) ChannelMessages(channelID string, limit int, beforeID, afterID, aroundID string, options ...discordgo.RequestOption) ([]*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var msgs []*discordgo.Message
+	for _, msg := range f.messages {
+		if msg.ChannelID == channelID {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, nil
+}
+
+func (f *fakeAPI) ChannelMessagesPinned(channelID string, options ...discordgo.RequestOption) ([]*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var pins []*discordgo.Message
+	for _, msg := range f.messages {
+		if msg.ChannelID == channelID && msg.Pinned {
+			pins = append(pins, msg)
+		}
+	}
+	return pins, nil
+}
+
+func (f *fakeAPI) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if errs := f.sendErrors[channelID]; len(errs) > 0 {
+		f.sendErrors[channelID] = errs[1:]
+		return nil, errs[0]
+	}
+	f.nextID++
+	msg := &discordgo.Message{
+		ID:        fmt.Sprintf("sent-%d", f.nextID),
+		ChannelID: channelID,
+		Content:   data.Content,
+		Embeds:    data.Embeds,
+	}
+	f.messages[channelID+"/"+msg.ID] = msg
+	f.sent = append(f.sent, sentMessage{ChannelID: channelID, Message: data})
+	return msg, nil
+}
+
+func (f *fakeAPI) ChannelMessageEditEmbed(channelID, messageID string, embed *discordgo.MessageEmbed, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	msg, ok := f.messages[channelID+"/"+messageID]
+	if !ok {
+		return nil, errNotFound
+	}
+	msg.Embeds = []*discordgo.MessageEmbed{embed}
+	f.edited = append(f.edited, channelID+"/"+messageID)
+	return msg, nil
+}
+
+func (f *fakeAPI) ChannelMessageDelete(channelID, messageID string, options ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.messages, channelID+"/"+messageID)
+	f.deleted = append(f.deleted, channelID+"/"+messageID)
+	return nil
+}
+
+func (f *fakeAPI) Guild(guildID string, options ...discordgo.RequestOption) (*discordgo.Guild, error) {
+	return nil, errNotFound
+}
+
+func (f *fakeAPI) GuildMember(guildID, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.memberLookups++
+	member, ok := f.members[guildID+"/"+userID]
+	if !ok {
+		return nil, errNotFound
+	}
+	copied := *member
+	return &copied, nil
+}
+
+func (f *fakeAPI) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, resp)
+	return nil
+}
+
+func (f *fakeAPI) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responseEdits = append(f.responseEdits, newresp)
+	return &discordgo.Message{}, nil
+}
+
+func (f *fakeAPI) MessageReactionAdd(channelID, messageID, emojiID string, options ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reactionsAdded = append(f.reactionsAdded, channelID+"/"+messageID+"/"+emojiID)
+	return nil
+}
+
+func (f *fakeAPI) MessageReactionRemove(channelID, messageID, emojiID, userID string, options ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reactionsRemoved = append(f.reactionsRemoved, channelID+"/"+messageID+"/"+emojiID+"/"+userID)
+	return nil
+}
+
+func (f *fakeAPI) MessageReactions(channelID, messageID, emojiID string, limit int, beforeID, afterID string, options ...discordgo.RequestOption) ([]*discordgo.User, error) {
+	// Options are applied to a dummy request to see which reaction type is listed.
+	req, _ := http.NewRequest("GET", "https://discord.com/api/reactions", nil)
+	cfg := &discordgo.RequestConfig{Request: req}
+	for _, opt := range options {
+		opt(cfg)
+	}
+	key := channelID + "/" + messageID + "/" + emojiID
+	if cfg.Request.URL.Query().Get("type") == "1" {
+		key += "/burst"
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if afterID != "" {
+		return nil, nil
+	}
+	return f.reactions[key], nil
+}
+
+func (f *fakeAPI) Request(method, urlStr string, data interface{}, options ...discordgo.RequestOption) ([]byte, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests[method+" "+urlStr] = body
+	return []byte("[]"), nil
+}
+
+func (f *fakeAPI) ThreadStart(channelID, name string, typ discordgo.ChannelType, archiveDuration int, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	thread := &discordgo.Channel{ID: fmt.Sprintf("thread-%d", f.nextID), Name: name, ParentID: channelID, Type: typ}
+	f.threads = append(f.threads, thread)
+	return thread, nil
+}
+
+func (f *fakeAPI) User(userID string, options ...discordgo.RequestOption) (*discordgo.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if user, ok := f.users[userID]; ok {
+		return user, nil
+	}
+	return nil, errNotFound
+}
+
+func (f *fakeAPI) UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	return &discordgo.Channel{ID: "dm-" + recipientID, Type: discordgo.ChannelTypeDM}, nil
+}
+
+// newTestBot returns a bot wired to a fake API, a temporary store and a state
+// holding one guild ("guild") with one text channel ("channel").
+func newTestBot(t *testing.T) (*Bot, *fakeAPI) {
+	t.Helper()
+
+	st, err := store.Open(filepath.Join(t.TempDir(), "bookmarks.json"))
+	if err != nil {
+		t.Fatalf("opening store: %v", err)
+	}
+
+	state := discordgo.NewState()
+	state.User = &discordgo.User{ID: "bot"}
+	err = state.GuildAdd(&discordgo.Guild{
+		ID:   "guild",
+		Name: "Test Guild",
+		Channels: []*discordgo.Channel{
+			{ID: "channel", GuildID: "guild", Type: discordgo.ChannelTypeGuildText},
+		},
+	})
+	if err != nil {
+		t.Fatalf("adding guild to state: %v", err)
+	}
+
+	cfg := config.Config{NSFWPolicy: config.NSFW_SPOILER, CatchUpLimit: 50}
+	api := newFakeAPI()
+	api.users["user"] = &discordgo.User{ID: "user", Username: "alice"}
+
+	return New(api, state, st, cfg, embed.DefaultTemplate(), log.New(io.Discard, "", 0)), api
+}
+
+// guildAdminCommand runs a /bookmarker subcommand in the test guild as an admin.
+func guildAdminCommand(b *Bot, subcommand string, options ...*discordgo.ApplicationCommandInteractionDataOption) {
+	b.InteractionCreate(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "admin"}},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name: "bookmarker",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+				Type:    discordgo.ApplicationCommandOptionSubCommand,
+				Name:    subcommand,
+				Options: options,
+			}},
+		},
+	}})
+}
+
+// clickComponent clicks a component of a message in the user's DMs,
+// choosing values in select menus.
+func clickComponent(b *Bot, userID, messageID, customID string, values ...string) {
+	b.InteractionCreate(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:      discordgo.InteractionMessageComponent,
+		User:      &discordgo.User{ID: userID},
+		ChannelID: "dm-" + userID,
+		Message:   &discordgo.Message{ID: messageID, ChannelID: "dm-" + userID},
+		Data:      discordgo.MessageComponentInteractionData{CustomID: customID, Values: values},
+	}})
+}
+
+// userCommand runs a /bookmarks subcommand as userID.
+func userCommand(b *Bot, userID, subcommand string, options ...*discordgo.ApplicationCommandInteractionDataOption) {
+	b.InteractionCreate(userCommandInteraction(userID, subcommand, options...))
+}
+
+// userCommandInteraction is the interaction userCommand runs, for tests
+// adding to it.
+func userCommandInteraction(userID, subcommand string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommand,
+		User: &discordgo.User{ID: userID},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name: "bookmarks",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+				Type:    discordgo.ApplicationCommandOptionSubCommand,
+				Name:    subcommand,
+				Options: options,
+			}},
+		},
+	}}
+}
+
+// stringOption is a string option of a command.
+func stringOption(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Type: discordgo.ApplicationCommandOptionString, Name: name, Value: value}
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// forgetMeCommand asks the user to confirm wiping everything stored about them.
+func (b *Bot) forgetMeCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	b.confirmForget(i, interactionUser(i).ID, opts, "This will delete everything I store about you: %d bookmark(s), your settings and handled reactions.")
+}
+
+// forgetUserCommand lets the owner wipe a user's data on their request.
+func (b *Bot) forgetUserCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	if !b.isOwner(interactionUser(i).ID) {
+		b.respondEphemeral(i, "Only the bot owner can delete other users' data.")
+		return
+	}
+
+	userID := strings.TrimSpace(opts["user_id"].StringValue())
+	if _, err := strconv.ParseUint(userID, 10, 64); err != nil {
+		b.respondEphemeral(i, "That isn't a user ID.")
+		return
+	}
+	b.confirmForget(i, userID, opts, "This will delete everything stored about <@"+userID+">: %d bookmark(s), their settings and handled reactions.")
+}
+
+// confirmForget shows the confirm and cancel buttons. The user and whether
+// to delete the bookmark messages are carried in the custom ID.
+func (b *Bot) confirmForget(i *discordgo.InteractionCreate, userID string, opts optionMap, prompt string) {
+	deleteMessages := false
+	if opt, ok := opts["delete_messages"]; ok {
+		deleteMessages = opt.BoolValue()
+	}
+
+	content := fmt.Sprintf(prompt, len(b.store.ForUser(userID)))
+	if deleteMessages {
+		content += " The bookmark messages are deleted too."
+	}
+	content += " This can't be undone."
+
+	err := b.api.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Delete everything",
+						Style:    discordgo.DangerButton,
+						CustomID: "forget:confirm:" + userID + ":" + strconv.FormatBool(deleteMessages),
+					},
+					discordgo.Button{
+						Label:    "Cancel",
+						Style:    discordgo.SecondaryButton,
+						CustomID: "forget:cancel",
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error responding to interaction %s: %v", i.ID, err)
+	}
+}
+
+// forgetComponent handles the confirm and cancel buttons of the forget commands.
+func (b *Bot) forgetComponent(i *discordgo.InteractionCreate, args string) {
+	parts := strings.Split(args, ":")
+	if parts[0] != "confirm" || len(parts) != 3 {
+		b.updateComponentMessage(i, "Cancelled, nothing was deleted.")
+		return
+	}
+	userID, deleteMessages := parts[1], parts[2] == "true"
+
+	requester := interactionUser(i)
+	if requester.ID != userID && !b.isOwner(requester.ID) {
+		b.updateComponentMessage(i, "Only the bot owner can delete other users' data.")
+		return
+	}
+
+	removed, err := b.store.ForgetUser(userID)
+	if err != nil {
+		b.logger.Printf("Error forgetting user %s: %v", userID, err)
+		b.recordFailure(alertStore, "", err)
+		b.updateComponentMessage(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	deleted := 0
+	if deleteMessages {
+		for _, bm := range removed {
+			if !bm.HasDM() {
+				continue
+			}
+			err := b.api.ChannelMessageDelete(bm.DMChannelID, bm.DMMessageID)
+			if err != nil {
+				b.logger.Printf("Error deleting bookmark DM %s for user %s: %v", bm.DMMessageID, userID, err)
+				continue
+			}
+			deleted++
+		}
+	}
+
+	// The entry mustn't name the user it just took out of the audit log.
+	actorID := requester.ID
+	if actorID == userID {
+		actorID = store.FORGOTTEN_USER
+	}
+	b.audit(store.AuditEntry{Action: store.AuditForget, ActorID: actorID, UserID: store.FORGOTTEN_USER, Detail: fmt.Sprintf("%d bookmark(s), %d message(s) deleted", len(removed), deleted)})
+	b.logger.Printf("Forgot user %s on request of user %s: %d bookmark(s), %d message(s) deleted", userID, requester.ID, len(removed), deleted)
+	b.updateComponentMessage(i, fmt.Sprintf("Done. Deleted %d bookmark(s) and %d bookmark message(s).", len(removed), deleted))
+}
@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestForgetMeDeletesDataAndMessages(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+
+	userCommand(b, "user", "forget-me", &discordgo.ApplicationCommandInteractionDataOption{
+		Type: discordgo.ApplicationCommandOptionBoolean, Name: "delete_messages", Value: true,
+	})
+
+	row := api.responses[len(api.responses)-1].Data.Components[0].(discordgo.ActionsRow)
+	confirmID := row.Components[0].(discordgo.Button).CustomID
+	if confirmID != "forget:confirm:user:true" {
+		t.Fatalf("confirm button ID = %q", confirmID)
+	}
+
+	clickComponent(b, "user", "prompt", confirmID)
+
+	if len(b.store.ForUser("user")) != 0 || b.store.Processed("user", "channel", "message") {
+		t.Error("user data is still stored")
+	}
+	if want := "dm-user/" + r.MessageID; len(api.deleted) != 1 || api.deleted[0] != want {
+		t.Errorf("deleted %v, want [%s]", api.deleted, want)
+	}
+}
+
+func TestForgetConfirmRejectsOtherUsers(t *testing.T) {
+	b, api := newTestBot(t)
+	b.setOwner("owner")
+	deliverTestBookmark(t, b, api)
+
+	clickComponent(b, "someone-else", "prompt", "forget:confirm:user:false")
+	if len(b.store.ForUser("user")) != 1 {
+		t.Fatal("another user could delete the user's data")
+	}
+
+	clickComponent(b, "owner", "prompt", "forget:confirm:user:false")
+	if len(b.store.ForUser("user")) != 0 {
+		t.Error("the owner couldn't delete the user's data")
+	}
+	if len(api.deleted) != 0 {
+		t.Errorf("deleted %v without delete_messages", api.deleted)
+	}
+}
+
+func TestForgetLeavesNoUserIDInTheAuditLog(t *testing.T) {
+	b, api := newTestBot(t)
+	b.setOwner("owner")
+	deliverTestBookmark(t, b, api)
+
+	clickComponent(b, "user", "prompt", "forget:confirm:user:false")
+
+	entries, _ := b.store.AuditLog(store.AuditFilter{})
+	if len(entries) != 2 {
+		t.Fatalf("audit log = %+v, want the create and forget entries", entries)
+	}
+	for _, e := range entries {
+		if e.ActorID == "user" || e.UserID == "user" {
+			t.Errorf("audit entry %+v names the forgotten user", e)
+		}
+	}
+	if entries[0].Action != store.AuditForget {
+		t.Errorf("newest entry = %+v, want the forget", entries[0])
+	}
+}
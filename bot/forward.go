@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	"github.com/anonmiraj/discord-bookmarker/msglink"
+	"github.com/bwmarrin/discordgo"
+)
+
+// DMMessageCreate bookmarks message links that users paste into the bot's DMs.
+func (b *Bot) DMMessageCreate(m *discordgo.MessageCreate) {
+	if m.Author == nil || m.Author.Bot || m.GuildID != "" {
+		return
+	}
+	links := msglink.Find(m.Content)
+	if len(links) == 0 {
+		return
+	}
+
+	// Instances sharing the store all receive the DM; one bookmarks its links.
+	release, ok := b.claim("dm:"+m.ChannelID+":"+m.ID, "DM of user "+m.Author.ID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	for _, link := range links {
+		b.logger.Printf("Processing forwarded link from user %s: %s", m.Author.ID, link)
+
+		reply := b.bookmarkFromLink(m.Author, link)
+		if reply == "" {
+			err := b.api.MessageReactionAdd(m.ChannelID, m.ID, BOOKMARK_EMOJI)
+			if err != nil {
+				b.logger.Printf("Error acknowledging forwarded link from user %s: %v", m.Author.ID, err)
+			}
+			continue
+		}
+
+		_, err := b.api.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+			Content:   reply,
+			Reference: m.Reference(),
+		})
+		if err != nil {
+			b.logger.Printf("Error replying to forwarded link from user %s: %v", m.Author.ID, err)
+		}
+	}
+}
+
+// bookmarkFromLink bookmarks the linked message for the user, provided they
+// can read it. It returns a message for the user on failure, or "" on success.
+func (b *Bot) bookmarkFromLink(user *discordgo.User, link msglink.Link) string {
+	if link.GuildID == msglink.DM_GUILD {
+		return "Only messages from servers can be bookmarked."
+	}
+	channelID, messageID := link.ChannelID, link.MessageID
+
+	channel, err := b.channel(channelID)
+	if err != nil {
+		b.logger.Printf("Error getting channel info for channel %s: %v", channelID, err)
+		return "I can't access that channel."
+	}
+
+	if channel.GuildID != link.GuildID {
+		return "That link doesn't point to a message I can find."
+	}
+
+	member, err := b.member(channel.GuildID, user.ID)
+	if err != nil {
+		b.logger.Printf("Error getting member %s of guild %s: %v", user.ID, channel.GuildID, err)
+		return "You need to be a member of that server to bookmark its messages."
+	}
+
+	if !b.canReadHistory(channel.GuildID, member, channelID) {
+		return "You can't read that channel."
+	}
+
+	if b.store.Has(user.ID, channelID, messageID) {
+		return "You already bookmarked that message."
+	}
+
+	msg, err := b.api.ChannelMessage(channelID, messageID)
+	if err != nil {
+		b.logger.Printf("Error getting message %s from channel %s: %v", messageID, channelID, err)
+		return "I couldn't find that message."
+	}
+
+	guild, err := b.guild(channel.GuildID)
+	if err != nil {
+		b.logger.Printf("Error getting guild info for guild %s: %v", channel.GuildID, err)
+		return "Something went wrong, please try again later."
+	}
+
+	err = b.deliverBookmark(context.Background(), user, guild, msg)
+	if errors.Is(err, errNSFWBlocked) {
+		return "Bookmarks from age-restricted channels are disabled on this bot."
+	}
+	if errors.Is(err, errAlreadyBookmarked) {
+		return "You already bookmarked that message."
+	}
+	if errors.Is(err, errGuildOptedOut) {
+		return optedOutMessage(guild)
+	}
+	if errors.Is(err, errRoleNotAllowed) {
+		return b.roleRefusalMessage(guild)
+	}
+	if err != nil {
+		b.logger.Printf("Error delivering bookmark to user %s (%s): %v", user.Username, user.ID, err)
+		return "Something went wrong, please try again later."
+	}
+
+	b.logger.Printf("Successfully sent bookmark to user %s (%s) from guild %s via link", user.Username, user.ID, guild.Name)
+	return ""
+}
@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"net/http"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func (b *Bot) Resumed(r *discordgo.Resumed) {
+	b.logger.Printf("Gateway session resumed")
+	b.ready.Store(true)
+}
+
+func (b *Bot) Disconnect(d *discordgo.Disconnect) {
+	b.logger.Printf("Gateway disconnected, reconnecting")
+	b.ready.Store(false)
+	b.recordFailure(alertGateway, "", nil)
+}
+
+// Shutdown marks the bot as not ready and stops background jobs ahead of
+// closing the session.
+func (b *Bot) Shutdown() {
+	b.ready.Store(false)
+	b.shutdownOnce.Do(func() { close(b.done) })
+}
+
+// Healthy reports whether the gateway is connected, as /readyz does.
+func (b *Bot) Healthy() bool {
+	return b.ready.Load()
+}
+
+// ServeHealth exposes /readyz for container orchestrators: 200 while the
+// gateway is connected, 503 otherwise.
+func (b *Bot) ServeHealth(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !b.Healthy() {
+			http.Error(w, "gateway not connected", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+
+	b.logger.Printf("Serving readiness endpoint on %s/readyz", addr)
+	err := http.ListenAndServe(addr, mux)
+	if err != nil {
+		b.logger.Printf("Error serving readiness endpoint on %s: %v", addr, err)
+	}
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	HISTORY_EMOJI = "🕘"
+	// HISTORY_EXCERPT_LENGTH bounds the content shown of each version, in characters.
+	HISTORY_EXCERPT_LENGTH = 300
+)
+
+// versionLabels name the reasons of versions in /bookmarks history.
+var versionLabels = map[store.VersionReason]string{
+	store.VersionCaptured: "Captured",
+	store.VersionEdited:   "Message edited",
+	store.VersionNote:     "Note changed",
+}
+
+// historyCommand lists the versions of a bookmark's content and note, oldest first.
+func (b *Bot) historyCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+
+	bm, reply, ok := b.findBookmark(user.ID, opts["bookmark"].StringValue())
+	if !ok {
+		b.respondEphemeral(i, reply)
+		return
+	}
+
+	spoiler := b.spoilerMedia(bm.ChannelID)
+	versions := bm.Versions()
+	blocks := make([]string, len(versions))
+	for j, v := range versions {
+		blocks[j] = versionBlock(j+1, v, spoiler)
+	}
+
+	description := joinLimited(blocks, embed.DESCRIPTION_MAX_LENGTH)
+	if len(versions) == 1 {
+		description += "\n\nThe message and note haven't changed since you bookmarked it."
+	}
+	b.respondEmbed(i, &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("%s History of %s", HISTORY_EMOJI, bm.ShortID),
+		Description: description,
+		Color:       embed.DEFAULT_EMBED_COLOR,
+	}, discordgo.MessageFlagsEphemeral)
+}
+
+// versionBlock shows one version: its number, reason and time, a quote of
+// the content and the note.
+func versionBlock(n int, v store.Version, spoiler bool) string {
+	label, ok := versionLabels[v.Reason]
+	if !ok {
+		label = string(v.Reason)
+	}
+
+	excerpt := strings.TrimSpace(v.Content)
+	if runes := []rune(excerpt); len(runes) > HISTORY_EXCERPT_LENGTH {
+		excerpt = string(runes[:HISTORY_EXCERPT_LENGTH]) + "…"
+	}
+	if excerpt == "" {
+		excerpt = "(no text)"
+	}
+	if spoiler {
+		excerpt = "||" + excerpt + "||"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**v%d · %s** <t:%d:f>\n", n, label, v.At.Unix())
+	sb.WriteString("> " + strings.ReplaceAll(excerpt, "\n", "\n> "))
+	if v.Note != "" {
+		sb.WriteString("\nNote: " + v.Note)
+	}
+	return sb.String() + "\n"
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestHistoryRecordsEditsAndNotes(t *testing.T) {
+	b, api := newTestBot(t)
+	deliverTestBookmark(t, b, api)
+	bm := b.store.ForUser("user")[0]
+
+	edited := sourceMessage()
+	edited.Content = "hello edited world"
+	api.addMessage(edited)
+	editedAt := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+	b.MessageUpdate(&discordgo.MessageUpdate{Message: &discordgo.Message{
+		ID: "message", ChannelID: "channel", GuildID: "guild", EditedTimestamp: &editedAt,
+	}})
+	userCommand(b, "user", "note", stringOption("bookmark", bm.ShortID), stringOption("note", "read later"))
+
+	versions := b.store.ForUser("user")[0].History
+	if len(versions) != 3 {
+		t.Fatalf("history = %+v, want 3 versions", versions)
+	}
+	if versions[0].Content != "hello world" || versions[1].Content != "hello edited world" || !versions[1].At.Equal(editedAt) || versions[2].Note != "read later" {
+		t.Errorf("history = %+v", versions)
+	}
+
+	userCommand(b, "user", "history", stringOption("bookmark", bm.ShortID))
+	description := api.responses[len(api.responses)-1].Data.Embeds[0].Description
+	for _, want := range []string{"v1 · Captured", "> hello world", "v2 · Message edited", "v3 · Note changed", "Note: read later"} {
+		if !strings.Contains(description, want) {
+			t.Errorf("history %q doesn't contain %q", description, want)
+		}
+	}
+}
+
+func TestHistoryOfUnchangedBookmark(t *testing.T) {
+	b, api := newTestBot(t)
+	deliverTestBookmark(t, b, api)
+	bm := b.store.ForUser("user")[0]
+
+	// Saving the same note again isn't a new version.
+	userCommand(b, "user", "note", stringOption("bookmark", bm.ShortID))
+	userCommand(b, "user", "history", stringOption("bookmark", bm.ShortID))
+
+	if history := b.store.ForUser("user")[0].History; len(history) != 0 {
+		t.Errorf("history = %+v, want none", history)
+	}
+	description := api.responses[len(api.responses)-1].Data.Embeds[0].Description
+	if !strings.Contains(description, "v1 · Captured") || !strings.Contains(description, "haven't changed") {
+		t.Errorf("history = %q", description)
+	}
+}
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestOtherReactionsAreIgnoredWithoutLookups(t *testing.T) {
+	b, api := newTestBot(t)
+	r := bookmarkReaction("👍")
+	r.ChannelID = "uncached-channel"
+
+	b.ReactionAdd(r)
+	b.DMReactionAdd(r)
+	if api.channelLookups != 0 || len(api.sent) != 0 {
+		t.Errorf("%d channel lookups and %d messages for an unrelated reaction", api.channelLookups, len(api.sent))
+	}
+}
+
+func TestBookmarkReactionInUncachedChannel(t *testing.T) {
+	b, api := newTestBot(t)
+	msg := sourceMessage()
+	msg.ChannelID = "uncached-channel"
+	api.addMessage(msg)
+	r := bookmarkReaction(BOOKMARK_EMOJI)
+	r.ChannelID = "uncached-channel"
+
+	b.ReactionAdd(r)
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want the bookmark", len(api.sent))
+	}
+	if bm := b.store.ForUser("user"); len(bm) != 1 || bm[0].GuildID != "guild" {
+		t.Errorf("stored %+v, want the bookmark in the reaction's guild", bm)
+	}
+}
+
+func TestDMActionInUncachedDM(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+	// A restart empties the state; DM channels only come back when used.
+	b.state.ChannelRemove(&discordgo.Channel{ID: "dm-user", Type: discordgo.ChannelTypeDM})
+	lookups := api.channelLookups
+
+	r.Emoji.Name = DELETE_EMOJI
+	b.DMReactionAdd(r)
+	if len(b.store.ForUser("user")) != 0 {
+		t.Error("bookmark not deleted")
+	}
+	if api.channelLookups != lookups {
+		t.Errorf("%d channel lookups for a DM action", api.channelLookups-lookups)
+	}
+}
+
+func TestOtherReactionRemovalsSkipTheBookmarkLock(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+	bm := b.store.ForUser("user")[0]
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(bm.UserID, bm.ChannelID, bm.MessageID))
+	defer unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.Emoji.Name = "👍"
+		b.DMReactionRemove(&discordgo.MessageReactionRemove{MessageReaction: r.MessageReaction})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("removing an unrelated reaction waited for the bookmark lock")
+	}
+}
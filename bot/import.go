@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func (b *Bot) importPinsCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	if i.GuildID == "" {
+		b.respondEphemeral(i, "Pins can only be imported from a server.")
+		return
+	}
+
+	guildSettings := b.store.GuildSettings(i.GuildID)
+	if guildSettings.BookmarksDisabled {
+		b.respondEphemeral(i, "The admins of this server turned off bookmarking.")
+		return
+	}
+
+	user := interactionUser(i)
+	if !b.memberAllowed(i.GuildID, user.ID, guildSettings) {
+		b.respondEphemeral(i, "The admins of this server only let members with certain roles bookmark messages.")
+		return
+	}
+	channelID := opts["channel"].Value.(string)
+
+	if !b.canReadHistory(i.GuildID, i.Member, channelID) {
+		b.respondEphemeral(i, "You can't read that channel's history.")
+		return
+	}
+
+	if !b.deferEphemeral(i) {
+		return
+	}
+
+	guild, err := b.guild(i.GuildID)
+	if err != nil {
+		b.logger.Printf("Error getting guild info for guild %s: %v", i.GuildID, err)
+		b.editResponse(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	pins, err := b.api.ChannelMessagesPinned(channelID)
+	if err != nil {
+		b.logger.Printf("Error getting pinned messages of channel %s: %v", channelID, err)
+		b.editResponse(i, "I couldn't read the pinned messages of that channel.")
+		return
+	}
+
+	imported, skipped, failed := 0, 0, 0
+	// Pins are returned newest first; deliver them oldest first.
+	for j := len(pins) - 1; j >= 0; j-- {
+		msg := pins[j]
+		err := b.deliverBookmark(context.Background(), user, guild, msg)
+		if errors.Is(err, errAlreadyBookmarked) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			b.logger.Printf("Error importing pinned message %s for user %s (%s): %v", msg.ID, user.Username, user.ID, err)
+			failed++
+			continue
+		}
+		imported++
+	}
+
+	b.logger.Printf("Imported %d pin(s) from channel %s for user %s (%s)", imported, channelID, user.Username, user.ID)
+
+	reply := fmt.Sprintf("Imported %d pinned message(s) from <#%s>.", imported, channelID)
+	if skipped > 0 {
+		reply += fmt.Sprintf(" %d were already bookmarked.", skipped)
+	}
+	if failed > 0 {
+		reply += fmt.Sprintf(" %d couldn't be delivered, check that your DMs are open.", failed)
+	}
+	b.editResponse(i, reply)
+}
+
+// canReadHistory reports whether a guild member may view and read the
+// message history of a channel.
+func (b *Bot) canReadHistory(guildID string, member *discordgo.Member, channelID string) bool {
+	return b.memberHasPermissions(guildID, member, channelID, discordgo.PermissionViewChannel|discordgo.PermissionReadMessageHistory)
+}
+
+// memberHasPermissions reports whether a guild member has all of the required
+// permissions in a channel.
+func (b *Bot) memberHasPermissions(guildID string, member *discordgo.Member, channelID string, required int64) bool {
+	if member == nil || member.User == nil {
+		return false
+	}
+
+	// Members of interactions and REST lookups are added to the state for
+	// the permission check below; those from the state are shared with it
+	// and left alone. MemberAdd fails if the guild isn't tracked; the check
+	// then fails closed.
+	if cached, err := b.state.Member(guildID, member.User.ID); err != nil || cached != member {
+		m := *member
+		m.GuildID = guildID
+		b.state.MemberAdd(&m)
+	}
+
+	perms, err := b.state.UserChannelPermissions(member.User.ID, channelID)
+	if err != nil {
+		b.logger.Printf("Error computing permissions of user %s in channel %s: %v", member.User.ID, channelID, err)
+		return false
+	}
+
+	return perms&required == required
+}
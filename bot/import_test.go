@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPermissionCheckLeavesMembersAlone(t *testing.T) {
+	b, _ := newTestBot(t)
+
+	// An interaction's member is added to the state as a copy.
+	member := &discordgo.Member{User: &discordgo.User{ID: "user"}}
+	b.canReadHistory("guild", member, "channel")
+	if member.GuildID != "" {
+		t.Error("permission check changed the caller's member")
+	}
+	cached, err := b.state.Member("guild", "user")
+	if err != nil || cached == member {
+		t.Fatalf("state member = %p, %v, want a copy of the interaction's", cached, err)
+	}
+
+	// The state's own member is only read, while others read it too.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		b.state.RLock()
+		defer b.state.RUnlock()
+		_ = cached.GuildID
+	}()
+	b.canReadHistory("guild", cached, "channel")
+	wg.Wait()
+}
@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/integration"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// sinks returns the integrations the user set up.
+func (b *Bot) sinks(settings store.UserSettings) []integration.Sink {
+	var sinks []integration.Sink
+	if settings.WebhookURL != "" {
+		sinks = append(sinks, integration.NewWebhook(settings.WebhookURL, b.integrationClient))
+	}
+	if settings.NotionToken != "" && settings.NotionDatabaseID != "" {
+		sinks = append(sinks, integration.NewNotion(settings.NotionToken, settings.NotionDatabaseID, b.integrationClient))
+	}
+	return sinks
+}
+
+// forwardBookmark sends a new bookmark to the user's integrations in the
+// background, so a slow endpoint doesn't hold up delivery.
+func (b *Bot) forwardBookmark(settings store.UserSettings, user *discordgo.User, guild *discordgo.Guild, msg *discordgo.Message, note string, tags []string) {
+	sinks := b.sinks(settings)
+	if len(sinks) == 0 {
+		return
+	}
+
+	link, origin := b.messageOrigin(guild, msg)
+	payload := integration.Bookmark{
+		Event:     integration.EVENT_BOOKMARK_CREATED,
+		UserID:    user.ID,
+		GuildID:   guild.ID,
+		GuildName: origin.Name,
+		ChannelID: msg.ChannelID,
+		MessageID: msg.ID,
+		Link:      link.String(),
+		Content:   msg.Content,
+		Note:      note,
+		Tags:      tags,
+		SentAt:    msg.Timestamp,
+		CreatedAt: time.Now(),
+	}
+	payload.Author = embed.AuthorOf(msg).Name
+	if msg.Author != nil {
+		payload.AuthorID = msg.Author.ID
+	}
+	for _, a := range msg.Attachments {
+		payload.Attachments = append(payload.Attachments, a.URL)
+	}
+
+	for _, sink := range sinks {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), integration.INTEGRATION_TIMEOUT)
+			defer cancel()
+
+			if err := sink.Send(ctx, payload); err != nil {
+				b.logger.Printf("Error forwarding bookmark of message %s to an integration of user %s: %v", msg.ID, user.ID, err)
+			}
+		}()
+	}
+}
+
+// integrationCommand sets up or removes the user's webhook or Notion integration.
+func (b *Bot) integrationCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+	settings := b.store.Settings(user.ID)
+
+	var reply string
+	switch {
+	case opts["webhook"] != nil:
+		url := strings.TrimSpace(opts["webhook"].StringValue())
+		if err := integration.ValidateWebhookURL(url); err != nil {
+			b.respondEphemeral(i, "That webhook URL won't work: "+err.Error()+".")
+			return
+		}
+		settings.WebhookURL = url
+		reply = "New bookmarks will also be posted to your webhook."
+	case opts["notion_token"] != nil || opts["notion_database"] != nil:
+		if opts["notion_token"] == nil || opts["notion_database"] == nil {
+			b.respondEphemeral(i, "Notion needs both `notion_token` and `notion_database`.")
+			return
+		}
+		databaseID, err := notionDatabaseID(opts["notion_database"].StringValue())
+		if err != nil {
+			b.respondEphemeral(i, "That doesn't look like a Notion database ID or link.")
+			return
+		}
+		settings.NotionToken = strings.TrimSpace(opts["notion_token"].StringValue())
+		settings.NotionDatabaseID = databaseID
+		reply = "New bookmarks will also be added to your Notion database. Make sure your Notion integration has access to it."
+	case opts["disable"] != nil && opts["disable"].BoolValue():
+		settings.WebhookURL, settings.NotionToken, settings.NotionDatabaseID = "", "", ""
+		reply = "Integrations removed."
+	default:
+		b.respondEphemeral(i, "Give a `webhook` URL, a Notion token and database, or set `disable` to remove your integrations.")
+		return
+	}
+
+	if err := b.store.SetSettings(user.ID, settings); err != nil {
+		b.logger.Printf("Error saving integrations of user %s: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	b.logger.Printf("User %s updated their integrations", user.ID)
+	b.respondEphemeral(i, reply)
+}
+
+// notionDatabaseID accepts a database ID, with or without dashes, or a link
+// to the database.
+func notionDatabaseID(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, "?#"); i != -1 {
+		s = s[:i]
+	}
+	s = s[strings.LastIndex(s, "/")+1:]
+	s = strings.ReplaceAll(s, "-", "")
+	if len(s) < 32 {
+		return "", errors.New("too short")
+	}
+	// Links end in the title followed by the ID.
+	id := s[len(s)-32:]
+	for _, r := range id {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return "", errors.New("not hexadecimal")
+		}
+	}
+	return id, nil
+}
@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/integration"
+)
+
+func TestBookmarkIsForwardedToWebhook(t *testing.T) {
+	posted := make(chan integration.Bookmark, 1)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var bm integration.Bookmark
+		json.NewDecoder(r.Body).Decode(&bm)
+		posted <- bm
+	}))
+	defer srv.Close()
+
+	b, api := newTestBot(t)
+	b.integrationClient = srv.Client()
+	api.addMessage(sourceMessage())
+
+	userCommand(b, "user", "integration", stringOption("webhook", srv.URL))
+	if url := b.store.Settings("user").WebhookURL; url != srv.URL {
+		t.Fatalf("webhook URL = %q, want %q", url, srv.URL)
+	}
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	select {
+	case bm := <-posted:
+		if bm.Event != integration.EVENT_BOOKMARK_CREATED || bm.Content != "hello world" || bm.GuildName != "Test Guild" || bm.Author != "bob" {
+			t.Errorf("posted %+v", bm)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("bookmark was not posted to the webhook")
+	}
+}
+
+func TestNotionDatabaseID(t *testing.T) {
+	const id = "0123456789abcdef0123456789abcdef"
+	for _, s := range []string{
+		id,
+		"01234567-89ab-cdef-0123-456789abcdef",
+		"https://www.notion.so/workspace/Reading-list-" + id + "?v=123",
+	} {
+		if got, err := notionDatabaseID(s); err != nil || got != id {
+			t.Errorf("notionDatabaseID(%q) = %q, %v", s, got, err)
+		}
+	}
+	if _, err := notionDatabaseID("reading list"); err == nil {
+		t.Error("accepted a database name")
+	}
+}
@@ -0,0 +1,38 @@
+package bot
+
+// JOB_QUEUE_SIZE is how many background jobs can wait for the job worker.
+const JOB_QUEUE_SIZE = 16
+
+// job is long-running work, like re-rendering many bookmark messages, run
+// one at a time by the job worker so it doesn't compete for rate limits.
+type job struct {
+	name string
+	run  func()
+}
+
+// startJobs starts the job worker once; later Ready events are no-ops.
+func (b *Bot) startJobs() {
+	b.jobsOnce.Do(func() {
+		go func() {
+			for {
+				select {
+				case <-b.done:
+					return
+				case j := <-b.jobs:
+					b.logger.Printf("Running background job %s", j.name)
+					j.run()
+				}
+			}
+		}()
+	})
+}
+
+// enqueue queues a background job, reporting false if the queue is full.
+func (b *Bot) enqueue(name string, run func()) bool {
+	select {
+	case b.jobs <- job{name: name, run: run}:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+const LIST_PAGE_SIZE = 10
+
+// Sort orders of /bookmarks list.
+const (
+	SORT_PINNED = "pinned"
+	SORT_NEWEST = "newest"
+	SORT_OLDEST = "oldest"
+	SORT_GUILD  = "guild"
+)
+
+// listCommand shows a page of the user's bookmarks. Pinned bookmarks come
+// first unless another order is asked for.
+func (b *Bot) listCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+
+	order := SORT_PINNED
+	if opt, ok := opts["sort"]; ok {
+		order = opt.StringValue()
+	}
+	page := 1
+	if opt, ok := opts["page"]; ok {
+		page = int(opt.IntValue())
+	}
+	status := store.StatusActive
+	if opt, ok := opts["archived"]; ok && opt.BoolValue() {
+		status = store.StatusArchived
+	}
+
+	var bookmarks []store.Bookmark
+	for _, bm := range b.store.ForUser(user.ID) {
+		if bm.Status == status {
+			bookmarks = append(bookmarks, bm)
+		}
+	}
+	b.sortBookmarks(bookmarks, order)
+
+	b.respondEmbed(i, b.listEmbed(bookmarks, status, page), discordgo.MessageFlagsEphemeral)
+}
+
+// sortBookmarks sorts in place; ties are broken newest first.
+func (b *Bot) sortBookmarks(bookmarks []store.Bookmark, order string) {
+	names := map[string]string{}
+	if order == SORT_GUILD {
+		for _, bm := range bookmarks {
+			if _, ok := names[bm.GuildID]; !ok {
+				names[bm.GuildID] = strings.ToLower(b.guildName(bm.GuildID))
+			}
+		}
+	}
+
+	sort.SliceStable(bookmarks, func(x, y int) bool {
+		bx, by := bookmarks[x], bookmarks[y]
+		switch order {
+		case SORT_OLDEST:
+			return bx.CreatedAt.Before(by.CreatedAt)
+		case SORT_GUILD:
+			if names[bx.GuildID] != names[by.GuildID] {
+				return names[bx.GuildID] < names[by.GuildID]
+			}
+		case SORT_PINNED:
+			if bx.Pinned != by.Pinned {
+				return bx.Pinned
+			}
+		}
+		return bx.CreatedAt.After(by.CreatedAt)
+	})
+}
+
+func (b *Bot) listEmbed(bookmarks []store.Bookmark, status store.BookmarkStatus, page int) *discordgo.MessageEmbed {
+	list := &discordgo.MessageEmbed{
+		Title: "Your bookmarks",
+		Color: embed.DEFAULT_EMBED_COLOR,
+	}
+	if status == store.StatusArchived {
+		list.Title = "Your archived bookmarks"
+	}
+
+	if len(bookmarks) == 0 {
+		list.Description = "No bookmarks yet. React with 🔖 to a message to bookmark it."
+		return list
+	}
+
+	pages := (len(bookmarks) + LIST_PAGE_SIZE - 1) / LIST_PAGE_SIZE
+	page = min(max(page, 1), pages)
+	start := (page - 1) * LIST_PAGE_SIZE
+	end := min(start+LIST_PAGE_SIZE, len(bookmarks))
+
+	lines := make([]string, 0, end-start)
+	for _, bm := range bookmarks[start:end] {
+		lines = append(lines, b.listLine(bm))
+	}
+	list.Description = strings.Join(lines, "\n")
+	list.Footer = &discordgo.MessageEmbedFooter{
+		Text: fmt.Sprintf("Page %d of %d · %d bookmark(s)", page, pages, len(bookmarks)),
+	}
+	return list
+}
+
+func (b *Bot) listLine(bm store.Bookmark) string {
+	marker := "•"
+	if bm.Pinned {
+		marker = PIN_EMOJI
+	}
+	return fmt.Sprintf("%s <t:%d:d> %s", marker, bm.CreatedAt.Unix(), b.bookmarkLine(bm))
+}
+
+// pinCommand pins or unpins a bookmark by its short ID or the link of its
+// message, which also works for bookmarks waiting for a digest.
+func (b *Bot) pinCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+
+	found, reply, ok := b.findBookmark(user.ID, opts["bookmark"].StringValue())
+	if !ok {
+		b.respondEphemeral(i, reply)
+		return
+	}
+
+	pinned := true
+	if opt, ok := opts["pinned"]; ok {
+		pinned = opt.BoolValue()
+	}
+
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(user.ID, found.ChannelID, found.MessageID))
+	defer unlock()
+
+	// Re-read the bookmark, a DM action may have changed or removed it meanwhile.
+	bm, ok := b.store.Get(user.ID, found.ChannelID, found.MessageID)
+	if !ok {
+		b.respondEphemeral(i, "That bookmark was just deleted.")
+		return
+	}
+
+	bm.Pinned = pinned
+	if err := b.store.Update(bm); err != nil {
+		b.logger.Printf("Error updating stored bookmark for user %s: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	if pinned {
+		b.respondEphemeral(i, "Pinned! It's now at the top of `/bookmarks list`.")
+	} else {
+		b.respondEphemeral(i, "Unpinned.")
+	}
+}
@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+)
+
+func TestSortBookmarks(t *testing.T) {
+	b, _ := newTestBot(t)
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	bookmarks := []store.Bookmark{
+		{MessageID: "old", GuildID: "guild", CreatedAt: day},
+		{MessageID: "pinned", GuildID: "guild", CreatedAt: day.Add(time.Hour), Pinned: true},
+		{MessageID: "new", GuildID: "elsewhere", CreatedAt: day.Add(2 * time.Hour)},
+	}
+
+	tests := []struct {
+		order string
+		want  string
+	}{
+		{SORT_PINNED, "pinned new old"},
+		{SORT_NEWEST, "new pinned old"},
+		{SORT_OLDEST, "old pinned new"},
+		// Unknown guilds are named by their ID, which sorts before "Test Guild".
+		{SORT_GUILD, "new pinned old"},
+	}
+	for _, tt := range tests {
+		sorted := append([]store.Bookmark(nil), bookmarks...)
+		b.sortBookmarks(sorted, tt.order)
+
+		var ids []string
+		for _, bm := range sorted {
+			ids = append(ids, bm.MessageID)
+		}
+		if got := strings.Join(ids, " "); got != tt.want {
+			t.Errorf("sort %s = %s, want %s", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestPinCommandMovesBookmarkToTop(t *testing.T) {
+	b, api := newTestBot(t)
+	now := time.Now()
+	b.store.Add(store.Bookmark{UserID: "user", GuildID: "guild", ChannelID: "222222222222222222", MessageID: "333333333333333333", Content: "first", CreatedAt: now.Add(-time.Hour)})
+	b.store.Add(store.Bookmark{UserID: "user", GuildID: "guild", ChannelID: "222222222222222222", MessageID: "444444444444444444", Content: "second", CreatedAt: now})
+
+	userCommand(b, "user", "pin", stringOption("bookmark", "https://discord.com/channels/111111111111111111/222222222222222222/333333333333333333"))
+	if bm, _ := b.store.Get("user", "222222222222222222", "333333333333333333"); !bm.Pinned {
+		t.Fatal("bookmark was not pinned")
+	}
+
+	userCommand(b, "user", "list")
+	list := api.responses[len(api.responses)-1].Data.Embeds[0]
+	lines := strings.Split(list.Description, "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], PIN_EMOJI) || !strings.Contains(lines[0], "[first]") {
+		t.Errorf("list = %q, want the pinned bookmark first", list.Description)
+	}
+}
@@ -0,0 +1,69 @@
+package bot
+
+import "sync"
+
+// keyedMutex serializes work per key, e.g. per bookmark, so events about the
+// same bookmark are handled one at a time and in arrival order of the lock.
+// The zero value is ready to use; unused keys are dropped.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// Lock locks key and returns the function that unlocks it.
+func (k *keyedMutex) Lock(key string) (unlock func()) {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = map[string]*keyedLock{}
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+
+		k.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
+
+// Len returns the number of holders and waiters of all keys.
+func (k *keyedMutex) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	n := 0
+	for _, l := range k.locks {
+		n += l.refs
+	}
+	return n
+}
+
+// bookmarkKey identifies a user's bookmark of a source message.
+func bookmarkKey(userID, channelID, messageID string) string {
+	return userID + "/" + channelID + "/" + messageID
+}
+
+// lockBookmarkDM locks the bookmark delivered as a DM message, keyed by its
+// source when it is tracked so DM actions serialize with source events.
+func (b *Bot) lockBookmarkDM(userID, dmChannelID, dmMessageID string) (unlock func()) {
+	if bm, ok := b.store.ByDM(dmChannelID, dmMessageID); ok {
+		return b.bookmarkLocks.Lock(bookmarkKey(bm.UserID, bm.ChannelID, bm.MessageID))
+	}
+	return b.bookmarkLocks.Lock(bookmarkKey(userID, dmChannelID, dmMessageID))
+}
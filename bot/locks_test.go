@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestKeyedMutexSerializesPerKey(t *testing.T) {
+	var k keyedMutex
+	var wg sync.WaitGroup
+	counts := map[string]*int{"a": new(int), "b": new(int)}
+
+	for n := 0; n < 100; n++ {
+		for _, key := range []string{"a", "b"} {
+			wg.Add(1)
+			go func(key string) {
+				defer wg.Done()
+				unlock := k.Lock(key)
+				defer unlock()
+				// Unsynchronized apart from the key lock; the race detector
+				// flags this if two holders of a key overlap.
+				c := *counts[key]
+				*counts[key] = c + 1
+			}(key)
+		}
+	}
+	wg.Wait()
+
+	if *counts["a"] != 100 || *counts["b"] != 100 {
+		t.Errorf("counts = %d, %d, want 100 each", *counts["a"], *counts["b"])
+	}
+	if len(k.locks) != 0 {
+		t.Errorf("%d locks left after use", len(k.locks))
+	}
+}
@@ -0,0 +1,263 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	BOOKMARK_COMMAND      = "Bookmark"
+	BOOKMARK_NOTE_COMMAND = "Bookmark with note"
+	// NOTE_INPUT_MAX_LENGTH fits the note in one embed field.
+	NOTE_INPUT_MAX_LENGTH = embed.NOTE_MAX_LENGTH
+	TAGS_INPUT_MAX_LENGTH = 200
+	MAX_TAGS              = 10
+	TAG_MAX_LENGTH        = CLEAR_TAG_MAX_LENGTH
+)
+
+// bookmarkMessageCommand bookmarks the message the "Bookmark" context menu
+// command was used on.
+func (b *Bot) bookmarkMessageCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	data := i.ApplicationCommandData()
+	msg, ok := data.Resolved.Messages[data.TargetID]
+	if !ok {
+		b.respondEphemeral(i, "I couldn't find that message.")
+		return
+	}
+	if !b.checkResolvedMessage(i, msg) {
+		return
+	}
+	b.resolvedMessages.Add(msg.ChannelID+"/"+msg.ID, msg)
+
+	b.bookmarkFromInteraction(i, msg.ChannelID, msg.ID, "", nil)
+}
+
+// bookmarkWithNoteCommand asks for a note and tags before bookmarking. The
+// target message is carried in the modal's custom ID.
+func (b *Bot) bookmarkWithNoteCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	data := i.ApplicationCommandData()
+	msg, ok := data.Resolved.Messages[data.TargetID]
+	if !ok {
+		b.respondEphemeral(i, "I couldn't find that message.")
+		return
+	}
+	if !b.checkResolvedMessage(i, msg) {
+		return
+	}
+	b.resolvedMessages.Add(msg.ChannelID+"/"+msg.ID, msg)
+
+	if b.store.Has(interactionUser(i).ID, msg.ChannelID, msg.ID) {
+		b.respondEphemeral(i, "You already bookmarked that message.")
+		return
+	}
+
+	err := b.api.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: "note:" + msg.ChannelID + ":" + msg.ID,
+			Title:    "Bookmark with note",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  "note",
+						Label:     "Note",
+						Style:     discordgo.TextInputParagraph,
+						Required:  true,
+						MaxLength: NOTE_INPUT_MAX_LENGTH,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "tags",
+						Label:       "Tags",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "recipes, to-read",
+						MaxLength:   TAGS_INPUT_MAX_LENGTH,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error opening note modal for interaction %s: %v", i.ID, err)
+	}
+}
+
+// noteModal creates the bookmark once the note modal is submitted.
+func (b *Bot) noteModal(i *discordgo.InteractionCreate, args string) {
+	channelID, messageID, ok := strings.Cut(args, ":")
+	if !ok {
+		b.logger.Printf("Warning: Invalid note modal arguments %q", args)
+		return
+	}
+
+	values := modalValues(i.ModalSubmitData())
+	b.bookmarkFromInteraction(i, channelID, messageID, values["note"], parseTags(values["tags"]))
+}
+
+// bookmarkFromInteraction delivers a bookmark requested through a command
+// or modal, answering ephemerally once it's done.
+func (b *Bot) bookmarkFromInteraction(i *discordgo.InteractionCreate, channelID, messageID, note string, tags []string) {
+	if i.GuildID == "" {
+		b.respondEphemeral(i, "Only messages from servers can be bookmarked.")
+		return
+	}
+
+	// Delivery may download attachments, which can outlast the response deadline.
+	if !b.deferEphemeral(i) {
+		return
+	}
+
+	user := interactionUser(i)
+	msg, err := b.interactionMessage(i, channelID, messageID)
+	if err != nil {
+		b.logger.Printf("Error getting message %s from channel %s: %v", messageID, channelID, err)
+		if classifyAPIError(err) == apiErrorNotFound {
+			b.editResponse(i, unreadableNotice(i))
+		} else {
+			b.editResponse(i, "I couldn't find that message.")
+		}
+		return
+	}
+
+	guild, err := b.interactionGuild(i)
+	if err != nil {
+		b.logger.Printf("Error getting guild info for guild %s: %v", i.GuildID, err)
+		b.editResponse(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	err = b.deliverAnnotatedBookmark(context.Background(), user, guild, msg, note, tags, b.userInstalled(i))
+	switch {
+	case errors.Is(err, errNSFWBlocked):
+		b.editResponse(i, "Bookmarks from age-restricted channels are disabled on this bot.")
+	case errors.Is(err, errAlreadyBookmarked):
+		b.editResponse(i, "You already bookmarked that message.")
+	case errors.Is(err, errGuildOptedOut):
+		b.editResponse(i, optedOutMessage(guild))
+	case errors.Is(err, errRoleNotAllowed):
+		b.editResponse(i, b.roleRefusalMessage(guild))
+	case err != nil:
+		b.reportDeliveryError(user, err)
+		b.editResponse(i, "I couldn't deliver that bookmark, please try again later.")
+	default:
+		b.logger.Printf("Successfully sent bookmark to user %s (%s) from guild %s via command", user.Username, user.ID, guild.Name)
+		b.editResponse(i, "Bookmarked!")
+	}
+}
+
+// modalValues returns the submitted text inputs of a modal by custom ID.
+func modalValues(data discordgo.ModalSubmitInteractionData) map[string]string {
+	values := map[string]string{}
+	for _, row := range data.Components {
+		actions, ok := row.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, c := range actions.Components {
+			if input, ok := c.(*discordgo.TextInput); ok {
+				values[input.CustomID] = input.Value
+			}
+		}
+	}
+	return values
+}
+
+// parseTags splits a comma or space separated list into lowercase tags,
+// without leading "#" or duplicates.
+func parseTags(s string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, tag := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		tag = strings.ToLower(strings.TrimLeft(tag, "#"))
+		if runes := []rune(tag); len(runes) > TAG_MAX_LENGTH {
+			tag = string(runes[:TAG_MAX_LENGTH])
+		}
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tags) == MAX_TAGS {
+			break
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// noteCommand replaces the note of a bookmark, or removes it without one.
+func (b *Bot) noteCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+
+	found, reply, ok := b.findBookmark(user.ID, opts["bookmark"].StringValue())
+	if !ok {
+		b.respondEphemeral(i, reply)
+		return
+	}
+	note := ""
+	if opt, ok := opts["note"]; ok {
+		note = strings.TrimSpace(opt.StringValue())
+	}
+
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(user.ID, found.ChannelID, found.MessageID))
+	defer unlock()
+
+	// Re-read the bookmark, a DM action may have changed or removed it meanwhile.
+	bm, ok := b.store.Get(user.ID, found.ChannelID, found.MessageID)
+	if !ok {
+		b.respondEphemeral(i, "That bookmark was just deleted.")
+		return
+	}
+
+	prev := bm
+	bm.Note = note
+	bm.AddVersion(prev, store.VersionNote, time.Now())
+	if bm.HasDM() {
+		b.updateNoteField(bm)
+	}
+	if err := b.store.Update(bm); err != nil {
+		b.logger.Printf("Error updating stored bookmark for user %s: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	if note == "" {
+		b.respondEphemeral(i, "Note removed.")
+	} else {
+		b.respondEphemeral(i, "Note saved.")
+	}
+}
+
+// updateNoteField shows a bookmark's changed note in its DM.
+func (b *Bot) updateNoteField(bm store.Bookmark) {
+	b.editDMEmbed(bm, func(e *discordgo.MessageEmbed) {
+		embed.SetNote(e, bm.Note, bm.Tags)
+	})
+}
+
+// editDMEmbed applies change to the embed of a bookmark's DM.
+func (b *Bot) editDMEmbed(bm store.Bookmark, change func(e *discordgo.MessageEmbed)) {
+	dmMsg, err := b.api.ChannelMessage(bm.DMChannelID, bm.DMMessageID)
+	if err != nil {
+		b.logger.Printf("Error getting bookmark message for user %s (channel: %s, message: %s): %v", bm.UserID, bm.DMChannelID, bm.DMMessageID, err)
+		return
+	}
+	if len(dmMsg.Embeds) == 0 {
+		return
+	}
+
+	changed := dmMsg.Embeds[0]
+	change(changed)
+	embed.Fit(changed, embed.TRUNCATED_LINK_NOTICE)
+	_, err = b.api.ChannelMessageEditEmbed(bm.DMChannelID, bm.DMMessageID, changed)
+	if err != nil {
+		b.logger.Printf("Error updating bookmark message for user %s (channel: %s, message: %s): %v", bm.UserID, bm.DMChannelID, bm.DMMessageID, err)
+	}
+}
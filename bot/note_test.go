@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseTags(t *testing.T) {
+	got := parseTags("#Recipes, to-read  recipes,,")
+	want := []string{"recipes", "to-read"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTags = %q, want %q", got, want)
+	}
+	if tags := parseTags(""); tags != nil {
+		t.Errorf("parseTags of empty input = %q, want none", tags)
+	}
+}
+
+func TestBookmarkWithNoteCommandOpensModal(t *testing.T) {
+	b, api := newTestBot(t)
+	msg := sourceMessage()
+
+	b.InteractionCreate(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "user"}},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:        BOOKMARK_NOTE_COMMAND,
+			CommandType: discordgo.MessageApplicationCommand,
+			TargetID:    msg.ID,
+			Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+				Messages: map[string]*discordgo.Message{msg.ID: msg},
+			},
+		},
+	}})
+
+	if len(api.responses) != 1 || api.responses[0].Type != discordgo.InteractionResponseModal {
+		t.Fatalf("responses = %+v, want a modal", api.responses)
+	}
+	if id := api.responses[0].Data.CustomID; id != "note:channel:message" {
+		t.Errorf("modal custom ID = %q", id)
+	}
+}
+
+func TestNoteModalCreatesAnnotatedBookmark(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	b.InteractionCreate(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:    discordgo.InteractionModalSubmit,
+		GuildID: "guild",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "user", Username: "alice"}},
+		Data: discordgo.ModalSubmitInteractionData{
+			CustomID: "note:channel:message",
+			Components: []discordgo.MessageComponent{
+				&discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					&discordgo.TextInput{CustomID: "note", Value: "try this weekend"},
+				}},
+				&discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					&discordgo.TextInput{CustomID: "tags", Value: "recipes"},
+				}},
+			},
+		},
+	}})
+
+	bm, ok := b.store.Get("user", "channel", "message")
+	if !ok {
+		t.Fatal("bookmark was not stored")
+	}
+	if bm.Note != "try this weekend" || !reflect.DeepEqual(bm.Tags, []string{"recipes"}) {
+		t.Errorf("stored note = %q, tags = %q", bm.Note, bm.Tags)
+	}
+
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(api.sent))
+	}
+	fields := map[string]string{}
+	for _, field := range api.sent[0].Message.Embeds[0].Fields {
+		fields[field.Name] = field.Value
+	}
+	if fields["📝 Note"] != "try this weekend" || fields["Tags"] != "#recipes" {
+		t.Errorf("embed fields = %v, want the note and tags", fields)
+	}
+}
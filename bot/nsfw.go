@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/config"
+	"github.com/bwmarrin/discordgo"
+)
+
+var errNSFWBlocked = errors.New("bookmarks from age-restricted channels are disabled")
+
+// isNSFWChannel reports whether a channel, or the parent of a thread, is age-restricted.
+func (b *Bot) isNSFWChannel(channelID string) bool {
+	channel, err := b.channel(channelID)
+	if err != nil {
+		b.logger.Printf("Error getting channel info for channel %s: %v", channelID, err)
+		return false
+	}
+
+	if channel.NSFW || !channel.IsThread() {
+		return channel.NSFW
+	}
+
+	parent, err := b.channel(channel.ParentID)
+	if err != nil {
+		b.logger.Printf("Error getting channel info for channel %s: %v", channel.ParentID, err)
+		return false
+	}
+	return parent.NSFW
+}
+
+// spoilerMedia reports whether media of a channel's messages must be hidden behind spoilers.
+func (b *Bot) spoilerMedia(channelID string) bool {
+	return b.cfg().NSFWPolicy == config.NSFW_SPOILER && b.isNSFWChannel(channelID)
+}
+
+// markSpoilerFiles renames uploads so Discord shows them behind a spoiler.
+func markSpoilerFiles(files []*discordgo.File) {
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name, "SPOILER_") {
+			f.Name = "SPOILER_" + f.Name
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+var errGuildOptedOut = errors.New("the guild turned off bookmarks")
+
+// optedOutMessage explains to a user why a message of the guild wasn't bookmarked.
+func optedOutMessage(guild *discordgo.Guild) string {
+	return fmt.Sprintf("The admins of **%s** turned off bookmarking in their server, so I can't save its messages.", guild.Name)
+}
+
+// guildOptOutCommand lets server admins turn bookmarking of their server off
+// or back on. Discord only shows it to members with Manage Server by default.
+func (b *Bot) guildOptOutCommand(i *discordgo.InteractionCreate, disabled bool) {
+	if i.GuildID == "" {
+		b.respondEphemeral(i, "Use this command in your server.")
+		return
+	}
+
+	settings := b.store.GuildSettings(i.GuildID)
+	settings.BookmarksDisabled = disabled
+	err := b.store.SetGuildSettings(i.GuildID, settings)
+	if err != nil {
+		b.logger.Printf("Error saving settings of guild %s: %v", i.GuildID, err)
+		b.recordFailure(alertStore, "", err)
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	b.audit(store.AuditEntry{Action: store.AuditGuild, ActorID: interactionUser(i).ID, GuildID: i.GuildID, Detail: fmt.Sprintf("bookmarks disabled: %t", disabled)})
+	b.logger.Printf("User %s set bookmarks of guild %s disabled: %t", interactionUser(i).ID, i.GuildID, disabled)
+	if disabled {
+		b.respondEphemeral(i, "Bookmarking is now off in this server: 🔖 reactions and the Bookmark commands are ignored, and members trying are told why. Existing bookmarks are kept.")
+	} else {
+		b.respondEphemeral(i, "Bookmarking is back on in this server.")
+	}
+}
+
+func (b *Bot) guildDisableCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	b.guildOptOutCommand(i, true)
+}
+
+func (b *Bot) guildEnableCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	b.guildOptOutCommand(i, false)
+}
@@ -0,0 +1,28 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptedOutGuildIgnoresReactions(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	guildAdminCommand(b, "disable")
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(b.store.ForUser("user")) != 0 {
+		t.Fatal("message of an opted out guild was bookmarked")
+	}
+	if len(api.sent) != 1 || !strings.Contains(api.sent[0].Message.Content, "turned off bookmarking") {
+		t.Errorf("sent %+v, want one explanation", api.sent)
+	}
+
+	guildAdminCommand(b, "enable")
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(b.store.ForUser("user")) != 1 {
+		t.Error("message not bookmarked after turning bookmarks back on")
+	}
+}
@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"strconv"
+	"time"
+)
+
+// PRESENCE_INTERVAL is how often the bookmark count in the bot's status is
+// refreshed when PresenceCount is enabled.
+const PRESENCE_INTERVAL = 10 * time.Minute
+
+// startPresence refreshes the bot's status on every (re)identify, which
+// resets it, and then periodically.
+func (b *Bot) startPresence() {
+	b.updatePresence()
+	b.presenceOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(PRESENCE_INTERVAL)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-b.done:
+					return
+				case <-ticker.C:
+					b.updatePresence()
+				}
+			}
+		}()
+	})
+}
+
+// updatePresence shows the bookmark count as a "Watching" status, or
+// clears it once PresenceCount was turned off by a reload.
+func (b *Bot) updatePresence() {
+	if b.updateStatus == nil {
+		return
+	}
+
+	name := ""
+	if b.cfg().PresenceCount {
+		name = presenceText(b.store.Count())
+	} else if !b.presenceShown.Load() {
+		return
+	}
+
+	if err := b.updateStatus(name); err != nil {
+		b.logger.Printf("Error updating the bot's status: %v", err)
+		return
+	}
+	b.presenceShown.Store(name != "")
+}
+
+// presenceText is the status shown for n bookmarks, e.g. "12,345 bookmarks".
+func presenceText(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	if n == 1 {
+		return s + " bookmark"
+	}
+	return s + " bookmarks"
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+)
+
+func TestPresenceText(t *testing.T) {
+	for n, want := range map[int]string{
+		0:       "0 bookmarks",
+		1:       "1 bookmark",
+		999:     "999 bookmarks",
+		12345:   "12,345 bookmarks",
+		1234567: "1,234,567 bookmarks",
+	} {
+		if got := presenceText(n); got != want {
+			t.Errorf("presenceText(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestUpdatePresence(t *testing.T) {
+	b, _ := newTestBot(t)
+	var statuses []string
+	b.updateStatus = func(name string) error {
+		statuses = append(statuses, name)
+		return nil
+	}
+
+	b.updatePresence()
+	if len(statuses) != 0 {
+		t.Fatalf("status updated while disabled: %q", statuses)
+	}
+
+	cfg := b.cfg()
+	cfg.PresenceCount = true
+	b.Reload(cfg, b.embedTemplate())
+	for _, id := range []string{"m1", "m2"} {
+		if err := b.store.Add(store.Bookmark{UserID: "user", ChannelID: "channel", MessageID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.updatePresence()
+
+	cfg.PresenceCount = false
+	b.Reload(cfg, b.embedTemplate())
+	b.updatePresence()
+	b.updatePresence()
+
+	if len(statuses) != 2 || statuses[0] != "2 bookmarks" || statuses[1] != "" {
+		t.Errorf("statuses = %q, want the count and then a single clear", statuses)
+	}
+}
@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"github.com/anonmiraj/discord-bookmarker/config"
+	"github.com/anonmiraj/discord-bookmarker/embed"
+)
+
+// Reload applies a changed configuration and embed template without
+// reconnecting, logging what changed. Settings that need a restart keep their
+// current value.
+func (b *Bot) Reload(cfg config.Config, tmpl *embed.Template) {
+	b.reloadMu.Lock()
+	defer b.reloadMu.Unlock()
+
+	changes := config.Diff(b.config, cfg)
+	for _, change := range changes {
+		if change.Restart {
+			b.logger.Printf("Configuration: %s; restart the bot to apply it", change)
+		} else {
+			b.logger.Printf("Configuration: %s", change)
+		}
+	}
+	if len(changes) == 0 {
+		b.logger.Printf("Configuration reloaded without changes")
+	}
+
+	b.config = cfg.KeepRestartFields(b.config)
+	b.template = tmpl
+	b.triggers = newTriggers(cfg.BookmarkEmojis)
+}
+
+// cfg returns the configuration in use.
+func (b *Bot) cfg() config.Config {
+	b.reloadMu.RLock()
+	defer b.reloadMu.RUnlock()
+	return b.config
+}
+
+// embedTemplate returns the embed template in use.
+func (b *Bot) embedTemplate() *embed.Template {
+	b.reloadMu.RLock()
+	defer b.reloadMu.RUnlock()
+	return b.template
+}
+
+// bookmarkTriggers returns the reactions that bookmark a message.
+func (b *Bot) bookmarkTriggers() emojiSet {
+	b.reloadMu.RLock()
+	defer b.reloadMu.RUnlock()
+	return b.triggers
+}
+
+// newTriggers parses the configured bookmark emojis, 🔖 if there are none.
+func newTriggers(emojis []string) emojiSet {
+	if len(emojis) == 0 {
+		return newEmojiSet([]string{BOOKMARK_EMOJI})
+	}
+	return newEmojiSet(emojis)
+}
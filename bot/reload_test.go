@@ -0,0 +1,30 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+)
+
+func TestReloadAppliesNewTriggers(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	cfg := b.cfg()
+	cfg.BookmarkEmojis = []string{"⭐"}
+	cfg.Token = "new token"
+	b.Reload(cfg, embed.DefaultTemplate())
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	if len(b.store.ForUser("user")) != 0 {
+		t.Fatal("the replaced trigger still bookmarks")
+	}
+	b.ReactionAdd(bookmarkReaction("⭐"))
+	if len(b.store.ForUser("user")) != 1 {
+		t.Error("the new trigger doesn't bookmark")
+	}
+
+	if b.cfg().Token != "" {
+		t.Error("Reload changed a setting that needs a restart")
+	}
+}
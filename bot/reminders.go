@@ -0,0 +1,236 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// REMINDER_CHECK_INTERVAL is how often due reminders are looked for.
+	REMINDER_CHECK_INTERVAL = time.Minute
+	REMINDER_EMOJI          = "⏰"
+	// REMINDER_OFF is the "when" choice that cancels a reminder.
+	REMINDER_OFF = "off"
+	// REMINDERS_MAX_CHARS is Discord's limit on embed descriptions.
+	REMINDERS_MAX_CHARS = 4096
+)
+
+// snoozeHours are the snooze buttons under a reminder DM.
+var snoozeHours = []struct {
+	label string
+	hours int
+}{
+	{"1 hour", 1},
+	{"1 day", 24},
+	{"1 week", 7 * 24},
+}
+
+// startReminders starts the reminder scheduler once; later Ready events are no-ops.
+func (b *Bot) startReminders() {
+	b.reminderOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(REMINDER_CHECK_INTERVAL)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-b.done:
+					return
+				case now := <-ticker.C:
+					b.sendDueReminders(now)
+				}
+			}
+		}()
+	})
+}
+
+// sendDueReminders DMs every reminder due at now.
+func (b *Bot) sendDueReminders(now time.Time) {
+	for _, bm := range b.store.DueReminders(now) {
+		b.sendReminder(bm, now)
+	}
+}
+
+// sendReminder DMs the user a link to a bookmark with snooze buttons and
+// clears its reminder. Failed sends are retried on the next check, unless
+// the user can't be DMed at all.
+func (b *Bot) sendReminder(found store.Bookmark, now time.Time) {
+	// Instances sharing the store all see the reminder due; the one holding
+	// the claim sends it, and the others find it cleared once released.
+	key := fmt.Sprintf("reminder:%s:%s:%s:%d", found.UserID, found.ChannelID, found.MessageID, found.RemindAt.Unix())
+	release, ok := b.claim(key, "reminder of user "+found.UserID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(found.UserID, found.ChannelID, found.MessageID))
+	defer unlock()
+
+	// Re-read the bookmark, the reminder may have been changed meanwhile.
+	bm, ok := b.store.Get(found.UserID, found.ChannelID, found.MessageID)
+	if !ok || bm.RemindAt == nil || bm.RemindAt.After(now) {
+		return
+	}
+
+	dmChannel, err := b.dmChannel(bm.UserID)
+	if err == nil {
+		_, err = b.send(dmChannel.ID, &discordgo.MessageSend{
+			Content:    REMINDER_EMOJI + " Reminder: " + b.bookmarkLine(bm),
+			Components: reminderComponents(bm),
+		})
+	}
+	if err != nil {
+		b.logger.Printf("Error sending reminder to user %s: %v", bm.UserID, err)
+		if classifyAPIError(err) != apiErrorForbidden {
+			return
+		}
+	} else {
+		b.logger.Printf("Sent reminder of bookmark %s to user %s", bm.ShortID, bm.UserID)
+	}
+
+	if err := b.setReminder(bm, nil); err != nil {
+		b.logger.Printf("Error clearing reminder of user %s: %v", bm.UserID, err)
+		b.recordFailure(alertStore, "", err)
+	}
+}
+
+// reminderComponents are the snooze and dismiss buttons of a reminder DM;
+// their custom IDs carry the bookmarked message.
+func reminderComponents(bm store.Bookmark) []discordgo.MessageComponent {
+	source := bm.ChannelID + ":" + bm.MessageID
+	var buttons []discordgo.MessageComponent
+	for _, snooze := range snoozeHours {
+		buttons = append(buttons, discordgo.Button{
+			Label:    "Snooze " + snooze.label,
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("reminder:snooze:%d:%s", snooze.hours, source),
+		})
+	}
+	buttons = append(buttons, discordgo.Button{
+		Label:    "Done",
+		Style:    discordgo.SuccessButton,
+		CustomID: "reminder:done",
+	})
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+// reminderComponent handles the buttons of a reminder DM.
+func (b *Bot) reminderComponent(i *discordgo.InteractionCreate, args string) {
+	action, rest, _ := strings.Cut(args, ":")
+	if action != "snooze" {
+		b.updateComponentMessage(i, REMINDER_EMOJI+" Reminder dismissed.")
+		return
+	}
+
+	hoursArg, source, _ := strings.Cut(rest, ":")
+	channelID, messageID, ok := strings.Cut(source, ":")
+	hours, err := strconv.Atoi(hoursArg)
+	if !ok || err != nil {
+		b.logger.Printf("Warning: Invalid reminder component arguments %q", args)
+		return
+	}
+
+	user := interactionUser(i)
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(user.ID, channelID, messageID))
+	defer unlock()
+
+	bm, ok := b.store.Get(user.ID, channelID, messageID)
+	if !ok {
+		b.updateComponentMessage(i, "That bookmark was deleted.")
+		return
+	}
+	at := time.Now().Add(time.Duration(hours) * time.Hour)
+	if err := b.setReminder(bm, &at); err != nil {
+		b.logger.Printf("Error snoozing reminder of user %s: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+	b.updateComponentMessage(i, fmt.Sprintf("%s Snoozed until <t:%d:f>: %s", REMINDER_EMOJI, at.Unix(), b.bookmarkLine(bm)))
+}
+
+// remindCommand sets or cancels the reminder of a bookmark.
+func (b *Bot) remindCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+
+	found, reply, ok := b.findBookmark(user.ID, opts["bookmark"].StringValue())
+	if !ok {
+		b.respondEphemeral(i, reply)
+		return
+	}
+	var at *time.Time
+	if when := opts["when"].StringValue(); when != REMINDER_OFF {
+		d, err := time.ParseDuration(when)
+		if err != nil || d <= 0 {
+			b.respondEphemeral(i, "That isn't a reminder time I know.")
+			return
+		}
+		remindAt := time.Now().Add(d)
+		at = &remindAt
+	}
+
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(user.ID, found.ChannelID, found.MessageID))
+	defer unlock()
+
+	// Re-read the bookmark, a DM action may have changed or removed it meanwhile.
+	bm, ok := b.store.Get(user.ID, found.ChannelID, found.MessageID)
+	if !ok {
+		b.respondEphemeral(i, "That bookmark was just deleted.")
+		return
+	}
+	if at == nil && bm.RemindAt == nil {
+		b.respondEphemeral(i, "That bookmark has no reminder.")
+		return
+	}
+
+	if err := b.setReminder(bm, at); err != nil {
+		b.logger.Printf("Error updating stored bookmark for user %s: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	if at == nil {
+		b.respondEphemeral(i, "Reminder cancelled.")
+	} else {
+		b.respondEphemeral(i, fmt.Sprintf("I'll remind you <t:%d:R>.", at.Unix()))
+	}
+}
+
+// remindersCommand lists the user's upcoming reminders.
+func (b *Bot) remindersCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	reminders := b.store.Reminders(interactionUser(i).ID)
+	if len(reminders) == 0 {
+		b.respondEphemeral(i, "You have no upcoming reminders. Set one with `/bookmarks remind`.")
+		return
+	}
+
+	lines := make([]string, len(reminders))
+	for j, bm := range reminders {
+		lines[j] = fmt.Sprintf("<t:%d:R> %s", bm.RemindAt.Unix(), b.bookmarkLine(bm))
+	}
+	b.respondEmbed(i, &discordgo.MessageEmbed{
+		Title:       REMINDER_EMOJI + " Upcoming reminders",
+		Description: joinLimited(lines, REMINDERS_MAX_CHARS),
+		Color:       embed.DEFAULT_EMBED_COLOR,
+	}, discordgo.MessageFlagsEphemeral)
+}
+
+// setReminder stores a bookmark's reminder, nil for none, and shows it in
+// its DM. Callers hold the bookmark's lock.
+func (b *Bot) setReminder(bm store.Bookmark, at *time.Time) error {
+	bm.RemindAt = at
+	if bm.HasDM() {
+		b.editDMEmbed(bm, func(e *discordgo.MessageEmbed) {
+			embed.SetReminder(e, at)
+		})
+	}
+	return b.store.Update(bm)
+}
@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/bwmarrin/discordgo"
+)
+
+func reminderField(e *discordgo.MessageEmbed) string {
+	for _, field := range e.Fields {
+		if field.Name == embed.REMINDER_FIELD_NAME {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+func TestRemindCommandShowsReminderInEmbed(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+	bm := b.store.ForUser("user")[0]
+
+	userCommand(b, "user", "remind", stringOption("bookmark", bm.ShortID), stringOption("when", "3h"))
+	bm = b.store.ForUser("user")[0]
+	if bm.RemindAt == nil || time.Until(*bm.RemindAt) < 2*time.Hour {
+		t.Fatalf("RemindAt = %v, want in 3 hours", bm.RemindAt)
+	}
+	dm := api.messages["dm-user/"+r.MessageID].Embeds[0]
+	if want := fmt.Sprintf("<t:%d:R>", bm.RemindAt.Unix()); reminderField(dm) != want {
+		t.Errorf("reminder field = %q, want %q", reminderField(dm), want)
+	}
+
+	userCommand(b, "user", "reminders")
+	list := api.responses[len(api.responses)-1].Data.Embeds[0].Description
+	if !strings.Contains(list, bm.ShortID) {
+		t.Errorf("reminders = %q, want the bookmark", list)
+	}
+
+	userCommand(b, "user", "remind", stringOption("bookmark", bm.ShortID), stringOption("when", REMINDER_OFF))
+	if b.store.ForUser("user")[0].RemindAt != nil {
+		t.Error("reminder wasn't cancelled")
+	}
+	if field := reminderField(api.messages["dm-user/"+r.MessageID].Embeds[0]); field != "" {
+		t.Errorf("reminder field = %q after cancelling", field)
+	}
+}
+
+func TestDueReminderIsSentAndSnoozed(t *testing.T) {
+	b, api := newTestBot(t)
+	deliverTestBookmark(t, b, api)
+	bm := b.store.ForUser("user")[0]
+	userCommand(b, "user", "remind", stringOption("bookmark", bm.ShortID), stringOption("when", "1h"))
+
+	b.sendDueReminders(time.Now())
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages before the reminder was due", len(api.sent)-1)
+	}
+	b.sendDueReminders(time.Now().Add(2 * time.Hour))
+	if len(api.sent) != 2 {
+		t.Fatalf("sent %d messages, want the reminder", len(api.sent))
+	}
+	reminder := api.sent[1].Message
+	if !strings.HasPrefix(reminder.Content, REMINDER_EMOJI+" Reminder: ") || !strings.Contains(reminder.Content, bm.ShortID) {
+		t.Errorf("reminder = %q", reminder.Content)
+	}
+	if b.store.ForUser("user")[0].RemindAt != nil {
+		t.Fatal("reminder wasn't cleared once sent")
+	}
+
+	snooze := reminder.Components[0].(discordgo.ActionsRow).Components[1].(discordgo.Button)
+	if snooze.CustomID != "reminder:snooze:24:channel:message" {
+		t.Fatalf("second button = %q, want snoozing for a day", snooze.CustomID)
+	}
+	clickComponent(b, "user", "reminder", snooze.CustomID)
+	remindAt := b.store.ForUser("user")[0].RemindAt
+	if remindAt == nil || time.Until(*remindAt) < 23*time.Hour {
+		t.Errorf("RemindAt = %v after snoozing, want in a day", remindAt)
+	}
+	if resp := api.responses[len(api.responses)-1]; resp.Type != discordgo.InteractionResponseUpdateMessage || !strings.Contains(resp.Data.Content, "Snoozed until") {
+		t.Errorf("snooze response = %+v", resp.Data)
+	}
+}
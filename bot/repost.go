@@ -0,0 +1,230 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/msglink"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// errRepostTargetGone means the bookmark a repost was to be merged into was
+// deleted meanwhile.
+var errRepostTargetGone = errors.New("bookmark to merge into is gone")
+
+// contentHash identifies msg's text and attachments wherever they were
+// posted. Attachments are compared by name and size, since every upload
+// gets its own URL. Messages with neither get no hash.
+func contentHash(msg *discordgo.Message) string {
+	content := strings.Join(strings.Fields(msg.Content), " ")
+	if content == "" && len(msg.Attachments) == 0 {
+		return ""
+	}
+
+	h := sha256.New()
+	h.Write([]byte(content))
+	for _, a := range msg.Attachments {
+		h.Write([]byte("\x00" + a.Filename + "\x00" + strconv.Itoa(a.Size)))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// repostOf returns the user's bookmark with the same content as msg, made
+// from another message, and whether msg was already merged into one.
+func (b *Bot) repostOf(userID string, msg *discordgo.Message) (original store.Bookmark, merged, ok bool) {
+	matches := b.store.ByContentHash(userID, contentHash(msg))
+	for _, bm := range matches {
+		if bm.HasSource(msg.ChannelID, msg.ID) {
+			return bm, true, true
+		}
+	}
+	if len(matches) == 0 {
+		return store.Bookmark{}, false, false
+	}
+	return matches[len(matches)-1], false, true
+}
+
+// offerRepostMerge asks the user whether to merge a bookmark of reposted
+// content into their existing bookmark of it, and reports whether the
+// reaction was handled that way. Reactions the user may not bookmark are
+// left to the regular delivery, which explains the refusal.
+func (b *Bot) offerRepostMerge(user *discordgo.User, guild *discordgo.Guild, msg *discordgo.Message) bool {
+	if b.store.Has(user.ID, msg.ChannelID, msg.ID) {
+		return false
+	}
+	original, merged, ok := b.repostOf(user.ID, msg)
+	if !ok {
+		return false
+	}
+	if merged {
+		b.logger.Printf("User %s already merged message %s into bookmark %s, ignoring repeated reaction", user.ID, msg.ID, original.ShortID)
+		return true
+	}
+	if _, err := b.checkBookmarkable(user.ID, guild, msg.ChannelID); err != nil {
+		return false
+	}
+
+	dmChannel, err := b.dmChannel(user.ID)
+	if err != nil {
+		b.logger.Printf("Error creating DM channel with user %s: %v", user.ID, err)
+		return false
+	}
+
+	source := msg.ChannelID + ":" + msg.ID
+	_, err = b.send(dmChannel.ID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("You already bookmarked this content as `%s` (%s). Merge %s into it, or keep a separate bookmark?",
+			original.ShortID, msglink.New(original.GuildID, original.ChannelID, original.MessageID), msglink.New(guild.ID, msg.ChannelID, msg.ID)),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Merge", Style: discordgo.PrimaryButton, CustomID: "repost:merge:" + source},
+				discordgo.Button{Label: "Keep both", Style: discordgo.SecondaryButton, CustomID: "repost:keep:" + source},
+			}},
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error offering user %s to merge repost %s: %v", user.ID, msg.ID, err)
+		return false
+	}
+
+	b.logger.Printf("Offered user %s to merge repost %s into bookmark %s", user.ID, msg.ID, original.ShortID)
+	return true
+}
+
+// repostComponent handles the buttons of a merge offer; args is the action
+// and the reposted message as "merge|keep:channelID:messageID".
+func (b *Bot) repostComponent(i *discordgo.InteractionCreate, args string) {
+	user := interactionUser(i)
+	parts := strings.SplitN(args, ":", 3)
+	if len(parts) != 3 {
+		b.updateComponentMessage(i, "Something went wrong, please react again.")
+		return
+	}
+	action, channelID, messageID := parts[0], parts[1], parts[2]
+
+	msg, err := withRetry(b, func() (*discordgo.Message, error) {
+		return b.api.ChannelMessage(channelID, messageID)
+	})
+	if err != nil {
+		b.logger.Printf("Error getting message %s from channel %s: %v", messageID, channelID, err)
+		b.updateComponentMessage(i, "Couldn't read the reposted message, it may have been deleted.")
+		return
+	}
+	channel, err := b.channel(channelID)
+	if err != nil {
+		b.logger.Printf("Error getting channel info for channel %s: %v", channelID, err)
+		b.updateComponentMessage(i, "Something went wrong, please try again later.")
+		return
+	}
+	guild, err := b.guild(channel.GuildID)
+	if err != nil {
+		b.logger.Printf("Error getting guild info for guild %s: %v", channel.GuildID, err)
+		b.updateComponentMessage(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	var reply string
+	if action == "merge" {
+		var original store.Bookmark
+		original, err = b.mergeRepost(user, guild, msg)
+		reply = fmt.Sprintf("Merged into `%s`.", original.ShortID)
+	} else {
+		err = b.deliverBookmark(context.Background(), user, guild, msg)
+		reply = "Bookmarked separately."
+	}
+
+	switch {
+	case errors.Is(err, errAlreadyBookmarked):
+		reply = "You already bookmarked this message."
+	case errors.Is(err, errRepostTargetGone):
+		reply = "The bookmark to merge into was deleted. React again to bookmark the message."
+	case errors.Is(err, errGuildOptedOut):
+		reply = optedOutMessage(guild)
+	case errors.Is(err, errRoleNotAllowed):
+		reply = b.roleRefusalMessage(guild)
+	case errors.Is(err, errNSFWBlocked):
+		reply = "Bookmarks from age-restricted channels are disabled on this bot."
+	case err != nil:
+		b.reportDeliveryError(user, err)
+		reply = "Something went wrong, please try again later."
+	default:
+		if err := b.store.MarkProcessed(user.ID, msg.ChannelID, msg.ID); err != nil {
+			b.logger.Printf("Error recording processed reaction for user %s: %v", user.ID, err)
+			b.recordFailure(alertStore, "", err)
+		}
+	}
+	b.updateComponentMessage(i, reply)
+}
+
+// mergeRepost adds msg as a source of the user's bookmark of its content.
+func (b *Bot) mergeRepost(user *discordgo.User, guild *discordgo.Guild, msg *discordgo.Message) (store.Bookmark, error) {
+	if b.store.Has(user.ID, msg.ChannelID, msg.ID) {
+		return store.Bookmark{}, errAlreadyBookmarked
+	}
+	if _, err := b.checkBookmarkable(user.ID, guild, msg.ChannelID); err != nil {
+		return store.Bookmark{}, err
+	}
+	original, merged, ok := b.repostOf(user.ID, msg)
+	if !ok {
+		return store.Bookmark{}, errRepostTargetGone
+	}
+	if merged {
+		return store.Bookmark{}, errAlreadyBookmarked
+	}
+
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(original.UserID, original.ChannelID, original.MessageID))
+	defer unlock()
+
+	// Re-read the bookmark, it may have changed or been removed meanwhile.
+	original, ok = b.store.Get(original.UserID, original.ChannelID, original.MessageID)
+	if !ok {
+		return store.Bookmark{}, errRepostTargetGone
+	}
+	original.Reposts = append(original.Reposts, store.Source{GuildID: guild.ID, ChannelID: msg.ChannelID, MessageID: msg.ID})
+	if err := b.store.Update(original); err != nil {
+		b.logger.Printf("Error merging repost %s into bookmark %s of user %s: %v", msg.ID, original.ShortID, user.ID, err)
+		b.recordFailure(alertStore, "", err)
+		return store.Bookmark{}, err
+	}
+
+	if original.HasDM() {
+		b.updateSeenInField(original)
+	}
+	b.logger.Printf("Merged repost %s into bookmark %s of user %s", msg.ID, original.ShortID, user.ID)
+	return original, nil
+}
+
+// sourceLinks links every message a bookmark was made from, the original first.
+func sourceLinks(bm store.Bookmark) []string {
+	links := []string{msglink.New(bm.GuildID, bm.ChannelID, bm.MessageID).String()}
+	for _, s := range bm.Reposts {
+		links = append(links, msglink.New(s.GuildID, s.ChannelID, s.MessageID).String())
+	}
+	return links
+}
+
+// updateSeenInField shows a bookmark's sources in its DM.
+func (b *Bot) updateSeenInField(bm store.Bookmark) {
+	dmMsg, err := b.api.ChannelMessage(bm.DMChannelID, bm.DMMessageID)
+	if err != nil {
+		b.logger.Printf("Error getting bookmark message for user %s (channel: %s, message: %s): %v", bm.UserID, bm.DMChannelID, bm.DMMessageID, err)
+		return
+	}
+	if len(dmMsg.Embeds) == 0 {
+		return
+	}
+
+	annotated := dmMsg.Embeds[0]
+	embed.SetSeenIn(annotated, sourceLinks(bm))
+	embed.Fit(annotated, embed.TRUNCATED_LINK_NOTICE)
+	_, err = b.api.ChannelMessageEditEmbed(bm.DMChannelID, bm.DMMessageID, annotated)
+	if err != nil {
+		b.logger.Printf("Error updating bookmark message for user %s (channel: %s, message: %s): %v", bm.UserID, bm.DMChannelID, bm.DMMessageID, err)
+	}
+}
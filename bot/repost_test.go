@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/bwmarrin/discordgo"
+)
+
+// reactToRepost posts a copy of sourceMessage as "repost" and bookmarks it.
+func reactToRepost(b *Bot, api *fakeAPI) {
+	repost := sourceMessage()
+	repost.ID = "repost"
+	repost.Content = "  hello\nworld "
+	api.addMessage(repost)
+
+	r := bookmarkReaction(BOOKMARK_EMOJI)
+	r.MessageID = "repost"
+	b.ReactionAdd(r)
+}
+
+func TestRepostMerge(t *testing.T) {
+	b, api := newTestBot(t)
+	deliverTestBookmark(t, b, api)
+
+	reactToRepost(b, api)
+	if len(api.sent) != 2 || len(b.store.ForUser("user")) != 1 {
+		t.Fatalf("sent %d messages and stored %d bookmarks, want a merge offer only", len(api.sent), len(b.store.ForUser("user")))
+	}
+	offer := api.sent[1].Message
+	row := offer.Components[0].(discordgo.ActionsRow)
+	if id := row.Components[0].(discordgo.Button).CustomID; id != "repost:merge:channel:repost" {
+		t.Fatalf("merge button = %q", id)
+	}
+
+	clickComponent(b, "user", "prompt", "repost:merge:channel:repost")
+
+	bookmarks := b.store.ForUser("user")
+	if len(bookmarks) != 1 || len(bookmarks[0].Reposts) != 1 || bookmarks[0].Reposts[0].MessageID != "repost" {
+		t.Fatalf("bookmarks = %+v, want the repost merged", bookmarks)
+	}
+	if resp := api.responses[len(api.responses)-1]; resp.Data.Content != "Merged into `"+bookmarks[0].ShortID+"`." {
+		t.Errorf("response = %q", resp.Data.Content)
+	}
+	dmMsg, _ := api.ChannelMessage(bookmarks[0].DMChannelID, bookmarks[0].DMMessageID)
+	var seenIn string
+	for _, field := range dmMsg.Embeds[0].Fields {
+		if strings.HasPrefix(field.Name, embed.SEEN_IN_FIELD_PREFIX) {
+			seenIn = field.Name + ": " + field.Value
+		}
+	}
+	if !strings.HasPrefix(seenIn, "Seen in 2 places: [#1](") || !strings.Contains(seenIn, "/channel/repost)") {
+		t.Errorf("seen in field = %q", seenIn)
+	}
+
+	// Reacting to a merged repost again does nothing.
+	reactToRepost(b, api)
+	if len(api.sent) != 2 {
+		t.Errorf("sent %d messages after reacting to a merged repost, want 2", len(api.sent))
+	}
+}
+
+func TestRepostKeepBoth(t *testing.T) {
+	b, api := newTestBot(t)
+	deliverTestBookmark(t, b, api)
+	reactToRepost(b, api)
+
+	clickComponent(b, "user", "prompt", "repost:keep:channel:repost")
+
+	if n := len(b.store.ForUser("user")); n != 2 {
+		t.Fatalf("stored %d bookmarks, want the repost kept separately", n)
+	}
+	if resp := api.responses[len(api.responses)-1]; resp.Data.Content != "Bookmarked separately." {
+		t.Errorf("response = %q", resp.Data.Content)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	msg := sourceMessage()
+	if contentHash(msg) == "" {
+		t.Fatal("no hash for a message with content")
+	}
+	other := sourceMessage()
+	other.Attachments = []*discordgo.MessageAttachment{{Filename: "a.png", Size: 10}}
+	if contentHash(other) == contentHash(msg) {
+		t.Error("attachments don't change the hash")
+	}
+	if contentHash(&discordgo.Message{}) != "" {
+		t.Error("empty message has a hash")
+	}
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// RERENDER_INTERVAL paces bookmark message edits during a re-render, well
+// under Discord's rate limits so interactive handlers aren't starved.
+const RERENDER_INTERVAL = 500 * time.Millisecond
+
+// rerenderCommand queues a job that edits bookmark messages in place with
+// the current embed template and format. The owner can re-render everyone's.
+func (b *Bot) rerenderCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+
+	all := false
+	if opt, ok := opts["all"]; ok {
+		all = opt.BoolValue()
+	}
+
+	var bookmarks []store.Bookmark
+	if all {
+		if !b.isOwner(user.ID) {
+			b.respondEphemeral(i, "Only the bot owner can re-render all bookmarks.")
+			return
+		}
+		bookmarks = b.store.All()
+	} else {
+		bookmarks = b.store.ForUser(user.ID)
+	}
+
+	var withDM []store.Bookmark
+	for _, bm := range bookmarks {
+		if bm.HasDM() {
+			withDM = append(withDM, bm)
+		}
+	}
+	if len(withDM) == 0 {
+		b.respondEphemeral(i, "There are no bookmark messages to re-render.")
+		return
+	}
+
+	queued := b.enqueue("rerender for user "+user.ID, func() {
+		b.rerenderBookmarks(user.ID, withDM)
+	})
+	if !queued {
+		b.respondEphemeral(i, "Too much background work is queued, please try again later.")
+		return
+	}
+	b.respondEphemeral(i, fmt.Sprintf("Re-rendering %d bookmark message(s) in the background. I'll DM you when it's done.", len(withDM)))
+}
+
+// rerenderBookmarks edits the messages of bookmarks one by one and DMs the
+// requester a summary.
+func (b *Bot) rerenderBookmarks(requesterID string, bookmarks []store.Bookmark) {
+	rendered, failed := 0, 0
+	for j, bm := range bookmarks {
+		if j > 0 && !sleepFor(b.done, RERENDER_INTERVAL) {
+			return
+		}
+		ok, err := b.rerenderBookmark(bm)
+		if err != nil {
+			b.logger.Printf("Error re-rendering bookmark %s of user %s: %v", bm.MessageID, bm.UserID, err)
+			failed++
+		} else if ok {
+			rendered++
+		}
+	}
+
+	b.logger.Printf("Re-rendered %d bookmark message(s) for user %s, %d failed", rendered, requesterID, failed)
+	summary := fmt.Sprintf("Re-rendered %d bookmark message(s).", rendered)
+	if failed > 0 {
+		summary += fmt.Sprintf(" %d couldn't be edited.", failed)
+	}
+	b.notifyUser(requesterID, summary)
+}
+
+// rerenderBookmark replaces the embed of one bookmark message, keeping it
+// compact or full as it was. It reports false if the bookmark is gone.
+func (b *Bot) rerenderBookmark(found store.Bookmark) (bool, error) {
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(found.UserID, found.ChannelID, found.MessageID))
+	defer unlock()
+
+	bm, ok := b.store.Get(found.UserID, found.ChannelID, found.MessageID)
+	if !ok || !bm.HasDM() {
+		return false, nil
+	}
+
+	var bookmarkEmbed *discordgo.MessageEmbed
+	if bm.Compact {
+		msg, guild := b.bookmarkSource(bm)
+		link, origin := b.messageOrigin(guild, msg)
+		bookmarkEmbed = b.buildCompactEmbed(msg, origin, link.String())
+		embed.AddNote(bookmarkEmbed, bm.Note, bm.Tags)
+		embed.SetReminder(bookmarkEmbed, bm.RemindAt)
+		addShortID(bookmarkEmbed, bm.ShortID)
+	} else {
+		bookmarkEmbed, _ = b.rebuildEmbed(bm)
+	}
+	embed.SetSeenIn(bookmarkEmbed, sourceLinks(bm))
+
+	_, err := withRetry(b, func() (*discordgo.Message, error) {
+		return b.api.ChannelMessageEditEmbed(bm.DMChannelID, bm.DMMessageID, b.archivedEmbed(bm, bookmarkEmbed))
+	})
+	return err == nil, err
+}
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/bwmarrin/discordgo"
+)
+
+// runQueuedJob runs the next background job, failing if none was queued.
+func runQueuedJob(t *testing.T, b *Bot) {
+	t.Helper()
+	select {
+	case j := <-b.jobs:
+		j.run()
+	default:
+		t.Fatal("no background job queued")
+	}
+}
+
+func TestRerenderEditsBookmarkMessagesInPlace(t *testing.T) {
+	noSleep(t)
+	b, api := newTestBot(t)
+	deliverTestBookmark(t, b, api)
+	bm := b.store.ForUser("user")[0]
+	// A note stored without its field stands in for an outdated format.
+	bm.Note = "read later"
+	b.store.Update(bm)
+
+	userCommand(b, "user", "rerender")
+	if resp := api.responses[len(api.responses)-1]; !strings.Contains(resp.Data.Content, "Re-rendering 1 bookmark") {
+		t.Fatalf("response = %q", resp.Data.Content)
+	}
+	runQueuedJob(t, b)
+
+	if len(api.edited) != 1 || api.edited[0] != bm.DMChannelID+"/"+bm.DMMessageID {
+		t.Fatalf("edited %v, want the bookmark message", api.edited)
+	}
+	dmMsg, _ := api.ChannelMessage(bm.DMChannelID, bm.DMMessageID)
+	hasNote := false
+	for _, field := range dmMsg.Embeds[0].Fields {
+		hasNote = hasNote || field.Name == embed.NOTE_FIELD_NAME
+	}
+	if !hasNote {
+		t.Error("re-rendered embed has no note field")
+	}
+	if summary := api.sent[len(api.sent)-1].Message.Content; summary != "Re-rendered 1 bookmark message(s)." {
+		t.Errorf("summary = %q", summary)
+	}
+}
+
+func TestRerenderAllIsOwnerOnly(t *testing.T) {
+	b, api := newTestBot(t)
+	deliverTestBookmark(t, b, api)
+
+	userCommand(b, "user", "rerender", &discordgo.ApplicationCommandInteractionDataOption{
+		Type: discordgo.ApplicationCommandOptionBoolean, Name: "all", Value: true,
+	})
+	if resp := api.responses[len(api.responses)-1]; !strings.Contains(resp.Data.Content, "Only the bot owner") {
+		t.Errorf("response = %q, want a refusal", resp.Data.Content)
+	}
+	if len(b.jobs) != 0 {
+		t.Error("job queued for a non-owner")
+	}
+}
@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/config"
+	"github.com/anonmiraj/discord-bookmarker/msglink"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// RETENTION_CHECK_INTERVAL is how often bookmarks of guilds the bot is no
+// longer in are looked for, in case a GuildDelete event was missed.
+const RETENTION_CHECK_INTERVAL = 24 * time.Hour
+
+// GuildDelete applies the retention policy once the bot leaves a guild or is
+// removed from it. Outages also send GuildDelete, marked unavailable.
+// Bookmarks made through a user install are left alone, as in cleanupOrphans.
+func (b *Bot) GuildDelete(g *discordgo.GuildDelete) {
+	if g.Unavailable {
+		return
+	}
+
+	b.logger.Printf("Left guild %s, applying %s retention policy", g.ID, b.cfg().GuildRetention)
+	b.applyRetention(func(bm store.Bookmark) bool { return bm.GuildID == g.ID && !bm.UserInstall })
+}
+
+// GuildBanAdd applies the retention policy to the bookmarks a banned user
+// made in the guild through the bot's guild install.
+func (b *Bot) GuildBanAdd(ban *discordgo.GuildBanAdd) {
+	if ban.User == nil {
+		return
+	}
+
+	b.logger.Printf("User %s was banned from guild %s, applying %s retention policy", ban.User.ID, ban.GuildID, b.cfg().GuildRetention)
+	b.applyRetention(func(bm store.Bookmark) bool {
+		return bm.UserID == ban.User.ID && bm.GuildID == ban.GuildID && !bm.UserInstall
+	})
+}
+
+// startRetention starts the periodic orphan check once; later Ready events are no-ops.
+func (b *Bot) startRetention() {
+	b.retentionOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(RETENTION_CHECK_INTERVAL)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-b.done:
+					return
+				case <-ticker.C:
+					b.cleanupOrphans()
+				}
+			}
+		}()
+	})
+}
+
+// cleanupOrphans applies the retention policy to bookmarks of guilds missing
+// from the state. It is skipped while disconnected, when the state may be
+// stale. Bookmarks made through a user install never needed the bot in the
+// guild and are left alone.
+func (b *Bot) cleanupOrphans() {
+	if !b.ready.Load() {
+		return
+	}
+
+	left := map[string]bool{}
+	for _, bm := range b.store.All() {
+		if bm.Orphaned || bm.UserInstall || bm.GuildID == "" || bm.GuildID == msglink.DM_GUILD || left[bm.GuildID] {
+			continue
+		}
+		if _, err := b.state.Guild(bm.GuildID); err != nil {
+			left[bm.GuildID] = true
+		}
+	}
+
+	for guildID := range left {
+		b.logger.Printf("Not in guild %s anymore, applying %s retention policy", guildID, b.cfg().GuildRetention)
+		b.applyRetention(func(bm store.Bookmark) bool { return bm.GuildID == guildID && !bm.UserInstall })
+	}
+}
+
+// applyRetention handles the bookmarks matching match as configured by
+// GuildRetention: they are kept as they are, kept without jump links, or
+// deleted along with their DMs. Either way they are marked Orphaned.
+func (b *Bot) applyRetention(match func(store.Bookmark) bool) {
+	if b.cfg().GuildRetention == config.RETENTION_PURGE {
+		removed, err := b.store.RemoveIf(match)
+		if err != nil {
+			b.logger.Printf("Error purging bookmarks: %v", err)
+			b.recordFailure(alertStore, "", err)
+			return
+		}
+		for _, bm := range removed {
+			if !bm.HasDM() {
+				continue
+			}
+			if err := b.api.ChannelMessageDelete(bm.DMChannelID, bm.DMMessageID); err != nil {
+				b.logger.Printf("Error deleting purged bookmark message (channel: %s, message: %s): %v", bm.DMChannelID, bm.DMMessageID, err)
+			}
+		}
+		for _, bm := range removed {
+			b.audit(store.AuditEntry{Action: store.AuditDelete, ActorID: b.state.User.ID, UserID: bm.UserID, GuildID: bm.GuildID,
+				ChannelID: bm.ChannelID, MessageID: bm.MessageID, Detail: "guild retention policy"})
+		}
+		b.logger.Printf("Purged %d bookmark(s)", len(removed))
+		return
+	}
+
+	n := 0
+	for _, bm := range b.store.All() {
+		if bm.Orphaned || !match(bm) {
+			continue
+		}
+		b.orphanBookmark(bm)
+		n++
+	}
+	b.logger.Printf("Marked %d bookmark(s) as orphaned", n)
+}
+
+func (b *Bot) orphanBookmark(bm store.Bookmark) {
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(bm.UserID, bm.ChannelID, bm.MessageID))
+	defer unlock()
+
+	bm, ok := b.store.Get(bm.UserID, bm.ChannelID, bm.MessageID)
+	if !ok || bm.Orphaned {
+		return
+	}
+
+	if b.cfg().GuildRetention == config.RETENTION_STRIP && bm.HasDM() {
+		b.stripJumpLink(bm)
+	}
+
+	bm.Orphaned = true
+	if err := b.store.Update(bm); err != nil {
+		b.logger.Printf("Error updating stored bookmark for user %s: %v", bm.UserID, err)
+		b.recordFailure(alertStore, "", err)
+	}
+}
+
+// stripJumpLink removes the link to the source message from a bookmark DM,
+// keeping the saved content.
+func (b *Bot) stripJumpLink(bm store.Bookmark) {
+	dmMsg, err := b.api.ChannelMessage(bm.DMChannelID, bm.DMMessageID)
+	if err != nil {
+		b.logger.Printf("Error getting bookmark message for user %s (channel: %s, message: %s): %v", bm.UserID, bm.DMChannelID, bm.DMMessageID, err)
+		return
+	}
+	if len(dmMsg.Embeds) == 0 {
+		return
+	}
+
+	embed := dmMsg.Embeds[0]
+	embed.URL = ""
+	fields := embed.Fields[:0]
+	for _, field := range embed.Fields {
+		if field.Name != "Source" {
+			fields = append(fields, field)
+		}
+	}
+	embed.Fields = fields
+	addFooterNotice(embed, bm.ShortID, "⚠️ The original server is no longer available · React with ❌ to remove this bookmark")
+
+	_, err = b.api.ChannelMessageEditEmbed(bm.DMChannelID, bm.DMMessageID, embed)
+	if err != nil {
+		b.logger.Printf("Error updating bookmark message for user %s (channel: %s, message: %s): %v", bm.UserID, bm.DMChannelID, bm.DMMessageID, err)
+	}
+}
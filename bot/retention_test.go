@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/config"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestGuildDeleteStripsJumpLinks(t *testing.T) {
+	b, api := newTestBot(t)
+	b.config.GuildRetention = config.RETENTION_STRIP
+	r := deliverTestBookmark(t, b, api)
+	// A footer of the embed template, after the short ID.
+	dm, _ := api.ChannelMessage("dm-user", r.MessageID)
+	dm.Embeds[0].Footer = &discordgo.MessageEmbedFooter{Text: "bk-1 · saved by the team", IconURL: "https://example.com/icon.png"}
+
+	b.GuildDelete(&discordgo.GuildDelete{Guild: &discordgo.Guild{ID: "guild"}})
+
+	bm, ok := b.store.ByDM("dm-user", r.MessageID)
+	if !ok || !bm.Orphaned {
+		t.Fatalf("stored bookmark = %+v, %t, want it kept and orphaned", bm, ok)
+	}
+	dm, _ = api.ChannelMessage("dm-user", r.MessageID)
+	embed := dm.Embeds[0]
+	if footer := embed.Footer; !strings.HasPrefix(footer.Text, "bk-1 · saved by the team · ⚠️") || footer.IconURL == "" {
+		t.Errorf("footer = %+v, want the notice after the template's", footer)
+	}
+	if embed.URL != "" {
+		t.Errorf("embed URL = %q, want none", embed.URL)
+	}
+	for _, field := range embed.Fields {
+		if field.Name == "Source" {
+			t.Errorf("embed still has the jump link field %q", field.Value)
+		}
+	}
+	if embed.Description != "hello world" {
+		t.Errorf("embed description = %q, want the saved content", embed.Description)
+	}
+}
+
+func TestGuildDeletePurgesBookmarks(t *testing.T) {
+	b, api := newTestBot(t)
+	b.config.GuildRetention = config.RETENTION_PURGE
+	r := deliverTestBookmark(t, b, api)
+
+	b.GuildDelete(&discordgo.GuildDelete{Guild: &discordgo.Guild{ID: "guild", Unavailable: true}})
+	if len(b.store.ForUser("user")) != 1 {
+		t.Fatal("an outage purged bookmarks")
+	}
+
+	b.GuildDelete(&discordgo.GuildDelete{Guild: &discordgo.Guild{ID: "guild"}})
+	if len(b.store.ForUser("user")) != 0 {
+		t.Error("bookmark was not purged")
+	}
+	if want := "dm-user/" + r.MessageID; len(api.deleted) != 1 || api.deleted[0] != want {
+		t.Errorf("deleted %v, want [%s]", api.deleted, want)
+	}
+}
+
+func TestGuildBanAddOnlyAffectsBannedUser(t *testing.T) {
+	b, api := newTestBot(t)
+	b.config.GuildRetention = config.RETENTION_PURGE
+	deliverTestBookmark(t, b, api)
+
+	b.GuildBanAdd(&discordgo.GuildBanAdd{GuildID: "guild", User: &discordgo.User{ID: "someone-else"}})
+	if len(b.store.ForUser("user")) != 1 {
+		t.Fatal("ban of another user purged the bookmark")
+	}
+
+	b.GuildBanAdd(&discordgo.GuildBanAdd{GuildID: "guild", User: &discordgo.User{ID: "user"}})
+	if len(b.store.ForUser("user")) != 0 {
+		t.Error("bookmark of the banned user was not purged")
+	}
+}
+
+func TestCleanupOrphansFindsMissingGuilds(t *testing.T) {
+	b, api := newTestBot(t)
+	b.config.GuildRetention = config.RETENTION_KEEP
+	deliverTestBookmark(t, b, api)
+	b.ready.Store(true)
+
+	b.cleanupOrphans()
+	if bm := b.store.ForUser("user")[0]; bm.Orphaned {
+		t.Fatal("bookmark of a joined guild was orphaned")
+	}
+
+	b.state.GuildRemove(&discordgo.Guild{ID: "guild"})
+	b.cleanupOrphans()
+	if bm := b.store.ForUser("user")[0]; !bm.Orphaned {
+		t.Error("bookmark of a left guild was not orphaned")
+	}
+}
+
+func TestRetentionSkipsUserInstalls(t *testing.T) {
+	b, api := newTestBot(t)
+	b.config.GuildRetention = config.RETENTION_PURGE
+	b.ready.Store(true)
+	msg := sourceMessage()
+	msg.ChannelID = "foreign-channel"
+	i := bookmarkMessageCommand(msg)
+	i.ID = "interaction"
+	i.GuildID = "foreign-guild"
+	b.InteractionEvent(interactionEvent(i, `{"authorizing_integration_owners":{"1":"user"}}`))
+
+	bookmarks := b.store.ForUser("user")
+	if len(bookmarks) != 1 || !bookmarks[0].UserInstall {
+		t.Fatalf("stored %+v, want a user-install bookmark", bookmarks)
+	}
+
+	b.cleanupOrphans()
+	b.GuildDelete(&discordgo.GuildDelete{Guild: &discordgo.Guild{ID: "foreign-guild"}})
+	b.GuildBanAdd(&discordgo.GuildBanAdd{GuildID: "foreign-guild", User: &discordgo.User{ID: "user"}})
+	if len(b.store.ForUser("user")) != 1 || len(api.deleted) != 0 {
+		t.Error("bookmark made through a user install was purged")
+	}
+}
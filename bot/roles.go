@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// MEMBER_ROLES_TTL bounds how long a member's roles are trusted for role
+// gating. Without the GuildMembers intent the gateway never updates cached
+// members, so older roles are fetched again.
+const MEMBER_ROLES_TTL = 5 * time.Minute
+
+var errRoleNotAllowed = errors.New("the member has none of the guild's bookmarking roles")
+
+// memberRoles are the roles of a guild member and when they were seen.
+type memberRoles struct {
+	roles []string
+	at    time.Time
+}
+
+// rememberRoles keeps the roles of a member seen in an event for role gating.
+func (b *Bot) rememberRoles(guildID string, member *discordgo.Member) {
+	b.memberRoles.Add(guildID+":"+member.User.ID, memberRoles{roles: member.Roles, at: time.Now()})
+}
+
+// currentRoles returns the roles of a guild member seen in the last
+// MEMBER_ROLES_TTL, fetching them otherwise.
+func (b *Bot) currentRoles(guildID, userID string) ([]string, error) {
+	if cached, ok := b.memberRoles.Get(guildID + ":" + userID); ok && time.Since(cached.at) < MEMBER_ROLES_TTL {
+		return cached.roles, nil
+	}
+
+	member, err := withRetry(b, func() (*discordgo.Member, error) {
+		return b.api.GuildMember(guildID, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	member.User = &discordgo.User{ID: userID}
+	b.rememberRoles(guildID, member)
+	return member.Roles, nil
+}
+
+// memberAllowed reports whether a member may bookmark messages of a guild
+// that limits bookmarking to some roles. Members whose roles can't be looked
+// up are refused.
+func (b *Bot) memberAllowed(guildID, userID string, settings store.GuildSettings) bool {
+	if len(settings.AllowedRoles) == 0 {
+		return true
+	}
+	roles, err := b.currentRoles(guildID, userID)
+	if err != nil {
+		b.logger.Printf("Error getting roles of user %s in guild %s: %v", userID, guildID, err)
+		return false
+	}
+	return settings.Allows(roles)
+}
+
+// roleRefusalMessage explains to a user which roles may bookmark messages of the guild.
+func (b *Bot) roleRefusalMessage(guild *discordgo.Guild) string {
+	allowed := b.store.GuildSettings(guild.ID).AllowedRoles
+	var names []string
+	for _, role := range guild.Roles {
+		if slices.Contains(allowed, role.ID) {
+			names = append(names, "**"+role.Name+"**")
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("Sorry, the admins of **%s** only let members with certain roles bookmark its messages.", guild.Name)
+	}
+	return fmt.Sprintf("Sorry, the admins of **%s** only let members with the %s role bookmark its messages.", guild.Name, strings.Join(names, " or "))
+}
+
+// guildRoleCommand adds a role to or removes it from the roles allowed to
+// bookmark in the guild. Without any, everyone may.
+func (b *Bot) guildRoleCommand(i *discordgo.InteractionCreate, opts optionMap, allow bool) {
+	if i.GuildID == "" {
+		b.respondEphemeral(i, "Use this command in your server.")
+		return
+	}
+
+	roleID := opts["role"].Value.(string)
+	settings := b.store.GuildSettings(i.GuildID)
+	roles := slices.DeleteFunc(slices.Clone(settings.AllowedRoles), func(id string) bool { return id == roleID })
+	if allow {
+		roles = append(roles, roleID)
+	}
+	settings.AllowedRoles = roles
+
+	if err := b.store.SetGuildSettings(i.GuildID, settings); err != nil {
+		b.logger.Printf("Error saving settings of guild %s: %v", i.GuildID, err)
+		b.recordFailure(alertStore, "", err)
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	b.audit(store.AuditEntry{Action: store.AuditGuild, ActorID: interactionUser(i).ID, GuildID: i.GuildID, Detail: fmt.Sprintf("bookmarking roles: %d", len(roles))})
+	b.logger.Printf("User %s set %d bookmarking role(s) in guild %s", interactionUser(i).ID, len(roles), i.GuildID)
+	switch {
+	case allow:
+		b.respondEphemeral(i, fmt.Sprintf("Members with <@&%s> can bookmark messages here; members without any of the %d allowed role(s) can't.", roleID, len(roles)))
+	case len(roles) == 0:
+		b.respondEphemeral(i, "No roles are required anymore: everyone can bookmark messages here.")
+	default:
+		b.respondEphemeral(i, fmt.Sprintf("Members with <@&%s> can't bookmark messages here anymore, unless they have another of the %d allowed role(s).", roleID, len(roles)))
+	}
+}
+
+func (b *Bot) guildAllowRoleCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	b.guildRoleCommand(i, opts, true)
+}
+
+func (b *Bot) guildRemoveRoleCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	b.guildRoleCommand(i, opts, false)
+}
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func roleOption(roleID string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Type: discordgo.ApplicationCommandOptionRole, Name: "role", Value: roleID}
+}
+
+func reactionWithRoles(roles ...string) *discordgo.MessageReactionAdd {
+	r := bookmarkReaction(BOOKMARK_EMOJI)
+	r.Member = &discordgo.Member{User: &discordgo.User{ID: "user", Username: "alice"}, Roles: roles}
+	return r
+}
+
+func TestRoleGatedGuildRefusesOtherMembers(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	guildAdminCommand(b, "allow-role", roleOption("members"))
+	b.ReactionAdd(reactionWithRoles("visitors"))
+
+	if len(b.store.ForUser("user")) != 0 {
+		t.Fatal("member without an allowed role bookmarked a message")
+	}
+	if len(api.sent) != 1 || !strings.Contains(api.sent[0].Message.Content, "certain roles") {
+		t.Errorf("sent %+v, want one refusal", api.sent)
+	}
+
+	b.ReactionAdd(reactionWithRoles("visitors", "members"))
+	if len(b.store.ForUser("user")) != 1 {
+		t.Error("member with an allowed role couldn't bookmark")
+	}
+}
+
+func TestRemovingLastRoleAllowsEveryone(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+
+	guildAdminCommand(b, "allow-role", roleOption("members"))
+	guildAdminCommand(b, "remove-role", roleOption("members"))
+	b.ReactionAdd(reactionWithRoles())
+
+	if len(b.store.ForUser("user")) != 1 {
+		t.Error("message not bookmarked once no roles are required")
+	}
+}
+
+func TestStaleRolesAreFetchedAgain(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+	guildAdminCommand(b, "allow-role", roleOption("members"))
+
+	// The member had the role when last seen but lost it since.
+	b.ReactionAdd(reactionWithRoles("members"))
+	if len(b.store.ForUser("user")) != 1 || api.memberLookups != 0 {
+		t.Fatalf("bookmarks = %d after %d lookups, want the fresh roles used", len(b.store.ForUser("user")), api.memberLookups)
+	}
+	b.memberRoles.Add("guild:user", memberRoles{roles: []string{"members"}, at: time.Now().Add(-MEMBER_ROLES_TTL)})
+	api.members["guild/user"] = &discordgo.Member{Roles: []string{"visitors"}}
+
+	if b.memberAllowed("guild", "user", b.store.GuildSettings("guild")) {
+		t.Error("member allowed on roles older than MEMBER_ROLES_TTL")
+	}
+	if api.memberLookups != 1 {
+		t.Errorf("member looked up %d times, want once", api.memberLookups)
+	}
+}
@@ -0,0 +1,245 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// SETTINGS_LANGUAGE_OFF is the language option turning translations off;
+// select options can't have empty values.
+const SETTINGS_LANGUAGE_OFF = "off"
+
+// languages are the choices of the language menu of the settings panel,
+// which bookmarks are translated into.
+var languages = []struct{ Code, Name string }{
+	{"en", "English"},
+	{"es", "Español"},
+	{"fr", "Français"},
+	{"de", "Deutsch"},
+	{"it", "Italiano"},
+	{"pt", "Português"},
+	{"nl", "Nederlands"},
+	{"pl", "Polski"},
+	{"ru", "Русский"},
+	{"uk", "Українська"},
+	{"tr", "Türkçe"},
+	{"ar", "العربية"},
+	{"hi", "हिन्दी"},
+	{"ja", "日本語"},
+	{"ko", "한국어"},
+	{"zh", "中文"},
+}
+
+func (b *Bot) settingsCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	settings := b.store.Settings(interactionUser(i).ID)
+	err := b.api.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    settingsSummary(settings),
+			Flags:      discordgo.MessageFlagsEphemeral,
+			Components: b.settingsComponents(i.GuildID, settings),
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error responding to interaction %s: %v", i.ID, err)
+	}
+}
+
+// settingsComponent applies a change made in the settings panel and shows
+// the updated panel.
+func (b *Bot) settingsComponent(i *discordgo.InteractionCreate, args string) {
+	user := interactionUser(i)
+	settings := b.store.Settings(user.ID)
+	values := i.MessageComponentData().Values
+
+	switch args {
+	case "delivery":
+		delivery := store.Delivery(firstValue(values))
+		if delivery == "instant" {
+			delivery = store.DeliveryInstant
+		}
+		if delivery != settings.Delivery {
+			settings = b.switchDelivery(user.ID, settings, delivery)
+		}
+	case "verbosity":
+		settings.Verbosity = store.Verbosity(firstValue(values))
+		if settings.Verbosity == "full" {
+			settings.Verbosity = store.VerbosityFull
+		}
+	case "language":
+		settings.Language = firstValue(values)
+		if settings.Language == SETTINGS_LANGUAGE_OFF {
+			settings.Language = ""
+		}
+	case "target":
+		channelID := firstValue(values)
+		if channelID == "" {
+			settings.ArchiveChannelID = ""
+			settings.ArchiveThreads = store.ThreadsNone
+			break
+		}
+		required := int64(discordgo.PermissionViewChannel | discordgo.PermissionSendMessages)
+		if i.GuildID == "" || !b.memberHasPermissions(i.GuildID, i.Member, channelID, required) {
+			b.respondEphemeral(i, "You can't send messages in that channel.")
+			return
+		}
+		// Threads of a previous archive channel can't be reused.
+		if settings.ArchiveChannelID != channelID {
+			b.forgetArchiveThreads(user.ID)
+		}
+		settings.ArchiveChannelID = channelID
+	case "dm":
+		settings.ArchiveChannelID = ""
+		settings.ArchiveThreads = store.ThreadsNone
+	case "unreact":
+		settings.RemoveReaction = !settings.RemoveReaction
+	default:
+		b.logger.Printf("Warning: Unknown settings component %q", args)
+		return
+	}
+
+	err := b.store.SetSettings(user.ID, settings)
+	if err != nil {
+		b.logger.Printf("Error saving settings of user %s: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	b.logger.Printf("User %s changed their %s setting", user.ID, args)
+	err = b.api.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    settingsSummary(settings),
+			Components: b.settingsComponents(i.GuildID, settings),
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error responding to interaction %s: %v", i.ID, err)
+	}
+}
+
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// settingsSummary describes the user's settings above the panel.
+func settingsSummary(settings store.UserSettings) string {
+	var sb strings.Builder
+	sb.WriteString("**Your bookmark settings**\n")
+
+	if settings.ArchiveChannelID == "" {
+		sb.WriteString("Delivered to: your DMs\n")
+	} else {
+		fmt.Fprintf(&sb, "Delivered to: <#%s>\n", settings.ArchiveChannelID)
+	}
+
+	switch settings.Delivery {
+	case store.DeliveryInstant:
+		sb.WriteString("Sent: right away\n")
+	default:
+		fmt.Fprintf(&sb, "Sent: in a %s digest\n", settings.Delivery)
+	}
+
+	if settings.Verbosity == store.VerbosityCompact {
+		sb.WriteString("Embeds: compact\n")
+	} else {
+		sb.WriteString("Embeds: full\n")
+	}
+
+	fmt.Fprintf(&sb, "Language: %s\n", languageName(settings.Language))
+
+	if settings.RemoveReaction {
+		sb.WriteString("Your 🔖 reaction is removed once the bookmark is delivered.")
+	} else {
+		sb.WriteString("Your 🔖 reaction is kept on the message.")
+	}
+	return sb.String()
+}
+
+func languageName(code string) string {
+	for _, l := range languages {
+		if l.Code == code {
+			return l.Name
+		}
+	}
+	return "off, bookmarks aren't translated"
+}
+
+// settingsComponents returns the menus and buttons of the settings panel.
+// Archive channels can only be picked from a server.
+func (b *Bot) settingsComponents(guildID string, settings store.UserSettings) []discordgo.MessageComponent {
+	option := func(label, value string, selected bool) discordgo.SelectMenuOption {
+		return discordgo.SelectMenuOption{Label: label, Value: value, Default: selected}
+	}
+
+	deliveries := []discordgo.SelectMenuOption{
+		option("Instant DM per bookmark", "instant", settings.Delivery == store.DeliveryInstant),
+		option("Daily digest", string(store.DeliveryDaily), settings.Delivery == store.DeliveryDaily),
+		option("Weekly digest", string(store.DeliveryWeekly), settings.Delivery == store.DeliveryWeekly),
+	}
+	verbosities := []discordgo.SelectMenuOption{
+		option("Full embeds", "full", settings.Verbosity == store.VerbosityFull),
+		option("Compact embeds", string(store.VerbosityCompact), settings.Verbosity == store.VerbosityCompact),
+	}
+	langs := []discordgo.SelectMenuOption{
+		option("Don't translate", SETTINGS_LANGUAGE_OFF, settings.Language == ""),
+	}
+	for _, l := range languages {
+		langs = append(langs, option(l.Name, l.Code, settings.Language == l.Code))
+	}
+
+	unreactLabel, unreactStyle := "Remove 🔖 after delivery: off", discordgo.SecondaryButton
+	if settings.RemoveReaction {
+		unreactLabel, unreactStyle = "Remove 🔖 after delivery: on", discordgo.SuccessButton
+	}
+
+	rows := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{CustomID: "settings:delivery", Options: deliveries},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{CustomID: "settings:verbosity", Options: verbosities},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{CustomID: "settings:language", Options: langs},
+		}},
+	}
+
+	if guildID != "" {
+		target := discordgo.SelectMenu{
+			MenuType:     discordgo.ChannelSelectMenu,
+			CustomID:     "settings:target",
+			Placeholder:  "Post bookmarks to a channel instead of DMs",
+			MinValues:    new(int),
+			MaxValues:    1,
+			ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+		}
+		// Only channels of this server can be shown as selected.
+		if ch, err := b.state.Channel(settings.ArchiveChannelID); err == nil && ch.GuildID == guildID {
+			target.DefaultValues = []discordgo.SelectMenuDefaultValue{{ID: ch.ID, Type: discordgo.SelectMenuDefaultValueChannel}}
+		}
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{target}})
+	}
+
+	rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.Button{
+			Label:    "Send to my DMs",
+			Style:    discordgo.SecondaryButton,
+			CustomID: "settings:dm",
+			Disabled: settings.ArchiveChannelID == "",
+		},
+		discordgo.Button{
+			Label:    unreactLabel,
+			Style:    unreactStyle,
+			CustomID: "settings:unreact",
+		},
+	}})
+	return rows
+}
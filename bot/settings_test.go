@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSettingsPanel(t *testing.T) {
+	b, api := newTestBot(t)
+
+	userCommand(b, "user", "settings")
+	panel := api.responses[0].Data
+	if panel.Flags != discordgo.MessageFlagsEphemeral || !strings.Contains(panel.Content, "Delivered to: your DMs") {
+		t.Fatalf("panel = %+v", panel)
+	}
+	// No channel menu outside of servers: menus for delivery, verbosity and
+	// language, then the buttons.
+	if len(panel.Components) != 4 {
+		t.Fatalf("panel has %d rows, want 4", len(panel.Components))
+	}
+
+	clickComponent(b, "user", "panel", "settings:delivery", string(store.DeliveryWeekly))
+	clickComponent(b, "user", "panel", "settings:verbosity", string(store.VerbosityCompact))
+	clickComponent(b, "user", "panel", "settings:language", "fr")
+	clickComponent(b, "user", "panel", "settings:unreact")
+
+	want := store.UserSettings{Delivery: store.DeliveryWeekly, Verbosity: store.VerbosityCompact, Language: "fr", RemoveReaction: true}
+	got := b.store.Settings("user")
+	got.LastDigest = want.LastDigest
+	if got != want {
+		t.Errorf("settings = %+v, want %+v", got, want)
+	}
+
+	resp := api.responses[len(api.responses)-1]
+	if resp.Type != discordgo.InteractionResponseUpdateMessage {
+		t.Errorf("response type = %v, want the panel updated", resp.Type)
+	}
+	for _, line := range []string{"weekly digest", "Embeds: compact", "Language: Français", "reaction is removed"} {
+		if !strings.Contains(resp.Data.Content, line) {
+			t.Errorf("panel content %q lacks %q", resp.Data.Content, line)
+		}
+	}
+
+	clickComponent(b, "user", "panel", "settings:delivery", "instant")
+	clickComponent(b, "user", "panel", "settings:language", SETTINGS_LANGUAGE_OFF)
+	clickComponent(b, "user", "panel", "settings:verbosity", "full")
+	clickComponent(b, "user", "panel", "settings:unreact")
+	if got := b.store.Settings("user"); got != (store.UserSettings{}) {
+		t.Errorf("settings = %+v after resetting everything, want none", got)
+	}
+}
+
+func TestSettingsPanelBackToDMs(t *testing.T) {
+	b, _ := newTestBot(t)
+	b.store.SetSettings("user", store.UserSettings{ArchiveChannelID: "archive", ArchiveThreads: store.ThreadsGuild})
+
+	clickComponent(b, "user", "panel", "settings:dm")
+	if got := b.store.Settings("user"); got.ArchiveChannelID != "" || got.ArchiveThreads != store.ThreadsNone {
+		t.Errorf("settings = %+v, want DMs", got)
+	}
+}
+
+func TestSettingsPanelRejectsChannelOutsideServer(t *testing.T) {
+	b, api := newTestBot(t)
+
+	clickComponent(b, "user", "panel", "settings:target", "archive")
+	if got := b.store.Settings("user"); got.ArchiveChannelID != "" {
+		t.Errorf("archive channel = %q, want none", got.ArchiveChannelID)
+	}
+	if resp := api.responses[0]; resp.Data.Flags != discordgo.MessageFlagsEphemeral {
+		t.Errorf("response = %+v, want an ephemeral refusal", resp.Data)
+	}
+}
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/msglink"
+	"github.com/bwmarrin/discordgo"
+)
+
+// shareCommand posts a card of one of the user's bookmarks, to the given
+// channel or where the command was used. The quoted content is left out
+// when the card could reach people who can't read the source channel.
+func (b *Bot) shareCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+
+	bm, reply, ok := b.findBookmark(user.ID, opts["bookmark"].StringValue())
+	if !ok {
+		b.respondEphemeral(i, reply)
+		return
+	}
+
+	targetID := i.ChannelID
+	if opt, ok := opts["channel"]; ok {
+		targetID = opt.Value.(string)
+	}
+
+	card := embed.Card{
+		GuildName:   b.guildName(bm.GuildID),
+		ChannelID:   bm.ChannelID,
+		MessageLink: msglink.New(bm.GuildID, bm.ChannelID, bm.MessageID).String(),
+		Content:     bm.Content,
+		SharedBy:    user.Username,
+		Restricted:  b.isNSFWChannel(bm.ChannelID) || !b.everyoneCanRead(bm.GuildID, bm.ChannelID),
+	}
+	if msg, err := b.api.ChannelMessage(bm.ChannelID, bm.MessageID); err == nil {
+		author := embed.AuthorOf(msg)
+		card.Author = &author
+		card.Content = msg.Content
+		card.Timestamp = msg.Timestamp
+	}
+
+	// In DMs the card is only shown to the user, who can forward it.
+	if i.GuildID == "" {
+		b.respondEmbed(i, embed.BuildCard(card), 0)
+		return
+	}
+
+	if bm.GuildID != i.GuildID {
+		card.Restricted = true
+	}
+
+	if !b.memberHasPermissions(i.GuildID, i.Member, targetID, discordgo.PermissionViewChannel|discordgo.PermissionSendMessages) {
+		b.respondEphemeral(i, "You can't send messages in that channel.")
+		return
+	}
+
+	if targetID == i.ChannelID {
+		b.respondEmbed(i, embed.BuildCard(card), 0)
+		return
+	}
+
+	_, err := b.api.ChannelMessageSendComplex(targetID, &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{embed.BuildCard(card)},
+	})
+	if err != nil {
+		b.logger.Printf("Error sharing bookmark of user %s to channel %s: %v", user.ID, targetID, err)
+		b.respondEphemeral(i, "I couldn't post in that channel.")
+		return
+	}
+
+	b.logger.Printf("User %s shared bookmark of message %s to channel %s", user.ID, bm.MessageID, targetID)
+	b.respondEphemeral(i, fmt.Sprintf("Shared to <#%s>.", targetID))
+}
+
+// everyoneCanRead reports whether the @everyone role may read a channel's
+// history, i.e. whether any member of the guild can follow its links.
+func (b *Bot) everyoneCanRead(guildID, channelID string) bool {
+	channel, err := b.channel(channelID)
+	if err != nil {
+		b.logger.Printf("Error getting channel info for channel %s: %v", channelID, err)
+		return false
+	}
+	if channel.IsThread() {
+		if channel, err = b.channel(channel.ParentID); err != nil {
+			b.logger.Printf("Error getting channel info for channel %s: %v", channelID, err)
+			return false
+		}
+	}
+
+	guild, err := b.guild(guildID)
+	if err != nil {
+		b.logger.Printf("Error getting guild info for guild %s: %v", guildID, err)
+		return false
+	}
+
+	// The @everyone role shares the guild's ID.
+	var perms int64
+	for _, role := range guild.Roles {
+		if role.ID == guildID {
+			perms = role.Permissions
+		}
+	}
+	if perms&discordgo.PermissionAdministrator != 0 {
+		return true
+	}
+	for _, overwrite := range channel.PermissionOverwrites {
+		if overwrite.Type == discordgo.PermissionOverwriteTypeRole && overwrite.ID == guildID {
+			perms &^= overwrite.Deny
+			perms |= overwrite.Allow
+		}
+	}
+
+	required := int64(discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory)
+	return perms&required == required
+}
@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/msglink"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// findBookmark looks up one of the user's bookmarks by its short ID or the
+// link of its message. If there's none, reply tells the user why.
+func (b *Bot) findBookmark(userID, ref string) (bm store.Bookmark, reply string, ok bool) {
+	if link, err := msglink.Parse(ref); err == nil {
+		if bm, ok = b.store.Get(userID, link.ChannelID, link.MessageID); !ok {
+			return bm, "You haven't bookmarked that message.", false
+		}
+		return bm, "", true
+	}
+
+	shortID, valid := store.NormalizeShortID(ref)
+	if !valid {
+		return bm, "That isn't a bookmark ID like `bk-7F3K` or a message link.", false
+	}
+	if bm, ok = b.store.ByShortID(userID, shortID); !ok {
+		return bm, fmt.Sprintf("You have no bookmark `%s`.", shortID), false
+	}
+	return bm, "", true
+}
+
+// shortIDFooter prefixes the footer text of a bookmark DM with its short ID.
+func shortIDFooter(shortID, text string) *discordgo.MessageEmbedFooter {
+	switch {
+	case shortID == "":
+	case text == "":
+		text = shortID
+	default:
+		text = shortID + " · " + text
+	}
+	return &discordgo.MessageEmbedFooter{Text: text}
+}
+
+// addShortID shows the short ID in the footer of a bookmark embed. The footer
+// is replaced rather than changed, it may be shared with other embeds.
+func addShortID(e *discordgo.MessageEmbed, shortID string) {
+	text := ""
+	if e.Footer != nil {
+		text = e.Footer.Text
+	}
+	e.Footer = shortIDFooter(shortID, text)
+}
+
+// addFooterNotice appends notice to the footer of a bookmark embed, keeping
+// the short ID and whatever the embed template put there. Embeds without a
+// footer get one with the short ID.
+func addFooterNotice(e *discordgo.MessageEmbed, shortID, notice string) {
+	if e.Footer == nil || e.Footer.Text == "" {
+		e.Footer = shortIDFooter(shortID, notice)
+		return
+	}
+	if strings.Contains(e.Footer.Text, notice) {
+		return
+	}
+	footer := *e.Footer
+	footer.Text += " · " + notice
+	e.Footer = &footer
+}
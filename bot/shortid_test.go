@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+)
+
+func TestShortIDInFooterAndCommands(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+
+	bm := b.store.ForUser("user")[0]
+	if !strings.HasPrefix(bm.ShortID, "bk-") {
+		t.Fatalf("short ID = %q", bm.ShortID)
+	}
+	if footer := api.sent[0].Message.Embeds[0].Footer.Text; !strings.HasPrefix(footer, bm.ShortID+" · ") {
+		t.Errorf("footer = %q, want the short ID first", footer)
+	}
+
+	// Typed back in lower case, without the prefix.
+	userCommand(b, "user", "note", stringOption("bookmark", strings.ToLower(strings.TrimPrefix(bm.ShortID, "bk-"))), stringOption("note", "read later"))
+	if got, _ := b.store.ByShortID("user", bm.ShortID); got.Note != "read later" {
+		t.Errorf("note = %q, want it saved", got.Note)
+	}
+	dm := api.messages["dm-user/"+r.MessageID].Embeds[0]
+	if field := dm.Fields[len(dm.Fields)-1]; field.Name != embed.NOTE_FIELD_NAME || field.Value != "read later" {
+		t.Errorf("last DM field = %+v, want the note", field)
+	}
+
+	userCommand(b, "user", "delete", stringOption("bookmark", bm.ShortID))
+	if len(b.store.ForUser("user")) != 0 {
+		t.Error("bookmark not deleted")
+	}
+	if len(api.deleted) != 1 {
+		t.Errorf("deleted = %v, want the bookmark message", api.deleted)
+	}
+}
+
+func TestUnknownShortID(t *testing.T) {
+	b, api := newTestBot(t)
+
+	userCommand(b, "user", "delete", stringOption("bookmark", "bk-ZZZZ"))
+
+	if content := api.responses[0].Data.Content; !strings.Contains(content, "no bookmark `bk-ZZZZ`") {
+		t.Errorf("response = %q", content)
+	}
+}
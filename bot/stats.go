@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/msglink"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+const STATS_TOP_N = 5
+
+func (b *Bot) resolveOwner() {
+	if b.cfg().OwnerID != "" {
+		b.setOwner(b.cfg().OwnerID)
+		return
+	}
+
+	app, err := b.api.Application("@me")
+	if err != nil {
+		b.logger.Printf("Error getting application info: %v", err)
+		return
+	}
+	if app.Team != nil {
+		b.setOwner(app.Team.OwnerID)
+	} else if app.Owner != nil {
+		b.setOwner(app.Owner.ID)
+	}
+}
+
+func (b *Bot) setOwner(userID string) {
+	b.ownerID.Store(&userID)
+}
+
+// owner returns the bot owner's user ID, empty until resolved.
+func (b *Bot) owner() string {
+	if id := b.ownerID.Load(); id != nil {
+		return *id
+	}
+	return ""
+}
+
+func (b *Bot) isOwner(userID string) bool {
+	owner := b.owner()
+	return owner != "" && userID == owner
+}
+
+func (b *Bot) statsCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	user := interactionUser(i)
+
+	global := false
+	if opt, ok := opts["global"]; ok {
+		global = opt.BoolValue()
+	}
+
+	var bookmarks []store.Bookmark
+	title := "Your bookmarks"
+	if global {
+		if !b.isOwner(user.ID) {
+			b.respondEphemeral(i, "Only the bot owner can view global statistics.")
+			return
+		}
+		bookmarks = b.store.All()
+		title = "All bookmarks"
+	} else {
+		bookmarks = b.store.ForUser(user.ID)
+	}
+
+	b.respondEmbed(i, b.statsEmbed(title, bookmarks, global), discordgo.MessageFlagsEphemeral)
+}
+
+func (b *Bot) statsEmbed(title string, bookmarks []store.Bookmark, global bool) *discordgo.MessageEmbed {
+	stats := &discordgo.MessageEmbed{
+		Title: title,
+		Color: embed.DEFAULT_EMBED_COLOR,
+	}
+
+	if len(bookmarks) == 0 {
+		stats.Description = "No bookmarks yet. React with 🔖 to a message to bookmark it."
+		return stats
+	}
+
+	perGuild := map[string]int{}
+	perTag := map[string]int{}
+	perChannel := map[string]int{}
+	users := map[string]bool{}
+	oldest := bookmarks[0]
+	for _, b := range bookmarks {
+		perGuild[b.GuildID]++
+		perChannel[b.ChannelID]++
+		users[b.UserID] = true
+		for _, tag := range b.Tags {
+			perTag[tag]++
+		}
+		if b.CreatedAt.Before(oldest.CreatedAt) {
+			oldest = b
+		}
+	}
+
+	total := fmt.Sprintf("%d", len(bookmarks))
+	if global {
+		total += fmt.Sprintf(" from %d user(s)", len(users))
+	}
+
+	stats.Fields = []*discordgo.MessageEmbedField{
+		{Name: "Total", Value: total, Inline: true},
+		{Name: "Servers", Value: fmt.Sprintf("%d", len(perGuild)), Inline: true},
+		{Name: "Per server", Value: topCounts(perGuild, func(id string) string { return b.guildName(id) })},
+		{Name: "Top channels", Value: topCounts(perChannel, func(id string) string { return "<#" + id + ">" })},
+	}
+
+	if len(perTag) > 0 {
+		stats.Fields = append(stats.Fields, &discordgo.MessageEmbedField{
+			Name:  "Per tag",
+			Value: topCounts(perTag, func(tag string) string { return "`" + tag + "`" }),
+		})
+	}
+
+	stats.Fields = append(stats.Fields, &discordgo.MessageEmbedField{
+		Name: "Oldest bookmark",
+		Value: fmt.Sprintf("<t:%d:D> — [jump](%s)",
+			oldest.CreatedAt.Unix(), msglink.New(oldest.GuildID, oldest.ChannelID, oldest.MessageID)),
+	})
+
+	return stats
+}
+
+// topCounts formats the STATS_TOP_N largest counts, one per line.
+func topCounts(counts map[string]int, label func(key string) string) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if counts[keys[a]] != counts[keys[b]] {
+			return counts[keys[a]] > counts[keys[b]]
+		}
+		return keys[a] < keys[b]
+	})
+
+	var lines []string
+	for j, k := range keys {
+		if j == STATS_TOP_N {
+			lines = append(lines, fmt.Sprintf("…and %d more", len(keys)-STATS_TOP_N))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s — %d", label(k), counts[k]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (b *Bot) guildName(guildID string) string {
+	guild, err := b.guild(guildID)
+	if err != nil {
+		return guildID
+	}
+	return guild.Name
+}
@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/bwmarrin/discordgo"
+)
+
+// statsCollector counts what the bot did since it started, for
+// /bookmarker status when no metrics stack is running.
+type statsCollector struct {
+	started time.Time
+
+	mu       sync.Mutex
+	events   map[string]int
+	failures map[alertKind]int
+}
+
+func newStatsCollector(now time.Time) *statsCollector {
+	return &statsCollector{started: now, events: map[string]int{}, failures: map[alertKind]int{}}
+}
+
+// event counts a gateway event by its type, e.g. MESSAGE_REACTION_ADD.
+func (s *statsCollector) event(typ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[typ]++
+}
+
+func (s *statsCollector) failure(kind alertKind) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[kind]++
+}
+
+// counts returns copies of the event and failure counts.
+func (s *statsCollector) counts() (events map[string]int, failures map[alertKind]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events = make(map[string]int, len(s.events))
+	for typ, n := range s.events {
+		events[typ] = n
+	}
+	failures = make(map[alertKind]int, len(s.failures))
+	for kind, n := range s.failures {
+		failures[kind] = n
+	}
+	return events, failures
+}
+
+// statusCommand shows the owner how the bot is doing.
+func (b *Bot) statusCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	if !b.isOwner(interactionUser(i).ID) {
+		b.respondEphemeral(i, "Only the bot owner can view the bot status.")
+		return
+	}
+
+	b.respondEmbed(i, b.statusEmbed(time.Now()), discordgo.MessageFlagsEphemeral)
+}
+
+func (b *Bot) statusEmbed(now time.Time) *discordgo.MessageEmbed {
+	events, failures := b.stats.counts()
+
+	gateway := "🔴 Disconnected"
+	if b.ready.Load() {
+		gateway = "🟢 Connected"
+		if b.heartbeatLatency != nil {
+			gateway += fmt.Sprintf(" · %d ms", b.heartbeatLatency().Milliseconds())
+		}
+	}
+
+	total := 0
+	for _, n := range events {
+		total += n
+	}
+	eventSummary := fmt.Sprintf("%d in total", total)
+	if total > 0 {
+		eventSummary += "\n" + topCounts(events, func(typ string) string { return "`" + typ + "`" })
+	}
+
+	var failureLines []string
+	for kind, n := range failures {
+		failureLines = append(failureLines, fmt.Sprintf("%s — %d", alertTitles[kind], n))
+	}
+	sort.Strings(failureLines)
+	if len(failureLines) == 0 {
+		failureLines = []string{"None"}
+	}
+
+	pending := 0
+	for userID := range b.store.AllSettings() {
+		pending += len(b.store.Pending(userID))
+	}
+
+	b.state.RLock()
+	guilds := len(b.state.Guilds)
+	b.state.RUnlock()
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return &discordgo.MessageEmbed{
+		Title: "Bot status",
+		Color: embed.DEFAULT_EMBED_COLOR,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Uptime", Value: formatUptime(now.Sub(b.stats.started)), Inline: true},
+			{Name: "Gateway", Value: gateway, Inline: true},
+			{Name: "Servers", Value: fmt.Sprintf("%d", guilds), Inline: true},
+			{Name: "Events processed", Value: eventSummary},
+			{Name: "Errors", Value: strings.Join(failureLines, "\n")},
+			{Name: "Queued", Value: fmt.Sprintf("%d bookmark event(s) in progress\n%d bookmark(s) awaiting a digest\n%d background job(s)", b.bookmarkLocks.Len(), pending, len(b.jobs))},
+			{Name: "Stored bookmarks", Value: fmt.Sprintf("%d", b.store.Count()), Inline: true},
+			{Name: "Memory", Value: fmt.Sprintf("%d MiB", mem.Alloc>>20), Inline: true},
+			{Name: "Goroutines", Value: fmt.Sprintf("%d", runtime.NumGoroutine()), Inline: true},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: "Counts since " + b.stats.started.UTC().Format(time.RFC1123)},
+	}
+}
+
+// formatUptime formats d as days, hours and minutes.
+func formatUptime(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	}
+	return fmt.Sprintf("%dh %dm", hours, minutes)
+}
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestStatusEmbed(t *testing.T) {
+	b, _ := newTestBot(t)
+	b.stats = newStatsCollector(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	b.ready.Store(true)
+	b.heartbeatLatency = func() time.Duration { return 42 * time.Millisecond }
+	b.stats.event("MESSAGE_REACTION_ADD")
+	b.stats.event("MESSAGE_REACTION_ADD")
+	b.stats.event("MESSAGE_CREATE")
+	b.recordFailure(alertStore, "", nil)
+
+	status := b.statusEmbed(time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC))
+
+	fields := map[string]string{}
+	for _, field := range status.Fields {
+		fields[field.Name] = field.Value
+	}
+	if v := fields["Uptime"]; v != "1d 3h 4m" {
+		t.Errorf("uptime = %q", v)
+	}
+	if v := fields["Gateway"]; !strings.Contains(v, "42 ms") {
+		t.Errorf("gateway = %q, want the latency", v)
+	}
+	if v := fields["Events processed"]; !strings.HasPrefix(v, "3 in total\n`MESSAGE_REACTION_ADD` — 2") {
+		t.Errorf("events = %q", v)
+	}
+	if v := fields["Errors"]; v != alertTitles[alertStore]+" — 1" {
+		t.Errorf("errors = %q", v)
+	}
+}
+
+func TestStatusCommandIsOwnerOnly(t *testing.T) {
+	b, api := newTestBot(t)
+	b.setOwner("owner")
+
+	for _, userID := range []string{"owner", "user"} {
+		b.InteractionCreate(&discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+			Type:    discordgo.InteractionApplicationCommand,
+			GuildID: "guild",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: userID}},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name:    "bookmarker",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "status"}},
+			},
+		}})
+	}
+
+	if len(api.responses) != 2 {
+		t.Fatalf("got %d responses, want 2", len(api.responses))
+	}
+	if len(api.responses[0].Data.Embeds) != 1 {
+		t.Error("the owner didn't get the status embed")
+	}
+	if len(api.responses[1].Data.Embeds) != 0 {
+		t.Error("another user got the status embed")
+	}
+}
@@ -0,0 +1,225 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// MessageUpdate syncs an edit of a message to its bookmarks. Bookmarks are
+// found by the message they were made from; reposts merged into a bookmark
+// aren't tracked, see markSourceDeleted.
+func (b *Bot) MessageUpdate(m *discordgo.MessageUpdate) {
+	// Updates without an edit timestamp are embed unfurls, not content edits.
+	if m.GuildID == "" || m.EditedTimestamp == nil {
+		return
+	}
+
+	bookmarks := b.store.BySource(m.ChannelID, m.ID)
+	if len(bookmarks) == 0 {
+		return
+	}
+
+	b.logger.Printf("Syncing edit of message %s in channel %s to %d bookmark(s)", m.ID, m.ChannelID, len(bookmarks))
+
+	msg, err := b.api.ChannelMessage(m.ChannelID, m.ID)
+	if err != nil {
+		b.logger.Printf("Error getting edited message %s from channel %s: %v", m.ID, m.ChannelID, err)
+		return
+	}
+	// Don't blank bookmarks with what the bot can't read.
+	if contentUnavailable(msg) {
+		b.logger.Printf("Not syncing edit of message %s, its content is unavailable", m.ID)
+		return
+	}
+
+	guild, err := b.guild(m.GuildID)
+	if err != nil {
+		b.logger.Printf("Error getting guild info for guild %s: %v", m.GuildID, err)
+		return
+	}
+
+	link, origin := b.messageOrigin(guild, msg)
+	spoiler := b.spoilerMedia(m.ChannelID)
+	rebuilt := b.buildEmbed(msg, origin, link.String(), spoiler)
+	if hasAudio(msg) {
+		// Keep the stored transcript rather than transcribing again.
+		voice := audioDetails{transcript: bookmarks[0].Transcript}
+		if b.transcriber == nil || voice.transcript == "" {
+			voice = b.describeAudio(msg)
+		}
+		applyAudio(rebuilt, msg, voice, spoiler)
+	}
+	embed.AddLinkPreview(rebuilt, b.linkPreview(msg, spoiler))
+	rebuilt.Fields = append(rebuilt.Fields, &discordgo.MessageEmbedField{
+		Name:   "Edited",
+		Value:  fmt.Sprintf("<t:%d:R>", m.EditedTimestamp.Unix()),
+		Inline: false,
+	})
+
+	compact := b.buildCompactEmbed(msg, origin, link.String())
+
+	// Translate once per language, before taking any bookmark's lock.
+	translations := map[string]string{}
+	languages := make([]string, len(bookmarks))
+	for j, bm := range bookmarks {
+		languages[j] = b.store.Settings(bm.UserID).Language
+		if _, ok := translations[languages[j]]; !ok {
+			translations[languages[j]] = b.translateMessage(msg, languages[j])
+		}
+	}
+
+	for j, bm := range bookmarks {
+		b.syncEdit(bm, msg, rebuilt, compact, translations[languages[j]], spoiler, m.EditedTimestamp)
+	}
+}
+
+// syncEdit applies an edit of the source message to one bookmark, using
+// the compact embed for bookmarks that weren't expanded, with the edited
+// message translated into the user's language.
+func (b *Bot) syncEdit(bm store.Bookmark, msg *discordgo.Message, rebuilt, compact *discordgo.MessageEmbed, translation string, spoiler bool, editedAt *time.Time) {
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(bm.UserID, bm.ChannelID, bm.MessageID))
+	defer unlock()
+
+	// Re-read the bookmark, a DM action may have changed or removed it meanwhile.
+	bm, ok := b.store.Get(bm.UserID, bm.ChannelID, bm.MessageID)
+	if !ok {
+		return
+	}
+	bm.Translation = translation
+
+	if bm.HasDM() {
+		base := rebuilt
+		if bm.Compact {
+			base = compact
+		}
+		// The embed is shared by every bookmark of the message; notes are per user.
+		annotated := *base
+		annotated.Fields = append([]*discordgo.MessageEmbedField(nil), base.Fields...)
+		if !bm.Compact {
+			embed.AddTranslation(&annotated, bm.Translation, spoiler)
+		}
+		embed.AddNote(&annotated, bm.Note, bm.Tags)
+		embed.SetReminder(&annotated, bm.RemindAt)
+		embed.SetSeenIn(&annotated, sourceLinks(bm))
+		addShortID(&annotated, bm.ShortID)
+		// Edits can't replace the attached full text, so point at the source.
+		embed.Fit(&annotated, embed.TRUNCATED_LINK_NOTICE)
+
+		_, err := withRetry(b, func() (*discordgo.Message, error) {
+			return b.api.ChannelMessageEditEmbed(bm.DMChannelID, bm.DMMessageID, b.archivedEmbed(bm, &annotated))
+		})
+		if err != nil {
+			b.logger.Printf("Error updating bookmark message for user %s (channel: %s, message: %s): %v", bm.UserID, bm.DMChannelID, bm.DMMessageID, err)
+			return
+		}
+	}
+
+	prev := bm
+	bm.Content = msg.Content
+	bm.EditedAt = editedAt
+	bm.AddVersion(prev, store.VersionEdited, *editedAt)
+	err := b.store.Update(bm)
+	if err != nil {
+		b.logger.Printf("Error updating stored bookmark for user %s: %v", bm.UserID, err)
+		b.recordFailure(alertStore, "", err)
+	}
+}
+
+// archivedEmbed keeps the re-uploaded image of an archived bookmark when its
+// embed is rebuilt, since the original attachment URL may no longer resolve.
+func (b *Bot) archivedEmbed(bm store.Bookmark, embed *discordgo.MessageEmbed) *discordgo.MessageEmbed {
+	if !bm.Archived {
+		return embed
+	}
+
+	dmMsg, err := b.api.ChannelMessage(bm.DMChannelID, bm.DMMessageID)
+	if err != nil || len(dmMsg.Embeds) == 0 {
+		return embed
+	}
+
+	rebuilt := *embed
+	rebuilt.Image = dmMsg.Embeds[0].Image
+	return &rebuilt
+}
+
+func (b *Bot) MessageDelete(m *discordgo.MessageDelete) {
+	if m.GuildID == "" {
+		return
+	}
+
+	b.markSourceDeleted(m.ChannelID, m.ID)
+}
+
+func (b *Bot) MessageDeleteBulk(m *discordgo.MessageDeleteBulk) {
+	if m.GuildID == "" {
+		return
+	}
+
+	for _, messageID := range m.Messages {
+		b.markSourceDeleted(m.ChannelID, messageID)
+	}
+}
+
+// markSourceDeleted flags every bookmark of a deleted message and updates the
+// DM footers, keeping the saved content while warning that the link is dead.
+//
+// Only the message a bookmark was made from is tracked, not the reposts
+// merged into it: looking those up would scan every bookmark on each edit or
+// deletion in every guild, and a bookmark whose original is still there
+// isn't dead, nor should a repost's edit replace the captured content.
+func (b *Bot) markSourceDeleted(channelID, messageID string) {
+	bookmarks := b.store.BySource(channelID, messageID)
+	if len(bookmarks) == 0 {
+		return
+	}
+
+	b.logger.Printf("Marking %d bookmark(s) of deleted message %s in channel %s", len(bookmarks), messageID, channelID)
+
+	for _, bm := range bookmarks {
+		b.markBookmarkSourceDeleted(bm)
+	}
+}
+
+func (b *Bot) markBookmarkSourceDeleted(bm store.Bookmark) {
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(bm.UserID, bm.ChannelID, bm.MessageID))
+	defer unlock()
+
+	bm, ok := b.store.Get(bm.UserID, bm.ChannelID, bm.MessageID)
+	if !ok || bm.SourceDeleted {
+		return
+	}
+
+	if bm.HasDM() {
+		b.markDMSourceDeleted(bm)
+	}
+
+	bm.SourceDeleted = true
+	err := b.store.Update(bm)
+	if err != nil {
+		b.logger.Printf("Error updating stored bookmark for user %s: %v", bm.UserID, err)
+		b.recordFailure(alertStore, "", err)
+	}
+}
+
+// markDMSourceDeleted updates the footer of a bookmark DM whose source was deleted.
+func (b *Bot) markDMSourceDeleted(bm store.Bookmark) {
+	dmMsg, err := b.api.ChannelMessage(bm.DMChannelID, bm.DMMessageID)
+	if err != nil {
+		b.logger.Printf("Error getting bookmark message for user %s (channel: %s, message: %s): %v", bm.UserID, bm.DMChannelID, bm.DMMessageID, err)
+		return
+	}
+
+	if len(dmMsg.Embeds) > 0 {
+		embed := dmMsg.Embeds[0]
+		addFooterNotice(embed, bm.ShortID, "⚠️ Original message deleted · React with ❌ to remove this bookmark")
+
+		_, err = b.api.ChannelMessageEditEmbed(bm.DMChannelID, bm.DMMessageID, embed)
+		if err != nil {
+			b.logger.Printf("Error updating bookmark message for user %s (channel: %s, message: %s): %v", bm.UserID, bm.DMChannelID, bm.DMMessageID, err)
+		}
+	}
+}
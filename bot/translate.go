@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/translate"
+	"github.com/bwmarrin/discordgo"
+)
+
+// translateMessage translates the text of msg into the language with code
+// target. It returns "" without a translator or target, if the message is
+// already in that language, or if translating fails.
+func (b *Bot) translateMessage(msg *discordgo.Message, target string) string {
+	text := strings.TrimSpace(msg.Content)
+	if b.translator == nil || target == "" || text == "" {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), translate.TRANSLATE_TIMEOUT)
+	defer cancel()
+	translation, err := b.translator.Translate(ctx, text, target)
+	if err != nil {
+		b.logger.Printf("Error translating message %s to %s: %v", msg.ID, target, err)
+		return ""
+	}
+	if translate.SameLanguage(translation.SourceLanguage, target) || strings.TrimSpace(translation.Text) == text {
+		return ""
+	}
+	return translation.Text
+}
+
+// translateLater translates a new bookmark into the language with code
+// target in the background, then adds the translation to it and its message,
+// so a slow translation service holds neither the bookmark's lock nor the
+// reaction's claim.
+func (b *Bot) translateLater(userID string, msg *discordgo.Message, target string, spoiler bool) {
+	if b.translator == nil || target == "" || strings.TrimSpace(msg.Content) == "" {
+		return
+	}
+
+	b.translations.Add(1)
+	go func() {
+		defer b.translations.Done()
+		if translation := b.translateMessage(msg, target); translation != "" {
+			b.addTranslation(userID, msg.ChannelID, msg.ID, translation, spoiler)
+		}
+	}()
+}
+
+// addTranslation stores the translation of a bookmark and adds it to the
+// bookmark message, unless the bookmark was deleted meanwhile.
+func (b *Bot) addTranslation(userID, channelID, messageID, translation string, spoiler bool) {
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(userID, channelID, messageID))
+	defer unlock()
+
+	bm, ok := b.store.Get(userID, channelID, messageID)
+	if !ok {
+		return
+	}
+	bm.Translation = translation
+	if err := b.store.Update(bm); err != nil {
+		b.logger.Printf("Error storing translation of bookmark %s for user %s: %v", messageID, userID, err)
+		b.recordFailure(alertStore, "", err)
+		return
+	}
+	// Compact bookmarks show the translation once expanded.
+	if !bm.HasDM() || bm.Compact {
+		return
+	}
+
+	dmMsg, err := b.api.ChannelMessage(bm.DMChannelID, bm.DMMessageID)
+	if err != nil || len(dmMsg.Embeds) == 0 {
+		b.logger.Printf("Error getting bookmark message %s of user %s to add its translation: %v", bm.DMMessageID, userID, err)
+		return
+	}
+	translated := *dmMsg.Embeds[0]
+	translated.Fields = append([]*discordgo.MessageEmbedField(nil), translated.Fields...)
+	embed.AddTranslation(&translated, translation, spoiler)
+	embed.Fit(&translated, embed.TRUNCATED_LINK_NOTICE)
+	_, err = withRetry(b, func() (*discordgo.Message, error) {
+		return b.api.ChannelMessageEditEmbed(bm.DMChannelID, bm.DMMessageID, &translated)
+	})
+	if err != nil {
+		b.logger.Printf("Error adding translation to bookmark message %s of user %s: %v", bm.DMMessageID, userID, err)
+	}
+}
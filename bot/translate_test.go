@@ -0,0 +1,151 @@
+package bot
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/anonmiraj/discord-bookmarker/translate"
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeTranslator "translates" text written in language by prefixing it.
+type fakeTranslator struct {
+	language string
+	// calls counts the translations, if set.
+	calls *atomic.Int32
+	// wait blocks translations until closed, if set.
+	wait chan struct{}
+}
+
+func (f fakeTranslator) Translate(ctx context.Context, text, target string) (translate.Translation, error) {
+	if f.calls != nil {
+		f.calls.Add(1)
+	}
+	if f.wait != nil {
+		<-f.wait
+	}
+	if translate.SameLanguage(f.language, target) {
+		return translate.Translation{Text: text, SourceLanguage: f.language}, nil
+	}
+	return translate.Translation{Text: "translated: " + text, SourceLanguage: f.language}, nil
+}
+
+// translationField waits for the translation of the user's bookmark and
+// returns the translation field of its message.
+func translationField(t *testing.T, b *Bot, api *fakeAPI) string {
+	t.Helper()
+	b.translations.Wait()
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want the bookmark", len(api.sent))
+	}
+	bm := b.store.ForUser("user")[0]
+	msg, err := api.ChannelMessage(bm.DMChannelID, bm.DMMessageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range msg.Embeds[0].Fields {
+		if field.Name == embed.TRANSLATION_FIELD_NAME {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+func TestBookmarkIsTranslatedIntoUserLanguage(t *testing.T) {
+	b, api := newTestBot(t)
+	b.translator = fakeTranslator{language: "de"}
+	b.store.SetSettings("user", store.UserSettings{Language: "en"})
+	src := sourceMessage()
+	src.Content = "Hallo Welt"
+	api.addMessage(src)
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	if field := translationField(t, b, api); field != "translated: Hallo Welt" {
+		t.Errorf("translation field = %q", field)
+	}
+	if bm := b.store.ForUser("user")[0]; bm.Translation != "translated: Hallo Welt" {
+		t.Errorf("stored translation = %q", bm.Translation)
+	}
+}
+
+func TestBookmarkInUserLanguageIsNotTranslated(t *testing.T) {
+	b, api := newTestBot(t)
+	b.translator = fakeTranslator{language: "en"}
+	b.store.SetSettings("user", store.UserSettings{Language: "en"})
+	api.addMessage(sourceMessage())
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	if field := translationField(t, b, api); field != "" {
+		t.Errorf("translation field = %q, want none", field)
+	}
+}
+
+func TestBookmarkWithoutLanguageIsNotTranslated(t *testing.T) {
+	b, api := newTestBot(t)
+	b.translator = fakeTranslator{language: "de"}
+	api.addMessage(sourceMessage())
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	if field := translationField(t, b, api); field != "" {
+		t.Errorf("translation field = %q, want none", field)
+	}
+}
+
+func TestTranslationDoesNotHoldTheBookmarkLock(t *testing.T) {
+	b, api := newTestBot(t)
+	wait := make(chan struct{})
+	b.translator = fakeTranslator{language: "de", wait: wait}
+	b.store.SetSettings("user", store.UserSettings{Language: "en"})
+	api.addMessage(sourceMessage())
+
+	done := make(chan struct{})
+	go func() {
+		b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		close(wait)
+		t.Fatal("delivery waited for the translation")
+	}
+	unlock := b.bookmarkLocks.Lock(bookmarkKey("user", "channel", "message"))
+	unlock()
+
+	close(wait)
+	if field := translationField(t, b, api); field != "translated: hello world" {
+		t.Errorf("translation field = %q", field)
+	}
+}
+
+func TestEditIsTranslatedOncePerLanguage(t *testing.T) {
+	b, api := newTestBot(t)
+	api.addMessage(sourceMessage())
+	for _, userID := range []string{"user", "other", "third"} {
+		b.store.SetSettings(userID, store.UserSettings{Language: "en"})
+		r := bookmarkReaction(BOOKMARK_EMOJI)
+		r.UserID = userID
+		b.ReactionAdd(r)
+	}
+
+	var calls atomic.Int32
+	b.translator = fakeTranslator{language: "de", calls: &calls}
+	edited := sourceMessage()
+	edited.Content = "Hallo Welt"
+	api.addMessage(edited)
+	editedAt := time.Now()
+	b.MessageUpdate(&discordgo.MessageUpdate{Message: &discordgo.Message{ID: "message", ChannelID: "channel", GuildID: "guild", EditedTimestamp: &editedAt}})
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("translated %d times, want once for the shared language", n)
+	}
+	for _, bm := range b.store.All() {
+		if bm.Translation != "translated: Hallo Welt" {
+			t.Errorf("translation of %s = %q", bm.UserID, bm.Translation)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// UNDO_WINDOW is how long a deleted bookmark can be restored.
+	UNDO_WINDOW = 10 * time.Minute
+	// TRASH_PURGE_INTERVAL is how often expired deleted bookmarks are dropped.
+	TRASH_PURGE_INTERVAL = time.Minute
+)
+
+// offerUndo DMs the user an Undo button for a bookmark they just deleted.
+func (b *Bot) offerUndo(userID string, bm store.Bookmark, deletedAt time.Time) {
+	dmChannel, err := b.dmChannel(userID)
+	if err != nil {
+		b.logger.Printf("Error creating DM channel with user %s: %v", userID, err)
+		return
+	}
+
+	_, err = b.send(dmChannel.ID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("Bookmark deleted. You can undo this until <t:%d:t>.", deletedAt.Add(UNDO_WINDOW).Unix()),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Undo",
+					Style:    discordgo.SecondaryButton,
+					CustomID: "undo:" + bm.ChannelID + ":" + bm.MessageID,
+				},
+			}},
+		},
+	})
+	if err != nil {
+		b.logger.Printf("Error offering undo of deleted bookmark %s to user %s: %v", bm.MessageID, userID, err)
+	}
+}
+
+// undoComponent restores a deleted bookmark from the trash and delivers it again.
+func (b *Bot) undoComponent(i *discordgo.InteractionCreate, args string) {
+	user := interactionUser(i)
+	channelID, messageID, _ := strings.Cut(args, ":")
+
+	unlock := b.bookmarkLocks.Lock(bookmarkKey(user.ID, channelID, messageID))
+	defer unlock()
+
+	if b.store.Has(user.ID, channelID, messageID) {
+		b.updateComponentMessage(i, "You already bookmarked this message again.")
+		return
+	}
+
+	bm, ok, err := b.store.Untrash(user.ID, channelID, messageID)
+	if err != nil {
+		b.logger.Printf("Error restoring deleted bookmark %s of user %s: %v", messageID, user.ID, err)
+		b.recordFailure(alertStore, "", err)
+		b.updateComponentMessage(i, "Something went wrong, please try again later.")
+		return
+	}
+	if !ok {
+		b.updateComponentMessage(i, "Too late, the bookmark was deleted for good.")
+		return
+	}
+
+	trashed := bm
+	reply := "Bookmark restored."
+	sent, err := b.redeliver(user, bm)
+	if err != nil {
+		b.reportDeliveryError(user, err)
+		// Keep the record even without a message, it still shows in /bookmarks list.
+		reply = "Bookmark restored, but its message couldn't be sent. It still shows in `/bookmarks list`."
+	} else {
+		bm.DMChannelID, bm.DMMessageID = sent.ChannelID, sent.ID
+		bm.Compact = false
+	}
+	if err := b.store.Add(bm); err != nil {
+		b.logger.Printf("Error storing restored bookmark for user %s: %v", user.ID, err)
+		b.recordFailure(alertStore, "", err)
+		// Without a record the new DM's actions can't work; keep the bookmark
+		// restorable instead, with a fresh undo window.
+		if sent != nil {
+			if err := b.api.ChannelMessageDelete(sent.ChannelID, sent.ID); err != nil {
+				b.logger.Printf("Error deleting unstored bookmark DM for user %s: %v", user.ID, err)
+			}
+		}
+		if err := b.store.Retrash(trashed, time.Now()); err != nil {
+			b.logger.Printf("Error putting deleted bookmark %s of user %s back in the trash: %v", messageID, user.ID, err)
+		}
+		b.updateComponentMessage(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	b.auditBookmark(store.AuditRestore, user.ID, bm)
+	b.logger.Printf("User %s restored deleted bookmark of message %s", user.ID, messageID)
+	b.updateComponentMessage(i, reply)
+}
+
+// redeliver sends a restored bookmark again, in full.
+func (b *Bot) redeliver(user *discordgo.User, bm store.Bookmark) (*discordgo.Message, error) {
+	bookmarkEmbed, guild := b.rebuildEmbed(bm)
+	sent, err := b.sendBookmark(user, guild, bm.Tags, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{bookmarkEmbed},
+		Components: bookmarkComponents(false),
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.addActionReactions(sent.ChannelID, sent.ID)
+	return sent, nil
+}
+
+// rebuildEmbed renders the full embed of a bookmark from its source message
+// or, if that's gone, from the stored content.
+func (b *Bot) rebuildEmbed(bm store.Bookmark) (*discordgo.MessageEmbed, *discordgo.Guild) {
+	msg, guild := b.bookmarkSource(bm)
+	spoiler := b.spoilerMedia(bm.ChannelID)
+	link, origin := b.messageOrigin(guild, msg)
+	bookmarkEmbed := b.buildEmbed(msg, origin, link.String(), spoiler)
+	embed.AddTranscript(bookmarkEmbed, bm.Transcript, spoiler)
+	embed.AddTranslation(bookmarkEmbed, bm.Translation, spoiler)
+	embed.AddNote(bookmarkEmbed, bm.Note, bm.Tags)
+	embed.SetReminder(bookmarkEmbed, bm.RemindAt)
+	addShortID(bookmarkEmbed, bm.ShortID)
+	embed.Fit(bookmarkEmbed, embed.TRUNCATED_LINK_NOTICE)
+	return bookmarkEmbed, guild
+}
+
+// bookmarkSource fetches the source message of a bookmark and its server,
+// falling back to the stored content if the message is gone.
+func (b *Bot) bookmarkSource(bm store.Bookmark) (*discordgo.Message, *discordgo.Guild) {
+	guild, err := b.guild(bm.GuildID)
+	if err != nil {
+		guild = &discordgo.Guild{ID: bm.GuildID, Name: "an unknown server"}
+	}
+
+	msg, err := b.api.ChannelMessage(bm.ChannelID, bm.MessageID)
+	if err != nil {
+		sentAt, _ := discordgo.SnowflakeTimestamp(bm.MessageID)
+		msg = &discordgo.Message{
+			ID:        bm.MessageID,
+			ChannelID: bm.ChannelID,
+			Content:   bm.Content,
+			Author:    &discordgo.User{Username: embed.UNKNOWN_AUTHOR},
+			Timestamp: sentAt,
+		}
+	}
+	return msg, guild
+}
+
+// startTrashPurge starts dropping expired deleted bookmarks once; later
+// Ready events are no-ops.
+func (b *Bot) startTrashPurge() {
+	b.trashOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(TRASH_PURGE_INTERVAL)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-b.done:
+					return
+				case now := <-ticker.C:
+					b.purgeTrash(now)
+				}
+			}
+		}()
+	})
+}
+
+func (b *Bot) purgeTrash(now time.Time) {
+	purged, err := b.store.PurgeTrash(now.Add(-UNDO_WINDOW))
+	if err != nil {
+		b.logger.Printf("Error purging deleted bookmarks: %v", err)
+		b.recordFailure(alertStore, "", err)
+		return
+	}
+	if purged > 0 {
+		b.logger.Printf("Purged %d deleted bookmark(s) past the undo window", purged)
+	}
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+func undoButtonID(t *testing.T, api *fakeAPI) string {
+	t.Helper()
+
+	undo := api.sent[len(api.sent)-1].Message
+	if len(undo.Components) == 0 {
+		t.Fatalf("last message %q has no undo button", undo.Content)
+	}
+	return undo.Components[0].(discordgo.ActionsRow).Components[0].(discordgo.Button).CustomID
+}
+
+func TestUndoRestoresDeletedBookmark(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+	b.setBookmarkState(r.ChannelID, r.MessageID, "user", func(bm *store.Bookmark) { bm.Pinned = true })
+
+	r.Emoji.Name = DELETE_EMOJI
+	b.DMReactionAdd(r)
+	if len(b.store.ForUser("user")) != 0 {
+		t.Fatal("bookmark still listed after deleting it")
+	}
+
+	clickComponent(b, "user", "undo-message", undoButtonID(t, api))
+
+	bookmarks := b.store.ForUser("user")
+	if len(bookmarks) != 1 {
+		t.Fatalf("%d bookmarks after undo, want 1", len(bookmarks))
+	}
+	bm := bookmarks[0]
+	if bm.DMMessageID == r.MessageID || !bm.Pinned {
+		t.Errorf("restored bookmark = %+v, want a new message and its state kept", bm)
+	}
+	resent := api.sent[len(api.sent)-1].Message
+	if len(resent.Embeds) != 1 || resent.Embeds[0].Description != "hello world" {
+		t.Errorf("restored bookmark message = %+v", resent)
+	}
+}
+
+func TestUndoExpires(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+
+	r.Emoji.Name = DELETE_EMOJI
+	b.DMReactionAdd(r)
+	b.purgeTrash(time.Now().Add(UNDO_WINDOW + time.Second))
+
+	clickComponent(b, "user", "undo-message", undoButtonID(t, api))
+
+	if len(b.store.ForUser("user")) != 0 {
+		t.Error("bookmark restored after the undo window")
+	}
+	if got := api.responses[len(api.responses)-1].Data.Content; got != "Too late, the bookmark was deleted for good." {
+		t.Errorf("reply = %q", got)
+	}
+}
+
+func TestUndoKeepsBookmarkRestorableWhenStoringFails(t *testing.T) {
+	b, api := newTestBot(t)
+	r := deliverTestBookmark(t, b, api)
+	r.Emoji.Name = DELETE_EMOJI
+	b.DMReactionAdd(r)
+	undoID := undoButtonID(t, api)
+
+	working := b.store
+	b.store = failingAddStore{working}
+	deleted := len(api.deleted)
+	clickComponent(b, "user", "undo-message", undoID)
+	if got := api.responses[len(api.responses)-1].Data.Content; got != "Something went wrong, please try again later." {
+		t.Errorf("reply = %q, want a failure", got)
+	}
+	if len(api.deleted) != deleted+1 {
+		t.Errorf("deleted %d DMs, want the unstored one", len(api.deleted)-deleted)
+	}
+
+	b.store = working
+	clickComponent(b, "user", "undo-message", undoID)
+	if len(b.store.ForUser("user")) != 1 {
+		t.Error("bookmark not restorable after the failed undo")
+	}
+}
@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/anonmiraj/discord-bookmarker/unfurl"
+	"github.com/bwmarrin/discordgo"
+)
+
+// linkPreview returns the preview of the page msg links to when the message
+// is nothing but a link. Discord's own preview of it is used when the
+// message already has one; otherwise the page is fetched. Previews are off
+// in spoilered channels, where the page's image would show unblurred.
+func (b *Bot) linkPreview(msg *discordgo.Message, spoiler bool) unfurl.Preview {
+	if !b.cfg().UnfurlLinks || spoiler {
+		return unfurl.Preview{}
+	}
+	link, ok := unfurl.LinkOnly(msg.Content)
+	if !ok {
+		return unfurl.Preview{}
+	}
+
+	for _, e := range msg.Embeds {
+		if p := embedPreview(e); !p.IsZero() {
+			return p
+		}
+	}
+
+	// The client refuses private addresses, since links come from users.
+	p, err := unfurl.Fetch(context.Background(), b.integrationClient, link)
+	if err != nil {
+		b.logger.Printf("Error fetching link preview of message %s: %v", msg.ID, err)
+		return unfurl.Preview{}
+	}
+	return p
+}
+
+// embedPreview converts Discord's preview of a link.
+func embedPreview(e *discordgo.MessageEmbed) unfurl.Preview {
+	p := unfurl.Preview{URL: e.URL, Title: e.Title, Description: e.Description}
+	if e.Provider != nil {
+		p.SiteName = e.Provider.Name
+	}
+	if e.Image != nil {
+		p.Image = e.Image.URL
+	} else if e.Thumbnail != nil {
+		p.Image = e.Thumbnail.URL
+	}
+	return p
+}
@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestLinkOnlyBookmarkUsesDiscordPreview(t *testing.T) {
+	b, api := newTestBot(t)
+	b.config.UnfurlLinks = true
+	msg := sourceMessage()
+	msg.Content = "https://example.com/article"
+	msg.Embeds = []*discordgo.MessageEmbed{{
+		Type:        discordgo.EmbedTypeArticle,
+		URL:         "https://example.com/article",
+		Title:       "An article",
+		Description: "What it is about",
+		Provider:    &discordgo.MessageEmbedProvider{Name: "Example"},
+		Thumbnail:   &discordgo.MessageEmbedThumbnail{URL: "https://example.com/cover.png"},
+	}}
+	api.addMessage(msg)
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(api.sent))
+	}
+	e := api.sent[0].Message.Embeds[0]
+	var preview string
+	for _, field := range e.Fields {
+		if field.Name == "🔗 Example" {
+			preview = field.Value
+		}
+	}
+	if preview != "**[An article](https://example.com/article)**\nWhat it is about" {
+		t.Errorf("preview field = %q", preview)
+	}
+	if e.Image == nil || e.Image.URL != "https://example.com/cover.png" {
+		t.Errorf("image = %+v", e.Image)
+	}
+}
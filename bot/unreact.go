@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// unreactAfterDelivery removes the user's bookmark reaction from the source
+// message once it's bookmarked, if they or the guild's admins want that. A
+// nil emoji removes every trigger reaction of the user. Removing another
+// user's reaction needs Manage Messages; without it the reaction stays.
+func (b *Bot) unreactAfterDelivery(guildID, userID, channelID, messageID string, emoji *discordgo.Emoji) {
+	if !b.store.GuildSettings(guildID).RemovesReaction(b.store.Settings(userID).RemoveReaction) {
+		return
+	}
+
+	if emoji == nil {
+		b.removeTriggerReactions(channelID, messageID, userID)
+		return
+	}
+
+	err := withRetryErr(b, func() error {
+		return b.api.MessageReactionRemove(channelID, messageID, emoji.APIName(), userID)
+	})
+	if classifyAPIError(err) == apiErrorForbidden {
+		b.logger.Printf("Can't remove bookmark reaction of user %s in channel %s without Manage Messages", userID, channelID)
+		return
+	}
+	if err != nil {
+		b.logger.Printf("Error removing bookmark reaction from original message (channel: %s, message: %s, user: %s): %v", channelID, messageID, userID, err)
+	}
+}
+
+// guildReactionsCommand lets server admins decide whether bookmark reactions
+// are removed after delivery for everyone, no one, or as each member chose.
+func (b *Bot) guildReactionsCommand(i *discordgo.InteractionCreate, opts optionMap) {
+	if i.GuildID == "" {
+		b.respondEphemeral(i, "Use this command in your server.")
+		return
+	}
+
+	removal := store.ReactionRemoval(opts["removal"].StringValue())
+	if removal == "members" {
+		removal = store.ReactionRemovalMembers
+	}
+
+	settings := b.store.GuildSettings(i.GuildID)
+	settings.ReactionRemoval = removal
+	err := b.store.SetGuildSettings(i.GuildID, settings)
+	if err != nil {
+		b.logger.Printf("Error saving settings of guild %s: %v", i.GuildID, err)
+		b.recordFailure(alertStore, "", err)
+		b.respondEphemeral(i, "Something went wrong, please try again later.")
+		return
+	}
+
+	b.audit(store.AuditEntry{Action: store.AuditGuild, ActorID: interactionUser(i).ID, GuildID: i.GuildID, Detail: fmt.Sprintf("reaction removal: %q", removal)})
+	b.logger.Printf("User %s set reaction removal of guild %s to %q", interactionUser(i).ID, i.GuildID, removal)
+	switch removal {
+	case store.ReactionRemovalAlways:
+		b.respondEphemeral(i, "🔖 reactions will be removed from this server's messages once they are bookmarked. I need the **Manage Messages** permission for that.")
+	case store.ReactionRemovalNever:
+		b.respondEphemeral(i, "🔖 reactions will stay on this server's messages, whatever members chose in their settings.")
+	default:
+		b.respondEphemeral(i, "Members choose in `/bookmarks settings` whether their 🔖 reactions are removed once bookmarked.")
+	}
+}
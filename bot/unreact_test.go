@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+)
+
+func TestUnreactAfterDelivery(t *testing.T) {
+	tests := []struct {
+		name        string
+		memberWants bool
+		removal     store.ReactionRemoval
+		removed     bool
+	}{
+		{"kept by default", false, store.ReactionRemovalMembers, false},
+		{"member's choice", true, store.ReactionRemovalMembers, true},
+		{"guild always removes", false, store.ReactionRemovalAlways, true},
+		{"guild never removes", true, store.ReactionRemovalNever, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, api := newTestBot(t)
+			b.store.SetSettings("user", store.UserSettings{RemoveReaction: tt.memberWants})
+			b.store.SetGuildSettings("guild", store.GuildSettings{ReactionRemoval: tt.removal})
+			api.addMessage(sourceMessage())
+
+			b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+			if len(api.sent) != 1 {
+				t.Fatalf("sent %d messages, want the bookmark", len(api.sent))
+			}
+			want := 0
+			if tt.removed {
+				want = 1
+			}
+			if len(api.reactionsRemoved) != want {
+				t.Fatalf("removed reactions %v, want %d", api.reactionsRemoved, want)
+			}
+			if tt.removed && api.reactionsRemoved[0] != "channel/message/"+BOOKMARK_EMOJI+"/user" {
+				t.Errorf("removed reaction %q", api.reactionsRemoved[0])
+			}
+		})
+	}
+}
+
+func TestUnreactSkipsRefusedBookmarks(t *testing.T) {
+	b, api := newTestBot(t)
+	b.store.SetSettings("user", store.UserSettings{RemoveReaction: true})
+	b.store.SetGuildSettings("guild", store.GuildSettings{BookmarksDisabled: true})
+	api.addMessage(sourceMessage())
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+	if len(api.reactionsRemoved) != 0 {
+		t.Errorf("removed reactions %v of a refused bookmark", api.reactionsRemoved)
+	}
+}
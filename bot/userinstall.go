@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"encoding/json"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Installation types and contexts of application commands, which discordgo
+// doesn't model yet.
+const (
+	INTEGRATION_GUILD_INSTALL = 0
+	INTEGRATION_USER_INSTALL  = 1
+
+	CONTEXT_GUILD           = 0
+	CONTEXT_BOT_DM          = 1
+	CONTEXT_PRIVATE_CHANNEL = 2
+)
+
+// USER_INSTALL_GUILD_NAME names servers the bot isn't in, whose details it
+// can't look up, in bookmarks made through a user install.
+const USER_INSTALL_GUILD_NAME = "a server"
+
+// installableCommand is an application command with where it can be
+// installed and used.
+type installableCommand struct {
+	*discordgo.ApplicationCommand
+	IntegrationTypes []int `json:"integration_types"`
+	Contexts         []int `json:"contexts"`
+}
+
+// installable makes the bookmark commands usable by users who installed the
+// bot on their account, in servers it wasn't invited to. Server settings
+// stay with server installs, as user installs can't manage a server.
+func installable(cmd *discordgo.ApplicationCommand) installableCommand {
+	switch {
+	case cmd.Name == "bookmarker":
+		return installableCommand{cmd, []int{INTEGRATION_GUILD_INSTALL}, []int{CONTEXT_GUILD}}
+	case cmd.Type == discordgo.MessageApplicationCommand:
+		return installableCommand{cmd, []int{INTEGRATION_GUILD_INSTALL, INTEGRATION_USER_INSTALL}, []int{CONTEXT_GUILD}}
+	default:
+		return installableCommand{cmd, []int{INTEGRATION_GUILD_INSTALL, INTEGRATION_USER_INSTALL}, []int{CONTEXT_GUILD, CONTEXT_BOT_DM, CONTEXT_PRIVATE_CHANNEL}}
+	}
+}
+
+// interactionInstalls is the part of an interaction payload telling which
+// installations of the bot authorized it, keyed by integration type, which
+// discordgo doesn't decode yet.
+type interactionInstalls struct {
+	AuthorizingIntegrationOwners map[int]string `json:"authorizing_integration_owners"`
+}
+
+// authorizedByUserOnly reports whether an interaction payload was authorized
+// by a user install alone, not by the bot's install in the server.
+func authorizedByUserOnly(raw json.RawMessage) bool {
+	var installs interactionInstalls
+	if err := json.Unmarshal(raw, &installs); err != nil {
+		return false
+	}
+	_, guild := installs.AuthorizingIntegrationOwners[INTEGRATION_GUILD_INSTALL]
+	_, user := installs.AuthorizingIntegrationOwners[INTEGRATION_USER_INSTALL]
+	return user && !guild
+}
+
+// InteractionEvent handles an interaction from its raw gateway event, noting
+// first which installation authorized it.
+func (b *Bot) InteractionEvent(e *discordgo.Event) {
+	i, ok := e.Struct.(*discordgo.InteractionCreate)
+	if !ok {
+		return
+	}
+	b.userInstalls.Add(i.ID, authorizedByUserOnly(e.RawData))
+	b.InteractionCreate(i)
+}
+
+// userInstalled reports whether an interaction in a guild comes through a
+// user install, the bot not being a member. This is told by the interaction,
+// not the state, which misses guilds while they are unavailable. Interactions
+// handled without their gateway event count as server installs.
+func (b *Bot) userInstalled(i *discordgo.InteractionCreate) bool {
+	userInstall, _ := b.userInstalls.Get(i.ID)
+	return userInstall && i.GuildID != ""
+}
+
+// interactionMessage returns the message a bookmark interaction targets.
+// Where the bot isn't a member it can't fetch messages, so it uses the one
+// Discord resolved for the message command instead.
+func (b *Bot) interactionMessage(i *discordgo.InteractionCreate, channelID, messageID string) (*discordgo.Message, error) {
+	if b.userInstalled(i) {
+		if msg, ok := b.resolvedMessages.Get(channelID + "/" + messageID); ok {
+			return msg, nil
+		}
+	}
+	return withRetry(b, func() (*discordgo.Message, error) {
+		return b.api.ChannelMessage(channelID, messageID)
+	})
+}
+
+// interactionGuild returns the guild of a bookmark interaction, or a
+// placeholder where the bot isn't a member.
+func (b *Bot) interactionGuild(i *discordgo.InteractionCreate) (*discordgo.Guild, error) {
+	if b.userInstalled(i) {
+		return &discordgo.Guild{ID: i.GuildID, Name: USER_INSTALL_GUILD_NAME}, nil
+	}
+	return b.guild(i.GuildID)
+}
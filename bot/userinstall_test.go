@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestRegisterCommandsAllowsUserInstalls(t *testing.T) {
+	api := newFakeAPI()
+	if _, err := RegisterCommands(api, "app"); err != nil {
+		t.Fatal(err)
+	}
+
+	var registered []struct {
+		Name             string `json:"name"`
+		IntegrationTypes []int  `json:"integration_types"`
+		Contexts         []int  `json:"contexts"`
+	}
+	body := api.requests[http.MethodPut+" "+discordgo.EndpointApplicationGlobalCommands("app")]
+	if err := json.Unmarshal(body, &registered); err != nil {
+		t.Fatalf("decoding registered commands: %v", err)
+	}
+	for _, cmd := range registered {
+		userInstallable := slices.Contains(cmd.IntegrationTypes, INTEGRATION_USER_INSTALL)
+		if userInstallable == (cmd.Name == "bookmarker") {
+			t.Errorf("command %q user-installable = %v", cmd.Name, userInstallable)
+		}
+		if !slices.Contains(cmd.Contexts, CONTEXT_GUILD) {
+			t.Errorf("command %q can't be used in servers", cmd.Name)
+		}
+	}
+}
+
+func interactionEvent(i *discordgo.InteractionCreate, raw string) *discordgo.Event {
+	return &discordgo.Event{Type: "INTERACTION_CREATE", RawData: json.RawMessage(raw), Struct: i}
+}
+
+func TestBookmarkCommandWhereBotIsOnlyUserInstalled(t *testing.T) {
+	b, api := newTestBot(t)
+	msg := sourceMessage()
+	msg.ChannelID = "foreign-channel"
+	i := bookmarkMessageCommand(msg)
+	i.ID = "interaction"
+	i.GuildID = "foreign-guild"
+
+	// The bot can't fetch the message, only use the one Discord resolved.
+	b.InteractionEvent(interactionEvent(i, `{"authorizing_integration_owners":{"1":"user"}}`))
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want the bookmark", len(api.sent))
+	}
+	if title := api.sent[0].Message.Embeds[0].Title; !strings.Contains(title, USER_INSTALL_GUILD_NAME) {
+		t.Errorf("title = %q, want the placeholder server name", title)
+	}
+	bookmarks := b.store.ForUser("user")
+	if len(bookmarks) != 1 || bookmarks[0].GuildID != "foreign-guild" || bookmarks[0].Content != msg.Content || !bookmarks[0].UserInstall {
+		t.Errorf("stored %+v", bookmarks)
+	}
+}
+
+func TestBookmarkCommandAuthorizedByServerInstall(t *testing.T) {
+	b, api := newTestBot(t)
+	msg := sourceMessage()
+	api.addMessage(msg)
+	i := bookmarkMessageCommand(msg)
+	i.ID = "interaction"
+
+	// Users who installed the bot too still bookmark through the server install.
+	b.InteractionEvent(interactionEvent(i, `{"authorizing_integration_owners":{"0":"guild","1":"user"}}`))
+	bookmarks := b.store.ForUser("user")
+	if len(bookmarks) != 1 || bookmarks[0].UserInstall {
+		t.Errorf("stored %+v, want a server bookmark", bookmarks)
+	}
+}
@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/audio"
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/bwmarrin/discordgo"
+)
+
+// AUDIO_MAX_BYTES caps audio downloads, matching common transcription limits.
+const AUDIO_MAX_BYTES = 25 << 20
+
+// audioDetails holds what was learned from a message's audio attachments.
+type audioDetails struct {
+	// durations maps attachment IDs to their playing time.
+	durations  map[string]time.Duration
+	transcript string
+}
+
+// describeAudio downloads the audio attachments of msg to read their duration
+// and, if a transcriber is configured, transcribe them.
+func (b *Bot) describeAudio(msg *discordgo.Message) audioDetails {
+	details := audioDetails{durations: map[string]time.Duration{}}
+
+	var transcripts []string
+	for _, a := range msg.Attachments {
+		if !embed.IsAudioAttachment(a) {
+			continue
+		}
+
+		data, err := downloadAttachment(a.URL, AUDIO_MAX_BYTES)
+		if err != nil {
+			b.logger.Printf("Error downloading audio attachment %s of message %s: %v", a.Filename, msg.ID, err)
+			continue
+		}
+
+		if d, ok := audio.Duration(data); ok {
+			details.durations[a.ID] = d
+		}
+
+		if b.transcriber == nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), audio.TRANSCRIBE_TIMEOUT)
+		text, err := b.transcriber.Transcribe(ctx, a.Filename, data)
+		cancel()
+		if err != nil {
+			b.logger.Printf("Error transcribing audio attachment %s of message %s: %v", a.Filename, msg.ID, err)
+			continue
+		}
+		transcripts = append(transcripts, strings.TrimSpace(text))
+	}
+
+	details.transcript = strings.Join(transcripts, "\n\n")
+	return details
+}
+
+// hasAudio reports whether any attachment of msg is audio.
+func hasAudio(msg *discordgo.Message) bool {
+	for _, a := range msg.Attachments {
+		if embed.IsAudioAttachment(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyAudio adds durations and the transcript to a bookmark embed.
+func applyAudio(e *discordgo.MessageEmbed, msg *discordgo.Message, details audioDetails, spoiler bool) {
+	for _, a := range msg.Attachments {
+		if d, ok := details.durations[a.ID]; ok {
+			embed.AddAudioDuration(e, a, d)
+		}
+	}
+	embed.AddTranscript(e, details.transcript, spoiler)
+}
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type fakeTranscriber struct {
+	calls []string
+}
+
+func (f *fakeTranscriber) Transcribe(ctx context.Context, filename string, data []byte) (string, error) {
+	f.calls = append(f.calls, filename)
+	return " remember the milk ", nil
+}
+
+// silentWAV returns a mono 8 kHz 16-bit WAV file of the given length in seconds.
+func silentWAV(seconds int) []byte {
+	var data bytes.Buffer
+	data.WriteString("RIFF\x00\x00\x00\x00WAVE")
+	data.WriteString("fmt ")
+	binary.Write(&data, binary.LittleEndian, []uint32{16})
+	binary.Write(&data, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(&data, binary.LittleEndian, []uint32{8000, 16000})
+	binary.Write(&data, binary.LittleEndian, []uint16{2, 16})
+	data.WriteString("data")
+	binary.Write(&data, binary.LittleEndian, []uint32{uint32(16000 * seconds)})
+	data.Write(make([]byte, 16000*seconds))
+	return data.Bytes()
+}
+
+func TestReactionAddTranscribesVoiceMessage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(silentWAV(65))
+	}))
+	defer srv.Close()
+
+	b, api := newTestBot(t)
+	transcriber := &fakeTranscriber{}
+	b.transcriber = transcriber
+
+	msg := sourceMessage()
+	msg.Content = ""
+	msg.Flags = discordgo.MessageFlagsIsVoiceMessage
+	msg.Attachments = []*discordgo.MessageAttachment{
+		{ID: "voice", Filename: "voice-message.wav", ContentType: "audio/wav", URL: srv.URL + "/voice-message.wav"},
+	}
+	api.addMessage(msg)
+
+	b.ReactionAdd(bookmarkReaction(BOOKMARK_EMOJI))
+
+	if len(api.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(api.sent))
+	}
+	fields := map[string]string{}
+	for _, field := range api.sent[0].Message.Embeds[0].Fields {
+		fields[field.Name] = field.Value
+	}
+	if v := fields["🎤 Voice message"]; !strings.HasSuffix(v, " · 1:05") {
+		t.Errorf("voice message field = %q, want the duration", v)
+	}
+	if v := fields["Transcript"]; v != "remember the milk" {
+		t.Errorf("transcript field = %q", v)
+	}
+	if len(transcriber.calls) != 1 {
+		t.Errorf("transcribed %d times, want 1", len(transcriber.calls))
+	}
+
+	bm, ok := b.store.Get("user", "channel", "message")
+	if !ok || bm.Transcript != "remember the milk" {
+		t.Errorf("stored bookmark = %+v, want the transcript", bm)
+	}
+}
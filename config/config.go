@@ -0,0 +1,363 @@
+// Package config loads the bot settings from the environment and flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/anonmiraj/discord-bookmarker/translate"
+)
+
+// NSFW policies for bookmarks from age-restricted channels.
+const (
+	NSFW_BLOCK   = "block"
+	NSFW_SPOILER = "spoiler"
+	NSFW_ALLOW   = "allow"
+)
+
+// Retention policies for bookmarks of guilds the bot left or the user was
+// banned from.
+const (
+	RETENTION_KEEP  = "keep"
+	RETENTION_STRIP = "strip"
+	RETENTION_PURGE = "purge"
+)
+
+// Storage backends; the SQL ones match the store package's dialects.
+const (
+	STORE_JSON     = "json"
+	STORE_SQLITE   = "sqlite"
+	STORE_POSTGRES = "postgres"
+)
+
+// Config holds the runtime settings. Every setting can be provided through
+// the environment (or a .env file) so the bot runs without a config file.
+type Config struct {
+	Token      string
+	OwnerID    string
+	LogStdout  bool
+	LogFile    string
+	StoreFile  string
+	HealthAddr string
+	// LogDir is the directory of LogFile when that is a relative path.
+	LogDir string
+	// PIDFile is written with the process ID while the bot runs (optional).
+	PIDFile string
+	// StoreDriver is one of STORE_JSON, STORE_SQLITE or STORE_POSTGRES. The
+	// JSON store uses StoreFile, the SQL stores connect to StoreDSN.
+	StoreDriver string
+	StoreDSN    string
+	// EmbedTemplate is the path of a JSON embed template file (optional).
+	EmbedTemplate string
+	// ArchiveAttachments re-uploads attachments to the DM, up to ArchiveMaxBytes per bookmark.
+	ArchiveAttachments bool
+	ArchiveMaxBytes    int64
+	// CatchUpChannels are rescanned for missed 🔖 reactions after a reconnect.
+	CatchUpChannels []string
+	CatchUpLimit    int
+	// NSFWPolicy is one of NSFW_BLOCK, NSFW_SPOILER or NSFW_ALLOW.
+	NSFWPolicy string
+	// GuildColors overrides the embed color of bookmarks per source guild.
+	GuildColors map[string]int
+	// TranscribeURL is an OpenAI-compatible transcription endpoint used to
+	// transcribe voice messages; transcription is disabled if empty.
+	TranscribeURL    string
+	TranscribeAPIKey string
+	TranscribeModel  string
+	// TranslateProvider is translate.PROVIDER_LIBRETRANSLATE or
+	// translate.PROVIDER_DEEPL; translation is disabled if empty.
+	// TranslateURL is the provider's translate endpoint, optional for DeepL.
+	TranslateProvider string
+	TranslateURL      string
+	TranslateAPIKey   string
+	// GuildIconColors derives the embed color of other guilds from their icon.
+	GuildIconColors bool
+	// AlertChannel receives operational alerts instead of the owner's DMs.
+	AlertChannel string
+	// BookmarkEmojis are the reactions that bookmark a message: unicode
+	// emojis, custom emoji names, IDs or mentions. Empty means 🔖 only.
+	BookmarkEmojis []string
+	// GuildRetention is one of RETENTION_KEEP, RETENTION_STRIP or RETENTION_PURGE.
+	GuildRetention string
+	// UnfurlLinks adds a preview of the linked page to bookmarks of
+	// link-only messages.
+	UnfurlLinks bool
+	// DMCleanup periodically removes the bookmarks whose message was deleted
+	// outside the bot; DMCleanupReactions also removes the user's bookmark
+	// reactions from the source message.
+	DMCleanup          bool
+	DMCleanupReactions bool
+	// Tracing exports OpenTelemetry spans of the bookmark pipeline over
+	// OTLP; it needs a binary built with -tags otel.
+	Tracing bool
+	// PresenceCount shows the number of stored bookmarks in the bot's status.
+	PresenceCount bool
+	// EncryptionKey encrypts bookmark content and notes at rest, see
+	// store.ParseEncryptionKey; encryption is disabled if empty.
+	// EncryptionOldKeys still decrypt what rotated-out keys encrypted.
+	EncryptionKey     string
+	EncryptionOldKeys []string
+}
+
+// Load reads the settings from the environment and then from the flags in
+// args, which take precedence, and validates them. The arguments left after
+// the flags are returned.
+func Load(args []string) (Config, []string, error) {
+	var env envParser
+	cfg := Config{
+		Token:      os.Getenv("DISCORD_TOKEN"),
+		OwnerID:    os.Getenv("OWNER_ID"),
+		LogStdout:  env.bool("LOG_STDOUT", false),
+		LogFile:    envString("LOG_FILE", "bookmark-bot.log"),
+		LogDir:     os.Getenv("LOG_DIR"),
+		StoreFile:  envString("STORE_FILE", "bookmarks.json"),
+		HealthAddr: os.Getenv("HEALTH_ADDR"),
+		PIDFile:    os.Getenv("PID_FILE"),
+
+		StoreDriver: envString("STORE_DRIVER", STORE_JSON),
+		StoreDSN:    os.Getenv("STORE_DSN"),
+
+		EmbedTemplate: os.Getenv("EMBED_TEMPLATE"),
+
+		ArchiveAttachments: env.bool("ARCHIVE_ATTACHMENTS", false),
+		ArchiveMaxBytes:    env.int64("ARCHIVE_MAX_BYTES", 8<<20),
+
+		CatchUpChannels: envList("CATCHUP_CHANNELS"),
+		CatchUpLimit:    int(env.int64("CATCHUP_LIMIT", 50)),
+
+		NSFWPolicy: envString("NSFW_POLICY", NSFW_SPOILER),
+
+		GuildIconColors: env.bool("GUILD_ICON_COLORS", true),
+
+		TranscribeURL:    os.Getenv("TRANSCRIBE_URL"),
+		TranscribeAPIKey: os.Getenv("TRANSCRIBE_API_KEY"),
+		TranscribeModel:  envString("TRANSCRIBE_MODEL", "whisper-1"),
+
+		TranslateProvider: os.Getenv("TRANSLATE_PROVIDER"),
+		TranslateURL:      os.Getenv("TRANSLATE_URL"),
+		TranslateAPIKey:   os.Getenv("TRANSLATE_API_KEY"),
+
+		AlertChannel: os.Getenv("ALERT_CHANNEL"),
+
+		BookmarkEmojis: envList("BOOKMARK_EMOJIS"),
+
+		GuildRetention: envString("GUILD_RETENTION", RETENTION_KEEP),
+
+		UnfurlLinks: env.bool("UNFURL_LINKS", true),
+
+		DMCleanup:          env.bool("DM_CLEANUP", false),
+		DMCleanupReactions: env.bool("DM_CLEANUP_REACTIONS", false),
+
+		EncryptionKey:     os.Getenv("ENCRYPTION_KEY"),
+		EncryptionOldKeys: envList("ENCRYPTION_OLD_KEYS"),
+
+		PresenceCount: env.bool("PRESENCE_COUNT", false),
+
+		Tracing: env.bool("TRACING", false),
+	}
+
+	if env.err != nil {
+		return cfg, nil, env.err
+	}
+
+	guildColors, err := parseGuildColors(os.Getenv("GUILD_COLORS"))
+	if err != nil {
+		return cfg, nil, fmt.Errorf("GUILD_COLORS: %w", err)
+	}
+	cfg.GuildColors = guildColors
+
+	fs := flag.NewFlagSet("discord-bookmarker", flag.ContinueOnError)
+	fs.BoolVar(&cfg.LogStdout, "stdout", cfg.LogStdout, "log to stdout instead of the log file")
+	fs.StringVar(&cfg.LogFile, "log-file", cfg.LogFile, "path of the log file")
+	fs.StringVar(&cfg.LogDir, "log-dir", cfg.LogDir, "directory of the log file, created if missing")
+	fs.StringVar(&cfg.PIDFile, "pid-file", cfg.PIDFile, "file to write the process ID to while running")
+	fs.StringVar(&cfg.StoreFile, "store", cfg.StoreFile, "path of the bookmark store file")
+	fs.StringVar(&cfg.StoreDriver, "store-driver", cfg.StoreDriver, "storage backend: json, or sqlite or postgres in binaries built with their tag")
+	fs.StringVar(&cfg.HealthAddr, "health-addr", cfg.HealthAddr, "address to serve /readyz on, e.g. :8080 (disabled if empty)")
+	fs.StringVar(&cfg.EmbedTemplate, "embed-template", cfg.EmbedTemplate, "path of a JSON embed template file")
+	fs.BoolVar(&cfg.ArchiveAttachments, "archive-attachments", cfg.ArchiveAttachments, "re-upload attachments to bookmark DMs")
+	fs.Int64Var(&cfg.ArchiveMaxBytes, "archive-max-bytes", cfg.ArchiveMaxBytes, "maximum total attachment size archived per bookmark")
+	fs.IntVar(&cfg.CatchUpLimit, "catchup-limit", cfg.CatchUpLimit, "recent messages rescanned per catch-up channel (max 100)")
+	fs.StringVar(&cfg.NSFWPolicy, "nsfw-policy", cfg.NSFWPolicy, "handling of age-restricted channels: block, spoiler or allow")
+	fs.BoolVar(&cfg.GuildIconColors, "guild-icon-colors", cfg.GuildIconColors, "color bookmark embeds after the source guild's icon")
+	fs.StringVar(&cfg.AlertChannel, "alert-channel", cfg.AlertChannel, "channel ID for operational alerts (default: DM the owner)")
+	fs.StringVar(&cfg.GuildRetention, "guild-retention", cfg.GuildRetention, "bookmarks of guilds the bot left: keep, strip or purge")
+	fs.BoolVar(&cfg.UnfurlLinks, "unfurl-links", cfg.UnfurlLinks, "preview the page of link-only bookmarked messages")
+	fs.BoolVar(&cfg.DMCleanup, "dm-cleanup", cfg.DMCleanup, "remove bookmarks whose message was deleted, checked daily")
+	fs.BoolVar(&cfg.DMCleanupReactions, "dm-cleanup-reactions", cfg.DMCleanupReactions, "also remove the user's bookmark reactions from the source message")
+	fs.BoolVar(&cfg.PresenceCount, "presence-count", cfg.PresenceCount, "show the number of bookmarks in the bot's status")
+	fs.BoolVar(&cfg.Tracing, "tracing", cfg.Tracing, "export OpenTelemetry traces over OTLP (needs -tags otel)")
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+
+	return cfg, fs.Args(), cfg.Validate()
+}
+
+// Validate reports the first invalid setting.
+func (cfg Config) Validate() error {
+	switch cfg.NSFWPolicy {
+	case NSFW_BLOCK, NSFW_SPOILER, NSFW_ALLOW:
+	default:
+		return fmt.Errorf("invalid NSFW policy %q, expected %s, %s or %s", cfg.NSFWPolicy, NSFW_BLOCK, NSFW_SPOILER, NSFW_ALLOW)
+	}
+
+	switch cfg.GuildRetention {
+	case RETENTION_KEEP, RETENTION_STRIP, RETENTION_PURGE:
+	default:
+		return fmt.Errorf("invalid guild retention policy %q, expected %s, %s or %s", cfg.GuildRetention, RETENTION_KEEP, RETENTION_STRIP, RETENTION_PURGE)
+	}
+
+	switch cfg.StoreDriver {
+	case STORE_JSON:
+	case STORE_SQLITE, STORE_POSTGRES:
+		if cfg.StoreDSN == "" {
+			return fmt.Errorf("STORE_DSN is required for the %s store", cfg.StoreDriver)
+		}
+		if !store.SQLAvailable(cfg.StoreDriver) {
+			return fmt.Errorf("the %s store isn't compiled in, build the binary with -tags %s", cfg.StoreDriver, cfg.StoreDriver)
+		}
+	default:
+		return fmt.Errorf("invalid store driver %q, expected %s, %s or %s", cfg.StoreDriver, STORE_JSON, STORE_SQLITE, STORE_POSTGRES)
+	}
+
+	switch cfg.TranslateProvider {
+	case "":
+	case translate.PROVIDER_LIBRETRANSLATE:
+		if cfg.TranslateURL == "" {
+			return fmt.Errorf("TRANSLATE_URL is required for %s", cfg.TranslateProvider)
+		}
+	case translate.PROVIDER_DEEPL:
+		if cfg.TranslateAPIKey == "" {
+			return fmt.Errorf("TRANSLATE_API_KEY is required for %s", cfg.TranslateProvider)
+		}
+	default:
+		return fmt.Errorf("invalid translation provider %q, expected %s or %s", cfg.TranslateProvider, translate.PROVIDER_LIBRETRANSLATE, translate.PROVIDER_DEEPL)
+	}
+
+	if _, err := cfg.EncryptionKeys(); err != nil {
+		return err
+	}
+
+	if cfg.ArchiveMaxBytes < 1 {
+		return fmt.Errorf("invalid archive size cap %d, expected a positive number of bytes", cfg.ArchiveMaxBytes)
+	}
+
+	if cfg.CatchUpLimit < 1 || cfg.CatchUpLimit > 100 {
+		return fmt.Errorf("invalid catch-up limit %d, expected 1 to 100", cfg.CatchUpLimit)
+	}
+
+	return nil
+}
+
+// LogPath is the path of the log file, in LogDir unless LogFile is absolute.
+func (cfg Config) LogPath() string {
+	if cfg.LogDir == "" || filepath.IsAbs(cfg.LogFile) {
+		return cfg.LogFile
+	}
+	return filepath.Join(cfg.LogDir, cfg.LogFile)
+}
+
+// EncryptionKeys decodes EncryptionKey followed by EncryptionOldKeys, or
+// returns none if encryption is disabled.
+func (cfg Config) EncryptionKeys() ([][]byte, error) {
+	if cfg.EncryptionKey == "" {
+		if len(cfg.EncryptionOldKeys) > 0 {
+			return nil, errors.New("ENCRYPTION_OLD_KEYS needs ENCRYPTION_KEY")
+		}
+		return nil, nil
+	}
+
+	var keys [][]byte
+	for _, s := range append([]string{cfg.EncryptionKey}, cfg.EncryptionOldKeys...) {
+		key, err := store.ParseEncryptionKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ENCRYPTION_KEY or ENCRYPTION_OLD_KEYS: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func envString(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+// envParser parses environment variables, keeping the first malformed one
+// in err; unset and empty variables take their fallback.
+type envParser struct {
+	err error
+}
+
+func (p *envParser) bool(key string, fallback bool) bool {
+	s := os.Getenv(key)
+	if s == "" {
+		return fallback
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		p.fail(fmt.Errorf("%s: expected true or false, got %q", key, s))
+		return fallback
+	}
+	return v
+}
+
+func (p *envParser) int64(key string, fallback int64) int64 {
+	s := os.Getenv(key)
+	if s == "" {
+		return fallback
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		p.fail(fmt.Errorf("%s: expected a whole number, got %q", key, s))
+		return fallback
+	}
+	return v
+}
+
+func (p *envParser) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// envList parses a comma-separated list, ignoring empty entries.
+func envList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// parseGuildColors parses a comma-separated list of guildID=#rrggbb pairs.
+func parseGuildColors(s string) (map[string]int, error) {
+	colors := map[string]int{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		guildID, hex, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected guildID=#rrggbb, got %q", pair)
+		}
+		color, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(hex), "#"), 16, 24)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color %q for guild %s", hex, guildID)
+		}
+		colors[strings.TrimSpace(guildID)] = int(color)
+	}
+	return colors, nil
+}
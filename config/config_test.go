@@ -0,0 +1,287 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/anonmiraj/discord-bookmarker/translate"
+)
+
+// validConfig has the defaults Load sets.
+func validConfig() Config {
+	return Config{
+		NSFWPolicy:      NSFW_SPOILER,
+		GuildRetention:  RETENTION_KEEP,
+		StoreDriver:     STORE_JSON,
+		ArchiveMaxBytes: 8 << 20,
+		CatchUpLimit:    50,
+	}
+}
+
+func testEncryptionKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func TestValidate(t *testing.T) {
+	// SQL stores are only valid in binaries built with their tag.
+	postgresErr := "isn't compiled in"
+	if store.SQLAvailable(STORE_POSTGRES) {
+		postgresErr = ""
+	}
+	tests := []struct {
+		name   string
+		change func(cfg *Config)
+		// wantErr is part of the error, empty if the config is valid.
+		wantErr string
+	}{
+		{"defaults", func(cfg *Config) {}, ""},
+		{"NSFW policy", func(cfg *Config) { cfg.NSFWPolicy = "maybe" }, "invalid NSFW policy"},
+		{"guild retention", func(cfg *Config) { cfg.GuildRetention = "forever" }, "invalid guild retention policy"},
+		{"store driver", func(cfg *Config) { cfg.StoreDriver = "mysql" }, "invalid store driver"},
+		{"SQL store without DSN", func(cfg *Config) { cfg.StoreDriver = STORE_SQLITE }, "STORE_DSN is required"},
+		{"SQL store with DSN", func(cfg *Config) { cfg.StoreDriver, cfg.StoreDSN = STORE_POSTGRES, "postgres://localhost/bookmarks" }, postgresErr},
+		{"translation provider", func(cfg *Config) { cfg.TranslateProvider = "babelfish" }, "invalid translation provider"},
+		{"LibreTranslate without URL", func(cfg *Config) { cfg.TranslateProvider = translate.PROVIDER_LIBRETRANSLATE }, "TRANSLATE_URL is required"},
+		{"DeepL without API key", func(cfg *Config) { cfg.TranslateProvider = translate.PROVIDER_DEEPL }, "TRANSLATE_API_KEY is required"},
+		{"DeepL with API key", func(cfg *Config) { cfg.TranslateProvider, cfg.TranslateAPIKey = translate.PROVIDER_DEEPL, "key" }, ""},
+		{"encryption key", func(cfg *Config) { cfg.EncryptionKey = testEncryptionKey('a') }, ""},
+		{"short encryption key", func(cfg *Config) { cfg.EncryptionKey = "c2hvcnQ=" }, "invalid ENCRYPTION_KEY"},
+		{"old keys without key", func(cfg *Config) { cfg.EncryptionOldKeys = []string{testEncryptionKey('a')} }, "ENCRYPTION_OLD_KEYS needs ENCRYPTION_KEY"},
+		{"catch-up limit too low", func(cfg *Config) { cfg.CatchUpLimit = 0 }, "invalid catch-up limit"},
+		{"catch-up limit too high", func(cfg *Config) { cfg.CatchUpLimit = 101 }, "invalid catch-up limit"},
+		{"archive size cap", func(cfg *Config) { cfg.ArchiveMaxBytes = 0 }, "invalid archive size cap"},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		tt.change(&cfg)
+		err := cfg.Validate()
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: Validate = %v, want no error", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: Validate = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadRejectsMalformedEnv(t *testing.T) {
+	tests := []struct {
+		key, value string
+		// wantErr is part of the error, empty if the value is valid.
+		wantErr string
+	}{
+		{"DM_CLEANUP", "true", ""},
+		{"DM_CLEANUP", "", ""},
+		{"DM_CLEANUP", "yes", "DM_CLEANUP: expected true or false"},
+		{"ARCHIVE_MAX_BYTES", "1048576", ""},
+		{"ARCHIVE_MAX_BYTES", "10MB", "ARCHIVE_MAX_BYTES: expected a whole number"},
+		{"ARCHIVE_MAX_BYTES", "-1", "invalid archive size cap"},
+		{"CATCHUP_LIMIT", "lots", "CATCHUP_LIMIT: expected a whole number"},
+	}
+	for _, tt := range tests {
+		t.Setenv(tt.key, tt.value)
+		_, _, err := Load(nil)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s=%q: Load = %v, want no error", tt.key, tt.value, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s=%q: Load = %v, want %q", tt.key, tt.value, err, tt.wantErr)
+		}
+		os.Unsetenv(tt.key)
+	}
+}
+
+func TestParseGuildColors(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    map[string]int
+		wantErr bool
+	}{
+		{"empty", "", map[string]int{}, false},
+		{"one guild", "1=#ff0000", map[string]int{"1": 0xff0000}, false},
+		{"without hash", "1=00ff00", map[string]int{"1": 0x00ff00}, false},
+		{"spaces and empty entries", " 1 = #0000ff , ,2=#123456,", map[string]int{"1": 0x0000ff, "2": 0x123456}, false},
+		{"missing color", "1", nil, true},
+		{"invalid color", "1=#zzzzzz", nil, true},
+		{"color too large", "1=#1000000", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseGuildColors(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: parseGuildColors(%q) error = %v, want error %t", tt.name, tt.s, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseGuildColors(%q) = %v, want %v", tt.name, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := validConfig()
+	tests := []struct {
+		name   string
+		change func(cfg *Config)
+		want   []Change
+	}{
+		{"unchanged", func(cfg *Config) {}, nil},
+		{"live setting", func(cfg *Config) { cfg.NSFWPolicy = NSFW_BLOCK }, []Change{{Field: "NSFWPolicy", Old: NSFW_SPOILER, New: NSFW_BLOCK}}},
+		{"restart setting", func(cfg *Config) { cfg.StoreFile = "other.json" }, []Change{{Field: "StoreFile", Restart: true, Old: "", New: "other.json"}}},
+		{"list", func(cfg *Config) { cfg.BookmarkEmojis = []string{"⭐"} }, []Change{{Field: "BookmarkEmojis", Old: []string(nil), New: []string{"⭐"}}}},
+		{"map", func(cfg *Config) { cfg.GuildColors = map[string]int{"1": 1} }, []Change{{Field: "GuildColors", Old: map[string]int(nil), New: map[string]int{"1": 1}}}},
+	}
+	for _, tt := range tests {
+		cfg := old
+		tt.change(&cfg)
+		if got := Diff(old, cfg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Diff = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChangeHidesSecrets(t *testing.T) {
+	tests := []struct {
+		change Change
+		want   string
+	}{
+		{Change{Field: "NSFWPolicy", Old: NSFW_SPOILER, New: NSFW_BLOCK}, "NSFWPolicy changed from spoiler to block"},
+		{Change{Field: "Token", Restart: true, Old: "old-token", New: "new-token"}, "Token changed"},
+		{Change{Field: "EncryptionKey", Restart: true, Old: "", New: "key"}, "EncryptionKey changed"},
+	}
+	for _, tt := range tests {
+		if got := tt.change.String(); got != tt.want {
+			t.Errorf("String = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestKeepRestartFields(t *testing.T) {
+	running := validConfig()
+	running.Token = "token"
+	running.StoreFile = "bookmarks.json"
+	running.LogDir = "/var/log/bookmarker"
+
+	reloaded := validConfig()
+	reloaded.Token = "new-token"
+	reloaded.StoreFile = "other.json"
+	reloaded.LogDir = ""
+	reloaded.NSFWPolicy = NSFW_BLOCK
+	reloaded.BookmarkEmojis = []string{"⭐"}
+
+	got := reloaded.KeepRestartFields(running)
+	if got.Token != "token" || got.StoreFile != "bookmarks.json" || got.LogDir != "/var/log/bookmarker" {
+		t.Errorf("restart fields = %q, %q, %q, want the running ones", got.Token, got.StoreFile, got.LogDir)
+	}
+	if got.NSFWPolicy != NSFW_BLOCK || !reflect.DeepEqual(got.BookmarkEmojis, []string{"⭐"}) {
+		t.Errorf("live fields = %q, %v, want the reloaded ones", got.NSFWPolicy, got.BookmarkEmojis)
+	}
+	for _, change := range Diff(running, got) {
+		if change.Restart {
+			t.Errorf("%s still differs from the running config", change.Field)
+		}
+	}
+}
+
+func TestEnvFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ENV_FILE)
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("BOOKMARKER_TEST_INHERITED", "from the environment")
+	for _, key := range []string{"BOOKMARKER_TEST_KEPT", "BOOKMARKER_TEST_REMOVED", "BOOKMARKER_TEST_ADDED"} {
+		t.Cleanup(func() { os.Unsetenv(key) })
+	}
+
+	write("BOOKMARKER_TEST_KEPT=1\nBOOKMARKER_TEST_REMOVED=1\nBOOKMARKER_TEST_INHERITED=from the file\n")
+	f, err := LoadEnvFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write("BOOKMARKER_TEST_KEPT=2\nBOOKMARKER_TEST_ADDED=1\nBOOKMARKER_TEST_INHERITED=changed in the file\n")
+	if err := f.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"BOOKMARKER_TEST_KEPT", "2", true},
+		{"BOOKMARKER_TEST_ADDED", "1", true},
+		{"BOOKMARKER_TEST_REMOVED", "", false},
+		{"BOOKMARKER_TEST_INHERITED", "from the environment", true},
+	}
+	for _, tt := range tests {
+		if got, ok := os.LookupEnv(tt.key); got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s = %q, %t, want %q, %t", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestEnvFileMissing(t *testing.T) {
+	f, err := LoadEnvFile(filepath.Join(t.TempDir(), ENV_FILE))
+	if err != nil {
+		t.Fatalf("LoadEnvFile of a missing file = %v, want no error", err)
+	}
+	if err := f.Reload(); err != nil {
+		t.Errorf("Reload of a missing file = %v, want no error", err)
+	}
+}
+
+func TestWatchFollowsChangedPaths(t *testing.T) {
+	oldPath := filepath.Join(t.TempDir(), "old.tmpl")
+	newPath := filepath.Join(t.TempDir(), "new.tmpl")
+	for _, path := range []string{oldPath, newPath} {
+		if err := os.WriteFile(path, []byte("{{.Content}}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes := make(chan struct{}, 4)
+	done := make(chan struct{})
+	defer close(done)
+	// The reload switches to the new template.
+	err := Watch([]string{oldPath}, done, log.New(io.Discard, "", 0), func() []string {
+		changes <- struct{}{}
+		return []string{newPath}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed := func(path string) bool {
+		if err := os.WriteFile(path, []byte("{{.Author}}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-changes:
+			return true
+		case <-time.After(4 * WATCH_DEBOUNCE):
+			return false
+		}
+	}
+	if !changed(oldPath) {
+		t.Fatal("change to the watched template not noticed")
+	}
+	if changed(oldPath) {
+		t.Error("change to the template no longer in use noticed")
+	}
+	if !changed(newPath) {
+		t.Error("change to the new template not noticed")
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// restartFields are the settings a running bot can't change: they are used
+// once, to connect or open the store and log file.
+var restartFields = map[string]bool{
+	"Token":             true,
+	"OwnerID":           true,
+	"LogStdout":         true,
+	"LogFile":           true,
+	"LogDir":            true,
+	"PIDFile":           true,
+	"StoreFile":         true,
+	"HealthAddr":        true,
+	"StoreDriver":       true,
+	"StoreDSN":          true,
+	"TranscribeURL":     true,
+	"TranscribeAPIKey":  true,
+	"TranscribeModel":   true,
+	"TranslateProvider": true,
+	"TranslateURL":      true,
+	"TranslateAPIKey":   true,
+	"Tracing":           true,
+	"EncryptionKey":     true,
+	"EncryptionOldKeys": true,
+}
+
+// secretFields are never logged.
+var secretFields = map[string]bool{
+	"Token":             true,
+	"StoreDSN":          true,
+	"TranscribeAPIKey":  true,
+	"TranslateAPIKey":   true,
+	"EncryptionKey":     true,
+	"EncryptionOldKeys": true,
+}
+
+// Change is a setting that differs between two configurations.
+type Change struct {
+	Field string
+	// Restart reports whether the change only applies after a restart.
+	Restart bool
+	Old     any
+	New     any
+}
+
+func (c Change) String() string {
+	if secretFields[c.Field] {
+		return c.Field + " changed"
+	}
+	return fmt.Sprintf("%s changed from %v to %v", c.Field, c.Old, c.New)
+}
+
+// Diff lists the settings that differ from old to cfg.
+func Diff(old, cfg Config) []Change {
+	var changes []Change
+	o, n := reflect.ValueOf(old), reflect.ValueOf(cfg)
+	for i := 0; i < o.NumField(); i++ {
+		if reflect.DeepEqual(o.Field(i).Interface(), n.Field(i).Interface()) {
+			continue
+		}
+		name := o.Type().Field(i).Name
+		changes = append(changes, Change{Field: name, Restart: restartFields[name], Old: o.Field(i).Interface(), New: n.Field(i).Interface()})
+	}
+	return changes
+}
+
+// KeepRestartFields returns cfg with the settings that need a restart copied
+// from running, the configuration in use.
+func (cfg Config) KeepRestartFields(running Config) Config {
+	c, r := reflect.ValueOf(&cfg).Elem(), reflect.ValueOf(running)
+	for i := 0; i < c.NumField(); i++ {
+		if restartFields[c.Type().Field(i).Name] {
+			c.Field(i).Set(r.Field(i))
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// ENV_FILE is the file settings are read from besides the environment.
+const ENV_FILE = ".env"
+
+// EnvFile applies the variables of a .env file to the environment and can
+// apply them again after the file changed. Variables set by the process
+// environment take precedence, as with godotenv.Load.
+type EnvFile struct {
+	Path string
+
+	mu sync.Mutex
+	// inherited are the variables set before the file was first read.
+	inherited map[string]bool
+	// keys are the variables last set from the file.
+	keys map[string]bool
+}
+
+// LoadEnvFile applies the file at path. A missing file is not an error: every
+// setting can come from the environment.
+func LoadEnvFile(path string) (*EnvFile, error) {
+	f := &EnvFile{Path: path, inherited: map[string]bool{}, keys: map[string]bool{}}
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		f.inherited[key] = true
+	}
+	return f, f.Reload()
+}
+
+// Reload applies the file again, unsetting the variables removed from it.
+func (f *EnvFile) Reload() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values, err := godotenv.Read(f.Path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	for key := range f.keys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+		}
+	}
+	f.keys = map[string]bool{}
+	for key, value := range values {
+		if f.inherited[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		f.keys[key] = true
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WATCH_DEBOUNCE groups the events of one save; editors often write a file
+// in several steps or replace it.
+const WATCH_DEBOUNCE = 500 * time.Millisecond
+
+// Watch calls changed after any of the files at paths is written, created,
+// replaced or removed, until done is closed. changed returns the paths to
+// watch from then on, as a reload can point to other files. The directories
+// are watched rather than the files so that files replaced by editors stay
+// watched.
+func Watch(paths []string, done <-chan struct{}, logger *log.Logger, changed func() []string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	files, dirs := map[string]bool{}, map[string]bool{}
+	// watch replaces the watched files with paths, adding and removing their
+	// directories as needed.
+	watch := func(paths []string) error {
+		newFiles, newDirs := map[string]bool{}, map[string]bool{}
+		for _, path := range paths {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			newFiles[abs] = true
+			newDirs[filepath.Dir(abs)] = true
+		}
+		for dir := range newDirs {
+			if dirs[dir] {
+				continue
+			}
+			if err := watcher.Add(dir); err != nil {
+				return err
+			}
+			dirs[dir] = true
+		}
+		for dir := range dirs {
+			if !newDirs[dir] {
+				watcher.Remove(dir)
+				delete(dirs, dir)
+			}
+		}
+		files = newFiles
+		return nil
+	}
+	if err := watch(paths); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-done:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if files[filepath.Clean(event.Name)] && !event.Has(fsnotify.Chmod) {
+					debounce = time.After(WATCH_DEBOUNCE)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Printf("Error watching the configuration files: %v", err)
+			case <-debounce:
+				debounce = nil
+				if err := watch(changed()); err != nil {
+					logger.Printf("Error watching the changed configuration files, changes to them need a restart: %v", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,392 @@
+// Package discordtest runs a fake Discord for end-to-end tests: a REST API
+// and a gateway that a real discordgo session connects to. Tests seed it
+// with guilds and messages, replay gateway events, and inspect the REST
+// requests the bot made.
+package discordtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// HEARTBEAT_INTERVAL is sent in Hello; long enough not to matter in tests.
+	HEARTBEAT_INTERVAL = time.Minute
+	// WAIT_TIMEOUT bounds Wait and Connected.
+	WAIT_TIMEOUT = 5 * time.Second
+	// WAIT_POLL_INTERVAL is how often Wait rechecks state that changes
+	// without a request, like the store.
+	WAIT_POLL_INTERVAL = 10 * time.Millisecond
+)
+
+// Request is a REST request the bot made.
+type Request struct {
+	Method string
+	// Path is relative to the API root, e.g. "channels/123/messages".
+	Path string
+	Body []byte
+}
+
+// Server is a fake Discord. Its zero value isn't usable, see NewServer.
+type Server struct {
+	t       testing.TB
+	http    *httptest.Server
+	botUser *discordgo.User
+
+	mu       sync.Mutex
+	guilds   []*discordgo.Guild
+	messages map[string]*discordgo.Message
+	users    map[string]*discordgo.User
+	requests []Request
+	nextID   int
+	conn     *websocket.Conn
+	seq      int
+	changed  chan struct{}
+}
+
+// NewServer starts a fake Discord whose bot user is botUser, stopped when
+// the test ends.
+func NewServer(t testing.TB, botUser *discordgo.User) *Server {
+	s := &Server{
+		t:        t,
+		botUser:  botUser,
+		messages: map[string]*discordgo.Message{},
+		users:    map[string]*discordgo.User{botUser.ID: botUser},
+		nextID:   1000,
+		changed:  make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gateway/", s.handleGateway)
+	mux.HandleFunc("GET /api/v"+discordgo.APIVersion+"/gateway", s.handleGatewayURL)
+	mux.HandleFunc("GET /api/v"+discordgo.APIVersion+"/gateway/bot", s.handleGatewayURL)
+	mux.HandleFunc("/api/v"+discordgo.APIVersion+"/{path...}", s.handleREST)
+	s.http = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Close disconnects the bot and stops the server.
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.mu.Unlock()
+	s.http.Close()
+}
+
+// Session returns a discordgo session whose REST requests and gateway
+// connection go to the fake.
+func (s *Server) Session() *discordgo.Session {
+	dg, err := discordgo.New("Bot test-token")
+	if err != nil {
+		s.t.Fatalf("creating session: %v", err)
+	}
+	target, _ := url.Parse(s.http.URL)
+	dg.Client = &http.Client{Transport: rewriteTransport{target: target}}
+	// Fail fast instead of retrying against a fake that won't change its mind.
+	dg.MaxRestRetries = 0
+	dg.Identify.Compress = false
+	return dg
+}
+
+// rewriteTransport sends requests for discord.com to the fake.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (rt rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	req.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// AddGuild makes a guild and its channels known; it's sent in READY.
+func (s *Server) AddGuild(g *discordgo.Guild) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ch := range g.Channels {
+		ch.GuildID = g.ID
+	}
+	s.guilds = append(s.guilds, g)
+}
+
+// AddUser makes a user fetchable.
+func (s *Server) AddUser(u *discordgo.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[u.ID] = u
+}
+
+// AddMessage makes a message fetchable.
+func (s *Server) AddMessage(msg *discordgo.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[msg.ChannelID+"/"+msg.ID] = msg
+}
+
+// Requests returns the REST requests made so far with the given method and
+// a path starting with prefix.
+func (s *Server) Requests(method, prefix string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matched []Request
+	for _, r := range s.requests {
+		if r.Method == method && strings.HasPrefix(r.Path, prefix) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// Wait blocks until cond holds, checking it after every request and
+// gateway event and every WAIT_POLL_INTERVAL, and fails the test after
+// WAIT_TIMEOUT.
+func (s *Server) Wait(what string, cond func() bool) {
+	s.t.Helper()
+	timeout := time.After(WAIT_TIMEOUT)
+	poll := time.NewTicker(WAIT_POLL_INTERVAL)
+	defer poll.Stop()
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+		if cond() {
+			return
+		}
+		select {
+		case <-changed:
+		case <-poll.C:
+		case <-timeout:
+			s.t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// notify wakes Wait; callers hold mu.
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Dispatch sends a gateway event to the connected bot.
+func (s *Server) Dispatch(eventType string, data any) {
+	s.t.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		s.t.Fatalf("encoding %s event: %v", eventType, err)
+	}
+	s.dispatchRaw(eventType, raw)
+}
+
+// Replay sends the gateway events recorded in a file, a JSON array of
+// dispatch payloads like {"t": "MESSAGE_REACTION_ADD", "d": {...}}.
+func (s *Server) Replay(path string) {
+	s.t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		s.t.Fatalf("reading recorded events: %v", err)
+	}
+	var events []struct {
+		Type string          `json:"t"`
+		Data json.RawMessage `json:"d"`
+	}
+	if err := json.Unmarshal(data, &events); err != nil {
+		s.t.Fatalf("decoding recorded events in %s: %v", path, err)
+	}
+	for _, e := range events {
+		s.dispatchRaw(e.Type, e.Data)
+	}
+}
+
+func (s *Server) dispatchRaw(eventType string, data json.RawMessage) {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		s.t.Fatalf("dispatching %s: the bot isn't connected", eventType)
+	}
+	s.seq++
+	err := s.conn.WriteJSON(map[string]any{"op": 0, "t": eventType, "s": s.seq, "d": data})
+	if err != nil {
+		s.t.Fatalf("dispatching %s: %v", eventType, err)
+	}
+}
+
+// Connected waits until the bot identified on the gateway.
+func (s *Server) Connected() {
+	s.t.Helper()
+	s.Wait("the bot to connect", func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.conn != nil
+	})
+}
+
+func (s *Server) handleGatewayURL(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"url": "ws" + strings.TrimPrefix(s.http.URL, "http") + "/gateway", "shards": 1})
+}
+
+var upgrader = websocket.Upgrader{}
+
+// handleGateway speaks just enough of the gateway protocol: Hello, READY
+// after Identify, and heartbeat acks.
+func (s *Server) handleGateway(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.t.Errorf("upgrading gateway connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// Dispatches write to the connection too; every write holds mu.
+	send := func(payload map[string]any) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return conn.WriteJSON(payload)
+	}
+	if err := send(map[string]any{"op": 10, "d": map[string]any{"heartbeat_interval": HEARTBEAT_INTERVAL.Milliseconds()}}); err != nil {
+		return
+	}
+
+	for {
+		var payload struct {
+			Op int `json:"op"`
+		}
+		if err := conn.ReadJSON(&payload); err != nil {
+			return
+		}
+
+		switch payload.Op {
+		case 1:
+			send(map[string]any{"op": 11})
+		case 2:
+			s.mu.Lock()
+			ready := map[string]any{
+				"v":          10,
+				"user":       s.botUser,
+				"session_id": "session",
+				"guilds":     s.guilds,
+			}
+			s.seq++
+			if err := conn.WriteJSON(map[string]any{"op": 0, "t": "READY", "s": s.seq, "d": ready}); err == nil {
+				s.conn = conn
+				s.notify()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Server) handleREST(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	path := r.PathValue("path")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: path, Body: body})
+	defer s.notify()
+
+	parts := strings.Split(path, "/")
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 4 && parts[0] == "channels" && parts[2] == "messages":
+		msg, ok := s.messages[parts[1]+"/"+parts[3]]
+		if !ok {
+			writeError(w, http.StatusNotFound, discordgo.ErrCodeUnknownMessage)
+			return
+		}
+		writeJSON(w, http.StatusOK, msg)
+
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "channels" && parts[2] == "messages":
+		// A sent message decodes as a message; unlike MessageSend, Message
+		// knows how to unmarshal components.
+		var msg discordgo.Message
+		if err := decodeMessageSend(r, body, &msg); err != nil {
+			writeError(w, http.StatusBadRequest, 0)
+			return
+		}
+		msg.ID = s.newID()
+		msg.ChannelID = parts[1]
+		msg.Author = s.botUser
+		msg.Timestamp = time.Now()
+		s.messages[msg.ChannelID+"/"+msg.ID] = &msg
+		writeJSON(w, http.StatusOK, &msg)
+
+	case r.Method == http.MethodPost && path == "users/@me/channels":
+		var req struct {
+			RecipientID string `json:"recipient_id"`
+		}
+		json.Unmarshal(body, &req)
+		writeJSON(w, http.StatusOK, &discordgo.Channel{ID: "dm-" + req.RecipientID, Type: discordgo.ChannelTypeDM})
+
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "users":
+		user, ok := s.users[parts[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, discordgo.ErrCodeUnknownUser)
+			return
+		}
+		writeJSON(w, http.StatusOK, user)
+
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "guilds":
+		for _, g := range s.guilds {
+			if g.ID == parts[1] {
+				writeJSON(w, http.StatusOK, g)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, discordgo.ErrCodeUnknownGuild)
+
+	case (r.Method == http.MethodPut || r.Method == http.MethodDelete) && len(parts) >= 5 && parts[2] == "messages" && parts[4] == "reactions":
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodPut && len(parts) == 3 && parts[0] == "applications" && parts[2] == "commands":
+		writeJSON(w, http.StatusOK, []any{})
+
+	default:
+		writeError(w, http.StatusNotFound, 0)
+	}
+}
+
+// decodeMessageSend reads a message body sent as JSON or, with files, as
+// multipart with a payload_json part.
+func decodeMessageSend(r *http.Request, body []byte, msg *discordgo.Message) error {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		return json.Unmarshal(body, msg)
+	}
+	r.Body = io.NopCloser(strings.NewReader(string(body)))
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(r.FormValue("payload_json")), msg)
+}
+
+// newID returns a fresh snowflake-like ID; callers hold mu.
+func (s *Server) newID() string {
+	s.nextID++
+	return fmt.Sprint(s.nextID)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status, code int) {
+	writeJSON(w, status, map[string]any{"code": code, "message": http.StatusText(status)})
+}
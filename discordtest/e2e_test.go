@@ -0,0 +1,110 @@
+package discordtest
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/bot"
+	"github.com/anonmiraj/discord-bookmarker/config"
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/bwmarrin/discordgo"
+)
+
+// startBot connects a bot backed by a fresh JSON store to a fake Discord
+// with one guild, one channel and one message in it.
+func startBot(t *testing.T) (*Server, *store.Store) {
+	t.Helper()
+	srv := NewServer(t, &discordgo.User{ID: "1", Username: "bookmarker", Bot: true})
+	srv.AddGuild(&discordgo.Guild{
+		ID:       "100",
+		Name:     "Guild",
+		Channels: []*discordgo.Channel{{ID: "300", Name: "general", Type: discordgo.ChannelTypeGuildText}},
+	})
+	alice := &discordgo.User{ID: "200", Username: "alice"}
+	srv.AddUser(alice)
+	srv.AddMessage(&discordgo.Message{
+		ID:        "400",
+		ChannelID: "300",
+		GuildID:   "100",
+		Content:   "worth keeping",
+		Author:    &discordgo.User{ID: "201", Username: "bob"},
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+
+	st, err := store.Open(filepath.Join(t.TempDir(), "bookmarks.json"))
+	if err != nil {
+		t.Fatalf("opening store: %v", err)
+	}
+	dg := srv.Session()
+	b := bot.New(dg, dg.State, st, config.Config{NSFWPolicy: config.NSFW_SPOILER, CatchUpLimit: 50}, embed.DefaultTemplate(), log.New(io.Discard, "", 0))
+	b.Register(dg)
+	if err := dg.Open(); err != nil {
+		t.Fatalf("connecting to the fake gateway: %v", err)
+	}
+	t.Cleanup(func() {
+		b.Shutdown()
+		dg.Close()
+	})
+	srv.Connected()
+	return srv, st
+}
+
+func TestReactionIsBookmarkedEndToEnd(t *testing.T) {
+	srv, st := startBot(t)
+	srv.Replay(filepath.Join("testdata", "bookmark_reaction.json"))
+
+	srv.Wait("the bookmark to be stored", func() bool { return len(st.ForUser("200")) == 1 })
+	sent := srv.Requests("POST", "channels/dm-200/messages")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d DMs, want the bookmark", len(sent))
+	}
+	if body := string(sent[0].Body); !strings.Contains(body, "worth keeping") {
+		t.Errorf("bookmark DM = %s, want the message content", body)
+	}
+	if bm := st.ForUser("200")[0]; bm.MessageID != "400" || bm.DMMessageID == "" {
+		t.Errorf("stored bookmark = %+v", bm)
+	}
+}
+
+func TestReactionIsRemovedAfterDeliveryEndToEnd(t *testing.T) {
+	srv, st := startBot(t)
+	if err := st.SetSettings("200", store.UserSettings{RemoveReaction: true}); err != nil {
+		t.Fatal(err)
+	}
+	srv.Replay(filepath.Join("testdata", "bookmark_reaction.json"))
+
+	srv.Wait("the reaction to be removed", func() bool {
+		return len(srv.Requests("DELETE", "channels/300/messages/400/reactions/")) == 1
+	})
+	if len(st.ForUser("200")) != 1 {
+		t.Error("bookmark wasn't stored")
+	}
+}
+
+func TestDeletedMessageIsNotBookmarkedEndToEnd(t *testing.T) {
+	srv, st := startBot(t)
+	srv.Dispatch("MESSAGE_REACTION_ADD", &discordgo.MessageReaction{
+		UserID:    "200",
+		ChannelID: "300",
+		MessageID: "404",
+		GuildID:   "100",
+		Emoji:     discordgo.Emoji{Name: bot.BOOKMARK_EMOJI},
+	})
+
+	srv.Wait("the message to be fetched", func() bool {
+		return len(srv.Requests("GET", "channels/300/messages/404")) > 0
+	})
+	// Give the bot a moment to (wrongly) deliver anyway.
+	time.Sleep(100 * time.Millisecond)
+	if sent := srv.Requests("POST", "channels/dm-200/messages"); len(sent) != 0 {
+		t.Errorf("sent %d DMs for a deleted message", len(sent))
+	}
+	if len(st.ForUser("200")) != 0 {
+		t.Error("stored a bookmark of a deleted message")
+	}
+}
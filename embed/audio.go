@@ -0,0 +1,76 @@
+package embed
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// TRANSCRIPT_MAX_LENGTH is Discord's limit on embed field values.
+const TRANSCRIPT_MAX_LENGTH = 1024
+
+var audioExtensions = map[string]bool{
+	".ogg": true, ".opus": true, ".mp3": true, ".wav": true, ".m4a": true, ".flac": true, ".aac": true,
+}
+
+// IsAudioAttachment reports whether an attachment is a sound file.
+func IsAudioAttachment(a *discordgo.MessageAttachment) bool {
+	return strings.HasPrefix(a.ContentType, "audio/") || audioExtensions[strings.ToLower(path.Ext(a.Filename))]
+}
+
+// IsVoiceMessage reports whether msg was recorded with Discord's voice message button.
+func IsVoiceMessage(msg *discordgo.Message) bool {
+	return msg.Flags&discordgo.MessageFlagsIsVoiceMessage != 0
+}
+
+// attachmentFieldName names the embed field linking the i-th attachment.
+func attachmentFieldName(msg *discordgo.Message, i int) string {
+	a := msg.Attachments[i]
+	switch {
+	case IsVoiceMessage(msg) && IsAudioAttachment(a):
+		return "🎤 Voice message"
+	case IsAudioAttachment(a):
+		return fmt.Sprintf("🔊 Audio %d", i+1)
+	}
+	return fmt.Sprintf("Attachment %d", i+1)
+}
+
+// AddAudioDuration appends the playing time to the field linking a.
+func AddAudioDuration(e *discordgo.MessageEmbed, a *discordgo.MessageAttachment, d time.Duration) {
+	for _, field := range e.Fields {
+		if strings.Contains(field.Value, a.URL) {
+			field.Value += " · " + formatDuration(d)
+			return
+		}
+	}
+}
+
+// AddTranscript adds a field with the speech-to-text of the message's audio.
+func AddTranscript(e *discordgo.MessageEmbed, transcript string, spoiler bool) {
+	transcript = strings.TrimSpace(transcript)
+	if transcript == "" {
+		return
+	}
+
+	limit := TRANSCRIPT_MAX_LENGTH - len("||…||")
+	if runes := []rune(transcript); len(runes) > limit {
+		transcript = string(runes[:limit]) + "…"
+	}
+	if spoiler {
+		transcript = "||" + transcript + "||"
+	}
+
+	e.Fields = append(e.Fields, &discordgo.MessageEmbedField{Name: "Transcript", Value: transcript})
+}
+
+// formatDuration formats d as m:ss, or h:mm:ss from an hour on.
+func formatDuration(d time.Duration) string {
+	s := int(d.Round(time.Second).Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
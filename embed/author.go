@@ -0,0 +1,46 @@
+package embed
+
+import "github.com/bwmarrin/discordgo"
+
+const (
+	// UNKNOWN_AUTHOR names the author of messages that came without one.
+	UNKNOWN_AUTHOR = "Unknown user"
+	// WEBHOOK_AUTHOR names webhook messages that came without the webhook's name.
+	WEBHOOK_AUTHOR = "Webhook"
+)
+
+// Author is who a bookmark credits for a message.
+type Author struct {
+	Name    string
+	IconURL string
+}
+
+// AuthorOf returns the author to show for msg. Webhook messages carry the
+// webhook's name and avatar in place of a user. Messages without an author,
+// e.g. of deleted accounts, fall back to the application that sent them,
+// if known. Messages sent through an application are labeled with it.
+func AuthorOf(msg *discordgo.Message) Author {
+	app := msg.Application
+	if msg.Author == nil || msg.Author.Username == "" {
+		switch {
+		case app != nil && app.Name != "":
+			return Author{Name: app.Name, IconURL: applicationIconURL(app)}
+		case msg.WebhookID != "":
+			return Author{Name: WEBHOOK_AUTHOR}
+		}
+		return Author{Name: UNKNOWN_AUTHOR}
+	}
+
+	author := Author{Name: msg.Author.Username, IconURL: msg.Author.AvatarURL("")}
+	if app != nil && app.Name != "" && app.Name != author.Name {
+		author.Name += " (via " + app.Name + ")"
+	}
+	return author
+}
+
+func applicationIconURL(app *discordgo.MessageApplication) string {
+	if app.Icon == "" {
+		return ""
+	}
+	return discordgo.EndpointCDN + "app-icons/" + app.ID + "/" + app.Icon + ".png"
+}
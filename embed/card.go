@@ -0,0 +1,59 @@
+package embed
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// CARD_CONTENT_LENGTH bounds the quoted content of a share card.
+const CARD_CONTENT_LENGTH = 300
+
+// Card describes a bookmark shared with others.
+type Card struct {
+	GuildName   string
+	ChannelID   string
+	MessageLink string
+	// Author is nil when the source message is no longer available.
+	Author    *Author
+	Content   string
+	Timestamp time.Time
+	SharedBy  string
+	// Restricted hides the content, for sources not everyone can read.
+	Restricted bool
+}
+
+// BuildCard creates the compact embed of a shared bookmark: attribution, a
+// short quote and the jump link.
+func BuildCard(c Card) *discordgo.MessageEmbed {
+	card := &discordgo.MessageEmbed{
+		URL:   c.MessageLink,
+		Title: fmt.Sprintf("Message in %s", c.GuildName),
+		Color: DEFAULT_EMBED_COLOR,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Source", Value: fmt.Sprintf("<#%s> · [Jump to message](%s)", c.ChannelID, c.MessageLink)},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: "Shared by " + c.SharedBy},
+	}
+
+	if c.Author != nil {
+		card.Author = &discordgo.MessageEmbedAuthor{Name: c.Author.Name, IconURL: c.Author.IconURL}
+	}
+	if !c.Timestamp.IsZero() {
+		card.Timestamp = c.Timestamp.Format(time.RFC3339)
+	}
+
+	switch {
+	case c.Restricted:
+		card.Description = "*This message is in a channel not everyone can read; open the link to view it.*"
+	case c.Content != "":
+		content := []rune(c.Content)
+		if len(content) > CARD_CONTENT_LENGTH {
+			content = append(content[:CARD_CONTENT_LENGTH], '…')
+		}
+		card.Description = string(content)
+	}
+
+	return card
+}
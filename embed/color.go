@@ -0,0 +1,47 @@
+package embed
+
+import (
+	"image"
+	"image/color"
+)
+
+// DominantColor returns the most common color of an image, ignoring
+// transparent, near-black and near-white pixels which are mostly background.
+// Colors are bucketed at 4 bits per channel and the bucket's average is
+// returned. ok is false if no pixel qualifies.
+func DominantColor(img image.Image) (rgb int, ok bool) {
+	type sum struct{ r, g, b, n int }
+	buckets := map[int]*sum{}
+	best := -1
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A < 128 {
+				continue
+			}
+			r, g, b := int(c.R), int(c.G), int(c.B)
+			if max(r, g, b) < 32 || min(r, g, b) > 224 {
+				continue
+			}
+
+			key := (r>>4)<<8 | (g>>4)<<4 | b>>4
+			s, found := buckets[key]
+			if !found {
+				s = &sum{}
+				buckets[key] = s
+			}
+			s.r, s.g, s.b, s.n = s.r+r, s.g+g, s.b+b, s.n+1
+			if best == -1 || s.n > buckets[best].n {
+				best = key
+			}
+		}
+	}
+
+	if best == -1 {
+		return 0, false
+	}
+	s := buckets[best]
+	return (s.r/s.n)<<16 | (s.g/s.n)<<8 | s.b/s.n, true
+}
@@ -0,0 +1,33 @@
+package embed
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDominantColor(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			switch {
+			case y < 2:
+				img.Set(x, y, color.White)
+			case y < 5:
+				img.Set(x, y, color.NRGBA{R: 0xe6, G: 0x7e, B: 0x22, A: 0xff})
+			default:
+				img.Set(x, y, color.NRGBA{R: 0x34, G: 0x98, B: 0xdb, A: 0x80})
+			}
+		}
+	}
+
+	// The semi-transparent blue half counts, and outnumbers the orange.
+	got, ok := DominantColor(img)
+	if !ok || got != 0x3498db {
+		t.Errorf("DominantColor = %06x, %t, want 3498db", got, ok)
+	}
+
+	if _, ok := DominantColor(image.NewNRGBA(image.Rect(0, 0, 4, 4))); ok {
+		t.Error("a transparent image has no dominant color")
+	}
+}
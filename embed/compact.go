@@ -0,0 +1,45 @@
+package embed
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// COMPACT_SNIPPET_MAX_LENGTH is the number of characters of the first line
+// of a message shown by a compact embed.
+const COMPACT_SNIPPET_MAX_LENGTH = 100
+
+// BuildCompact creates a compact bookmark embed for msg: the author, the
+// start of the first line and the jump link, without images, attachments
+// or template fields.
+func BuildCompact(msg *discordgo.Message, guildName, messageLink string, color int) *discordgo.MessageEmbed {
+	e := &discordgo.MessageEmbed{
+		Description: fmt.Sprintf("%s · [Jump](%s)", Snippet(msg), messageLink),
+		Timestamp:   msg.Timestamp.Format(time.RFC3339),
+		Color:       color,
+		Footer:      &discordgo.MessageEmbedFooter{Text: guildName},
+	}
+	author := AuthorOf(msg)
+	e.Author = &discordgo.MessageEmbedAuthor{Name: author.Name, IconURL: author.IconURL}
+	return e
+}
+
+// Snippet returns the first line of a message cut to
+// COMPACT_SNIPPET_MAX_LENGTH, or a count of its attachments if it has no text.
+func Snippet(msg *discordgo.Message) string {
+	// A code fence on the first line would swallow the jump link.
+	line, _, _ := strings.Cut(strings.TrimSpace(strings.ReplaceAll(msg.Content, "```", "")), "\n")
+	switch {
+	case line == "" && len(msg.Attachments) == 1:
+		return "📎 1 attachment"
+	case line == "" && len(msg.Attachments) > 1:
+		return fmt.Sprintf("📎 %d attachments", len(msg.Attachments))
+	case line == "":
+		return "*No text*"
+	}
+
+	return closeMarkdown(truncateWords(line, COMPACT_SNIPPET_MAX_LENGTH))
+}
@@ -0,0 +1,106 @@
+package embed
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Build creates the bookmark embed for msg. With spoiler set, no attachment
+// is previewed and every attachment link is hidden behind a spoiler.
+//
+// Template errors don't prevent building: the affected text falls back to
+// the default and the errors are returned alongside the embed.
+func (t *Template) Build(msg *discordgo.Message, guildName, messageLink string, spoiler bool) (*discordgo.MessageEmbed, error) {
+	author := AuthorOf(msg)
+	data := Data{
+		GuildName:   guildName,
+		ChannelID:   msg.ChannelID,
+		MessageID:   msg.ID,
+		MessageLink: messageLink,
+		Author:      author.Name,
+		Content:     msg.Content,
+		Timestamp:   msg.Timestamp,
+		Attachments: len(msg.Attachments),
+	}
+
+	var errs []error
+	renderOr := func(tmpl *template.Template, fallback string) string {
+		text, err := render(tmpl, data)
+		if err != nil {
+			errs = append(errs, err)
+			return fallback
+		}
+		return text
+	}
+
+	embed := &discordgo.MessageEmbed{
+		// The title links to the source so the bookmark can always be traced
+		// back to it, whichever fields the template shows.
+		URL:         messageLink,
+		Title:       renderOr(t.Title, fmt.Sprintf("Bookmark from %s", guildName)),
+		Description: renderOr(t.Description, msg.Content),
+		Timestamp:   msg.Timestamp.Format(time.RFC3339),
+		Color:       t.Color,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: renderOr(t.Footer, DEFAULT_FOOTER_TEMPLATE),
+		},
+	}
+
+	if t.ShowAuthor {
+		embed.Author = &discordgo.MessageEmbedAuthor{
+			Name:    author.Name,
+			IconURL: author.IconURL,
+		}
+	}
+
+	if t.ShowSource {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Source",
+			Value:  fmt.Sprintf("[Jump to message](%s)", messageLink),
+			Inline: false,
+		})
+	}
+
+	if t.ShowImage && !spoiler {
+		for _, a := range msg.Attachments {
+			// Embed images can't be spoilered, so spoiler attachments stay links.
+			if strings.HasPrefix(a.ContentType, "image/") && !IsSpoilerAttachment(a) {
+				embed.Image = &discordgo.MessageEmbedImage{URL: a.URL}
+				break
+			}
+		}
+	}
+
+	if t.ShowAttachments {
+		for i, a := range msg.Attachments {
+			if embed.Image != nil && embed.Image.URL == a.URL {
+				continue
+			}
+			value := fmt.Sprintf("[%s](%s)", a.Filename, a.URL)
+			if spoiler || IsSpoilerAttachment(a) {
+				value = "||" + value + "||"
+			}
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+				Name:   attachmentFieldName(msg, i),
+				Value:  value,
+				Inline: false,
+			})
+		}
+	}
+
+	if t.ShowReactions {
+		SetReactions(embed, msg, time.Now())
+	}
+
+	return embed, errors.Join(errs...)
+}
+
+// IsSpoilerAttachment reports whether Discord shows the attachment behind a spoiler.
+func IsSpoilerAttachment(a *discordgo.MessageAttachment) bool {
+	return strings.HasPrefix(a.Filename, "SPOILER_")
+}
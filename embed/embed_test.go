@@ -0,0 +1,356 @@
+package embed
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/unfurl"
+	"github.com/bwmarrin/discordgo"
+)
+
+func testMessage() *discordgo.Message {
+	return &discordgo.Message{
+		ID:        "message",
+		ChannelID: "channel",
+		Content:   "hello ||world||",
+		Author:    &discordgo.User{ID: "author", Username: "bob"},
+		Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Attachments: []*discordgo.MessageAttachment{
+			{Filename: "cat.png", ContentType: "image/png", URL: "https://cdn/cat.png"},
+			{Filename: "notes.txt", ContentType: "text/plain", URL: "https://cdn/notes.txt"},
+		},
+	}
+}
+
+func TestBuildDefault(t *testing.T) {
+	embed, err := DefaultTemplate().Build(testMessage(), "Guild", "https://discord.com/channels/g/c/m", false)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	if embed.Title != "Bookmark from Guild" {
+		t.Errorf("title = %q", embed.Title)
+	}
+	if embed.Description != "hello ||world||" {
+		t.Errorf("description = %q, spoiler markers must be preserved", embed.Description)
+	}
+	if embed.Author == nil || embed.Author.Name != "bob" {
+		t.Errorf("author = %+v", embed.Author)
+	}
+	if embed.Image == nil || embed.Image.URL != "https://cdn/cat.png" {
+		t.Errorf("image = %+v, want the first image attachment", embed.Image)
+	}
+	if len(embed.Fields) != 2 {
+		t.Fatalf("got %d fields, want source and one attachment", len(embed.Fields))
+	}
+	if embed.Fields[0].Name != "Source" || !strings.Contains(embed.Fields[0].Value, "https://discord.com/channels/g/c/m") {
+		t.Errorf("source field = %+v", embed.Fields[0])
+	}
+	if embed.Fields[1].Value != "[notes.txt](https://cdn/notes.txt)" {
+		t.Errorf("attachment field = %q", embed.Fields[1].Value)
+	}
+}
+
+func TestBuildSpoiler(t *testing.T) {
+	msg := testMessage()
+	msg.Attachments[1].Filename = "SPOILER_notes.txt"
+
+	embed, _ := DefaultTemplate().Build(msg, "Guild", "link", false)
+	if got := embed.Fields[1].Value; !strings.HasPrefix(got, "||") || !strings.HasSuffix(got, "||") {
+		t.Errorf("spoiler attachment field = %q, want it spoilered", got)
+	}
+
+	embed, _ = DefaultTemplate().Build(testMessage(), "Guild", "link", true)
+	if embed.Image != nil {
+		t.Errorf("image = %+v, want none in spoiler mode", embed.Image)
+	}
+	for _, field := range embed.Fields[1:] {
+		if !strings.HasPrefix(field.Value, "||") {
+			t.Errorf("attachment field %q is not spoilered", field.Value)
+		}
+	}
+}
+
+func writeTemplate(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "template.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadTemplate(t *testing.T) {
+	path := writeTemplate(t, `{
+		"title": "{{.Author}} in {{.GuildName}}",
+		"color": "#ff0000",
+		"fields": {"author": false, "attachments": false}
+	}`)
+
+	tmpl, err := LoadTemplate(path)
+	if err != nil {
+		t.Fatalf("LoadTemplate: %v", err)
+	}
+
+	embed, err := tmpl.Build(testMessage(), "Guild", "link", false)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if embed.Title != "bob in Guild" {
+		t.Errorf("title = %q", embed.Title)
+	}
+	if embed.Color != 0xff0000 {
+		t.Errorf("color = %#x", embed.Color)
+	}
+	if embed.Author != nil {
+		t.Error("author shown although disabled")
+	}
+	if len(embed.Fields) != 1 {
+		t.Errorf("got %d fields, want only the source", len(embed.Fields))
+	}
+	if embed.Description != "hello ||world||" {
+		t.Errorf("description = %q, want the default", embed.Description)
+	}
+}
+
+func TestLoadTemplateRejectsInvalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown field": `{"title": "{{.Nope}}"}`,
+		"syntax":        `{"footer": "{{.Author"}`,
+		"color":         `{"color": "blue"}`,
+	} {
+		if _, err := LoadTemplate(writeTemplate(t, content)); err == nil {
+			t.Errorf("%s: LoadTemplate succeeded, want an error", name)
+		}
+	}
+}
+
+func TestBuildCard(t *testing.T) {
+	c := Card{
+		GuildName:   "Guild",
+		ChannelID:   "channel",
+		MessageLink: "https://discord.com/channels/g/c/m",
+		Author:      &Author{Name: "bob"},
+		Content:     strings.Repeat("x", CARD_CONTENT_LENGTH+10),
+		SharedBy:    "alice",
+	}
+
+	card := BuildCard(c)
+	if card.URL != c.MessageLink || card.Author.Name != "bob" || card.Footer.Text != "Shared by alice" {
+		t.Errorf("card = %+v", card)
+	}
+	if n := len([]rune(card.Description)); n != CARD_CONTENT_LENGTH+1 {
+		t.Errorf("description has %d runes, want it truncated to %d", n, CARD_CONTENT_LENGTH+1)
+	}
+
+	c.Restricted = true
+	if card := BuildCard(c); strings.Contains(card.Description, "xxx") {
+		t.Error("restricted card quotes the content")
+	}
+}
+
+func TestBuildVoiceMessage(t *testing.T) {
+	msg := testMessage()
+	msg.Flags = discordgo.MessageFlagsIsVoiceMessage
+	msg.Attachments = []*discordgo.MessageAttachment{
+		{Filename: "voice-message.ogg", ContentType: "audio/ogg", URL: "https://cdn/voice-message.ogg"},
+	}
+
+	embed, _ := DefaultTemplate().Build(msg, "Guild", "link", false)
+	AddAudioDuration(embed, msg.Attachments[0], 3723*time.Second)
+	AddTranscript(embed, "hello there", true)
+
+	if len(embed.Fields) != 3 {
+		t.Fatalf("got %d fields, want source, voice message and transcript", len(embed.Fields))
+	}
+	if f := embed.Fields[1]; f.Name != "🎤 Voice message" || !strings.HasSuffix(f.Value, " · 1:02:03") {
+		t.Errorf("voice message field = %+v", f)
+	}
+	if f := embed.Fields[2]; f.Name != "Transcript" || f.Value != "||hello there||" {
+		t.Errorf("transcript field = %+v", f)
+	}
+}
+
+func TestFitTruncatesLongContent(t *testing.T) {
+	msg := testMessage()
+	msg.Content = strings.Repeat("word ", 1000)
+	e, err := DefaultTemplate().Build(msg, "Guild", "https://discord.com/channels/g/c/m", false)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	AddNote(e, strings.Repeat("n", NOTE_MAX_LENGTH), nil)
+	fields := len(e.Fields)
+
+	if !Overflows(e) {
+		t.Fatal("embed with 5000 characters of content doesn't overflow")
+	}
+	if !Fit(e, TRUNCATED_FILE_NOTICE) {
+		t.Fatal("Fit reported nothing was cut")
+	}
+	if Overflows(e) {
+		t.Errorf("embed still overflows, length %d", Length(e))
+	}
+	if !strings.HasSuffix(e.Description, "word…"+TRUNCATED_FILE_NOTICE) {
+		t.Errorf("description doesn't end at a word with the notice: %q", e.Description[len(e.Description)-80:])
+	}
+	if len(e.Fields) != fields {
+		t.Errorf("fields = %d, want all %d kept", len(e.Fields), fields)
+	}
+}
+
+func TestFitLeavesShortContent(t *testing.T) {
+	e, _ := DefaultTemplate().Build(testMessage(), "Guild", "https://discord.com/channels/g/c/m", false)
+	if Fit(e, TRUNCATED_FILE_NOTICE) || e.Description != "hello ||world||" {
+		t.Errorf("short embed was changed: %q", e.Description)
+	}
+}
+
+func TestResolveMentions(t *testing.T) {
+	names := Names{
+		Users:    map[string]string{"1": "alice"},
+		Roles:    map[string]string{"2": "mods"},
+		Channels: map[string]string{"3": "general"},
+	}
+	content := "hi <@1> and <@!1>, ping <@&2> in <#3> <:party:44> <a:wave:55> <@9>\n```\n<@1> stays\n``` and `<#3>`"
+
+	got := ResolveMentions(content, names)
+	want := "hi @alice and @alice, ping @mods in #general [:party:](https://cdn.discordapp.com/emojis/44.webp) " +
+		"[:wave:](https://cdn.discordapp.com/emojis/55.gif) @unknown-user\n```\n<@1> stays\n``` and `<#3>`"
+	if got != want {
+		t.Errorf("ResolveMentions =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFitClosesCodeBlock(t *testing.T) {
+	e := &discordgo.MessageEmbed{Description: "```go\n" + strings.Repeat("x := 1\n", 1000) + "```"}
+
+	Fit(e, TRUNCATED_LINK_NOTICE)
+
+	if !strings.HasSuffix(e.Description, "\n```"+TRUNCATED_LINK_NOTICE) {
+		t.Errorf("code block left open: %q", e.Description[len(e.Description)-80:])
+	}
+	if Overflows(e) {
+		t.Errorf("embed still overflows, length %d", Length(e))
+	}
+}
+
+func TestAddLinkPreview(t *testing.T) {
+	e := &discordgo.MessageEmbed{}
+	AddLinkPreview(e, unfurl.Preview{URL: "https://example.com", SiteName: "Example", Title: "A [draft]", Description: "About it", Image: "https://example.com/a.png"})
+
+	if len(e.Fields) != 1 || e.Fields[0].Name != "🔗 Example" || e.Fields[0].Value != "**[A \\[draft\\]](https://example.com)**\nAbout it" {
+		t.Errorf("fields = %+v", e.Fields)
+	}
+	if e.Image == nil || e.Image.URL != "https://example.com/a.png" {
+		t.Errorf("image = %+v", e.Image)
+	}
+
+	AddLinkPreview(e, unfurl.Preview{})
+	if len(e.Fields) != 1 {
+		t.Error("empty preview added a field")
+	}
+}
+
+func TestSetReactions(t *testing.T) {
+	msg := testMessage()
+	msg.Reactions = []*discordgo.MessageReactions{
+		{Count: 2, Emoji: &discordgo.Emoji{Name: "❤️"}},
+		{Count: 12, Emoji: &discordgo.Emoji{Name: "party", ID: "123"}},
+	}
+	at := time.Unix(1700000000, 0)
+	e := &discordgo.MessageEmbed{Fields: []*discordgo.MessageEmbedField{{Name: "Source"}, {Name: "Tags"}}}
+
+	SetReactions(e, msg, at)
+	if len(e.Fields) != 3 || e.Fields[2].Value != "<:party:123> 12 · ❤️ 2\n*as of <t:1700000000:R>*" {
+		t.Fatalf("fields = %+v", e.Fields)
+	}
+
+	// Refreshing replaces the snapshot where it was.
+	e.Fields[1], e.Fields[2] = e.Fields[2], e.Fields[1]
+	msg.Reactions = nil
+	msg.Thread = &discordgo.Channel{MessageCount: 1}
+	SetReactions(e, msg, at)
+	if len(e.Fields) != 3 || e.Fields[1].Name != REACTIONS_FIELD_NAME || e.Fields[1].Value != "💬 1 reply in thread\n*as of <t:1700000000:R>*" {
+		t.Errorf("refreshed fields = %+v", e.Fields)
+	}
+
+	msg.Thread = nil
+	SetReactions(e, msg, at)
+	if len(e.Fields) != 2 {
+		t.Errorf("snapshot kept without reactions: %+v", e.Fields)
+	}
+}
+
+func TestBuildCompact(t *testing.T) {
+	e := BuildCompact(testMessage(), "Guild", "https://discord.com/channels/g/c/m", 0x123456)
+	if e.Description != "hello ||world|| · [Jump](https://discord.com/channels/g/c/m)" {
+		t.Errorf("description = %q", e.Description)
+	}
+	if e.Image != nil || len(e.Fields) != 0 {
+		t.Errorf("compact embed has an image or fields: %+v", e)
+	}
+	if e.Author == nil || e.Author.Name != "bob" || e.Color != 0x123456 {
+		t.Errorf("author = %+v, color = %x", e.Author, e.Color)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("word ", 40)
+	tests := []struct {
+		name string
+		msg  *discordgo.Message
+		want string
+	}{
+		{"first line", &discordgo.Message{Content: "first\nsecond"}, "first"},
+		{"code fence", &discordgo.Message{Content: "```go\nfmt.Println()\n```"}, "go"},
+		{"attachments only", &discordgo.Message{Attachments: make([]*discordgo.MessageAttachment, 2)}, "📎 2 attachments"},
+		{"empty", &discordgo.Message{}, "*No text*"},
+		{"long", &discordgo.Message{Content: long}, strings.TrimSpace(long[:95]) + "…"},
+	}
+	for _, tt := range tests {
+		if got := Snippet(tt.msg); got != tt.want {
+			t.Errorf("%s: Snippet = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorOf(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *discordgo.Message
+		want string
+	}{
+		{"user", &discordgo.Message{Author: &discordgo.User{ID: "1", Username: "bob"}}, "bob"},
+		{"webhook", &discordgo.Message{WebhookID: "w", Author: &discordgo.User{ID: "w", Username: "GitHub"}}, "GitHub"},
+		{"webhook without author", &discordgo.Message{WebhookID: "w"}, WEBHOOK_AUTHOR},
+		{"via application", &discordgo.Message{
+			Author:      &discordgo.User{ID: "1", Username: "bob"},
+			Application: &discordgo.MessageApplication{ID: "app", Name: "Spotify"},
+		}, "bob (via Spotify)"},
+		{"application without author", &discordgo.Message{Application: &discordgo.MessageApplication{ID: "app", Name: "Spotify", Icon: "abc"}}, "Spotify"},
+		{"no author", &discordgo.Message{}, UNKNOWN_AUTHOR},
+		{"deleted account", &discordgo.Message{Author: &discordgo.User{ID: "1"}}, UNKNOWN_AUTHOR},
+	}
+	for _, tt := range tests {
+		if got := AuthorOf(tt.msg); got.Name != tt.want {
+			t.Errorf("%s: author = %q, want %q", tt.name, got.Name, tt.want)
+		}
+	}
+
+	app := AuthorOf(&discordgo.Message{Application: &discordgo.MessageApplication{ID: "app", Name: "Spotify", Icon: "abc"}})
+	if app.IconURL != "https://cdn.discordapp.com/app-icons/app/abc.png" {
+		t.Errorf("application icon = %q", app.IconURL)
+	}
+}
+
+func TestBuildWithoutAuthor(t *testing.T) {
+	msg := testMessage()
+	msg.Author = nil
+	embed, _ := DefaultTemplate().Build(msg, "Guild", "https://discord.com/channels/g/c/m", false)
+	if embed.Author == nil || embed.Author.Name != UNKNOWN_AUTHOR {
+		t.Errorf("author = %+v, want %q", embed.Author, UNKNOWN_AUTHOR)
+	}
+}
@@ -0,0 +1,93 @@
+package embed
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord's limits on embeds. Lengths are in characters.
+const (
+	DESCRIPTION_MAX_LENGTH = 4096
+	FIELD_MAX_COUNT        = 25
+	EMBED_MAX_LENGTH       = 6000
+)
+
+// Notices appended to truncated content, depending on whether the full text
+// could be attached.
+const (
+	TRUNCATED_FILE_NOTICE = "\n\n*Content truncated, full text attached.*"
+	TRUNCATED_LINK_NOTICE = "\n\n*Content truncated, see the original message.*"
+)
+
+// Length returns the length of e as Discord counts it against EMBED_MAX_LENGTH.
+func Length(e *discordgo.MessageEmbed) int {
+	n := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	if e.Author != nil {
+		n += utf8.RuneCountInString(e.Author.Name)
+	}
+	if e.Footer != nil {
+		n += utf8.RuneCountInString(e.Footer.Text)
+	}
+	for _, f := range e.Fields {
+		n += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+	return n
+}
+
+// Overflows reports whether Discord would reject e for its size.
+func Overflows(e *discordgo.MessageEmbed) bool {
+	return utf8.RuneCountInString(e.Description) > DESCRIPTION_MAX_LENGTH ||
+		len(e.Fields) > FIELD_MAX_COUNT ||
+		Length(e) > EMBED_MAX_LENGTH
+}
+
+// Fit shrinks e to Discord's limits, cutting the description at a word
+// boundary, closing any code block it cut, and ending it with notice.
+// Fields past the limit are dropped, last first, when shortening the
+// description isn't enough. It reports whether anything was cut.
+func Fit(e *discordgo.MessageEmbed, notice string) bool {
+	if !Overflows(e) {
+		return false
+	}
+
+	if len(e.Fields) > FIELD_MAX_COUNT {
+		e.Fields = e.Fields[:FIELD_MAX_COUNT]
+	}
+
+	noticeLength := utf8.RuneCountInString(notice)
+	for {
+		budget := min(DESCRIPTION_MAX_LENGTH, EMBED_MAX_LENGTH-(Length(e)-utf8.RuneCountInString(e.Description)))
+		if budget >= noticeLength || len(e.Fields) == 0 {
+			// Leave room to close a code block cut in half.
+			limit := max(budget-noticeLength-len("\n```"), 0)
+			e.Description = closeMarkdown(truncateWords(e.Description, limit)) + notice
+			return true
+		}
+		e.Fields = e.Fields[:len(e.Fields)-1]
+	}
+}
+
+// truncateWords cuts s to at most limit characters, including the ellipsis,
+// preferring to break at whitespace near the end.
+func truncateWords(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	if limit <= 1 {
+		return ""
+	}
+
+	cut := runes[:limit-1]
+	// Only back off to a word boundary if it doesn't lose too much text.
+	for i := len(cut) - 1; i >= 0 && i >= len(cut)-100; i-- {
+		if unicode.IsSpace(cut[i]) {
+			cut = cut[:i]
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(cut), unicode.IsSpace) + "…"
+}
@@ -0,0 +1,75 @@
+package embed
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Names maps the IDs mentioned in a message to display names.
+type Names struct {
+	Users    map[string]string
+	Roles    map[string]string
+	Channels map[string]string
+}
+
+var (
+	codePattern    = regexp.MustCompile("(?s)```.*?```|`[^`\n]+`")
+	mentionPattern = regexp.MustCompile(`<(@!?|@&|#)(\d+)>|<(a?):(\w+):(\d+)>`)
+)
+
+// ResolveMentions rewrites mentions into readable names and custom emoji into
+// links to their image, since bookmarks are read in DMs where neither
+// renders. Code spans and blocks are left as written.
+func ResolveMentions(content string, names Names) string {
+	var sb strings.Builder
+	last := 0
+	for _, span := range codePattern.FindAllStringIndex(content, -1) {
+		sb.WriteString(resolveText(content[last:span[0]], names))
+		sb.WriteString(content[span[0]:span[1]])
+		last = span[1]
+	}
+	sb.WriteString(resolveText(content[last:], names))
+	return sb.String()
+}
+
+func resolveText(text string, names Names) string {
+	return mentionPattern.ReplaceAllStringFunc(text, func(m string) string {
+		groups := mentionPattern.FindStringSubmatch(m)
+		if groups[5] != "" {
+			ext := "webp"
+			if groups[3] == "a" {
+				ext = "gif"
+			}
+			return fmt.Sprintf("[:%s:](https://cdn.discordapp.com/emojis/%s.%s)", groups[4], groups[5], ext)
+		}
+
+		id := groups[2]
+		switch groups[1] {
+		case "@&":
+			return "@" + nameOr(names.Roles[id], "unknown-role")
+		case "#":
+			return "#" + nameOr(names.Channels[id], "unknown-channel")
+		default:
+			return "@" + nameOr(names.Users[id], "unknown-user")
+		}
+	})
+}
+
+func nameOr(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// closeMarkdown closes a code block or span left open by truncating s.
+func closeMarkdown(s string) string {
+	if strings.Count(s, "```")%2 == 1 {
+		return s + "\n```"
+	}
+	if strings.Count(strings.ReplaceAll(s, "```", ""), "`")%2 == 1 {
+		return s + "`"
+	}
+	return s
+}
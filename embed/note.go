@@ -0,0 +1,40 @@
+package embed
+
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// NOTE_MAX_LENGTH is Discord's limit on embed field values.
+	NOTE_MAX_LENGTH = 1024
+	NOTE_FIELD_NAME = "📝 Note"
+	TAGS_FIELD_NAME = "Tags"
+)
+
+// AddNote adds the user's note and tags to a bookmark embed.
+func AddNote(e *discordgo.MessageEmbed, note string, tags []string) {
+	if note = strings.TrimSpace(note); note != "" {
+		if runes := []rune(note); len(runes) > NOTE_MAX_LENGTH {
+			note = string(runes[:NOTE_MAX_LENGTH-1]) + "…"
+		}
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{Name: NOTE_FIELD_NAME, Value: note})
+	}
+
+	if len(tags) > 0 {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{Name: TAGS_FIELD_NAME, Value: "#" + strings.Join(tags, " #")})
+	}
+}
+
+// SetNote replaces the note and tags of a bookmark embed.
+func SetNote(e *discordgo.MessageEmbed, note string, tags []string) {
+	fields := e.Fields[:0:0]
+	for _, field := range e.Fields {
+		if field.Name != NOTE_FIELD_NAME && field.Name != TAGS_FIELD_NAME {
+			fields = append(fields, field)
+		}
+	}
+	e.Fields = fields
+	AddNote(e, note, tags)
+}
@@ -0,0 +1,49 @@
+package embed
+
+import (
+	"strings"
+
+	"github.com/anonmiraj/discord-bookmarker/unfurl"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// PREVIEW_MAX_LENGTH is Discord's limit on embed field values.
+	PREVIEW_MAX_LENGTH = 1024
+	PREVIEW_FIELD_NAME = "🔗 Link preview"
+)
+
+// AddLinkPreview adds the preview of the linked page to a bookmark embed,
+// showing its image unless the embed already has one.
+func AddLinkPreview(e *discordgo.MessageEmbed, p unfurl.Preview) {
+	if p.IsZero() {
+		return
+	}
+
+	var lines []string
+	if p.Title != "" {
+		lines = append(lines, "**["+escapeLinkText(p.Title)+"]("+p.URL+")**")
+	}
+	if p.Description != "" {
+		lines = append(lines, p.Description)
+	}
+	if value := strings.Join(lines, "\n"); value != "" {
+		if runes := []rune(value); len(runes) > PREVIEW_MAX_LENGTH {
+			value = string(runes[:PREVIEW_MAX_LENGTH-1]) + "…"
+		}
+		name := PREVIEW_FIELD_NAME
+		if p.SiteName != "" {
+			name = "🔗 " + p.SiteName
+		}
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{Name: name, Value: value})
+	}
+
+	if p.Image != "" && e.Image == nil {
+		e.Image = &discordgo.MessageEmbedImage{URL: p.Image}
+	}
+}
+
+// escapeLinkText keeps brackets in a title from ending the link text early.
+func escapeLinkText(s string) string {
+	return strings.NewReplacer("[", "\\[", "]", "\\]").Replace(s)
+}
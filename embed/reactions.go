@@ -0,0 +1,75 @@
+package embed
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	REACTIONS_FIELD_NAME = "Reactions"
+	// REACTIONS_MAX_SHOWN caps the reactions listed, most used first.
+	REACTIONS_MAX_SHOWN = 10
+)
+
+// SetReactions adds or replaces the snapshot of msg's reactions and thread
+// replies taken at the given time, e.g. "👍 12 · ❤️ 5". Messages without
+// either get no field.
+func SetReactions(e *discordgo.MessageEmbed, msg *discordgo.Message, at time.Time) {
+	fields := e.Fields[:0:0]
+	index := -1
+	for _, field := range e.Fields {
+		if field.Name == REACTIONS_FIELD_NAME {
+			index = len(fields)
+			continue
+		}
+		fields = append(fields, field)
+	}
+	e.Fields = fields
+
+	value := reactionSummary(msg)
+	if value == "" {
+		return
+	}
+	field := &discordgo.MessageEmbedField{Name: REACTIONS_FIELD_NAME, Value: fmt.Sprintf("%s\n*as of <t:%d:R>*", value, at.Unix())}
+	if index < 0 {
+		e.Fields = append(e.Fields, field)
+		return
+	}
+	e.Fields = append(e.Fields[:index], append([]*discordgo.MessageEmbedField{field}, e.Fields[index:]...)...)
+}
+
+func reactionSummary(msg *discordgo.Message) string {
+	reactions := make([]*discordgo.MessageReactions, 0, len(msg.Reactions))
+	for _, r := range msg.Reactions {
+		if r.Emoji != nil && r.Count > 0 {
+			reactions = append(reactions, r)
+		}
+	}
+	sort.SliceStable(reactions, func(i, j int) bool { return reactions[i].Count > reactions[j].Count })
+
+	var counts []string
+	for i, r := range reactions {
+		if i == REACTIONS_MAX_SHOWN {
+			counts = append(counts, fmt.Sprintf("+%d more", len(reactions)-i))
+			break
+		}
+		counts = append(counts, fmt.Sprintf("%s %d", r.Emoji.MessageFormat(), r.Count))
+	}
+
+	var lines []string
+	if len(counts) > 0 {
+		lines = append(lines, strings.Join(counts, " · "))
+	}
+	if msg.Thread != nil && msg.Thread.MessageCount > 0 {
+		replies := "replies"
+		if msg.Thread.MessageCount == 1 {
+			replies = "reply"
+		}
+		lines = append(lines, fmt.Sprintf("💬 %d %s in thread", msg.Thread.MessageCount, replies))
+	}
+	return strings.Join(lines, "\n")
+}
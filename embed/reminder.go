@@ -0,0 +1,27 @@
+package embed
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const REMINDER_FIELD_NAME = "⏰ Reminder"
+
+// SetReminder shows when the user will be reminded of a bookmark, as a
+// relative timestamp each reader's client renders, or removes the field
+// without a reminder.
+func SetReminder(e *discordgo.MessageEmbed, at *time.Time) {
+	fields := e.Fields[:0:0]
+	for _, field := range e.Fields {
+		if field.Name != REMINDER_FIELD_NAME {
+			fields = append(fields, field)
+		}
+	}
+	e.Fields = fields
+
+	if at != nil {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{Name: REMINDER_FIELD_NAME, Value: fmt.Sprintf("<t:%d:R>", at.Unix())})
+	}
+}
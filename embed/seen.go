@@ -0,0 +1,44 @@
+package embed
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// SEEN_IN_FIELD_PREFIX starts the name of the field listing where the
+// content of a bookmark was posted, e.g. "Seen in 3 places".
+const SEEN_IN_FIELD_PREFIX = "Seen in "
+
+// SetSeenIn adds or replaces the field linking every message a bookmark's
+// content was seen in. A single source gets no field.
+func SetSeenIn(e *discordgo.MessageEmbed, links []string) {
+	fields := e.Fields[:0:0]
+	for _, field := range e.Fields {
+		if !strings.HasPrefix(field.Name, SEEN_IN_FIELD_PREFIX) {
+			fields = append(fields, field)
+		}
+	}
+	e.Fields = fields
+	if len(links) < 2 {
+		return
+	}
+
+	var value string
+	for i, link := range links {
+		entry := fmt.Sprintf("[#%d](%s)", i+1, link)
+		if i > 0 {
+			entry = " · " + entry
+		}
+		if len(value)+len(entry) > NOTE_MAX_LENGTH-len(" · …") {
+			value += " · …"
+			break
+		}
+		value += entry
+	}
+	e.Fields = append(e.Fields, &discordgo.MessageEmbedField{
+		Name:  fmt.Sprintf("%s%d places", SEEN_IN_FIELD_PREFIX, len(links)),
+		Value: value,
+	})
+}
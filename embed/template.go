@@ -0,0 +1,155 @@
+// Package embed builds the bookmark embeds delivered to users.
+package embed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Template controls the layout of bookmark embeds. Title, description
+// and footer are text/templates executed against Data.
+type Template struct {
+	Title       *template.Template
+	Description *template.Template
+	Footer      *template.Template
+	Color       int
+
+	ShowAuthor      bool
+	ShowSource      bool
+	ShowImage       bool
+	ShowAttachments bool
+	// ShowReactions adds a snapshot of the message's reactions and replies.
+	ShowReactions bool
+}
+
+// Data is the data available to embed templates.
+type Data struct {
+	GuildName   string
+	ChannelID   string
+	MessageID   string
+	MessageLink string
+	Author      string
+	Content     string
+	Timestamp   time.Time
+	Attachments int
+}
+
+// templateFile is the on-disk JSON form of a Template. Omitted
+// keys keep their default value.
+type templateFile struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Footer      *string `json:"footer"`
+	Color       *string `json:"color"`
+	Fields      struct {
+		Author      *bool `json:"author"`
+		Source      *bool `json:"source"`
+		Image       *bool `json:"image"`
+		Attachments *bool `json:"attachments"`
+		Reactions   *bool `json:"reactions"`
+	} `json:"fields"`
+}
+
+const (
+	DEFAULT_TITLE_TEMPLATE       = "Bookmark from {{.GuildName}}"
+	DEFAULT_DESCRIPTION_TEMPLATE = "{{.Content}}"
+	DEFAULT_FOOTER_TEMPLATE      = "React with ❌ to remove this bookmark"
+	DEFAULT_EMBED_COLOR          = 0x3498db
+)
+
+// DefaultTemplate returns the built-in embed layout.
+func DefaultTemplate() *Template {
+	return &Template{
+		Title:           template.Must(template.New("title").Parse(DEFAULT_TITLE_TEMPLATE)),
+		Description:     template.Must(template.New("description").Parse(DEFAULT_DESCRIPTION_TEMPLATE)),
+		Footer:          template.Must(template.New("footer").Parse(DEFAULT_FOOTER_TEMPLATE)),
+		Color:           DEFAULT_EMBED_COLOR,
+		ShowAuthor:      true,
+		ShowSource:      true,
+		ShowImage:       true,
+		ShowAttachments: true,
+		ShowReactions:   true,
+	}
+}
+
+// LoadTemplate reads and validates an embed template file. An empty path
+// yields the default template.
+func LoadTemplate(path string) (*Template, error) {
+	tmpl := DefaultTemplate()
+	if path == "" {
+		return tmpl, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file templateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	parse := func(name string, text *string, dst **template.Template) error {
+		if text == nil {
+			return nil
+		}
+		t, err := template.New(name).Option("missingkey=error").Parse(*text)
+		if err != nil {
+			return err
+		}
+		*dst = t
+		return nil
+	}
+	if err := parse("title", file.Title, &tmpl.Title); err != nil {
+		return nil, err
+	}
+	if err := parse("description", file.Description, &tmpl.Description); err != nil {
+		return nil, err
+	}
+	if err := parse("footer", file.Footer, &tmpl.Footer); err != nil {
+		return nil, err
+	}
+
+	if file.Color != nil {
+		color, err := strconv.ParseUint(strings.TrimPrefix(*file.Color, "#"), 16, 24)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color %q: %w", *file.Color, err)
+		}
+		tmpl.Color = int(color)
+	}
+
+	setBool := func(src *bool, dst *bool) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setBool(file.Fields.Author, &tmpl.ShowAuthor)
+	setBool(file.Fields.Source, &tmpl.ShowSource)
+	setBool(file.Fields.Image, &tmpl.ShowImage)
+	setBool(file.Fields.Attachments, &tmpl.ShowAttachments)
+	setBool(file.Fields.Reactions, &tmpl.ShowReactions)
+
+	// Render sample data so references to unknown fields fail at load time.
+	sample := Data{GuildName: "guild", Author: "author", Content: "content", Timestamp: time.Now()}
+	for _, t := range []*template.Template{tmpl.Title, tmpl.Description, tmpl.Footer} {
+		if _, err := render(t, sample); err != nil {
+			return nil, err
+		}
+	}
+
+	return tmpl, nil
+}
+
+func render(t *template.Template, data Data) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
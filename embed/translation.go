@@ -0,0 +1,32 @@
+package embed
+
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	TRANSLATION_FIELD_NAME = "🌐 Translation"
+	// TRANSLATION_MAX_LENGTH is Discord's limit on embed field values.
+	TRANSLATION_MAX_LENGTH = 1024
+)
+
+// AddTranslation adds a field with the message text translated into the
+// user's language, below the original.
+func AddTranslation(e *discordgo.MessageEmbed, translation string, spoiler bool) {
+	translation = strings.TrimSpace(translation)
+	if translation == "" {
+		return
+	}
+
+	limit := TRANSLATION_MAX_LENGTH - len("||…||")
+	if runes := []rune(translation); len(runes) > limit {
+		translation = string(runes[:limit]) + "…"
+	}
+	if spoiler {
+		translation = "||" + translation + "||"
+	}
+
+	e.Fields = append(e.Fields, &discordgo.MessageEmbedField{Name: TRANSLATION_FIELD_NAME, Value: translation})
+}
@@ -0,0 +1,137 @@
+// Package integration forwards new bookmarks to a user's own tools: any
+// webhook accepting JSON, or a Notion database.
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const (
+	// INTEGRATION_TIMEOUT bounds a single outbound request.
+	INTEGRATION_TIMEOUT = 15 * time.Second
+	// EVENT_BOOKMARK_CREATED is the event of the webhook payload.
+	EVENT_BOOKMARK_CREATED = "bookmark.created"
+)
+
+// Bookmark is the payload sent for a new bookmark.
+type Bookmark struct {
+	Event       string    `json:"event"`
+	UserID      string    `json:"user_id"`
+	GuildID     string    `json:"guild_id"`
+	GuildName   string    `json:"guild_name"`
+	ChannelID   string    `json:"channel_id"`
+	MessageID   string    `json:"message_id"`
+	Link        string    `json:"link"`
+	Author      string    `json:"author"`
+	AuthorID    string    `json:"author_id"`
+	Content     string    `json:"content"`
+	Note        string    `json:"note,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Attachments []string  `json:"attachments,omitempty"`
+	SentAt      time.Time `json:"sent_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Sink receives new bookmarks.
+type Sink interface {
+	Send(ctx context.Context, bm Bookmark) error
+}
+
+var errPrivateAddress = errors.New("refusing to connect to a private address")
+
+// NewClient returns an HTTP client that only connects to public addresses,
+// since webhook URLs are chosen by users.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: INTEGRATION_TIMEOUT,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   INTEGRATION_TIMEOUT,
+		Transport: transport,
+	}
+}
+
+func isPublic(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() || ip.IsInterfaceLocalMulticast())
+}
+
+// ValidateWebhookURL checks that a user supplied webhook URL is an absolute
+// https URL. Where it resolves to is checked when connecting.
+func ValidateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errors.New("not a valid URL")
+	}
+	if u.Scheme != "https" {
+		return errors.New("the URL must start with https://")
+	}
+	return nil
+}
+
+// Webhook POSTs bookmarks as JSON to a URL.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhook(url string, client *http.Client) *Webhook {
+	return &Webhook{URL: url, Client: client}
+}
+
+func (w *Webhook) Send(ctx context.Context, bm Bookmark) error {
+	return postJSON(ctx, w.Client, w.URL, nil, bm)
+}
+
+// postJSON posts body and fails on any status but 2xx.
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "discord-bookmarker")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookPostsJSON(t *testing.T) {
+	var got Bookmark
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("content type = %q", ct)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	bm := Bookmark{Event: EVENT_BOOKMARK_CREATED, UserID: "user", Content: "hello", Tags: []string{"a"}}
+	if err := NewWebhook(srv.URL, srv.Client()).Send(context.Background(), bm); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got.UserID != "user" || got.Content != "hello" || len(got.Tags) != 1 {
+		t.Errorf("posted %+v", got)
+	}
+}
+
+func TestWebhookReportsErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	err := NewWebhook(srv.URL, srv.Client()).Send(context.Background(), Bookmark{})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Send error = %v, want the status", err)
+	}
+}
+
+func TestNotionCreatesPage(t *testing.T) {
+	var page struct {
+		Parent struct {
+			DatabaseID string `json:"database_id"`
+		} `json:"parent"`
+		Properties struct {
+			Title struct {
+				Title []struct {
+					Text struct {
+						Content string `json:"content"`
+					} `json:"text"`
+				} `json:"title"`
+			} `json:"title"`
+		} `json:"properties"`
+		Children []map[string]any `json:"children"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pages" || r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Notion-Version") == "" {
+			t.Errorf("request %s %v", r.URL.Path, r.Header)
+		}
+		json.NewDecoder(r.Body).Decode(&page)
+	}))
+	defer srv.Close()
+
+	notion := NewNotion("secret", "db", srv.Client())
+	notion.BaseURL = srv.URL
+	bm := Bookmark{Content: "first line\nsecond line", Note: "later", Link: "https://discord.com/channels/1/2/3", GuildName: "Guild"}
+	if err := notion.Send(context.Background(), bm); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if page.Parent.DatabaseID != "db" {
+		t.Errorf("database ID = %q", page.Parent.DatabaseID)
+	}
+	if title := page.Properties.Title.Title; len(title) != 1 || title[0].Text.Content != "first line" {
+		t.Errorf("title = %+v", title)
+	}
+	// Content, note and the link back to Discord.
+	if len(page.Children) != 3 {
+		t.Errorf("page has %d blocks, want 3", len(page.Children))
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("connected to a loopback address")
+	}))
+	defer srv.Close()
+
+	err := NewWebhook(srv.URL, NewClient()).Send(context.Background(), Bookmark{})
+	if err == nil || !strings.Contains(err.Error(), errPrivateAddress.Error()) {
+		t.Errorf("Send error = %v, want %v", err, errPrivateAddress)
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	for raw, valid := range map[string]bool{
+		"https://example.com/hook": true,
+		"http://example.com/hook":  false,
+		"example.com/hook":         false,
+		"https://":                 false,
+	} {
+		if err := ValidateWebhookURL(raw); (err == nil) != valid {
+			t.Errorf("ValidateWebhookURL(%q) = %v, want valid %t", raw, err, valid)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const (
+	NOTION_API_URL = "https://api.notion.com/v1"
+	NOTION_VERSION = "2022-06-28"
+	// NOTION_TEXT_MAX_LENGTH is Notion's limit on a rich text object.
+	NOTION_TEXT_MAX_LENGTH = 2000
+	NOTION_TITLE_LENGTH    = 100
+)
+
+// Notion adds bookmarks as pages of a database. The integration of the
+// token must have been given access to the database.
+type Notion struct {
+	Token      string
+	DatabaseID string
+	// BaseURL is NOTION_API_URL, except in tests.
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewNotion(token, databaseID string, client *http.Client) *Notion {
+	return &Notion{Token: token, DatabaseID: databaseID, BaseURL: NOTION_API_URL, Client: client}
+}
+
+// Send creates a page titled after the start of the message, with the
+// content, note and a link back to Discord. Only the database's title
+// property is set, so it works with any database.
+func (n *Notion) Send(ctx context.Context, bm Bookmark) error {
+	title := strings.TrimSpace(strings.SplitN(bm.Content, "\n", 2)[0])
+	if runes := []rune(title); len(runes) > NOTION_TITLE_LENGTH {
+		title = string(runes[:NOTION_TITLE_LENGTH]) + "…"
+	}
+	if title == "" {
+		title = "Bookmark from " + bm.GuildName
+	}
+
+	var children []any
+	for _, chunk := range chunkText(bm.Content, NOTION_TEXT_MAX_LENGTH) {
+		children = append(children, block("paragraph", map[string]any{"rich_text": richText(chunk)}))
+	}
+	if bm.Note != "" {
+		children = append(children, block("callout", map[string]any{
+			"rich_text": richText(truncate(bm.Note, NOTION_TEXT_MAX_LENGTH)),
+			"icon":      map[string]any{"emoji": "📝"},
+		}))
+	}
+	for _, url := range bm.Attachments {
+		children = append(children, block("bookmark", map[string]any{"url": url}))
+	}
+	children = append(children, block("bookmark", map[string]any{
+		"url":     bm.Link,
+		"caption": richText(truncate("by "+bm.Author+" in "+bm.GuildName, NOTION_TEXT_MAX_LENGTH)),
+	}))
+
+	page := map[string]any{
+		"parent": map[string]any{"database_id": n.DatabaseID},
+		"properties": map[string]any{
+			"title": map[string]any{"title": richText(title)},
+		},
+		"children": children,
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+n.Token)
+	header.Set("Notion-Version", NOTION_VERSION)
+	return postJSON(ctx, n.Client, n.BaseURL+"/pages", header, page)
+}
+
+func block(typ string, content map[string]any) map[string]any {
+	return map[string]any{"object": "block", "type": typ, typ: content}
+}
+
+func richText(s string) []any {
+	return []any{map[string]any{"type": "text", "text": map[string]any{"content": s}}}
+}
+
+// chunkText splits s into pieces of at most limit runes.
+func chunkText(s string, limit int) []string {
+	var chunks []string
+	runes := []rune(s)
+	for len(runes) > 0 {
+		n := min(limit, len(runes))
+		chunks = append(chunks, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return chunks
+}
+
+func truncate(s string, limit int) string {
+	if runes := []rune(s); len(runes) > limit {
+		return string(runes[:limit-1]) + "…"
+	}
+	return s
+}
@@ -1,253 +1,261 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"os/signal"
+	"sort"
 	"strings"
-	"syscall"
 	"time"
 
+	"github.com/anonmiraj/discord-bookmarker/bot"
+	"github.com/anonmiraj/discord-bookmarker/config"
+	"github.com/anonmiraj/discord-bookmarker/embed"
+	"github.com/anonmiraj/discord-bookmarker/store"
+	"github.com/anonmiraj/discord-bookmarker/telemetry"
 	"github.com/bwmarrin/discordgo"
-	"github.com/joho/godotenv"
 )
 
-const (
-	BOOKMARK_EMOJI = "🔖"
-	DELETE_EMOJI   = "❌"
-)
+// TRACING_FLUSH_TIMEOUT bounds exporting the last spans on shutdown.
+const TRACING_FLUSH_TIMEOUT = 5 * time.Second
+
+// command is a subcommand of the binary. Flags are the shared configuration
+// flags; args are the arguments left after them.
+type command struct {
+	usage   string
+	summary string
+	run     func(cfg config.Config, args []string) error
+}
 
+// envFile and flagArgs are where the configuration was loaded from, for
+// the running bot to reload it.
 var (
-	logger *log.Logger
+	envFile  *config.EnvFile
+	flagArgs []string
 )
 
+var commands = map[string]command{
+	"run":               {"run [flags]", "start the bot (default)", runBot},
+	"migrate":           {"migrate [flags]", "apply pending database migrations", migrateStore},
+	"register-commands": {"register-commands [flags]", "sync the slash and message commands with Discord", registerCommands},
+	"export-user":       {"export-user [flags] <user-id>", "print a user's bookmarks as JSON", exportUser},
+	"backup":            {"backup [flags] <file>", "write a backup of the whole store", backupStore},
+	"restore":           {"restore [flags] <file>", "replace the whole store with a backup", restoreStore},
+	"rotate-key":        {"rotate-key [flags]", "re-encrypt bookmarks with ENCRYPTION_KEY", rotateKey},
+}
+
 func main() {
-	logFile, err := os.OpenFile("bookmark-bot.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		log.Fatalf("Error opening log file: %v", err)
+	// Without a subcommand the bot runs, as it did before subcommands existed.
+	name, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
-	defer logFile.Close()
-	logger = log.New(logFile, "", log.Ldate|log.Ltime|log.Lshortfile)
-	godotenv.Load()
-
-	token := os.Getenv("DISCORD_TOKEN")
-	if token == "" {
-		logger.Fatal("DISCORD_TOKEN not set in environment")
+	if name == "help" {
+		usage()
+		return
 	}
-
-	dg, err := discordgo.New("Bot " + token)
-	if err != nil {
-		logger.Fatalf("Error creating Discord session: %v", err)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
 	}
 
-	dg.AddHandler(reactionAdd)
-	dg.AddHandler(dmReactionAdd)
-
-	dg.Identify.Intents = discordgo.IntentsGuilds |
-		discordgo.IntentsGuildMessages |
-		discordgo.IntentsGuildMessageReactions |
-		discordgo.IntentsDirectMessages |
-		discordgo.IntentsDirectMessageReactions
-
-	err = dg.Open()
+	var err error
+	envFile, err = config.LoadEnvFile(config.ENV_FILE)
 	if err != nil {
-		logger.Fatalf("Error opening connection: %v", err)
+		log.Printf("Error reading %s: %v", config.ENV_FILE, err)
+		os.Exit(EXIT_CONFIG)
 	}
-	defer dg.Close()
-
-	fmt.Println("Bot is now running. Press CTRL-C to exit.")
-	sc := make(chan os.Signal, 1)
-	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
-	<-sc
-}
+	flagArgs = args
 
-func extractMessageInfoFromLink(messageLink string) (channelID, messageID string, ok bool) {
-	parts := strings.Split(messageLink, "/")
-	if len(parts) < 3 {
-		logger.Printf("Error: Invalid message link format: %s", messageLink)
-		return "", "", false
-	}
-	
-	messageID = parts[len(parts)-1]
-	channelID = parts[len(parts)-2]
-	
-	return channelID, messageID, true
-}
-
-func reactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
-	if r.UserID == s.State.User.ID {
+	cfg, args, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
 		return
 	}
-
-	channelInfo, err := s.Channel(r.ChannelID)
 	if err != nil {
-		logger.Printf("Error getting channel info for channel %s: %v", r.ChannelID, err)
-		return
+		log.Printf("Error in configuration: %v", err)
+		os.Exit(EXIT_CONFIG)
 	}
 
-	if channelInfo.Type == discordgo.ChannelTypeDM {
-		return
+	if err := cmd.run(cfg, args); err != nil {
+		log.Printf("Error: %v", err)
+		os.Exit(exitCode(err))
 	}
+}
 
-	if r.Emoji.Name != BOOKMARK_EMOJI {
-		return
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	logger.Printf("Processing bookmark reaction from user %s in channel %s:%s", r.UserID, r.ChannelID, r.MessageID)
-
-	msg, err := s.ChannelMessage(r.ChannelID, r.MessageID)
-	if err != nil {
-		logger.Printf("Error getting message %s from channel %s: %v", r.MessageID, r.ChannelID, err)
-		return
+	fmt.Fprintln(os.Stderr, "Usage: discord-bookmarker <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-40s %s\n", commands[name].usage, commands[name].summary)
 	}
+	fmt.Fprintln(os.Stderr, "\nRun discord-bookmarker <command> -h to list the flags.")
+}
 
-	user, err := s.User(r.UserID)
+// runBot runs the bot until it's stopped. Errors in the configuration and
+// failures to reach Discord end it with their own exit codes, see EXIT_CONFIG.
+func runBot(cfg config.Config, args []string) error {
+	logger, closeLog, err := openLog(cfg)
 	if err != nil {
-		logger.Printf("Error getting user info for user %s: %v", r.UserID, err)
-		return
+		return err
 	}
+	defer closeLog()
 
-	guild, err := s.Guild(channelInfo.GuildID)
-	if err != nil {
-		logger.Printf("Error getting guild info for guild %s: %v", channelInfo.GuildID, err)
-		return
+	if cfg.PIDFile != "" {
+		removePIDFile, err := writePIDFile(cfg.PIDFile)
+		if err != nil {
+			return fmt.Errorf("writing PID file: %w", err)
+		}
+		defer removePIDFile()
 	}
 
-	messageLink := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", channelInfo.GuildID, r.ChannelID, r.MessageID)
-
-	embed := createBookmarkEmbed(msg, guild.Name, messageLink)
+	return runService(func(stop <-chan string) error {
+		err := serveBot(cfg, logger, stop)
+		if err != nil {
+			logger.Printf("Error: %v", err)
+		}
+		return err
+	})
+}
 
-	dmChannel, err := s.UserChannelCreate(user.ID)
+// openLog opens the configured log, creating the log directory if needed.
+func openLog(cfg config.Config) (*log.Logger, func(), error) {
+	if cfg.LogStdout {
+		return log.New(os.Stdout, "", log.Ldate|log.Ltime|log.Lshortfile), func() {}, nil
+	}
+	if cfg.LogDir != "" {
+		if err := os.MkdirAll(cfg.LogDir, 0755); err != nil {
+			return nil, nil, configError(fmt.Errorf("creating log directory: %w", err))
+		}
+	}
+	logFile, err := os.OpenFile(cfg.LogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
-		logger.Printf("Error creating DM channel with user %s (%s): %v", user.Username, user.ID, err)
-		return
+		return nil, nil, configError(fmt.Errorf("opening log file: %w", err))
 	}
+	return log.New(logFile, "", log.Ldate|log.Ltime|log.Lshortfile), func() { logFile.Close() }, nil
+}
 
-	sentMsg, err := s.ChannelMessageSendEmbed(dmChannel.ID, embed)
-	if err != nil {
-		logger.Printf("Error sending bookmark embed to user %s (%s): %v", user.Username, user.ID, err)
-		return
+// serveBot connects the bot and serves events until stop receives the reason to stop.
+func serveBot(cfg config.Config, logger *log.Logger, stop <-chan string) error {
+	if cfg.Tracing {
+		shutdown, err := telemetry.Enable(context.Background())
+		if err != nil {
+			return configError(fmt.Errorf("enabling tracing: %w", err))
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), TRACING_FLUSH_TIMEOUT)
+			defer cancel()
+			if err := shutdown(ctx); err != nil {
+				logger.Printf("Error flushing traces: %v", err)
+			}
+		}()
 	}
 
-	err = s.MessageReactionAdd(dmChannel.ID, sentMsg.ID, DELETE_EMOJI)
+	tmpl, err := embed.LoadTemplate(cfg.EmbedTemplate)
 	if err != nil {
-		logger.Printf("Error adding delete reaction to bookmark message for user %s: %v", user.Username, err)
+		return configError(fmt.Errorf("loading embed template %s: %w", cfg.EmbedTemplate, err))
 	}
 
-	logger.Printf("Successfully sent bookmark to user %s (%s) from guild %s", user.Username, user.ID, guild.Name)
-}
-
-func dmReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
-	if r.UserID == s.State.User.ID {
-		return
+	st, err := openStore(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("opening %s bookmark store: %w", cfg.StoreDriver, err)
 	}
+	defer st.Close()
 
-	channelInfo, err := s.Channel(r.ChannelID)
+	dg, err := newSession(cfg)
 	if err != nil {
-		logger.Printf("Error getting DM channel info for channel %s: %v", r.ChannelID, err)
-		return
+		return configError(err)
 	}
 
-	if channelInfo.Type != discordgo.ChannelTypeDM {
-		return
-	}
+	b := bot.New(dg, dg.State, st, cfg, tmpl, logger)
+	b.Register(dg)
 
-	if r.Emoji.Name != DELETE_EMOJI {
-		return
+	dg.ShouldReconnectOnError = true
+
+	dg.Identify.Intents = discordgo.IntentsGuilds |
+		discordgo.IntentsGuildMessages |
+		discordgo.IntentsMessageContent |
+		discordgo.IntentsGuildMessageReactions |
+		discordgo.IntentsGuildBans |
+		discordgo.IntentsDirectMessages |
+		discordgo.IntentsDirectMessageReactions
+
+	if cfg.HealthAddr != "" {
+		go b.ServeHealth(cfg.HealthAddr)
 	}
 
-	logger.Printf("Processing delete reaction from user %s in DM", r.UserID)
+	stopWatching := make(chan struct{})
+	defer close(stopWatching)
+	watchConfig(b, cfg, logger, stopWatching)
 
-	msg, err := s.ChannelMessage(r.ChannelID, r.MessageID)
+	err = dg.Open()
 	if err != nil {
-		logger.Printf("Error getting DM message %s from channel %s: %v", r.MessageID, r.ChannelID, err)
-		return
+		return openError(fmt.Errorf("opening connection: %w", err))
 	}
+	defer dg.Close()
 
-	if len(msg.Embeds) == 0 {
-		logger.Printf("Warning: User %s reacted to delete on a message with no embeds", r.UserID)
-		return
+	if err := sdNotify("READY=1"); err != nil {
+		logger.Printf("Error notifying systemd: %v", err)
 	}
+	startWatchdog(b, logger, stopWatching)
 
-	embed := msg.Embeds[0]
-	var messageLink string
-	
-	for _, field := range embed.Fields {
-		if field.Name == "Source" {
-			start := strings.Index(field.Value, "(")
-			end := strings.Index(field.Value, ")")
-			if start != -1 && end != -1 && end > start {
-				messageLink = field.Value[start+1 : end]
-			}
-			break
-		}
-	}
+	fmt.Println("Bot is now running. Press CTRL-C to exit.")
+	reason := <-stop
 
-	if messageLink == "" {
-		logger.Printf("Error: Could not extract message link from bookmark embed for user %s", r.UserID)
-		return
-	}
+	logger.Printf("Received %s, shutting down", reason)
+	sdNotify("STOPPING=1")
+	b.Shutdown()
+	return nil
+}
 
-	channelID, messageID, ok := extractMessageInfoFromLink(messageLink)
-	if !ok {
-		logger.Printf("Error: Failed to parse message link %s for user %s", messageLink, r.UserID)
-		return
+func newSession(cfg config.Config) (*discordgo.Session, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("DISCORD_TOKEN not set in environment")
 	}
 
-	err = s.MessageReactionRemove(channelID, messageID, BOOKMARK_EMOJI, r.UserID)
+	dg, err := discordgo.New("Bot " + cfg.Token)
 	if err != nil {
-		logger.Printf("Error removing bookmark reaction from original message (channel: %s, message: %s, user: %s): %v", channelID, messageID, r.UserID, err)
+		return nil, fmt.Errorf("creating Discord session: %w", err)
 	}
+	return dg, nil
+}
 
-	err = s.ChannelMessageDelete(r.ChannelID, r.MessageID)
+// openStore opens the configured store, encrypting bookmark text at rest
+// if an encryption key is set.
+func openStore(cfg config.Config, logger *log.Logger) (store.BookmarkStore, error) {
+	var st store.BookmarkStore
+	var err error
+	if cfg.StoreDriver == config.STORE_JSON {
+		st, err = store.Open(cfg.StoreFile)
+	} else {
+		st, err = store.OpenSQL(cfg.StoreDriver, cfg.StoreDSN, logger)
+	}
 	if err != nil {
-		logger.Printf("Error deleting bookmark message from DM (channel: %s, message: %s): %v", r.ChannelID, r.MessageID, err)
-		return
+		return nil, err
 	}
 
-	logger.Printf("Successfully processed bookmark deletion for user %s", r.UserID)
-}
-
-func createBookmarkEmbed(msg *discordgo.Message, guildName, messageLink string) *discordgo.MessageEmbed {
-	embed := &discordgo.MessageEmbed{
-		Title:       fmt.Sprintf("Bookmark from %s", guildName),
-		Description: msg.Content,
-		Timestamp:   msg.Timestamp.Format(time.RFC3339),
-		Color:       0x3498db,
-		Author: &discordgo.MessageEmbedAuthor{
-			Name:    msg.Author.Username,
-			IconURL: msg.Author.AvatarURL(""),
-		},
-		Fields: []*discordgo.MessageEmbedField{
-			{
-				Name:   "Source",
-				Value:  fmt.Sprintf("[Jump to message](%s)", messageLink),
-				Inline: false,
-			},
-		},
-		Footer: &discordgo.MessageEmbedFooter{
-			Text: "React with ❌ to remove this bookmark",
-		},
-	}
-
-	for _, a := range msg.Attachments {
-		if strings.HasPrefix(a.ContentType, "image/") {
-			embed.Image = &discordgo.MessageEmbedImage{URL: a.URL}
-			break
-		}
+	keys, err := cfg.EncryptionKeys()
+	if err != nil {
+		st.Close()
+		return nil, err
 	}
-
-	for i, a := range msg.Attachments {
-		if embed.Image != nil && embed.Image.URL == a.URL {
-			continue
-		}
-		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-			Name:   fmt.Sprintf("Attachment %d", i+1),
-			Value:  fmt.Sprintf("[%s](%s)", a.Filename, a.URL),
-			Inline: false,
-		})
+	if keys == nil {
+		return st, nil
 	}
-
-	return embed
+	c, err := store.NewCipher(keys...)
+	if err != nil {
+		st.Close()
+		return nil, err
+	}
+	return store.Encrypted(st, c, logger), nil
 }
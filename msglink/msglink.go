@@ -0,0 +1,89 @@
+// Package msglink parses and builds Discord message links.
+package msglink
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DM_GUILD is the guild segment of links to messages outside of guilds.
+const DM_GUILD = "@me"
+
+var (
+	ErrInvalidLink = errors.New("not a Discord message link")
+
+	hosts = map[string]bool{
+		"discord.com":           true,
+		"www.discord.com":       true,
+		"ptb.discord.com":       true,
+		"canary.discord.com":    true,
+		"discordapp.com":        true,
+		"ptb.discordapp.com":    true,
+		"canary.discordapp.com": true,
+	}
+
+	snowflake = regexp.MustCompile(`^[0-9]{15,21}$`)
+	candidate = regexp.MustCompile(`https?://[A-Za-z0-9.-]+/channels/(?:@me|[0-9]+)/[0-9]+/[0-9]+`)
+)
+
+// Link identifies a message by its guild, channel and message IDs. GuildID is
+// DM_GUILD for direct messages.
+type Link struct {
+	GuildID   string
+	ChannelID string
+	MessageID string
+}
+
+// Parse validates a message link of the form
+// https://discord.com/channels/<guild>/<channel>/<message>, accepting the ptb
+// and canary clients and the legacy discordapp.com domain.
+func Parse(raw string) (Link, error) {
+	u, err := url.Parse(strings.Trim(strings.TrimSpace(raw), "<>"))
+	if err != nil {
+		return Link{}, ErrInvalidLink
+	}
+
+	if (u.Scheme != "https" && u.Scheme != "http") || !hosts[strings.ToLower(u.Host)] {
+		return Link{}, ErrInvalidLink
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "channels" {
+		return Link{}, ErrInvalidLink
+	}
+
+	l := Link{GuildID: parts[1], ChannelID: parts[2], MessageID: parts[3]}
+	if (l.GuildID != DM_GUILD && !snowflake.MatchString(l.GuildID)) ||
+		!snowflake.MatchString(l.ChannelID) || !snowflake.MatchString(l.MessageID) {
+		return Link{}, ErrInvalidLink
+	}
+
+	return l, nil
+}
+
+// Find returns every valid message link in text, in order of appearance.
+func Find(text string) []Link {
+	var links []Link
+	for _, match := range candidate.FindAllString(text, -1) {
+		if l, err := Parse(match); err == nil {
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+// New builds the link of a message; an empty guildID denotes a DM.
+func New(guildID, channelID, messageID string) Link {
+	if guildID == "" {
+		guildID = DM_GUILD
+	}
+	return Link{GuildID: guildID, ChannelID: channelID, MessageID: messageID}
+}
+
+// String returns the canonical https://discord.com link.
+func (l Link) String() string {
+	return fmt.Sprintf("https://discord.com/channels/%s/%s/%s", l.GuildID, l.ChannelID, l.MessageID)
+}
@@ -0,0 +1,73 @@
+package msglink
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	want := Link{GuildID: "111111111111111111", ChannelID: "222222222222222222", MessageID: "333333333333333333"}
+
+	for _, raw := range []string{
+		"https://discord.com/channels/111111111111111111/222222222222222222/333333333333333333",
+		"https://ptb.discord.com/channels/111111111111111111/222222222222222222/333333333333333333",
+		"https://canary.discord.com/channels/111111111111111111/222222222222222222/333333333333333333",
+		"https://discordapp.com/channels/111111111111111111/222222222222222222/333333333333333333",
+		"<https://discord.com/channels/111111111111111111/222222222222222222/333333333333333333>",
+		"https://discord.com/channels/111111111111111111/222222222222222222/333333333333333333?foo=bar",
+	} {
+		got, err := Parse(raw)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", raw, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Parse(%q) = %+v, want %+v", raw, got, want)
+		}
+	}
+}
+
+func TestParseDM(t *testing.T) {
+	link, err := Parse("https://discord.com/channels/@me/222222222222222222/333333333333333333")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.GuildID != DM_GUILD {
+		t.Errorf("guild = %q, want %q", link.GuildID, DM_GUILD)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"https://example.com/channels/111111111111111111/222222222222222222/333333333333333333",
+		"https://discord.com.evil.example/channels/111111111111111111/222222222222222222/333333333333333333",
+		"ftp://discord.com/channels/111111111111111111/222222222222222222/333333333333333333",
+		"https://discord.com/channels/111111111111111111/222222222222222222",
+		"https://discord.com/channels/guild/channel/message",
+		"https://discord.com/invite/111111111111111111/222222222222222222/333333333333333333",
+	} {
+		if _, err := Parse(raw); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", raw)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	text := "look at https://discord.com/channels/111111111111111111/222222222222222222/333333333333333333, " +
+		"and <https://canary.discord.com/channels/@me/444444444444444444/555555555555555555> " +
+		"but not https://example.com/channels/1/2/3"
+
+	links := Find(text)
+	if len(links) != 2 {
+		t.Fatalf("found %d links, want 2: %+v", len(links), links)
+	}
+	if links[0].MessageID != "333333333333333333" || links[1].GuildID != DM_GUILD {
+		t.Errorf("found %+v", links)
+	}
+}
+
+func TestString(t *testing.T) {
+	got := New("", "222222222222222222", "333333333333333333").String()
+	want := "https://discord.com/channels/@me/222222222222222222/333333333333333333"
+	if got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log"
+
+	"github.com/anonmiraj/discord-bookmarker/bot"
+	"github.com/anonmiraj/discord-bookmarker/config"
+	"github.com/anonmiraj/discord-bookmarker/embed"
+)
+
+// watchConfig reloads the configuration into the running bot whenever the
+// .env file or the embed template changes, until done is closed. After each
+// reload it watches the template the configuration now names.
+func watchConfig(b *bot.Bot, cfg config.Config, logger *log.Logger, done <-chan struct{}) {
+	current := cfg
+	err := config.Watch(configPaths(cfg), done, logger, func() []string {
+		cfg, applied := reloadConfig(b, logger)
+		if applied {
+			current = cfg
+		}
+		paths := configPaths(current)
+		// A new template that failed to load is watched too, so fixing it
+		// applies the change.
+		if !applied && cfg.EmbedTemplate != "" {
+			paths = append(paths, cfg.EmbedTemplate)
+		}
+		return paths
+	})
+	if err != nil {
+		logger.Printf("Error watching the configuration files, changes need a restart: %v", err)
+	}
+}
+
+// configPaths lists the files a configuration is read from.
+func configPaths(cfg config.Config) []string {
+	paths := []string{envFile.Path}
+	if cfg.EmbedTemplate != "" {
+		paths = append(paths, cfg.EmbedTemplate)
+	}
+	return paths
+}
+
+// reloadConfig loads the configuration again, keeping the current one if the
+// new one is invalid. It returns the configuration it loaded, if any, and
+// whether it was applied.
+func reloadConfig(b *bot.Bot, logger *log.Logger) (config.Config, bool) {
+	if err := envFile.Reload(); err != nil {
+		logger.Printf("Error reading %s, keeping the current configuration: %v", envFile.Path, err)
+		return config.Config{}, false
+	}
+	cfg, _, err := config.Load(flagArgs)
+	if err != nil {
+		logger.Printf("Error in the changed configuration, keeping the current one: %v", err)
+		return config.Config{}, false
+	}
+	tmpl, err := embed.LoadTemplate(cfg.EmbedTemplate)
+	if err != nil {
+		logger.Printf("Error loading embed template %s, keeping the current configuration: %v", cfg.EmbedTemplate, err)
+		return cfg, false
+	}
+	b.Reload(cfg, tmpl)
+	return cfg, true
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/bot"
+	"github.com/gorilla/websocket"
+)
+
+// Exit codes for service managers, from sysexits.h. systemd can be told not
+// to restart after EXIT_CONFIG with RestartPreventExitStatus=78.
+const (
+	EXIT_FAILURE = 1
+	// EXIT_TEMPFAIL is a failure that may go away by itself, like Discord's
+	// gateway being unreachable; restarting later should help.
+	EXIT_TEMPFAIL = 75
+	// EXIT_CONFIG is an invalid configuration; restarting won't help.
+	EXIT_CONFIG = 78
+)
+
+// exitError is an error that ends the process with a given exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func configError(err error) error {
+	return &exitError{EXIT_CONFIG, err}
+}
+
+func transientError(err error) error {
+	return &exitError{EXIT_TEMPFAIL, err}
+}
+
+// gatewayConfigCloses are the gateway close codes that need the bot's
+// configuration fixed: an invalid token (4004), an invalid shard (4010),
+// sharding required (4011), an invalid API version (4012), and invalid or
+// disallowed intents (4013, 4014).
+var gatewayConfigCloses = []int{4004, 4010, 4011, 4012, 4013, 4014}
+
+// openError classifies an error connecting to the gateway: a close for a bad
+// token or intents is a config error, anything else may be transient.
+func openError(err error) error {
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) && slices.Contains(gatewayConfigCloses, closeErr.Code) {
+		return configError(err)
+	}
+	return transientError(err)
+}
+
+// exitCode is the exit code for an error returned by a command.
+func exitCode(err error) int {
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+	return EXIT_FAILURE
+}
+
+// waitForSignal sends the name of the first SIGINT or SIGTERM received to stop.
+func waitForSignal(stop chan<- string) {
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		stop <- (<-sc).String()
+	}()
+}
+
+// writePIDFile writes the process ID to path; the returned function removes it.
+func writePIDFile(path string) (func(), error) {
+	err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+	if err != nil {
+		return nil, err
+	}
+	return func() { os.Remove(path) }, nil
+}
+
+// sdNotify sends a state like "READY=1" to systemd, see sd_notify(3). It
+// does nothing unless systemd started the bot as a Type=notify service.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogInterval is how often systemd's watchdog expects a ping, half of
+// WatchdogSec, or zero without a watchdog for this process.
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// startWatchdog pings systemd's watchdog while the gateway is connected,
+// until done is closed, so a bot that stays disconnected gets restarted.
+func startWatchdog(b *bot.Bot, logger *log.Logger, done <-chan struct{}) {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return
+	}
+
+	logger.Printf("Pinging the systemd watchdog every %v", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if !b.Healthy() {
+					continue
+				}
+				if err := sdNotify("WATCHDOG=1"); err != nil {
+					logger.Printf("Error pinging the systemd watchdog: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+// runService runs the bot until it receives SIGINT or SIGTERM.
+func runService(run func(stop <-chan string) error) error {
+	stop := make(chan string, 1)
+	waitForSignal(stop)
+	return run(stop)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"plain error", errors.New("boom"), EXIT_FAILURE},
+		{"config error", configError(errors.New("invalid NSFW policy")), EXIT_CONFIG},
+		{"transient error", transientError(errors.New("gateway unreachable")), EXIT_TEMPFAIL},
+		{"wrapped config error", fmt.Errorf("loading: %w", configError(errors.New("bad"))), EXIT_CONFIG},
+		{"wrapped transient error", fmt.Errorf("connecting: %w", transientError(errors.New("timeout"))), EXIT_TEMPFAIL},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: exitCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOpenError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"network error", errors.New("dial tcp: connection refused"), EXIT_TEMPFAIL},
+		{"authentication failed", &websocket.CloseError{Code: 4004, Text: "Authentication failed."}, EXIT_CONFIG},
+		{"disallowed intents", &websocket.CloseError{Code: 4014, Text: "Disallowed intent(s)."}, EXIT_CONFIG},
+		{"wrapped invalid intents", fmt.Errorf("opening connection: %w", &websocket.CloseError{Code: 4013}), EXIT_CONFIG},
+		{"session timed out", &websocket.CloseError{Code: 4009, Text: "Session timed out."}, EXIT_TEMPFAIL},
+		{"abnormal closure", &websocket.CloseError{Code: websocket.CloseAbnormalClosure}, EXIT_TEMPFAIL},
+	}
+	for _, tt := range tests {
+		if got := exitCode(openError(tt.err)); got != tt.want {
+			t.Errorf("%s: exitCode(openError) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExitErrorUnwraps(t *testing.T) {
+	cause := errors.New("cause")
+	err := configError(cause)
+	if !errors.Is(err, cause) || err.Error() != "cause" {
+		t.Errorf("configError(cause) = %v, want it to wrap cause", err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name string
+		usec string
+		pid  string
+		want time.Duration
+	}{
+		{"no watchdog", "", "", 0},
+		{"half of WatchdogSec", "30000000", "", 15 * time.Second},
+		{"for this process", "30000000", pid, 15 * time.Second},
+		{"for another process", "30000000", "1", 0},
+		{"zero", "0", "", 0},
+		{"negative", "-5", "", 0},
+		{"not a number", "soon", "", 0},
+	}
+	for _, tt := range tests {
+		t.Setenv("WATCHDOG_USEC", tt.usec)
+		t.Setenv("WATCHDOG_PID", tt.pid)
+		if got := watchdogInterval(); got != tt.want {
+			t.Errorf("%s: watchdogInterval = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWritePIDFile(t *testing.T) {
+	tests := []struct {
+		name string
+		// stale is what the file holds beforehand, if it exists.
+		stale string
+	}{
+		{"new file", ""},
+		{"stale file", "999999\n"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "bookmarker.pid")
+		if tt.stale != "" {
+			if err := os.WriteFile(path, []byte(tt.stale), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		remove, err := writePIDFile(path)
+		if err != nil {
+			t.Fatalf("%s: writePIDFile = %v", tt.name, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != strconv.Itoa(os.Getpid())+"\n" {
+			t.Errorf("%s: PID file = %q, %v, want the process ID", tt.name, data, err)
+		}
+
+		remove()
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: PID file still exists after removing it: %v", tt.name, err)
+		}
+	}
+}
+
+func TestWritePIDFileInMissingDirectory(t *testing.T) {
+	if _, err := writePIDFile(filepath.Join(t.TempDir(), "missing", "bookmarker.pid")); err == nil {
+		t.Error("writePIDFile in a missing directory succeeded")
+	}
+}
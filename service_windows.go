@@ -0,0 +1,65 @@
+//go:build windows
+
+package main
+
+import (
+	"golang.org/x/sys/windows/svc"
+)
+
+// SERVICE_NAME is the name the bot is installed under as a Windows service.
+const SERVICE_NAME = "discord-bookmarker"
+
+// runService runs the bot as a Windows service when started by the service
+// manager, until it's stopped, or else until it's interrupted.
+func runService(run func(stop <-chan string) error) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+	stop := make(chan string, 1)
+	if !isService {
+		waitForSignal(stop)
+		return run(stop)
+	}
+
+	s := &windowsService{run: run, stop: stop}
+	if err := svc.Run(SERVICE_NAME, s); err != nil {
+		return err
+	}
+	return s.err
+}
+
+// windowsService answers the service manager while run runs the bot.
+type windowsService struct {
+	run  func(stop <-chan string) error
+	stop chan string
+	err  error
+}
+
+func (s *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	done := make(chan error, 1)
+	go func() { done <- s.run(s.stop) }()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case s.err = <-done:
+			if s.err != nil {
+				return true, uint32(exitCode(s.err))
+			}
+			return false, 0
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				status <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				select {
+				case s.stop <- "service stop request":
+				default: // Already stopping.
+				}
+			}
+		}
+	}
+}
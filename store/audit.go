@@ -0,0 +1,163 @@
+package store
+
+import "time"
+
+// AUDIT_RETENTION bounds how long audit log entries are kept.
+const AUDIT_RETENTION = 90 * 24 * time.Hour
+
+// AuditAction is what an audit log entry records.
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditExport  AuditAction = "export"
+	AuditImport  AuditAction = "import"
+	AuditForget  AuditAction = "forget"
+	AuditGuild   AuditAction = "guild"
+	// AuditRekey records re-encrypting the store with a new key.
+	AuditRekey AuditAction = "rekey"
+)
+
+// FORGOTTEN_USER replaces the ID of a forgotten user in the audit log, see
+// ForgetUser.
+const FORGOTTEN_USER = "forgotten"
+
+// AuditEntry records who did what to which bookmarks. It holds IDs only,
+// never message content.
+type AuditEntry struct {
+	At     time.Time   `json:"at"`
+	Action AuditAction `json:"action"`
+	// ActorID is who acted, UserID whose bookmarks were affected; they
+	// differ for owner commands.
+	ActorID   string `json:"actor_id"`
+	UserID    string `json:"user_id,omitempty"`
+	GuildID   string `json:"guild_id,omitempty"`
+	ChannelID string `json:"channel_id,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// forget replaces userID with FORGOTTEN_USER.
+func (e AuditEntry) forget(userID string) AuditEntry {
+	if e.ActorID == userID {
+		e.ActorID = FORGOTTEN_USER
+	}
+	if e.UserID == userID {
+		e.UserID = FORGOTTEN_USER
+	}
+	return e
+}
+
+// AuditFilter selects audit log entries. Zero fields match everything; UserID
+// matches both the actor and the affected user.
+type AuditFilter struct {
+	UserID  string
+	GuildID string
+	Since   time.Time
+	Until   time.Time
+	// Limit caps the number of entries returned, newest first.
+	Limit int
+}
+
+func (f AuditFilter) match(e AuditEntry) bool {
+	if f.UserID != "" && e.ActorID != f.UserID && e.UserID != f.UserID {
+		return false
+	}
+	if f.GuildID != "" && e.GuildID != f.GuildID {
+		return false
+	}
+	if !f.Since.IsZero() && e.At.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.At.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// AddAudit appends an entry to the audit log, forgetting entries older than
+// AUDIT_RETENTION.
+func (st *Store) AddAudit(e AuditEntry) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	cutoff := time.Now().Add(-AUDIT_RETENTION)
+	kept := st.audit[:0]
+	for _, old := range st.audit {
+		if old.At.After(cutoff) {
+			kept = append(kept, old)
+		}
+	}
+	st.audit = append(kept, e)
+	return st.flush()
+}
+
+// AuditLog returns the entries matching f, newest first.
+func (st *Store) AuditLog(f AuditFilter) ([]AuditEntry, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var found []AuditEntry
+	for i := len(st.audit) - 1; i >= 0; i-- {
+		if f.Limit > 0 && len(found) == f.Limit {
+			break
+		}
+		if f.match(st.audit[i]) {
+			found = append(found, st.audit[i])
+		}
+	}
+	return found, nil
+}
+
+func (st *SQLStore) AddAudit(e AuditEntry) error {
+	err := st.exec(`DELETE FROM audit_log WHERE at < ?`, time.Now().Add(-AUDIT_RETENTION))
+	if err != nil {
+		return err
+	}
+	return st.exec(`INSERT INTO audit_log (at, action, actor_id, user_id, guild_id, channel_id, message_id, detail)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.At, string(e.Action), e.ActorID, e.UserID, e.GuildID, e.ChannelID, e.MessageID, e.Detail)
+}
+
+func (st *SQLStore) AuditLog(f AuditFilter) ([]AuditEntry, error) {
+	where, args := `WHERE TRUE`, []any{}
+	if f.UserID != "" {
+		where += ` AND (actor_id = ? OR user_id = ?)`
+		args = append(args, f.UserID, f.UserID)
+	}
+	if f.GuildID != "" {
+		where += ` AND guild_id = ?`
+		args = append(args, f.GuildID)
+	}
+	if !f.Since.IsZero() {
+		where += ` AND at >= ?`
+		args = append(args, f.Since)
+	}
+	if !f.Until.IsZero() {
+		where += ` AND at < ?`
+		args = append(args, f.Until)
+	}
+	query := `SELECT at, action, actor_id, user_id, guild_id, channel_id, message_id, detail FROM audit_log ` + where + ` ORDER BY at DESC`
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+
+	rows, err := st.db.Query(st.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.At, &e.Action, &e.ActorID, &e.UserID, &e.GuildID, &e.ChannelID, &e.MessageID, &e.Detail); err != nil {
+			return nil, err
+		}
+		found = append(found, e)
+	}
+	return found, rows.Err()
+}
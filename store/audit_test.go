@@ -0,0 +1,34 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b testBackend) {
+		st := b.newStore(t)
+		now := time.Now()
+		st.AddAudit(AuditEntry{At: now.Add(-AUDIT_RETENTION - time.Hour), Action: AuditCreate, ActorID: "user", UserID: "user"})
+		st.AddAudit(AuditEntry{At: now.Add(-2 * time.Hour), Action: AuditCreate, ActorID: "user", UserID: "user", GuildID: "guild"})
+		st.AddAudit(AuditEntry{At: now.Add(-time.Hour), Action: AuditDelete, ActorID: "user", UserID: "user", GuildID: "other"})
+		st.AddAudit(AuditEntry{At: now, Action: AuditForget, ActorID: "owner", UserID: "user"})
+
+		all, _ := st.AuditLog(AuditFilter{})
+		if len(all) != 3 || all[0].Action != AuditForget {
+			t.Errorf("AuditLog = %+v, want the 3 recent entries newest first", all)
+		}
+		if found, _ := st.AuditLog(AuditFilter{GuildID: "guild"}); len(found) != 1 || found[0].Action != AuditCreate {
+			t.Errorf("by guild = %+v", found)
+		}
+		if found, _ := st.AuditLog(AuditFilter{UserID: "owner"}); len(found) != 1 {
+			t.Errorf("by actor = %+v", found)
+		}
+		if found, _ := st.AuditLog(AuditFilter{Since: now.Add(-90 * time.Minute), Until: now}); len(found) != 1 || found[0].Action != AuditDelete {
+			t.Errorf("by time range = %+v", found)
+		}
+		if found, _ := st.AuditLog(AuditFilter{UserID: "user", Limit: 2}); len(found) != 2 {
+			t.Errorf("limited = %d entries, want 2", len(found))
+		}
+	})
+}
@@ -0,0 +1,20 @@
+//go:build sqlite
+
+package store
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func init() {
+	testBackends = append(testBackends, testBackend{
+		name: DIALECT_SQLITE,
+		location: func(t *testing.T) string {
+			return filepath.Join(t.TempDir(), "bookmarks.db")
+		},
+		open: func(location string) (BookmarkStore, error) {
+			return OpenSQL(DIALECT_SQLITE, location, testLogger)
+		},
+	})
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+)
+
+// testBackend is a storage backend the store tests run against: JSON
+// always, SQLite when built with -tags sqlite, and PostgreSQL when built
+// with -tags postgres and STORE_TEST_POSTGRES_DSN is set.
+type testBackend struct {
+	name string
+	// location returns where a new empty store lives: a file or a DSN.
+	location func(t *testing.T) string
+	open     func(location string) (BookmarkStore, error)
+}
+
+var testBackends = []testBackend{{
+	name: "json",
+	location: func(t *testing.T) string {
+		return filepath.Join(t.TempDir(), "bookmarks.json")
+	},
+	open: func(location string) (BookmarkStore, error) {
+		return Open(location)
+	},
+}}
+
+// testLogger discards what the SQL stores log.
+var testLogger = log.New(io.Discard, "", 0)
+
+// forEachBackend runs test as a subtest for every available backend.
+func forEachBackend(t *testing.T, test func(t *testing.T, b testBackend)) {
+	for _, b := range testBackends {
+		t.Run(b.name, func(t *testing.T) {
+			test(t, b)
+		})
+	}
+}
+
+// openStore opens the store at location, closing it when the test ends.
+// Opening the same location again is like restarting the bot.
+func (b testBackend) openStore(t *testing.T, location string) BookmarkStore {
+	t.Helper()
+	st, err := b.open(location)
+	if err != nil {
+		t.Fatalf("opening %s store: %v", b.name, err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+// newStore opens a new empty store.
+func (b testBackend) newStore(t *testing.T) BookmarkStore {
+	t.Helper()
+	return b.openStore(t, b.location(t))
+}
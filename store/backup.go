@@ -0,0 +1,291 @@
+package store
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SNAPSHOT_VERSION is increased when the backup format changes incompatibly.
+const SNAPSHOT_VERSION = 1
+
+// Snapshot is a copy of a whole store that doesn't depend on the backend, so
+// a backup of one store can be restored into another. Deleted bookmarks
+// awaiting an undo and the audit log aren't included.
+type Snapshot struct {
+	Version     int                      `json:"version"`
+	CreatedAt   time.Time                `json:"created_at"`
+	Bookmarks   []Bookmark               `json:"bookmarks"`
+	Processed   []ProcessedReaction      `json:"processed,omitempty"`
+	Settings    map[string]UserSettings  `json:"settings,omitempty"`
+	Threads     []ArchiveThread          `json:"threads,omitempty"`
+	Guilds      map[string]GuildSettings `json:"guilds,omitempty"`
+	Collections []Collection             `json:"collections,omitempty"`
+}
+
+// Encrypted reports whether bookmarks of s are encrypted, see
+// EncryptedStore, so only a store with their key can restore it.
+func (s Snapshot) Encrypted() bool {
+	for _, b := range s.Bookmarks {
+		if b.EncryptionKey != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessedReaction is a handled 🔖 reaction, see MarkProcessed.
+type ProcessedReaction struct {
+	UserID    string    `json:"user_id"`
+	ChannelID string    `json:"channel_id"`
+	MessageID string    `json:"message_id"`
+	At        time.Time `json:"at"`
+}
+
+// ArchiveThread is a remembered thread of a user's archive channel.
+type ArchiveThread struct {
+	UserID   string `json:"user_id"`
+	Key      string `json:"key"`
+	ThreadID string `json:"thread_id"`
+}
+
+// WriteSnapshot writes s as gzip-compressed JSON.
+func WriteSnapshot(w io.Writer, s Snapshot) error {
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(s); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot.
+func ReadSnapshot(r io.Reader) (Snapshot, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("not a bookmark backup: %w", err)
+	}
+	defer zr.Close()
+
+	var s Snapshot
+	if err := json.NewDecoder(zr).Decode(&s); err != nil {
+		return Snapshot{}, fmt.Errorf("decoding backup: %w", err)
+	}
+	if s.Version < 1 || s.Version > SNAPSHOT_VERSION {
+		return Snapshot{}, fmt.Errorf("unsupported backup version %d", s.Version)
+	}
+	return s, nil
+}
+
+func (st *Store) Snapshot() (Snapshot, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	s := Snapshot{
+		Version:   SNAPSHOT_VERSION,
+		CreatedAt: time.Now(),
+		Bookmarks: append([]Bookmark(nil), st.bookmarks...),
+		Settings:  map[string]UserSettings{},
+		Guilds:    map[string]GuildSettings{},
+	}
+	for key, at := range st.processed {
+		parts := strings.SplitN(key, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		s.Processed = append(s.Processed, ProcessedReaction{UserID: parts[0], ChannelID: parts[1], MessageID: parts[2], At: at})
+	}
+	for userID, settings := range st.users {
+		s.Settings[userID] = settings
+	}
+	for key, threadID := range st.threads {
+		userID, threadKey, _ := strings.Cut(key, ":")
+		s.Threads = append(s.Threads, ArchiveThread{UserID: userID, Key: threadKey, ThreadID: threadID})
+	}
+	for guildID, settings := range st.guilds {
+		s.Guilds[guildID] = settings
+	}
+	for _, c := range st.collections {
+		s.Collections = append(s.Collections, c)
+	}
+	sortCollections(s.Collections)
+	return s, nil
+}
+
+// Restore replaces the whole content of the store with s.
+func (st *Store) Restore(s Snapshot) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	// The current content is kept if the restored one can't be written.
+	bookmarks, processed, users, threads := st.bookmarks, st.processed, st.users, st.threads
+	guilds, collections, trash := st.guilds, st.collections, st.trash
+	st.bookmarks = append([]Bookmark(nil), s.Bookmarks...)
+	st.processed = map[string]time.Time{}
+	for _, p := range s.Processed {
+		st.processed[processedKey(p.UserID, p.ChannelID, p.MessageID)] = p.At
+	}
+	st.users = map[string]UserSettings{}
+	for userID, settings := range s.Settings {
+		st.users[userID] = settings
+	}
+	st.threads = map[string]string{}
+	for _, t := range s.Threads {
+		st.threads[threadKey(t.UserID, t.Key)] = t.ThreadID
+	}
+	st.guilds = map[string]GuildSettings{}
+	for guildID, settings := range s.Guilds {
+		st.guilds[guildID] = settings
+	}
+	st.collections = keyCollections(s.Collections)
+	st.trash = nil
+	// Backups made before short IDs existed have none.
+	st.assignShortIDs()
+	if err := st.flush(); err != nil {
+		st.bookmarks, st.processed, st.users, st.threads = bookmarks, processed, users, threads
+		st.guilds, st.collections, st.trash = guilds, collections, trash
+		return err
+	}
+	return nil
+}
+
+func (st *SQLStore) Snapshot() (Snapshot, error) {
+	s := Snapshot{Version: SNAPSHOT_VERSION, CreatedAt: time.Now(), Settings: st.AllSettings()}
+
+	bookmarks, err := st.query(``)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	s.Bookmarks = bookmarks
+
+	rows, err := st.db.Query(`SELECT user_id, channel_id, message_id, processed_at FROM processed`)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p ProcessedReaction
+		if err := rows.Scan(&p.UserID, &p.ChannelID, &p.MessageID, &p.At); err != nil {
+			return Snapshot{}, err
+		}
+		s.Processed = append(s.Processed, p)
+	}
+	if err := rows.Err(); err != nil {
+		return Snapshot{}, err
+	}
+
+	threadRows, err := st.db.Query(`SELECT user_id, key, thread_id FROM archive_threads`)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer threadRows.Close()
+	for threadRows.Next() {
+		var t ArchiveThread
+		if err := threadRows.Scan(&t.UserID, &t.Key, &t.ThreadID); err != nil {
+			return Snapshot{}, err
+		}
+		s.Threads = append(s.Threads, t)
+	}
+	if err := threadRows.Err(); err != nil {
+		return Snapshot{}, err
+	}
+
+	guildRows, err := st.db.Query(`SELECT guild_id, ` + guildSettingsColumns + ` FROM guild_settings`)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer guildRows.Close()
+	s.Guilds = map[string]GuildSettings{}
+	for guildRows.Next() {
+		var guildID string
+		settings, err := scanGuildSettings(guildRows, &guildID)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		s.Guilds[guildID] = settings
+	}
+	if err := guildRows.Err(); err != nil {
+		return Snapshot{}, err
+	}
+
+	s.Collections, err = st.allCollections()
+	return s, err
+}
+
+// Restore replaces the whole content of the database with s, in one transaction.
+func (st *SQLStore) Restore(s Snapshot) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	exec := func(query string, args ...any) error {
+		_, err := tx.Exec(st.rebind(query), args...)
+		return err
+	}
+
+	for _, table := range []string{"bookmarks", "deleted_bookmarks", "processed", "user_settings", "archive_threads", "guild_settings", "collections"} {
+		if err := exec(`DELETE FROM ` + table); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range s.Bookmarks {
+		args, err := bookmarkArgs(b)
+		if err != nil {
+			return err
+		}
+		err = exec(`INSERT INTO bookmarks (`+bookmarkColumns+`) VALUES (`+bookmarkValues+`)`, args...)
+		if err != nil {
+			return fmt.Errorf("restoring bookmark of user %s: %w", b.UserID, err)
+		}
+	}
+	for _, p := range s.Processed {
+		err := exec(`INSERT INTO processed (user_id, channel_id, message_id, processed_at) VALUES (?, ?, ?, ?)`,
+			p.UserID, p.ChannelID, p.MessageID, p.At)
+		if err != nil {
+			return err
+		}
+	}
+	for userID, settings := range s.Settings {
+		err := exec(`INSERT INTO user_settings (user_id, `+settingsColumns+`) VALUES (?, `+settingsValues+`)`,
+			append([]any{userID}, settingsArgs(settings)...)...)
+		if err != nil {
+			return err
+		}
+	}
+	for _, t := range s.Threads {
+		err := exec(`INSERT INTO archive_threads (user_id, key, thread_id) VALUES (?, ?, ?)`, t.UserID, t.Key, t.ThreadID)
+		if err != nil {
+			return err
+		}
+	}
+	for guildID, settings := range s.Guilds {
+		args, err := guildSettingsArgs(settings)
+		if err != nil {
+			return err
+		}
+		err = exec(`INSERT INTO guild_settings (guild_id, `+guildSettingsColumns+`) VALUES (`+guildSettingsValues+`)`, append([]any{guildID}, args...)...)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range s.Collections {
+		args, err := collectionArgs(c)
+		if err != nil {
+			return err
+		}
+		if err := exec(`INSERT INTO collections (`+collectionColumns+`) VALUES (`+collectionValues+`)`, args...); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	// Backups made before short IDs existed have none.
+	return st.assignShortIDs()
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b testBackend) {
+		src := b.newStore(t)
+		src.Add(Bookmark{UserID: "user", GuildID: "guild", ChannelID: "channel", MessageID: "message", Note: "read later", CreatedAt: time.Now()})
+		src.MarkProcessed("user", "channel", "message")
+		src.SetSettings("user", UserSettings{Delivery: DeliveryDaily, ArchiveChannelID: "archive"})
+		src.SetArchiveThread("user", "guild:guild", "thread")
+		src.SetGuildSettings("guild", GuildSettings{BookmarksDisabled: true, AllowedRoles: []string{"role"}})
+
+		snapshot, err := src.Snapshot()
+		if err != nil {
+			t.Fatalf("Snapshot: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := WriteSnapshot(&buf, snapshot); err != nil {
+			t.Fatalf("WriteSnapshot: %v", err)
+		}
+		restored, err := ReadSnapshot(&buf)
+		if err != nil {
+			t.Fatalf("ReadSnapshot: %v", err)
+		}
+
+		dst := b.newStore(t)
+		dst.Add(Bookmark{UserID: "other", ChannelID: "c", MessageID: "m"})
+		if err := dst.Restore(restored); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+
+		if bm, ok := dst.Get("user", "channel", "message"); !ok || bm.Note != "read later" {
+			t.Errorf("restored bookmark = %+v, %t", bm, ok)
+		}
+		if dst.Has("other", "c", "m") {
+			t.Error("restore kept a bookmark missing from the backup")
+		}
+		if !dst.Processed("user", "channel", "message") {
+			t.Error("processed reaction was not restored")
+		}
+		if s := dst.Settings("user"); s.Delivery != DeliveryDaily || s.ArchiveChannelID != "archive" {
+			t.Errorf("restored settings = %+v", s)
+		}
+		if id, ok := dst.ArchiveThread("user", "guild:guild"); !ok || id != "thread" {
+			t.Errorf("restored archive thread = %q, %t", id, ok)
+		}
+		if g := dst.GuildSettings("guild"); !g.BookmarksDisabled || len(g.AllowedRoles) != 1 {
+			t.Error("guild settings were not restored")
+		}
+	})
+}
+
+func TestReadSnapshotRejectsOtherFiles(t *testing.T) {
+	if _, err := ReadSnapshot(strings.NewReader(`{"bookmarks": []}`)); err == nil {
+		t.Error("ReadSnapshot accepted a file that isn't a backup")
+	}
+}
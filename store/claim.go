@@ -0,0 +1,65 @@
+package store
+
+import "time"
+
+// CLAIM_TTL bounds how long a claim is held when its instance stops before
+// releasing it.
+const CLAIM_TTL = 5 * time.Minute
+
+// Claim records that this instance handles the event identified by key and
+// reports whether it won: false means another instance sharing the store
+// holds it.
+//
+// The JSON store can't be shared between processes, so its claims are only
+// kept in memory.
+func (st *Store) Claim(key string, now time.Time) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.claims == nil {
+		st.claims = map[string]time.Time{}
+	}
+	for k, at := range st.claims {
+		if now.Sub(at) > CLAIM_TTL {
+			delete(st.claims, k)
+		}
+	}
+
+	if _, ok := st.claims[key]; ok {
+		return false, nil
+	}
+	st.claims[key] = now
+	return true, nil
+}
+
+// Claim inserts the key into the claims table; its primary key lets exactly
+// one instance insert it.
+func (st *SQLStore) Claim(key string, now time.Time) (bool, error) {
+	if err := st.exec(`DELETE FROM claims WHERE claimed_at < ?`, now.Add(-CLAIM_TTL)); err != nil {
+		return false, err
+	}
+
+	res, err := st.db.Exec(st.rebind(`INSERT INTO claims (claim_key, claimed_at) VALUES (?, ?)
+		ON CONFLICT (claim_key) DO NOTHING`), key, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Release gives up a claim once its event was handled.
+func (st *Store) Release(key string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	delete(st.claims, key)
+	return nil
+}
+
+func (st *SQLStore) Release(key string) error {
+	return st.exec(`DELETE FROM claims WHERE claim_key = ?`, key)
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaim(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b testBackend) {
+		st := b.newStore(t)
+
+		now := time.Now()
+		if ok, err := st.Claim("event", now); err != nil || !ok {
+			t.Fatalf("first Claim = %t, %v", ok, err)
+		}
+		if ok, _ := st.Claim("event", now.Add(time.Second)); ok {
+			t.Error("event claimed twice")
+		}
+		if ok, _ := st.Claim("other", now); !ok {
+			t.Error("claim of another event refused")
+		}
+		if ok, _ := st.Claim("event", now.Add(CLAIM_TTL+time.Second)); !ok {
+			t.Error("expired claim still held")
+		}
+
+		if err := st.Release("other"); err != nil {
+			t.Fatal(err)
+		}
+		if ok, _ := st.Claim("other", now); !ok {
+			t.Error("released claim still held")
+		}
+	})
+}
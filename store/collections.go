@@ -0,0 +1,210 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Collection is a named list of bookmarks curated by a team: a bookmark
+// tagged with its name is delivered to every subscriber, while only the
+// bookmarking user stores it. Names are unique per owner.
+type Collection struct {
+	Name    string `json:"name"`
+	OwnerID string `json:"owner_id"`
+	// Members are the users the owner shared the collection with, who may subscribe.
+	Members     []string  `json:"members,omitempty"`
+	Subscribers []string  `json:"subscribers,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NormalizeCollectionName folds a collection name like tags are compared.
+func NormalizeCollectionName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// CanSubscribe reports whether the owner or a member is userID.
+func (c Collection) CanSubscribe(userID string) bool {
+	return userID == c.OwnerID || slices.Contains(c.Members, userID)
+}
+
+// Subscribed reports whether userID gets the collection's bookmarks.
+func (c Collection) Subscribed(userID string) bool {
+	return slices.Contains(c.Subscribers, userID)
+}
+
+// WithoutUser drops userID from the members and subscribers, e.g. when the
+// owner stops sharing the collection with them.
+func (c Collection) WithoutUser(userID string) Collection {
+	c.Members = slices.DeleteFunc(slices.Clone(c.Members), func(id string) bool { return id == userID })
+	c.Subscribers = slices.DeleteFunc(slices.Clone(c.Subscribers), func(id string) bool { return id == userID })
+	return c
+}
+
+// collectionKey keys the collections of the JSON store.
+func collectionKey(ownerID, name string) string {
+	return ownerID + ":" + NormalizeCollectionName(name)
+}
+
+// keyCollections keys collections by owner and name.
+func keyCollections(collections []Collection) map[string]Collection {
+	keyed := map[string]Collection{}
+	for _, c := range collections {
+		c.Name = NormalizeCollectionName(c.Name)
+		keyed[collectionKey(c.OwnerID, c.Name)] = c
+	}
+	return keyed
+}
+
+func sortCollections(collections []Collection) {
+	sort.Slice(collections, func(i, j int) bool {
+		if collections[i].Name != collections[j].Name {
+			return collections[i].Name < collections[j].Name
+		}
+		return collections[i].OwnerID < collections[j].OwnerID
+	})
+}
+
+// Collection returns the collection an owner named name, see NormalizeCollectionName.
+func (st *Store) Collection(ownerID, name string) (Collection, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	c, ok := st.collections[collectionKey(ownerID, name)]
+	return c, ok
+}
+
+// SetCollection creates or replaces a collection.
+func (st *Store) SetCollection(c Collection) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	c.Name = NormalizeCollectionName(c.Name)
+	st.collections[collectionKey(c.OwnerID, c.Name)] = c
+	return st.flush()
+}
+
+// DeleteCollection deletes an owner's collection, if there is one.
+func (st *Store) DeleteCollection(ownerID, name string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	key := collectionKey(ownerID, name)
+	if _, ok := st.collections[key]; !ok {
+		return nil
+	}
+	delete(st.collections, key)
+	return st.flush()
+}
+
+// Collections returns the collections a user owns, was shared or subscribes
+// to, by name and owner.
+func (st *Store) Collections(userID string) []Collection {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var collections []Collection
+	for _, c := range st.collections {
+		if c.CanSubscribe(userID) || c.Subscribed(userID) {
+			collections = append(collections, c)
+		}
+	}
+	sortCollections(collections)
+	return collections
+}
+
+const collectionColumns = `name, owner_id, members, subscribers, created_at`
+
+// collectionValues has a placeholder for each of collectionColumns.
+var collectionValues = strings.Repeat("?, ", strings.Count(collectionColumns, ",")) + "?"
+
+func collectionArgs(c Collection) ([]any, error) {
+	members, err := json.Marshal(append([]string{}, c.Members...))
+	if err != nil {
+		return nil, err
+	}
+	subscribers, err := json.Marshal(append([]string{}, c.Subscribers...))
+	if err != nil {
+		return nil, err
+	}
+	return []any{NormalizeCollectionName(c.Name), c.OwnerID, string(members), string(subscribers), c.CreatedAt}, nil
+}
+
+func scanCollection(row interface{ Scan(...any) error }) (Collection, error) {
+	var c Collection
+	var members, subscribers string
+	if err := row.Scan(&c.Name, &c.OwnerID, &members, &subscribers, &c.CreatedAt); err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal([]byte(members), &c.Members); err != nil {
+		return c, fmt.Errorf("decoding members of collection %s: %w", c.Name, err)
+	}
+	if err := json.Unmarshal([]byte(subscribers), &c.Subscribers); err != nil {
+		return c, fmt.Errorf("decoding subscribers of collection %s: %w", c.Name, err)
+	}
+	return c, nil
+}
+
+func (st *SQLStore) Collection(ownerID, name string) (Collection, bool) {
+	row := st.db.QueryRow(st.rebind(`SELECT `+collectionColumns+` FROM collections WHERE owner_id = ? AND name = ?`), ownerID, NormalizeCollectionName(name))
+	c, err := scanCollection(row)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			st.logger.Printf("Error querying collection %s: %v", name, err)
+		}
+		return Collection{}, false
+	}
+	return c, true
+}
+
+func (st *SQLStore) SetCollection(c Collection) error {
+	args, err := collectionArgs(c)
+	if err != nil {
+		return err
+	}
+	return st.exec(`INSERT INTO collections (`+collectionColumns+`) VALUES (`+collectionValues+`)
+		ON CONFLICT (owner_id, name) DO UPDATE SET members = excluded.members, subscribers = excluded.subscribers`,
+		args...)
+}
+
+func (st *SQLStore) DeleteCollection(ownerID, name string) error {
+	return st.exec(`DELETE FROM collections WHERE owner_id = ? AND name = ?`, ownerID, NormalizeCollectionName(name))
+}
+
+// Collections filters every collection, as membership is stored as JSON.
+// Teams have few collections, so this stays cheap.
+func (st *SQLStore) Collections(userID string) []Collection {
+	all, err := st.allCollections()
+	if err != nil {
+		st.logger.Printf("Error querying collections: %v", err)
+		return nil
+	}
+	var collections []Collection
+	for _, c := range all {
+		if c.CanSubscribe(userID) || c.Subscribed(userID) {
+			collections = append(collections, c)
+		}
+	}
+	sortCollections(collections)
+	return collections
+}
+
+// allCollections returns every collection by name and owner.
+func (st *SQLStore) allCollections() ([]Collection, error) {
+	rows, err := st.db.Query(`SELECT ` + collectionColumns + ` FROM collections ORDER BY name, owner_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var collections []Collection
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"testing"
+)
+
+func TestCollections(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b testBackend) {
+		st := b.newStore(t)
+		for _, c := range []Collection{
+			{Name: "Research", OwnerID: "owner", Members: []string{"member"}, Subscribers: []string{"owner", "member"}},
+			{Name: "recipes", OwnerID: "member", Subscribers: []string{"member"}},
+			{Name: "research", OwnerID: "other", Subscribers: []string{"other"}},
+		} {
+			if err := st.SetCollection(c); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if c, ok := st.Collection("owner", " RESEARCH "); !ok || c.Name != "research" || !c.CanSubscribe("member") || c.CanSubscribe("stranger") {
+			t.Errorf("Collection = %+v, %v", c, ok)
+		}
+		if c, ok := st.Collection("other", "research"); !ok || c.CanSubscribe("member") {
+			t.Errorf("Collection of another owner = %+v, %v", c, ok)
+		}
+		if _, ok := st.Collection("member", "research"); ok {
+			t.Error("found research owned by member")
+		}
+		if got := st.Collections("member"); len(got) != 2 || got[0].Name != "recipes" || got[1].Name != "research" {
+			t.Errorf("Collections(member) = %+v", got)
+		}
+
+		if _, err := st.ForgetUser("member"); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := st.Collection("member", "recipes"); ok {
+			t.Error("collection of forgotten owner kept")
+		}
+		if c, _ := st.Collection("owner", "research"); c.CanSubscribe("member") || c.Subscribed("member") {
+			t.Errorf("forgotten member kept in %+v", c)
+		}
+
+		if err := st.DeleteCollection("owner", "Research"); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := st.Collection("owner", "research"); ok {
+			t.Error("deleted collection found")
+		}
+		if _, ok := st.Collection("other", "research"); !ok {
+			t.Error("collection of another owner deleted too")
+		}
+	})
+}
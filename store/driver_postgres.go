@@ -0,0 +1,6 @@
+//go:build postgres
+
+package store
+
+// Registers the "pgx" database/sql driver for DIALECT_POSTGRES.
+import _ "github.com/jackc/pgx/v5/stdlib"
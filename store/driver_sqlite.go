@@ -0,0 +1,6 @@
+//go:build sqlite
+
+package store
+
+// Registers the "sqlite" database/sql driver for DIALECT_SQLITE.
+import _ "modernc.org/sqlite"
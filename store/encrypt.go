@@ -0,0 +1,459 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// ENCRYPTED_PREFIX starts encrypted values, followed by the key ID and
+	// the base64 nonce and ciphertext: "enc:v1:<key id>:<data>". Whether a
+	// bookmark is encrypted is told by its EncryptionKey, not this prefix,
+	// which plain text may start with too.
+	ENCRYPTED_PREFIX = "enc:v1:"
+	// ENCRYPTION_KEY_SIZE selects AES-256.
+	ENCRYPTION_KEY_SIZE = 32
+	// CONTENT_HASH_KEY_INFO derives the key content hashes are keyed with
+	// from an encryption key.
+	CONTENT_HASH_KEY_INFO = "discord-bookmarker content hash"
+)
+
+// ParseEncryptionKey decodes a 32-byte key given as base64 or hex, e.g.
+// generated with `openssl rand -base64 32`.
+func ParseEncryptionKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	// Hex keys are valid base64 too, so they are tried first.
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		if key, err = base64.StdEncoding.DecodeString(s); err != nil {
+			return nil, errors.New("encryption key is neither base64 nor hex")
+		}
+	}
+	if len(key) != ENCRYPTION_KEY_SIZE {
+		return nil, fmt.Errorf("encryption key is %d bytes, expected %d", len(key), ENCRYPTION_KEY_SIZE)
+	}
+	return key, nil
+}
+
+// Cipher encrypts stored text with AES-256-GCM. It encrypts with its first
+// key and decrypts with any of them, so old keys can be rotated out.
+type Cipher struct {
+	keys []cipherKey
+}
+
+type cipherKey struct {
+	// id tells which key encrypted a value, without revealing the key.
+	id   string
+	aead cipher.AEAD
+	// hashKey keys content hashes, see keyedHash.
+	hashKey []byte
+}
+
+// NewCipher returns a cipher encrypting with the first of keys.
+func NewCipher(keys ...[]byte) (*Cipher, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption key")
+	}
+	c := &Cipher{}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(key)
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(CONTENT_HASH_KEY_INFO))
+		c.keys = append(c.keys, cipherKey{id: hex.EncodeToString(sum[:4]), aead: aead, hashKey: mac.Sum(nil)})
+	}
+	return c, nil
+}
+
+// Encrypt encrypts s with the current key.
+func (c *Cipher) Encrypt(s string) (string, error) {
+	key := c.keys[0]
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := key.aead.Seal(nonce, nonce, []byte(s), []byte(key.id))
+	return ENCRYPTED_PREFIX + key.id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt.
+func (c *Cipher) Decrypt(s string) (string, error) {
+	rest, ok := strings.CutPrefix(s, ENCRYPTED_PREFIX)
+	if !ok {
+		return "", errors.New("value isn't encrypted")
+	}
+	id, data, _ := strings.Cut(rest, ":")
+	for _, key := range c.keys {
+		if key.id != id {
+			continue
+		}
+		sealed, err := base64.StdEncoding.DecodeString(data)
+		if err != nil || len(sealed) < key.aead.NonceSize() {
+			return "", errors.New("malformed encrypted value")
+		}
+		nonce, ciphertext := sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():]
+		plain, err := key.aead.Open(nil, nonce, ciphertext, []byte(id))
+		if err != nil {
+			return "", fmt.Errorf("decrypting with key %s: %w", id, err)
+		}
+		return string(plain), nil
+	}
+	return "", fmt.Errorf("no encryption key %s configured", id)
+}
+
+// bookmarkText lists the fields of a bookmark that are encrypted: the
+// message content, its transcript and translation, the user's note, and
+// their earlier versions. The history is copied first, b shares it with the
+// bookmark it was copied from.
+func bookmarkText(b *Bookmark) []*string {
+	fields := []*string{&b.Content, &b.Transcript, &b.Translation, &b.Note}
+	b.History = slices.Clone(b.History)
+	for i := range b.History {
+		fields = append(fields, &b.History[i].Content, &b.History[i].Note)
+	}
+	return fields
+}
+
+// keyedHash keys a content hash with key, so it can't be used to confirm
+// guesses of the content without the key.
+func (key cipherKey) keyedHash(hash string) string {
+	if hash == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key.hashKey)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// encryptBookmark encrypts the text of a plain bookmark with the current key
+// and keys its content hash. Bookmarks that are still encrypted, because
+// decrypting them failed, are returned as they are, for writes keeping them
+// whole such as Add and Restore; Update and Replace refuse them instead, see
+// writable.
+func (c *Cipher) encryptBookmark(b Bookmark) (Bookmark, error) {
+	if b.EncryptionKey != "" {
+		return b, nil
+	}
+	key := c.keys[0]
+	for _, field := range bookmarkText(&b) {
+		if *field == "" {
+			continue
+		}
+		encrypted, err := c.Encrypt(*field)
+		if err != nil {
+			return b, err
+		}
+		*field = encrypted
+	}
+	if b.ContentHash != "" {
+		sealed, err := c.Encrypt(b.ContentHash)
+		if err != nil {
+			return b, err
+		}
+		b.SealedContentHash, b.ContentHash = sealed, key.keyedHash(b.ContentHash)
+	}
+	b.EncryptionKey = key.id
+	return b, nil
+}
+
+// decryptBookmark returns the plain text of a stored bookmark. Bookmarks
+// that can't be decrypted are returned as stored, so writing them back
+// doesn't lose their text.
+func (c *Cipher) decryptBookmark(stored Bookmark) (Bookmark, error) {
+	if stored.EncryptionKey == "" {
+		return stored, nil
+	}
+	b := stored
+	for _, field := range append(bookmarkText(&b), &b.SealedContentHash) {
+		if *field == "" {
+			continue
+		}
+		plain, err := c.Decrypt(*field)
+		if err != nil {
+			return stored, err
+		}
+		*field = plain
+	}
+	if b.SealedContentHash != "" {
+		b.ContentHash, b.SealedContentHash = b.SealedContentHash, ""
+	}
+	b.EncryptionKey = ""
+	return b, nil
+}
+
+// EncryptedStore encrypts the text of bookmarks written to the store it
+// wraps and decrypts it when read, so the rest of the bot sees plain text.
+type EncryptedStore struct {
+	BookmarkStore
+	cipher *Cipher
+	logger *log.Logger
+}
+
+// Encrypted wraps st to encrypt bookmark text at rest with c.
+func Encrypted(st BookmarkStore, c *Cipher, logger *log.Logger) *EncryptedStore {
+	return &EncryptedStore{BookmarkStore: st, cipher: c, logger: logger}
+}
+
+func (st *EncryptedStore) decrypt(b Bookmark) Bookmark {
+	b, err := st.cipher.decryptBookmark(b)
+	if err != nil {
+		st.logger.Printf("Error decrypting bookmark %s of user %s: %v", b.MessageID, b.UserID, err)
+	}
+	return b
+}
+
+func (st *EncryptedStore) decryptAll(bookmarks []Bookmark) []Bookmark {
+	for i, b := range bookmarks {
+		bookmarks[i] = st.decrypt(b)
+	}
+	return bookmarks
+}
+
+func (st *EncryptedStore) decryptFound(b Bookmark, ok bool) (Bookmark, bool) {
+	if !ok {
+		return b, false
+	}
+	return st.decrypt(b), true
+}
+
+func (st *EncryptedStore) Add(b Bookmark) error {
+	b, err := st.cipher.encryptBookmark(b)
+	if err != nil {
+		return err
+	}
+	return st.BookmarkStore.Add(b)
+}
+
+// errUndecrypted refuses writes of bookmarks that failed to decrypt: their
+// changed text would be stored as plain text next to the old ciphertext, and
+// the bookmark could never be decrypted again.
+var errUndecrypted = errors.New("bookmark couldn't be decrypted, refusing to change it")
+
+func writable(b Bookmark) error {
+	if b.EncryptionKey != "" {
+		return fmt.Errorf("bookmark %s of user %s: %w", b.MessageID, b.UserID, errUndecrypted)
+	}
+	return nil
+}
+
+func (st *EncryptedStore) Update(b Bookmark) error {
+	if err := writable(b); err != nil {
+		return err
+	}
+	b, err := st.cipher.encryptBookmark(b)
+	if err != nil {
+		return err
+	}
+	return st.BookmarkStore.Update(b)
+}
+
+func (st *EncryptedStore) Replace(dmChannelID, dmMessageID string, b Bookmark) error {
+	if err := writable(b); err != nil {
+		return err
+	}
+	b, err := st.cipher.encryptBookmark(b)
+	if err != nil {
+		return err
+	}
+	return st.BookmarkStore.Replace(dmChannelID, dmMessageID, b)
+}
+
+func (st *EncryptedStore) BySource(channelID, messageID string) []Bookmark {
+	return st.decryptAll(st.BookmarkStore.BySource(channelID, messageID))
+}
+
+func (st *EncryptedStore) Get(userID, channelID, messageID string) (Bookmark, bool) {
+	return st.decryptFound(st.BookmarkStore.Get(userID, channelID, messageID))
+}
+
+func (st *EncryptedStore) ForUser(userID string) []Bookmark {
+	return st.decryptAll(st.BookmarkStore.ForUser(userID))
+}
+
+func (st *EncryptedStore) All() []Bookmark {
+	return st.decryptAll(st.BookmarkStore.All())
+}
+
+func (st *EncryptedStore) ByDM(dmChannelID, dmMessageID string) (Bookmark, bool) {
+	return st.decryptFound(st.BookmarkStore.ByDM(dmChannelID, dmMessageID))
+}
+
+func (st *EncryptedStore) RemoveIf(match func(Bookmark) bool) ([]Bookmark, error) {
+	removed, err := st.BookmarkStore.RemoveIf(func(b Bookmark) bool { return match(st.decrypt(b)) })
+	return st.decryptAll(removed), err
+}
+
+func (st *EncryptedStore) Pending(userID string) []Bookmark {
+	return st.decryptAll(st.BookmarkStore.Pending(userID))
+}
+
+func (st *EncryptedStore) Reminders(userID string) []Bookmark {
+	return st.decryptAll(st.BookmarkStore.Reminders(userID))
+}
+
+func (st *EncryptedStore) DueReminders(now time.Time) []Bookmark {
+	return st.decryptAll(st.BookmarkStore.DueReminders(now))
+}
+
+func (st *EncryptedStore) ForgetUser(userID string) ([]Bookmark, error) {
+	removed, err := st.BookmarkStore.ForgetUser(userID)
+	return st.decryptAll(removed), err
+}
+
+func (st *EncryptedStore) Trash(dmChannelID, dmMessageID string, at time.Time) (Bookmark, bool, error) {
+	b, ok, err := st.BookmarkStore.Trash(dmChannelID, dmMessageID, at)
+	b, ok = st.decryptFound(b, ok)
+	return b, ok, err
+}
+
+func (st *EncryptedStore) Untrash(userID, channelID, messageID string) (Bookmark, bool, error) {
+	b, ok, err := st.BookmarkStore.Untrash(userID, channelID, messageID)
+	b, ok = st.decryptFound(b, ok)
+	return b, ok, err
+}
+
+// Retrash encrypts the bookmark again, unless it couldn't be decrypted and
+// is still encrypted.
+func (st *EncryptedStore) Retrash(b Bookmark, at time.Time) error {
+	b, err := st.cipher.encryptBookmark(b)
+	if err != nil {
+		return err
+	}
+	return st.BookmarkStore.Retrash(b, at)
+}
+
+// UpdateTrash hands update the decrypted bookmarks and encrypts what it
+// returns. Bookmarks that can't be decrypted are left as they are.
+func (st *EncryptedStore) UpdateTrash(update func(Bookmark) (Bookmark, bool)) (int, error) {
+	var encryptErr error
+	changed, err := st.BookmarkStore.UpdateTrash(func(stored Bookmark) (Bookmark, bool) {
+		plain, err := st.cipher.decryptBookmark(stored)
+		if err != nil || encryptErr != nil {
+			return stored, false
+		}
+		b, ok := update(plain)
+		if !ok {
+			return stored, false
+		}
+		encrypted, err := st.cipher.encryptBookmark(b)
+		if err != nil {
+			encryptErr = err
+			return stored, false
+		}
+		return encrypted, true
+	})
+	if err == nil {
+		err = encryptErr
+	}
+	return changed, err
+}
+
+func (st *EncryptedStore) ByShortID(userID, shortID string) (Bookmark, bool) {
+	return st.decryptFound(st.BookmarkStore.ByShortID(userID, shortID))
+}
+
+// ByContentHash looks for the hash keyed with each key, and the plain hash,
+// so bookmarks not yet re-encrypted after a rotation are found too.
+func (st *EncryptedStore) ByContentHash(userID, hash string) []Bookmark {
+	found := st.BookmarkStore.ByContentHash(userID, hash)
+	for _, key := range st.cipher.keys {
+		found = append(found, st.BookmarkStore.ByContentHash(userID, key.keyedHash(hash))...)
+	}
+	slices.SortStableFunc(found, func(a, b Bookmark) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return st.decryptAll(found)
+}
+
+// DecryptedSnapshot is Snapshot with the bookmarks decrypted, so the backup
+// restores into stores with other keys or none. Snapshot itself keeps them
+// encrypted, so backups don't hold the text in plain.
+func (st *EncryptedStore) DecryptedSnapshot() (Snapshot, error) {
+	s, err := st.BookmarkStore.Snapshot()
+	s.Bookmarks = st.decryptAll(s.Bookmarks)
+	return s, err
+}
+
+// Restore encrypts the plain bookmarks of s and refuses encrypted ones none
+// of the keys decrypt, which would be unreadable once restored.
+func (st *EncryptedStore) Restore(s Snapshot) error {
+	bookmarks := make([]Bookmark, len(s.Bookmarks))
+	for i, b := range s.Bookmarks {
+		if _, err := st.cipher.decryptBookmark(b); err != nil {
+			return fmt.Errorf("bookmark %s of user %s is encrypted with another key: %w", b.MessageID, b.UserID, err)
+		}
+		encrypted, err := st.cipher.encryptBookmark(b)
+		if err != nil {
+			return err
+		}
+		bookmarks[i] = encrypted
+	}
+	s.Bookmarks = bookmarks
+	return st.BookmarkStore.Restore(s)
+}
+
+// Reencrypt rewrites every bookmark not encrypted with the current key,
+// deleted ones included, e.g. after rotating keys or enabling encryption,
+// re-keying its content hash. It returns how many changed and how many
+// couldn't be decrypted and were left as they are, still needing an old key.
+func (st *EncryptedStore) Reencrypt() (changed, skipped int, err error) {
+	for _, stored := range st.BookmarkStore.All() {
+		if stored.EncryptionKey == st.cipher.keys[0].id {
+			continue
+		}
+		plain, err := st.cipher.decryptBookmark(stored)
+		if err != nil {
+			st.logger.Printf("Error decrypting bookmark %s of user %s, leaving it: %v", stored.MessageID, stored.UserID, err)
+			skipped++
+			continue
+		}
+		if err := st.Update(plain); err != nil {
+			return changed, skipped, fmt.Errorf("rewriting bookmark %s of user %s: %w", stored.MessageID, stored.UserID, err)
+		}
+		changed++
+	}
+
+	// Deleted bookmarks can still be restored, so they need the current key too.
+	var encryptErr error
+	trashed, err := st.BookmarkStore.UpdateTrash(func(stored Bookmark) (Bookmark, bool) {
+		if stored.EncryptionKey == st.cipher.keys[0].id || encryptErr != nil {
+			return stored, false
+		}
+		plain, err := st.cipher.decryptBookmark(stored)
+		if err != nil {
+			st.logger.Printf("Error decrypting deleted bookmark %s of user %s, leaving it: %v", stored.MessageID, stored.UserID, err)
+			skipped++
+			return stored, false
+		}
+		encrypted, err := st.cipher.encryptBookmark(plain)
+		if err != nil {
+			encryptErr = err
+			return stored, false
+		}
+		return encrypted, true
+	})
+	changed += trashed
+	if err == nil {
+		err = encryptErr
+	}
+	if err != nil {
+		return changed, skipped, fmt.Errorf("rewriting deleted bookmarks: %w", err)
+	}
+	return changed, skipped, nil
+}
@@ -0,0 +1,277 @@
+package store
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, ENCRYPTION_KEY_SIZE)
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	key := testKey(7)
+	if got, err := ParseEncryptionKey(base64.StdEncoding.EncodeToString(key)); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("base64 key = %x, %v", got, err)
+	}
+	if got, err := ParseEncryptionKey(strings.Repeat("07", ENCRYPTION_KEY_SIZE)); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("hex key = %x, %v", got, err)
+	}
+	if _, err := ParseEncryptionKey(base64.StdEncoding.EncodeToString(key[:16])); err == nil {
+		t.Error("short key accepted")
+	}
+}
+
+func TestCipherRoundTrip(t *testing.T) {
+	c, err := NewCipher(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := c.Encrypt("secret plans")
+	if err != nil || !strings.HasPrefix(encrypted, ENCRYPTED_PREFIX) || strings.Contains(encrypted, "secret") {
+		t.Fatalf("Encrypt = %q, %v", encrypted, err)
+	}
+	if plain, err := c.Decrypt(encrypted); err != nil || plain != "secret plans" {
+		t.Errorf("Decrypt = %q, %v", plain, err)
+	}
+	if plain, err := c.Decrypt("stored before encryption"); err == nil {
+		t.Errorf("Decrypt of plain text = %q, want an error", plain)
+	}
+	// Text that looks encrypted is encrypted like any other.
+	if encrypted, err := c.Encrypt(encrypted); err != nil || strings.Count(encrypted, ENCRYPTED_PREFIX) != 1 {
+		t.Errorf("Encrypt of encrypted-looking text = %q, %v", encrypted, err)
+	}
+
+	other, _ := NewCipher(testKey(2))
+	if _, err := other.Decrypt(encrypted); err == nil {
+		t.Error("decrypted with the wrong key")
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend testBackend) {
+		location := backend.location(t)
+		raw := backend.openStore(t, location)
+		c, _ := NewCipher(testKey(1))
+		st := Encrypted(raw, c, testLogger)
+
+		err := st.Add(Bookmark{UserID: "user", ChannelID: "c", MessageID: "m", DMChannelID: "dm", DMMessageID: "d", Content: "secret plans", Note: "my note"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bm, ok := st.ByDM("dm", "d"); !ok || bm.Content != "secret plans" || bm.Note != "my note" || bm.EncryptionKey != "" {
+			t.Errorf("ByDM = %+v, want it decrypted", bm)
+		}
+		if stored := raw.ForUser("user")[0]; !strings.HasPrefix(stored.Content, ENCRYPTED_PREFIX) || !strings.HasPrefix(stored.Note, ENCRYPTED_PREFIX) || stored.EncryptionKey == "" {
+			t.Errorf("stored %+v, want it encrypted", stored)
+		}
+		if backend.name == "json" {
+			data, _ := os.ReadFile(location)
+			if bytes.Contains(data, []byte("secret")) {
+				t.Error("store file contains the plain content")
+			}
+		}
+
+		bm, _ := st.ByDM("dm", "d")
+		prev := bm
+		bm.Content = "secret plans, revised"
+		bm.AddVersion(prev, VersionEdited, time.Now())
+		if err := st.Update(bm); err != nil {
+			t.Fatal(err)
+		}
+		if stored := raw.ForUser("user")[0]; !strings.HasPrefix(stored.History[0].Content, ENCRYPTED_PREFIX) {
+			t.Errorf("stored history %+v, want it encrypted", stored.History)
+		}
+		if bm, _ := st.ByDM("dm", "d"); bm.History[0].Content != "secret plans" || bm.History[1].Content != "secret plans, revised" {
+			t.Errorf("history %+v, want it decrypted", bm.History)
+		}
+
+		removed, _ := st.RemoveIf(func(b Bookmark) bool { return b.Content == "secret plans, revised" })
+		if len(removed) != 1 || removed[0].Content != "secret plans, revised" {
+			t.Errorf("RemoveIf = %+v, want the decrypted bookmark", removed)
+		}
+	})
+}
+
+func TestEncryptedStoreEncryptsPrefixedText(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend testBackend) {
+		raw := backend.newStore(t)
+		c, _ := NewCipher(testKey(1))
+		st := Encrypted(raw, c, testLogger)
+
+		content := ENCRYPTED_PREFIX + "not really: secret"
+		if err := st.Add(Bookmark{UserID: "user", ChannelID: "c", MessageID: "m", Content: content}); err != nil {
+			t.Fatal(err)
+		}
+		if stored := raw.ForUser("user")[0]; strings.Contains(stored.Content, "secret") {
+			t.Errorf("stored content %q, want it encrypted", stored.Content)
+		}
+		if bm := st.ForUser("user")[0]; bm.Content != content {
+			t.Errorf("content = %q, want %q", bm.Content, content)
+		}
+	})
+}
+
+func TestEncryptedStoreKeysContentHash(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend testBackend) {
+		raw := backend.newStore(t)
+		c, _ := NewCipher(testKey(1))
+		st := Encrypted(raw, c, testLogger)
+
+		if err := st.Add(Bookmark{UserID: "user", ChannelID: "c", MessageID: "m", Content: "secret", ContentHash: "abc123"}); err != nil {
+			t.Fatal(err)
+		}
+		stored := raw.ForUser("user")[0]
+		if stored.ContentHash == "abc123" || strings.Contains(stored.SealedContentHash, "abc123") {
+			t.Errorf("stored hash %q, sealed %q, want it keyed", stored.ContentHash, stored.SealedContentHash)
+		}
+		if found := raw.ByContentHash("user", "abc123"); len(found) != 0 {
+			t.Errorf("plain hash found %d bookmarks in the store", len(found))
+		}
+		if found := st.ByContentHash("user", "abc123"); len(found) != 1 || found[0].ContentHash != "abc123" {
+			t.Errorf("ByContentHash = %+v, want the bookmark with its plain hash", found)
+		}
+	})
+}
+
+func TestEncryptedStoreSnapshotStaysEncrypted(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend testBackend) {
+		c, _ := NewCipher(testKey(1))
+		st := Encrypted(backend.newStore(t), c, testLogger)
+		if err := st.Add(Bookmark{UserID: "user", ChannelID: "c", MessageID: "m", Content: "secret plans"}); err != nil {
+			t.Fatal(err)
+		}
+
+		snapshot, err := st.Snapshot()
+		if err != nil || !snapshot.Encrypted() || strings.Contains(snapshot.Bookmarks[0].Content, "secret") {
+			t.Fatalf("Snapshot = %+v, %v, want the bookmark encrypted", snapshot.Bookmarks, err)
+		}
+		decrypted, err := st.DecryptedSnapshot()
+		if err != nil || decrypted.Encrypted() || decrypted.Bookmarks[0].Content != "secret plans" {
+			t.Fatalf("DecryptedSnapshot = %+v, %v, want the bookmark decrypted", decrypted.Bookmarks, err)
+		}
+
+		// The old key is enough to restore an encrypted backup.
+		rotated, _ := NewCipher(testKey(2), testKey(1))
+		restored := Encrypted(backend.newStore(t), rotated, testLogger)
+		if err := restored.Restore(snapshot); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if bm := restored.ForUser("user"); len(bm) != 1 || bm[0].Content != "secret plans" {
+			t.Errorf("restored %+v, want the decrypted bookmark", bm)
+		}
+
+		other, _ := NewCipher(testKey(2))
+		if err := Encrypted(backend.newStore(t), other, testLogger).Restore(snapshot); err == nil {
+			t.Error("Restore accepted a backup encrypted with another key")
+		}
+	})
+}
+
+func TestReencryptRotatesKeys(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend testBackend) {
+		raw := backend.newStore(t)
+		oldCipher, _ := NewCipher(testKey(1))
+		Encrypted(raw, oldCipher, testLogger).Add(Bookmark{UserID: "user", ChannelID: "c", MessageID: "old", Content: "old secret", ContentHash: "old hash"})
+		raw.Add(Bookmark{UserID: "user", ChannelID: "c", MessageID: "plain", Content: "plain text", ContentHash: "plain hash"})
+
+		rotated, _ := NewCipher(testKey(2), testKey(1))
+		n, skipped, err := Encrypted(raw, rotated, testLogger).Reencrypt()
+		if err != nil || n != 2 || skipped != 0 {
+			t.Fatalf("Reencrypt = %d, %d, %v, want both rewritten", n, skipped, err)
+		}
+		if n, _, _ := Encrypted(raw, rotated, testLogger).Reencrypt(); n != 0 {
+			t.Errorf("second Reencrypt rewrote %d bookmarks, want none", n)
+		}
+
+		newCipher, _ := NewCipher(testKey(2))
+		st := Encrypted(raw, newCipher, testLogger)
+		for _, bm := range st.ForUser("user") {
+			if bm.Content != "old secret" && bm.Content != "plain text" {
+				t.Errorf("bookmark %s = %q after rotation", bm.MessageID, bm.Content)
+			}
+		}
+		// Only the new key is left, so the hashes must have been re-keyed.
+		for _, hash := range []string{"old hash", "plain hash"} {
+			if found := raw.ByContentHash("user", hash); len(found) != 0 {
+				t.Errorf("plain hash %q still stored after rotation", hash)
+			}
+			if found := st.ByContentHash("user", hash); len(found) != 1 {
+				t.Errorf("ByContentHash(%q) found %d bookmarks after rotation, want 1", hash, len(found))
+			}
+		}
+	})
+}
+
+func TestReencryptRotatesDeletedBookmarks(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend testBackend) {
+		raw := backend.newStore(t)
+		oldCipher, _ := NewCipher(testKey(1))
+		Encrypted(raw, oldCipher, testLogger).Add(Bookmark{UserID: "user", ChannelID: "c", MessageID: "m", DMChannelID: "dm", DMMessageID: "dm-m", Content: "secret"})
+		if _, ok, err := raw.Trash("dm", "dm-m", time.Now()); !ok || err != nil {
+			t.Fatalf("Trash = %t, %v", ok, err)
+		}
+
+		rotated, _ := NewCipher(testKey(2), testKey(1))
+		if n, _, err := Encrypted(raw, rotated, testLogger).Reencrypt(); err != nil || n != 1 {
+			t.Fatalf("Reencrypt = %d, %v, want the deleted bookmark rewritten", n, err)
+		}
+
+		// The old key is gone, but the bookmark can still be restored.
+		newCipher, _ := NewCipher(testKey(2))
+		b, ok, err := Encrypted(raw, newCipher, testLogger).Untrash("user", "c", "m")
+		if !ok || err != nil || b.Content != "secret" {
+			t.Errorf("Untrash = %q, %t, %v after rotation, want the decrypted bookmark", b.Content, ok, err)
+		}
+	})
+}
+
+func TestReencryptCountsUndecryptableBookmarks(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend testBackend) {
+		raw := backend.newStore(t)
+		lost, _ := NewCipher(testKey(1))
+		lostStore := Encrypted(raw, lost, testLogger)
+		lostStore.Add(Bookmark{UserID: "user", ChannelID: "c", MessageID: "m", Content: "secret"})
+		lostStore.Add(Bookmark{UserID: "user", ChannelID: "c", MessageID: "deleted", DMChannelID: "dm", DMMessageID: "dm-m", Content: "secret"})
+		if _, ok, err := raw.Trash("dm", "dm-m", time.Now()); !ok || err != nil {
+			t.Fatalf("Trash = %t, %v", ok, err)
+		}
+
+		other, _ := NewCipher(testKey(2))
+		n, skipped, err := Encrypted(raw, other, testLogger).Reencrypt()
+		if err != nil || n != 0 || skipped != 2 {
+			t.Errorf("Reencrypt = %d, %d, %v, want both skipped", n, skipped, err)
+		}
+	})
+}
+
+func TestEncryptedStoreRefusesUndecryptedUpdates(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend testBackend) {
+		raw := backend.newStore(t)
+		right, _ := NewCipher(testKey(1))
+		Encrypted(raw, right, testLogger).Add(Bookmark{UserID: "user", ChannelID: "c", MessageID: "m", DMChannelID: "dm", DMMessageID: "d", Content: "secret", Note: "note"})
+		stored := raw.ForUser("user")[0]
+
+		wrong, _ := NewCipher(testKey(2))
+		st := Encrypted(raw, wrong, testLogger)
+		bm, _ := st.ByDM("dm", "d")
+		bm.Note = "new note"
+		if err := st.Update(bm); !errors.Is(err, errUndecrypted) {
+			t.Errorf("Update = %v, want errUndecrypted", err)
+		}
+		if err := st.Replace("dm", "d", bm); !errors.Is(err, errUndecrypted) {
+			t.Errorf("Replace = %v, want errUndecrypted", err)
+		}
+		if got := raw.ForUser("user")[0]; got.Note != stored.Note || got.Content != stored.Content {
+			t.Errorf("stored %+v after refused writes, want %+v", got, stored)
+		}
+		if bm, _ := Encrypted(raw, right, testLogger).ByDM("dm", "d"); bm.Content != "secret" || bm.Note != "note" {
+			t.Errorf("ByDM with the right key = %+v, want it decrypted", bm)
+		}
+	})
+}
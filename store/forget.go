@@ -0,0 +1,100 @@
+package store
+
+import "strings"
+
+// ForgetUser removes every bookmark, deleted bookmark, handled reaction,
+// setting and archive thread of the user, drops their collections and takes
+// them out of others', and returns the removed bookmarks. Their audit log
+// entries are kept with FORGOTTEN_USER in place of their ID.
+func (st *Store) ForgetUser(userID string) ([]Bookmark, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var removed []Bookmark
+	kept := st.bookmarks[:0]
+	for _, b := range st.bookmarks {
+		if b.UserID == userID {
+			removed = append(removed, b)
+		} else {
+			kept = append(kept, b)
+		}
+	}
+	st.bookmarks = kept
+
+	for key := range st.processed {
+		if strings.HasPrefix(key, userID+":") {
+			delete(st.processed, key)
+		}
+	}
+	delete(st.users, userID)
+	for key := range st.threads {
+		if strings.HasPrefix(key, userID+":") {
+			delete(st.threads, key)
+		}
+	}
+	trash := st.trash[:0]
+	for _, t := range st.trash {
+		if t.UserID != userID {
+			trash = append(trash, t)
+		}
+	}
+	st.trash = trash
+	for i, e := range st.audit {
+		st.audit[i] = e.forget(userID)
+	}
+	for key, c := range st.collections {
+		if c.OwnerID == userID {
+			delete(st.collections, key)
+		} else {
+			st.collections[key] = c.WithoutUser(userID)
+		}
+	}
+
+	return removed, st.flush()
+}
+
+func (st *SQLStore) ForgetUser(userID string) ([]Bookmark, error) {
+	removed, err := st.query(`WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	collections, err := st.allCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := st.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"bookmarks", "deleted_bookmarks", "processed", "user_settings", "archive_threads"} {
+		if _, err := tx.Exec(st.rebind(`DELETE FROM `+table+` WHERE user_id = ?`), userID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(st.rebind(`DELETE FROM collections WHERE owner_id = ?`), userID); err != nil {
+		return nil, err
+	}
+	for _, column := range []string{"actor_id", "user_id"} {
+		_, err := tx.Exec(st.rebind(`UPDATE audit_log SET `+column+` = ? WHERE `+column+` = ?`), FORGOTTEN_USER, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range collections {
+		if c.OwnerID == userID || (!c.CanSubscribe(userID) && !c.Subscribed(userID)) {
+			continue
+		}
+		args, err := collectionArgs(c.WithoutUser(userID))
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(st.rebind(`UPDATE collections SET members = ?, subscribers = ? WHERE owner_id = ? AND name = ?`), args[2], args[3], c.OwnerID, c.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return removed, tx.Commit()
+}
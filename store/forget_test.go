@@ -0,0 +1,51 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForgetUser(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b testBackend) {
+		st := b.newStore(t)
+		st.Add(Bookmark{UserID: "user", ChannelID: "channel", MessageID: "message", DMChannelID: "dm", DMMessageID: "dm-message"})
+		st.Add(Bookmark{UserID: "other", ChannelID: "channel", MessageID: "message"})
+		st.MarkProcessed("user", "channel", "message")
+		st.MarkProcessed("other", "channel", "message")
+		st.SetSettings("user", UserSettings{Delivery: DeliveryDaily})
+		st.SetArchiveThread("user", "guild:guild", "thread")
+		now := time.Now()
+		st.AddAudit(AuditEntry{At: now, Action: AuditCreate, ActorID: "user", UserID: "user", ChannelID: "channel", MessageID: "message"})
+		st.AddAudit(AuditEntry{At: now, Action: AuditDelete, ActorID: "owner", UserID: "user"})
+		st.AddAudit(AuditEntry{At: now, Action: AuditCreate, ActorID: "other", UserID: "other"})
+
+		removed, err := st.ForgetUser("user")
+		if err != nil {
+			t.Fatalf("ForgetUser: %v", err)
+		}
+		if len(removed) != 1 || removed[0].DMMessageID != "dm-message" {
+			t.Errorf("removed %+v, want the user's bookmark", removed)
+		}
+
+		if len(st.ForUser("user")) != 0 || st.Processed("user", "channel", "message") ||
+			st.Settings("user") != (UserSettings{}) {
+			t.Error("data of the forgotten user is still stored")
+		}
+		if _, ok := st.ArchiveThread("user", "guild:guild"); ok {
+			t.Error("archive thread of the forgotten user is still stored")
+		}
+		if len(st.ForUser("other")) != 1 || !st.Processed("other", "channel", "message") {
+			t.Error("data of another user was removed")
+		}
+
+		if entries, _ := st.AuditLog(AuditFilter{UserID: "user"}); len(entries) != 0 {
+			t.Errorf("audit log still names the forgotten user: %+v", entries)
+		}
+		if entries, _ := st.AuditLog(AuditFilter{UserID: FORGOTTEN_USER}); len(entries) != 2 {
+			t.Errorf("audit log has %d pseudonymized entries, want 2", len(entries))
+		}
+		if entries, _ := st.AuditLog(AuditFilter{UserID: "other"}); len(entries) != 1 || entries[0].ActorID != "other" {
+			t.Errorf("audit log of another user = %+v", entries)
+		}
+	})
+}
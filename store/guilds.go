@@ -0,0 +1,136 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ReactionRemoval is whether bookmark reactions are removed from a guild's
+// messages once the bookmark is delivered.
+type ReactionRemoval string
+
+const (
+	// ReactionRemovalMembers leaves it to each member's settings.
+	ReactionRemovalMembers ReactionRemoval = ""
+	ReactionRemovalAlways  ReactionRemoval = "always"
+	ReactionRemovalNever   ReactionRemoval = "never"
+)
+
+// GuildSettings are a guild's choices about the bot, made by its admins.
+type GuildSettings struct {
+	// BookmarksDisabled opts the guild out: its messages can't be bookmarked.
+	BookmarksDisabled bool `json:"bookmarks_disabled,omitempty"`
+	// AllowedRoles, if set, limits bookmarking to members with one of these roles.
+	AllowedRoles    []string        `json:"allowed_roles,omitempty"`
+	ReactionRemoval ReactionRemoval `json:"reaction_removal,omitempty"`
+	// AnalyticsDisabled hides the guild's bookmark counts from /bookmarker analytics.
+	AnalyticsDisabled bool `json:"analytics_disabled,omitempty"`
+}
+
+// IsZero reports whether the settings are all defaults.
+func (s GuildSettings) IsZero() bool {
+	return !s.BookmarksDisabled && len(s.AllowedRoles) == 0 && s.ReactionRemoval == ReactionRemovalMembers && !s.AnalyticsDisabled
+}
+
+// RemovesReaction reports whether a member's bookmark reaction is removed
+// after delivery, given whether they asked for it.
+func (s GuildSettings) RemovesReaction(memberWants bool) bool {
+	switch s.ReactionRemoval {
+	case ReactionRemovalAlways:
+		return true
+	case ReactionRemovalNever:
+		return false
+	}
+	return memberWants
+}
+
+// Allows reports whether a member with the given roles may bookmark.
+func (s GuildSettings) Allows(roles []string) bool {
+	if len(s.AllowedRoles) == 0 {
+		return true
+	}
+	for _, role := range roles {
+		if slices.Contains(s.AllowedRoles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// GuildSettings returns a guild's settings, the zero value if its admins never changed any.
+func (st *Store) GuildSettings(guildID string) GuildSettings {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.guilds[guildID]
+}
+
+func (st *Store) SetGuildSettings(guildID string, settings GuildSettings) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if settings.IsZero() {
+		delete(st.guilds, guildID)
+	} else {
+		st.guilds[guildID] = settings
+	}
+	return st.flush()
+}
+
+const guildSettingsColumns = `bookmarks_disabled, allowed_roles, reaction_removal, analytics_disabled`
+
+// guildSettingsValues has a placeholder for guild_id and each of guildSettingsColumns.
+var guildSettingsValues = "?, " + strings.Repeat("?, ", strings.Count(guildSettingsColumns, ",")) + "?"
+
+func guildSettingsArgs(settings GuildSettings) ([]any, error) {
+	roles, err := json.Marshal(settings.AllowedRoles)
+	if err != nil {
+		return nil, err
+	}
+	if settings.AllowedRoles == nil {
+		roles = []byte("[]")
+	}
+	return []any{settings.BookmarksDisabled, string(roles), string(settings.ReactionRemoval), settings.AnalyticsDisabled}, nil
+}
+
+// scanGuildSettings reads guildSettingsColumns, after any leading columns in dest.
+func scanGuildSettings(row interface{ Scan(...any) error }, dest ...any) (GuildSettings, error) {
+	var settings GuildSettings
+	var roles string
+	if err := row.Scan(append(dest, &settings.BookmarksDisabled, &roles, &settings.ReactionRemoval, &settings.AnalyticsDisabled)...); err != nil {
+		return settings, err
+	}
+	if err := json.Unmarshal([]byte(roles), &settings.AllowedRoles); err != nil {
+		return settings, fmt.Errorf("decoding allowed roles: %w", err)
+	}
+	if len(settings.AllowedRoles) == 0 {
+		settings.AllowedRoles = nil
+	}
+	return settings, nil
+}
+
+func (st *SQLStore) GuildSettings(guildID string) GuildSettings {
+	row := st.db.QueryRow(st.rebind(`SELECT `+guildSettingsColumns+` FROM guild_settings WHERE guild_id = ?`), guildID)
+	settings, err := scanGuildSettings(row)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		st.logger.Printf("Error querying settings of guild %s: %v", guildID, err)
+	}
+	return settings
+}
+
+func (st *SQLStore) SetGuildSettings(guildID string, settings GuildSettings) error {
+	if settings.IsZero() {
+		return st.exec(`DELETE FROM guild_settings WHERE guild_id = ?`, guildID)
+	}
+	args, err := guildSettingsArgs(settings)
+	if err != nil {
+		return err
+	}
+	return st.exec(`INSERT INTO guild_settings (guild_id, `+guildSettingsColumns+`) VALUES (`+guildSettingsValues+`)
+		ON CONFLICT (guild_id) DO UPDATE SET bookmarks_disabled = excluded.bookmarks_disabled, allowed_roles = excluded.allowed_roles,
+			reaction_removal = excluded.reaction_removal, analytics_disabled = excluded.analytics_disabled`,
+		append([]any{guildID}, args...)...)
+}
@@ -0,0 +1,50 @@
+package store
+
+import "time"
+
+// MAX_VERSIONS bounds the history of a bookmark; the captured version is
+// always kept, the oldest changes after it are dropped.
+const MAX_VERSIONS = 20
+
+// VersionReason tells what made a new version of a bookmark.
+type VersionReason string
+
+const (
+	VersionCaptured VersionReason = "captured"
+	VersionEdited   VersionReason = "edited"
+	VersionNote     VersionReason = "note"
+)
+
+// Version is the content and note of a bookmark at some point.
+type Version struct {
+	Reason  VersionReason `json:"reason"`
+	At      time.Time     `json:"at"`
+	Content string        `json:"content"`
+	Note    string        `json:"note,omitempty"`
+}
+
+// AddVersion records the bookmark's content and note as a new version, if
+// they differ from prev, the bookmark before the change. Bookmarks that
+// never change have no history; the first change also records prev as the
+// captured version.
+func (b *Bookmark) AddVersion(prev Bookmark, reason VersionReason, at time.Time) {
+	if b.Content == prev.Content && b.Note == prev.Note {
+		return
+	}
+	if len(b.History) == 0 {
+		b.History = []Version{{Reason: VersionCaptured, At: prev.CreatedAt, Content: prev.Content, Note: prev.Note}}
+	}
+	b.History = append(b.History, Version{Reason: reason, At: at, Content: b.Content, Note: b.Note})
+	if len(b.History) > MAX_VERSIONS {
+		b.History = append(b.History[:1], b.History[len(b.History)-MAX_VERSIONS+1:]...)
+	}
+}
+
+// Versions returns the bookmark's history, oldest first; a bookmark that
+// never changed has only its captured version.
+func (b Bookmark) Versions() []Version {
+	if len(b.History) > 0 {
+		return b.History
+	}
+	return []Version{{Reason: VersionCaptured, At: b.CreatedAt, Content: b.Content, Note: b.Note}}
+}
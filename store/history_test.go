@@ -0,0 +1,58 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAddVersion(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := Bookmark{Content: "first", CreatedAt: created}
+	if v := b.Versions(); len(v) != 1 || v[0].Reason != VersionCaptured || v[0].Content != "first" {
+		t.Fatalf("Versions of an unchanged bookmark = %+v", v)
+	}
+
+	prev := b
+	b.Content = "second"
+	b.AddVersion(prev, VersionEdited, created.Add(time.Hour))
+	prev = b
+	b.AddVersion(prev, VersionEdited, created.Add(2*time.Hour))
+	prev = b
+	b.Note = "mine"
+	b.AddVersion(prev, VersionNote, created.Add(3*time.Hour))
+
+	want := []Version{
+		{Reason: VersionCaptured, At: created, Content: "first"},
+		{Reason: VersionEdited, At: created.Add(time.Hour), Content: "second"},
+		{Reason: VersionNote, At: created.Add(3 * time.Hour), Content: "second", Note: "mine"},
+	}
+	got := b.Versions()
+	if len(got) != len(want) {
+		t.Fatalf("Versions = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("version %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAddVersionKeepsCaptured(t *testing.T) {
+	b := Bookmark{Content: "original"}
+	for i := 0; i < MAX_VERSIONS*2; i++ {
+		prev := b
+		b.Content = fmt.Sprint("edit ", i)
+		b.AddVersion(prev, VersionEdited, time.Now())
+	}
+
+	if len(b.History) != MAX_VERSIONS {
+		t.Fatalf("%d versions, want %d", len(b.History), MAX_VERSIONS)
+	}
+	if b.History[0].Content != "original" || b.History[1].Content != fmt.Sprint("edit ", MAX_VERSIONS+1) {
+		t.Errorf("history starts with %q, %q", b.History[0].Content, b.History[1].Content)
+	}
+	if last := b.History[MAX_VERSIONS-1]; last.Content != b.Content {
+		t.Errorf("last version %q, want the current content %q", last.Content, b.Content)
+	}
+}
@@ -0,0 +1,9 @@
+ALTER TABLE user_settings ADD COLUMN archive_channel_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_settings ADD COLUMN archive_threads TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE archive_threads (
+    user_id   TEXT NOT NULL,
+    key       TEXT NOT NULL,
+    thread_id TEXT NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
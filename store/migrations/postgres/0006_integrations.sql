@@ -0,0 +1,3 @@
+ALTER TABLE user_settings ADD COLUMN webhook_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_settings ADD COLUMN notion_token TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_settings ADD COLUMN notion_database_id TEXT NOT NULL DEFAULT '';
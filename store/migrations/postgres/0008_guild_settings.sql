@@ -0,0 +1,4 @@
+CREATE TABLE guild_settings (
+    guild_id           TEXT PRIMARY KEY,
+    bookmarks_disabled BOOLEAN NOT NULL DEFAULT FALSE
+);
@@ -0,0 +1,15 @@
+CREATE TABLE audit_log (
+    at         TIMESTAMPTZ NOT NULL,
+    action     TEXT NOT NULL,
+    actor_id   TEXT NOT NULL,
+    user_id    TEXT NOT NULL DEFAULT '',
+    guild_id   TEXT NOT NULL DEFAULT '',
+    channel_id TEXT NOT NULL DEFAULT '',
+    message_id TEXT NOT NULL DEFAULT '',
+    detail     TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_log_at ON audit_log (at);
+CREATE INDEX audit_log_actor ON audit_log (actor_id);
+CREATE INDEX audit_log_user ON audit_log (user_id);
+CREATE INDEX audit_log_guild ON audit_log (guild_id);
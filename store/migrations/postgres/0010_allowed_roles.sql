@@ -0,0 +1 @@
+ALTER TABLE guild_settings ADD COLUMN allowed_roles TEXT NOT NULL DEFAULT '[]';
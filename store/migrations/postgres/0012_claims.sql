@@ -0,0 +1,4 @@
+CREATE TABLE claims (
+    claim_key  TEXT PRIMARY KEY,
+    claimed_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,3 @@
+ALTER TABLE user_settings ADD COLUMN verbosity TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_settings ADD COLUMN language TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_settings ADD COLUMN remove_reaction BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE bookmarks ADD COLUMN remind_at TIMESTAMPTZ;
+ALTER TABLE deleted_bookmarks ADD COLUMN remind_at TIMESTAMPTZ;
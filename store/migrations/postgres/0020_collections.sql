@@ -0,0 +1,7 @@
+CREATE TABLE collections (
+    name        TEXT PRIMARY KEY,
+    owner_id    TEXT NOT NULL,
+    members     TEXT NOT NULL DEFAULT '[]',
+    subscribers TEXT NOT NULL DEFAULT '[]',
+    created_at  TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,2 @@
+ALTER TABLE bookmarks ADD COLUMN history TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE deleted_bookmarks ADD COLUMN history TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1,3 @@
+-- 0012 first created claimed_at as TIMESTAMP, which instances in different
+-- session time zones read differently.
+ALTER TABLE claims ALTER COLUMN claimed_at TYPE TIMESTAMPTZ;
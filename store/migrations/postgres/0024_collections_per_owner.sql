@@ -0,0 +1,3 @@
+-- Collection names are unique per owner, not globally.
+ALTER TABLE collections DROP CONSTRAINT collections_pkey;
+ALTER TABLE collections ADD PRIMARY KEY (owner_id, name);
@@ -0,0 +1,35 @@
+CREATE TABLE bookmarks (
+    user_id        TEXT        NOT NULL,
+    guild_id       TEXT        NOT NULL,
+    channel_id     TEXT        NOT NULL,
+    message_id     TEXT        NOT NULL,
+    dm_channel_id  TEXT        NOT NULL DEFAULT '',
+    dm_message_id  TEXT        NOT NULL DEFAULT '',
+    content        TEXT        NOT NULL DEFAULT '',
+    created_at     TIMESTAMP NOT NULL,
+    edited_at      TIMESTAMP,
+    source_deleted BOOLEAN     NOT NULL DEFAULT FALSE,
+    archived       BOOLEAN     NOT NULL DEFAULT FALSE,
+    pending        BOOLEAN     NOT NULL DEFAULT FALSE,
+    pinned         BOOLEAN     NOT NULL DEFAULT FALSE,
+    status         TEXT        NOT NULL DEFAULT '',
+    tags           TEXT        NOT NULL DEFAULT '[]',
+    PRIMARY KEY (user_id, channel_id, message_id)
+);
+
+CREATE INDEX bookmarks_source ON bookmarks (channel_id, message_id);
+CREATE INDEX bookmarks_dm ON bookmarks (dm_channel_id, dm_message_id);
+
+CREATE TABLE processed (
+    user_id      TEXT        NOT NULL,
+    channel_id   TEXT        NOT NULL,
+    message_id   TEXT        NOT NULL,
+    processed_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, channel_id, message_id)
+);
+
+CREATE TABLE user_settings (
+    user_id     TEXT PRIMARY KEY,
+    delivery    TEXT NOT NULL DEFAULT '',
+    last_digest TIMESTAMP
+);
@@ -0,0 +1 @@
+ALTER TABLE bookmarks ADD COLUMN transcript TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE bookmarks ADD COLUMN note TEXT NOT NULL DEFAULT '';
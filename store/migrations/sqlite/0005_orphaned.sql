@@ -0,0 +1 @@
+ALTER TABLE bookmarks ADD COLUMN orphaned BOOLEAN NOT NULL DEFAULT FALSE;
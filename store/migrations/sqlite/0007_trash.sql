@@ -0,0 +1,24 @@
+CREATE TABLE deleted_bookmarks (
+    user_id        TEXT        NOT NULL,
+    guild_id       TEXT        NOT NULL,
+    channel_id     TEXT        NOT NULL,
+    message_id     TEXT        NOT NULL,
+    dm_channel_id  TEXT        NOT NULL DEFAULT '',
+    dm_message_id  TEXT        NOT NULL DEFAULT '',
+    content        TEXT        NOT NULL DEFAULT '',
+    created_at     TIMESTAMP NOT NULL,
+    edited_at      TIMESTAMP,
+    source_deleted BOOLEAN     NOT NULL DEFAULT FALSE,
+    archived       BOOLEAN     NOT NULL DEFAULT FALSE,
+    pending        BOOLEAN     NOT NULL DEFAULT FALSE,
+    pinned         BOOLEAN     NOT NULL DEFAULT FALSE,
+    status         TEXT        NOT NULL DEFAULT '',
+    tags           TEXT        NOT NULL DEFAULT '[]',
+    transcript     TEXT        NOT NULL DEFAULT '',
+    note           TEXT        NOT NULL DEFAULT '',
+    orphaned       BOOLEAN     NOT NULL DEFAULT FALSE,
+    deleted_at     TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, channel_id, message_id)
+);
+
+CREATE INDEX deleted_bookmarks_deleted_at ON deleted_bookmarks (deleted_at);
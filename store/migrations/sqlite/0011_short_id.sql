@@ -0,0 +1,4 @@
+ALTER TABLE bookmarks ADD COLUMN short_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE deleted_bookmarks ADD COLUMN short_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX bookmarks_short_id ON bookmarks (user_id, short_id);
@@ -0,0 +1,6 @@
+ALTER TABLE bookmarks ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN reposts TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE deleted_bookmarks ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE deleted_bookmarks ADD COLUMN reposts TEXT NOT NULL DEFAULT '[]';
+
+CREATE INDEX bookmarks_content_hash ON bookmarks (user_id, content_hash);
@@ -0,0 +1 @@
+ALTER TABLE guild_settings ADD COLUMN reaction_removal TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE bookmarks ADD COLUMN compact BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE deleted_bookmarks ADD COLUMN compact BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1 @@
+ALTER TABLE guild_settings ADD COLUMN analytics_disabled BOOLEAN NOT NULL DEFAULT FALSE;
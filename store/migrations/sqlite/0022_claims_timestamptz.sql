@@ -0,0 +1,4 @@
+-- PostgreSQL's 0022 changes claimed_at to TIMESTAMPTZ. SQLite stores
+-- timestamps with their time zone already, so this is a no-op, kept because
+-- both dialects must have the same migrations (see TestMigrationsMatch).
+SELECT 1;
@@ -0,0 +1,4 @@
+ALTER TABLE bookmarks ADD COLUMN encryption_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN sealed_content_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE deleted_bookmarks ADD COLUMN encryption_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE deleted_bookmarks ADD COLUMN sealed_content_hash TEXT NOT NULL DEFAULT '';
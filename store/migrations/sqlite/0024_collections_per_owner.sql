@@ -0,0 +1,14 @@
+-- Collection names are unique per owner, not globally. SQLite can't change
+-- a primary key, so the table is rebuilt.
+CREATE TABLE collections_per_owner (
+    name        TEXT NOT NULL,
+    owner_id    TEXT NOT NULL,
+    members     TEXT NOT NULL DEFAULT '[]',
+    subscribers TEXT NOT NULL DEFAULT '[]',
+    created_at  TIMESTAMP NOT NULL,
+    PRIMARY KEY (owner_id, name)
+);
+INSERT INTO collections_per_owner (name, owner_id, members, subscribers, created_at)
+    SELECT name, owner_id, members, subscribers, created_at FROM collections;
+DROP TABLE collections;
+ALTER TABLE collections_per_owner RENAME TO collections;
@@ -0,0 +1,2 @@
+ALTER TABLE bookmarks ADD COLUMN user_install BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE deleted_bookmarks ADD COLUMN user_install BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"sort"
+	"time"
+)
+
+func (st *Store) Reminders(userID string) []Bookmark {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var reminders []Bookmark
+	for _, b := range st.bookmarks {
+		if b.UserID == userID && b.RemindAt != nil {
+			reminders = append(reminders, b)
+		}
+	}
+	sortByReminder(reminders)
+	return reminders
+}
+
+func (st *Store) DueReminders(now time.Time) []Bookmark {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var due []Bookmark
+	for _, b := range st.bookmarks {
+		if b.RemindAt != nil && !b.RemindAt.After(now) {
+			due = append(due, b)
+		}
+	}
+	sortByReminder(due)
+	return due
+}
+
+func (st *SQLStore) Reminders(userID string) []Bookmark {
+	reminders := st.mustQuery(`WHERE user_id = ? AND remind_at IS NOT NULL`, userID)
+	sortByReminder(reminders)
+	return reminders
+}
+
+func (st *SQLStore) DueReminders(now time.Time) []Bookmark {
+	due := st.mustQuery(`WHERE remind_at <= ?`, now)
+	sortByReminder(due)
+	return due
+}
+
+// sortByReminder orders bookmarks with a reminder soonest first.
+func sortByReminder(bookmarks []Bookmark) {
+	sort.SliceStable(bookmarks, func(i, j int) bool {
+		return bookmarks[i].RemindAt.Before(*bookmarks[j].RemindAt)
+	})
+}
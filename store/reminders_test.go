@@ -0,0 +1,33 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReminders(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b testBackend) {
+		st := b.newStore(t)
+		now := time.Now()
+		later, soon, past := now.Add(time.Hour), now.Add(time.Minute), now.Add(-time.Minute)
+		for _, bm := range []Bookmark{
+			{UserID: "user", ChannelID: "c", MessageID: "later", RemindAt: &later},
+			{UserID: "user", ChannelID: "c", MessageID: "none"},
+			{UserID: "user", ChannelID: "c", MessageID: "soon", RemindAt: &soon},
+			{UserID: "other", ChannelID: "c", MessageID: "past", RemindAt: &past},
+		} {
+			if err := st.Add(bm); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		reminders := st.Reminders("user")
+		if len(reminders) != 2 || reminders[0].MessageID != "soon" || reminders[1].MessageID != "later" {
+			t.Errorf("Reminders = %+v, want soon then later", reminders)
+		}
+		due := st.DueReminders(now)
+		if len(due) != 1 || due[0].MessageID != "past" {
+			t.Errorf("DueReminders = %+v, want past", due)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package store
+
+// ByContentHash returns the user's bookmarks with the given content hash,
+// oldest first. An empty hash matches nothing.
+func (st *Store) ByContentHash(userID, hash string) []Bookmark {
+	if hash == "" {
+		return nil
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var matches []Bookmark
+	for _, b := range st.bookmarks {
+		if b.UserID == userID && b.ContentHash == hash {
+			matches = append(matches, b)
+		}
+	}
+	return matches
+}
+
+func (st *SQLStore) ByContentHash(userID, hash string) []Bookmark {
+	if hash == "" {
+		return nil
+	}
+	return st.mustQuery(`WHERE user_id = ? AND content_hash = ?`, userID, hash)
+}
+
+// HasSource reports whether the bookmark was made from the message, itself
+// or as one of its reposts.
+func (b Bookmark) HasSource(channelID, messageID string) bool {
+	if b.ChannelID == channelID && b.MessageID == messageID {
+		return true
+	}
+	for _, s := range b.Reposts {
+		if s.ChannelID == channelID && s.MessageID == messageID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package store
+
+import "time"
+
+// Delivery is how new bookmarks reach a user.
+type Delivery string
+
+const (
+	DeliveryInstant Delivery = ""
+	DeliveryDaily   Delivery = "daily"
+	DeliveryWeekly  Delivery = "weekly"
+)
+
+// Interval returns how often digests are sent, or 0 for instant delivery.
+func (d Delivery) Interval() time.Duration {
+	switch d {
+	case DeliveryDaily:
+		return 24 * time.Hour
+	case DeliveryWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// ThreadMode is how bookmarks are organized in a personal archive channel.
+type ThreadMode string
+
+const (
+	ThreadsNone  ThreadMode = ""
+	ThreadsGuild ThreadMode = "guild"
+	ThreadsTag   ThreadMode = "tag"
+)
+
+// Verbosity is how much of a message its bookmark embed shows.
+type Verbosity string
+
+const (
+	VerbosityFull    Verbosity = ""
+	VerbosityCompact Verbosity = "compact"
+)
+
+// UserSettings are a user's preferences.
+type UserSettings struct {
+	Delivery Delivery `json:"delivery,omitempty"`
+	// LastDigest is when the user's digest period last started.
+	LastDigest time.Time `json:"last_digest,omitempty"`
+
+	// ArchiveChannelID is a guild channel bookmarks are posted to instead
+	// of DMs, optionally in one thread per guild or tag.
+	ArchiveChannelID string     `json:"archive_channel_id,omitempty"`
+	ArchiveThreads   ThreadMode `json:"archive_threads,omitempty"`
+
+	// New bookmarks are also posted to WebhookURL as JSON, and added as
+	// pages of a Notion database with NotionToken, if set.
+	WebhookURL       string `json:"webhook_url,omitempty"`
+	NotionToken      string `json:"notion_token,omitempty"`
+	NotionDatabaseID string `json:"notion_database_id,omitempty"`
+
+	Verbosity Verbosity `json:"verbosity,omitempty"`
+	// Language is the language code bookmarks are translated into, empty
+	// to leave them untranslated.
+	Language string `json:"language,omitempty"`
+	// RemoveReaction removes the user's bookmark reaction from the source
+	// message once the bookmark is delivered.
+	RemoveReaction bool `json:"remove_reaction,omitempty"`
+}
+
+// Settings returns a user's settings, the zero value if they never changed any.
+func (st *Store) Settings(userID string) UserSettings {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.users[userID]
+}
+
+func (st *Store) SetSettings(userID string, settings UserSettings) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if settings == (UserSettings{}) {
+		delete(st.users, userID)
+	} else {
+		st.users[userID] = settings
+	}
+	return st.flush()
+}
+
+// AllSettings returns the settings of every user who changed any.
+func (st *Store) AllSettings() map[string]UserSettings {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	all := make(map[string]UserSettings, len(st.users))
+	for userID, settings := range st.users {
+		all[userID] = settings
+	}
+	return all
+}
+
+// Pending returns the user's bookmarks awaiting a digest.
+func (st *Store) Pending(userID string) []Bookmark {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var pending []Bookmark
+	for _, b := range st.bookmarks {
+		if b.UserID == userID && b.Pending {
+			pending = append(pending, b)
+		}
+	}
+	return pending
+}
+
+// MarkDigested clears the Pending flag of the user's bookmarks created up to
+// at, and starts their next digest period at at.
+func (st *Store) MarkDigested(userID string, at time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for i := range st.bookmarks {
+		if st.bookmarks[i].UserID == userID && st.bookmarks[i].Pending && !st.bookmarks[i].CreatedAt.After(at) {
+			st.bookmarks[i].Pending = false
+		}
+	}
+
+	settings := st.users[userID]
+	settings.LastDigest = at
+	st.users[userID] = settings
+	return st.flush()
+}
+
+// ArchiveThread returns the thread of the user's archive channel that holds
+// bookmarks for key, a guild ID or tag.
+func (st *Store) ArchiveThread(userID, key string) (string, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	threadID, ok := st.threads[threadKey(userID, key)]
+	return threadID, ok
+}
+
+// SetArchiveThread records the thread for key; an empty threadID forgets it.
+func (st *Store) SetArchiveThread(userID, key, threadID string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if threadID == "" {
+		delete(st.threads, threadKey(userID, key))
+	} else {
+		st.threads[threadKey(userID, key)] = threadID
+	}
+	return st.flush()
+}
+
+func threadKey(userID, key string) string {
+	return userID + ":" + key
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+const (
+	// SHORT_ID_PREFIX starts every short ID, e.g. bk-7F3K.
+	SHORT_ID_PREFIX = "bk-"
+	// SHORT_ID_LENGTH is the number of characters after the prefix; IDs get
+	// longer for users who have used up most of them.
+	SHORT_ID_LENGTH = 4
+	// shortIDAlphabet is Crockford's base32: no I, L, O or U, which are easily
+	// mistaken when typed back.
+	shortIDAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	// shortIDAttempts is how many random IDs are tried before lengthening.
+	shortIDAttempts = 8
+)
+
+// newShortID picks a random short ID for which taken returns false.
+func newShortID(taken func(id string) bool) string {
+	for length := SHORT_ID_LENGTH; ; length++ {
+		for range shortIDAttempts {
+			var id strings.Builder
+			id.WriteString(SHORT_ID_PREFIX)
+			for range length {
+				id.WriteByte(shortIDAlphabet[rand.IntN(len(shortIDAlphabet))])
+			}
+			if !taken(id.String()) {
+				return id.String()
+			}
+		}
+	}
+}
+
+// NormalizeShortID canonicalizes a short ID typed by a user: case, a missing
+// prefix and the letters Crockford's base32 reads as digits are forgiven. It
+// reports false for anything that can't be a short ID.
+func NormalizeShortID(s string) (string, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, strings.ToUpper(SHORT_ID_PREFIX))
+	s = strings.NewReplacer("O", "0", "I", "1", "L", "1").Replace(s)
+	if len(s) < SHORT_ID_LENGTH {
+		return "", false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune(shortIDAlphabet, c) {
+			return "", false
+		}
+	}
+	return SHORT_ID_PREFIX + s, true
+}
+
+// NewShortID returns a short ID none of the user's bookmarks has yet.
+func (st *Store) NewShortID(userID string) string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.newShortID(userID)
+}
+
+// newShortID also avoids the IDs of the user's deleted bookmarks, which keep
+// theirs when restored.
+func (st *Store) newShortID(userID string) string {
+	return newShortID(func(id string) bool {
+		if _, ok := st.byShortID(userID, id); ok {
+			return true
+		}
+		for _, t := range st.trash {
+			if t.UserID == userID && t.ShortID == id {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ByShortID returns the user's bookmark with the given short ID, which must
+// be normalized.
+func (st *Store) ByShortID(userID, shortID string) (Bookmark, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.byShortID(userID, shortID)
+}
+
+func (st *Store) byShortID(userID, shortID string) (Bookmark, bool) {
+	for _, b := range st.bookmarks {
+		if b.UserID == userID && b.ShortID == shortID {
+			return b, true
+		}
+	}
+	return Bookmark{}, false
+}
+
+// assignShortIDs gives bookmarks saved before short IDs existed one. It
+// reports whether any was missing.
+func (st *Store) assignShortIDs() bool {
+	assigned := false
+	for i := range st.bookmarks {
+		if st.bookmarks[i].ShortID == "" {
+			st.bookmarks[i].ShortID = st.newShortID(st.bookmarks[i].UserID)
+			assigned = true
+		}
+	}
+	return assigned
+}
+
+func (st *SQLStore) NewShortID(userID string) string {
+	return newShortID(func(id string) bool {
+		var n int
+		err := st.db.QueryRow(st.rebind(`SELECT
+			(SELECT COUNT(*) FROM bookmarks WHERE user_id = ? AND short_id = ?) +
+			(SELECT COUNT(*) FROM deleted_bookmarks WHERE user_id = ? AND short_id = ?)`), userID, id, userID, id).Scan(&n)
+		if err != nil {
+			st.logger.Printf("Error checking short ID %s of user %s: %v", id, userID, err)
+		}
+		return n > 0
+	})
+}
+
+func (st *SQLStore) ByShortID(userID, shortID string) (Bookmark, bool) {
+	return st.queryOne(`WHERE user_id = ? AND short_id = ?`, userID, shortID)
+}
+
+// assignShortIDs gives bookmarks saved before short IDs existed one.
+func (st *SQLStore) assignShortIDs() error {
+	missing, err := st.query(`WHERE short_id = ''`)
+	if err != nil {
+		return err
+	}
+	for _, b := range missing {
+		err := st.exec(`UPDATE bookmarks SET short_id = ? WHERE user_id = ? AND channel_id = ? AND message_id = ?`,
+			st.NewShortID(b.UserID), b.UserID, b.ChannelID, b.MessageID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeShortID(t *testing.T) {
+	for in, want := range map[string]string{
+		"bk-7F3K":  "bk-7F3K",
+		" 7f3k ":   "bk-7F3K",
+		"BK-7FOL":  "bk-7F01",
+		"bk-7F3K9": "bk-7F3K9",
+	} {
+		if got, ok := NormalizeShortID(in); !ok || got != want {
+			t.Errorf("NormalizeShortID(%q) = %q, %t, want %q", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "bk-7F", "bk-7F3U", "https://discord.com/channels/1/2/3"} {
+		if got, ok := NormalizeShortID(in); ok {
+			t.Errorf("NormalizeShortID(%q) = %q, want it rejected", in, got)
+		}
+	}
+}
+
+func TestAddAssignsUniqueShortIDs(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b testBackend) {
+		st := b.newStore(t)
+
+		seen := map[string]bool{}
+		for _, id := range []string{"1", "2", "3", "4", "5"} {
+			st.Add(Bookmark{UserID: "user", ChannelID: "channel", MessageID: id})
+			bm, _ := st.Get("user", "channel", id)
+			if _, ok := NormalizeShortID(bm.ShortID); !ok || seen[bm.ShortID] {
+				t.Fatalf("short ID %q invalid or reused", bm.ShortID)
+			}
+			seen[bm.ShortID] = true
+
+			if found, ok := st.ByShortID("user", bm.ShortID); !ok || found.MessageID != id {
+				t.Errorf("ByShortID(%q) = %+v, %t", bm.ShortID, found, ok)
+			}
+		}
+		if _, ok := st.ByShortID("other", st.ForUser("user")[0].ShortID); ok {
+			t.Error("ByShortID found another user's bookmark")
+		}
+	})
+}
+
+func TestOpenAssignsMissingShortIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	os.WriteFile(path, []byte(`[{"user_id": "user", "channel_id": "channel", "message_id": "message"}]`), 0o644)
+
+	st, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bm, _ := st.Get("user", "channel", "message"); bm.ShortID == "" {
+		t.Error("legacy bookmark got no short ID")
+	}
+}
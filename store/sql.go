@@ -0,0 +1,541 @@
+package store
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQL dialects, also the STORE_DRIVER values selecting them.
+const (
+	DIALECT_SQLITE   = "sqlite"
+	DIALECT_POSTGRES = "postgres"
+)
+
+// sqlDrivers maps dialects to the database/sql driver registered for them by
+// the files built with the matching build tag.
+var sqlDrivers = map[string]string{
+	DIALECT_SQLITE:   "sqlite",
+	DIALECT_POSTGRES: "pgx",
+}
+
+// SQLAvailable reports whether the driver of a dialect is compiled in.
+func SQLAvailable(dialect string) bool {
+	driver, ok := sqlDrivers[dialect]
+	return ok && slices.Contains(sql.Drivers(), driver)
+}
+
+//go:embed migrations
+var migrations embed.FS
+
+const bookmarkColumns = `user_id, guild_id, channel_id, message_id, dm_channel_id, dm_message_id, content,
+	created_at, edited_at, source_deleted, archived, pending, pinned, status, tags, transcript, note, orphaned, short_id,
+	content_hash, reposts, compact, remind_at, translation, history, encryption_key, sealed_content_hash,
+	user_install`
+
+// bookmarkValues has a placeholder for each of bookmarkColumns.
+var bookmarkValues = strings.Repeat("?, ", strings.Count(bookmarkColumns, ",")) + "?"
+
+const settingsColumns = `delivery, last_digest, archive_channel_id, archive_threads, webhook_url, notion_token, notion_database_id, verbosity, language, remove_reaction`
+
+var (
+	// settingsValues has a placeholder for each of settingsColumns.
+	settingsValues = strings.Repeat("?, ", strings.Count(settingsColumns, ",")) + "?"
+	// settingsUpsert overwrites every one of settingsColumns on conflict.
+	settingsUpsert = func() string {
+		var set []string
+		for _, column := range strings.Split(settingsColumns, ", ") {
+			set = append(set, column+" = excluded."+column)
+		}
+		return strings.Join(set, ", ")
+	}()
+)
+
+// SQLStore is a BookmarkStore backed by SQLite or PostgreSQL, so several bot
+// instances can share one database.
+//
+// Read methods can't return errors through BookmarkStore; they are logged and
+// an empty result is returned instead.
+type SQLStore struct {
+	db      *sql.DB
+	dialect string
+	logger  *log.Logger
+}
+
+var _ BookmarkStore = (*SQLStore)(nil)
+
+// OpenSQL connects to the database and applies pending migrations.
+func OpenSQL(dialect, dsn string, logger *log.Logger) (*SQLStore, error) {
+	driver, ok := sqlDrivers[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unknown SQL dialect %q", dialect)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w (is the binary built with -tags %s?)", err, dialect)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	st := &SQLStore{db: db, dialect: dialect, logger: logger}
+	if err := st.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating database: %w", err)
+	}
+	if err := st.assignShortIDs(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("assigning short IDs: %w", err)
+	}
+	return st, nil
+}
+
+func (st *SQLStore) Close() error {
+	return st.db.Close()
+}
+
+// migrate applies the embedded migrations of the dialect that haven't been
+// applied yet, each in its own transaction.
+func (st *SQLStore) migrate() error {
+	_, err := st.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return err
+	}
+
+	dir := path.Join("migrations", st.dialect)
+	entries, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	for _, entry := range entries {
+		version, err := strconv.Atoi(strings.SplitN(entry.Name(), "_", 2)[0])
+		if err != nil {
+			return fmt.Errorf("migration %s: file name must start with its version", entry.Name())
+		}
+
+		var applied int
+		err = st.db.QueryRow(st.rebind(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`), version).Scan(&applied)
+		if err != nil {
+			return err
+		}
+		if applied > 0 {
+			continue
+		}
+
+		script, err := migrations.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := st.applyMigration(version, string(script)); err != nil {
+			return fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		st.logger.Printf("Applied database migration %s", entry.Name())
+	}
+	return nil
+}
+
+func (st *SQLStore) applyMigration(version int, script string) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range strings.Split(script, ";") {
+		if strings.TrimSpace(stmt) == "" {
+			continue
+		}
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(st.rebind(`INSERT INTO schema_migrations (version) VALUES (?)`), version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// rebind rewrites ? placeholders to $n for PostgreSQL.
+func (st *SQLStore) rebind(query string) string {
+	if st.dialect != DIALECT_POSTGRES {
+		return query
+	}
+
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func (st *SQLStore) exec(query string, args ...any) error {
+	_, err := st.db.Exec(st.rebind(query), args...)
+	return err
+}
+
+// query returns the bookmarks selected by where, oldest first.
+func (st *SQLStore) query(where string, args ...any) ([]Bookmark, error) {
+	return st.queryFrom("bookmarks", where, args...)
+}
+
+// queryFrom is query for a table with the bookmark columns.
+func (st *SQLStore) queryFrom(table, where string, args ...any) ([]Bookmark, error) {
+	rows, err := st.db.Query(st.rebind(`SELECT `+bookmarkColumns+` FROM `+table+` `+where+` ORDER BY created_at`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookmarks []Bookmark
+	for rows.Next() {
+		var b Bookmark
+		var editedAt, remindAt sql.NullTime
+		var tags, reposts, history string
+		err := rows.Scan(&b.UserID, &b.GuildID, &b.ChannelID, &b.MessageID, &b.DMChannelID, &b.DMMessageID, &b.Content,
+			&b.CreatedAt, &editedAt, &b.SourceDeleted, &b.Archived, &b.Pending, &b.Pinned, &b.Status, &tags, &b.Transcript, &b.Note, &b.Orphaned, &b.ShortID,
+			&b.ContentHash, &reposts, &b.Compact, &remindAt, &b.Translation, &history, &b.EncryptionKey, &b.SealedContentHash,
+			&b.UserInstall)
+		if err != nil {
+			return nil, err
+		}
+		if editedAt.Valid {
+			b.EditedAt = &editedAt.Time
+		}
+		if remindAt.Valid {
+			b.RemindAt = &remindAt.Time
+		}
+		if err := json.Unmarshal([]byte(tags), &b.Tags); err != nil {
+			return nil, fmt.Errorf("decoding tags: %w", err)
+		}
+		if err := json.Unmarshal([]byte(reposts), &b.Reposts); err != nil {
+			return nil, fmt.Errorf("decoding reposts: %w", err)
+		}
+		if err := json.Unmarshal([]byte(history), &b.History); err != nil {
+			return nil, fmt.Errorf("decoding history: %w", err)
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, rows.Err()
+}
+
+// mustQuery is query for the read methods, which log errors.
+func (st *SQLStore) mustQuery(where string, args ...any) []Bookmark {
+	bookmarks, err := st.query(where, args...)
+	if err != nil {
+		st.logger.Printf("Error querying bookmarks: %v", err)
+	}
+	return bookmarks
+}
+
+func (st *SQLStore) queryOne(where string, args ...any) (Bookmark, bool) {
+	bookmarks := st.mustQuery(where, args...)
+	if len(bookmarks) == 0 {
+		return Bookmark{}, false
+	}
+	return bookmarks[0], true
+}
+
+func bookmarkArgs(b Bookmark) ([]any, error) {
+	tags, err := json.Marshal(b.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if b.Tags == nil {
+		tags = []byte("[]")
+	}
+	reposts, err := json.Marshal(b.Reposts)
+	if err != nil {
+		return nil, err
+	}
+	if b.Reposts == nil {
+		reposts = []byte("[]")
+	}
+	history, err := json.Marshal(b.History)
+	if err != nil {
+		return nil, err
+	}
+	if b.History == nil {
+		history = []byte("[]")
+	}
+
+	var editedAt, remindAt sql.NullTime
+	if b.EditedAt != nil {
+		editedAt = sql.NullTime{Time: *b.EditedAt, Valid: true}
+	}
+	if b.RemindAt != nil {
+		remindAt = sql.NullTime{Time: *b.RemindAt, Valid: true}
+	}
+
+	return []any{b.UserID, b.GuildID, b.ChannelID, b.MessageID, b.DMChannelID, b.DMMessageID, b.Content,
+		b.CreatedAt, editedAt, b.SourceDeleted, b.Archived, b.Pending, b.Pinned, string(b.Status), string(tags), b.Transcript, b.Note, b.Orphaned, b.ShortID,
+		b.ContentHash, string(reposts), b.Compact, remindAt, b.Translation, string(history), b.EncryptionKey, b.SealedContentHash,
+		b.UserInstall}, nil
+}
+
+func (st *SQLStore) Add(b Bookmark) error {
+	if b.ShortID == "" {
+		b.ShortID = st.NewShortID(b.UserID)
+	}
+	args, err := bookmarkArgs(b)
+	if err != nil {
+		return err
+	}
+	return st.exec(`INSERT INTO bookmarks (`+bookmarkColumns+`) VALUES (`+bookmarkValues+`)`, args...)
+}
+
+func (st *SQLStore) BySource(channelID, messageID string) []Bookmark {
+	return st.mustQuery(`WHERE channel_id = ? AND message_id = ?`, channelID, messageID)
+}
+
+func (st *SQLStore) Has(userID, channelID, messageID string) bool {
+	_, ok := st.Get(userID, channelID, messageID)
+	return ok
+}
+
+func (st *SQLStore) Get(userID, channelID, messageID string) (Bookmark, bool) {
+	return st.queryOne(`WHERE user_id = ? AND channel_id = ? AND message_id = ?`, userID, channelID, messageID)
+}
+
+func (st *SQLStore) ForUser(userID string) []Bookmark {
+	return st.mustQuery(`WHERE user_id = ?`, userID)
+}
+
+func (st *SQLStore) All() []Bookmark {
+	return st.mustQuery(``)
+}
+
+func (st *SQLStore) Count() int {
+	var n int
+	if err := st.db.QueryRow(`SELECT COUNT(*) FROM bookmarks`).Scan(&n); err != nil {
+		st.logger.Printf("Error counting bookmarks: %v", err)
+	}
+	return n
+}
+
+func (st *SQLStore) ByDM(dmChannelID, dmMessageID string) (Bookmark, bool) {
+	if dmMessageID == "" {
+		return Bookmark{}, false
+	}
+	return st.queryOne(`WHERE dm_channel_id = ? AND dm_message_id = ?`, dmChannelID, dmMessageID)
+}
+
+func (st *SQLStore) Update(b Bookmark) error {
+	return st.replaceWhere(b, `user_id = ? AND channel_id = ? AND message_id = ?`, b.UserID, b.ChannelID, b.MessageID)
+}
+
+func (st *SQLStore) Replace(dmChannelID, dmMessageID string, b Bookmark) error {
+	return st.replaceWhere(b, `dm_channel_id = ? AND dm_message_id = ?`, dmChannelID, dmMessageID)
+}
+
+// replaceWhere overwrites the bookmark matching where with b.
+// errBookmarkNotFound is returned by replaceIn when no row matched.
+var errBookmarkNotFound = errors.New("bookmark not found")
+
+func (st *SQLStore) replaceWhere(b Bookmark, where string, whereArgs ...any) error {
+	return st.replaceIn("bookmarks", b, where, whereArgs...)
+}
+
+// replaceIn overwrites the bookmark matching where in a table with
+// bookmarkColumns, bookmarks or deleted_bookmarks.
+func (st *SQLStore) replaceIn(table string, b Bookmark, where string, whereArgs ...any) error {
+	args, err := bookmarkArgs(b)
+	if err != nil {
+		return err
+	}
+
+	res, err := st.db.Exec(st.rebind(`UPDATE `+table+` SET user_id = ?, guild_id = ?, channel_id = ?, message_id = ?,
+		dm_channel_id = ?, dm_message_id = ?, content = ?, created_at = ?, edited_at = ?, source_deleted = ?,
+		archived = ?, pending = ?, pinned = ?, status = ?, tags = ?, transcript = ?, note = ?, orphaned = ?, short_id = ?,
+		content_hash = ?, reposts = ?, compact = ?, remind_at = ?, translation = ?, history = ?, encryption_key = ?,
+		sealed_content_hash = ?, user_install = ? WHERE `+where), append(args, whereArgs...)...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errBookmarkNotFound
+	}
+	return nil
+}
+
+func (st *SQLStore) Remove(dmChannelID, dmMessageID string) error {
+	if dmMessageID == "" {
+		return nil
+	}
+	return st.exec(`DELETE FROM bookmarks WHERE dm_channel_id = ? AND dm_message_id = ?`, dmChannelID, dmMessageID)
+}
+
+func (st *SQLStore) RemoveIf(match func(Bookmark) bool) ([]Bookmark, error) {
+	all, err := st.query(``)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := st.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var removed []Bookmark
+	for _, b := range all {
+		if !match(b) {
+			continue
+		}
+		_, err := tx.Exec(st.rebind(`DELETE FROM bookmarks WHERE user_id = ? AND channel_id = ? AND message_id = ?`),
+			b.UserID, b.ChannelID, b.MessageID)
+		if err != nil {
+			return nil, err
+		}
+		removed = append(removed, b)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+func (st *SQLStore) MarkProcessed(userID, channelID, messageID string) error {
+	now := time.Now()
+	err := st.exec(`DELETE FROM processed WHERE processed_at < ?`, now.Add(-PROCESSED_RETENTION))
+	if err != nil {
+		return err
+	}
+	return st.exec(`INSERT INTO processed (user_id, channel_id, message_id, processed_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, channel_id, message_id) DO UPDATE SET processed_at = excluded.processed_at`,
+		userID, channelID, messageID, now)
+}
+
+func (st *SQLStore) Processed(userID, channelID, messageID string) bool {
+	var n int
+	err := st.db.QueryRow(st.rebind(`SELECT COUNT(*) FROM processed WHERE user_id = ? AND channel_id = ? AND message_id = ?`),
+		userID, channelID, messageID).Scan(&n)
+	if err != nil {
+		st.logger.Printf("Error querying processed reactions: %v", err)
+	}
+	return n > 0
+}
+
+func (st *SQLStore) Settings(userID string) UserSettings {
+	row := st.db.QueryRow(st.rebind(`SELECT `+settingsColumns+` FROM user_settings WHERE user_id = ?`), userID)
+	settings, err := scanSettings(row.Scan)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		st.logger.Printf("Error querying settings of user %s: %v", userID, err)
+	}
+	return settings
+}
+
+// scanSettings reads settingsColumns, after the destinations in prefix.
+func scanSettings(scan func(dest ...any) error, prefix ...any) (UserSettings, error) {
+	var settings UserSettings
+	var lastDigest sql.NullTime
+	err := scan(append(prefix, &settings.Delivery, &lastDigest, &settings.ArchiveChannelID, &settings.ArchiveThreads,
+		&settings.WebhookURL, &settings.NotionToken, &settings.NotionDatabaseID,
+		&settings.Verbosity, &settings.Language, &settings.RemoveReaction)...)
+	settings.LastDigest = lastDigest.Time
+	return settings, err
+}
+
+// settingsArgs returns the values of settingsColumns.
+func settingsArgs(settings UserSettings) []any {
+	return []any{string(settings.Delivery), nullTime(settings.LastDigest), settings.ArchiveChannelID, string(settings.ArchiveThreads),
+		settings.WebhookURL, settings.NotionToken, settings.NotionDatabaseID,
+		string(settings.Verbosity), settings.Language, settings.RemoveReaction}
+}
+
+func (st *SQLStore) SetSettings(userID string, settings UserSettings) error {
+	if settings == (UserSettings{}) {
+		return st.exec(`DELETE FROM user_settings WHERE user_id = ?`, userID)
+	}
+	return st.exec(`INSERT INTO user_settings (user_id, `+settingsColumns+`) VALUES (?, `+settingsValues+`)
+		ON CONFLICT (user_id) DO UPDATE SET `+settingsUpsert,
+		append([]any{userID}, settingsArgs(settings)...)...)
+}
+
+func (st *SQLStore) AllSettings() map[string]UserSettings {
+	all := map[string]UserSettings{}
+
+	rows, err := st.db.Query(`SELECT user_id, ` + settingsColumns + ` FROM user_settings`)
+	if err != nil {
+		st.logger.Printf("Error querying user settings: %v", err)
+		return all
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID string
+		settings, err := scanSettings(rows.Scan, &userID)
+		if err != nil {
+			st.logger.Printf("Error reading user settings: %v", err)
+			return all
+		}
+		all[userID] = settings
+	}
+	return all
+}
+
+func (st *SQLStore) Pending(userID string) []Bookmark {
+	return st.mustQuery(`WHERE user_id = ? AND pending`, userID)
+}
+
+// MarkDigested clears the pending bookmarks and records the digest in one
+// transaction, writing only last_digest so a concurrent change of the
+// user's other settings isn't overwritten.
+func (st *SQLStore) MarkDigested(userID string, at time.Time) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(st.rebind(`UPDATE bookmarks SET pending = FALSE WHERE user_id = ? AND pending AND created_at <= ?`), userID, at)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(st.rebind(`INSERT INTO user_settings (user_id, last_digest) VALUES (?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET last_digest = excluded.last_digest`), userID, nullTime(at))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (st *SQLStore) ArchiveThread(userID, key string) (string, bool) {
+	var threadID string
+	err := st.db.QueryRow(st.rebind(`SELECT thread_id FROM archive_threads WHERE user_id = ? AND key = ?`), userID, key).Scan(&threadID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		st.logger.Printf("Error querying archive thread of user %s: %v", userID, err)
+	}
+	return threadID, err == nil
+}
+
+func (st *SQLStore) SetArchiveThread(userID, key, threadID string) error {
+	if threadID == "" {
+		return st.exec(`DELETE FROM archive_threads WHERE user_id = ? AND key = ?`, userID, key)
+	}
+	return st.exec(`INSERT INTO archive_threads (user_id, key, thread_id) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, key) DO UPDATE SET thread_id = excluded.thread_id`, userID, key, threadID)
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
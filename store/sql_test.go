@@ -0,0 +1,61 @@
+package store
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	query := `SELECT a FROM t WHERE b = ? AND c = ?`
+
+	sqlite := &SQLStore{dialect: DIALECT_SQLITE}
+	if got := sqlite.rebind(query); got != query {
+		t.Errorf("sqlite rebind = %q", got)
+	}
+
+	postgres := &SQLStore{dialect: DIALECT_POSTGRES}
+	if got, want := postgres.rebind(query), `SELECT a FROM t WHERE b = $1 AND c = $2`; got != want {
+		t.Errorf("postgres rebind = %q, want %q", got, want)
+	}
+}
+
+// Both dialects must define the same migrations.
+func TestMigrationsMatch(t *testing.T) {
+	sqlite, err := fs.ReadDir(migrations, "migrations/"+DIALECT_SQLITE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgres, err := fs.ReadDir(migrations, "migrations/"+DIALECT_POSTGRES)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sqlite) == 0 || len(sqlite) != len(postgres) {
+		t.Fatalf("%d sqlite and %d postgres migrations", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].Name() != postgres[i].Name() {
+			t.Errorf("migration %d is %s for sqlite but %s for postgres", i, sqlite[i].Name(), postgres[i].Name())
+		}
+	}
+}
+
+func TestPlaceholdersMatchColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns string
+		values  string
+	}{
+		{"bookmarks", bookmarkColumns, bookmarkValues},
+		{"user settings", settingsColumns, settingsValues},
+		{"guild settings", "guild_id, " + guildSettingsColumns, guildSettingsValues},
+		{"collections", collectionColumns, collectionValues},
+	}
+	for _, tt := range tests {
+		columns := len(strings.Split(tt.columns, ","))
+		if values := strings.Count(tt.values, "?"); values != columns {
+			t.Errorf("%s: %d placeholders for %d columns", tt.name, values, columns)
+		}
+	}
+}
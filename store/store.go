@@ -0,0 +1,507 @@
+// Package store persists bookmarks, user settings and handled reaction events.
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// PROCESSED_RETENTION bounds how long handled reaction events are remembered.
+	PROCESSED_RETENTION = 14 * 24 * time.Hour
+)
+
+// BookmarkStatus tells whether a bookmark is shown in default listings.
+type BookmarkStatus string
+
+const (
+	StatusActive   BookmarkStatus = ""
+	StatusArchived BookmarkStatus = "archived"
+)
+
+// Bookmark links a source message to the DM embed delivered for it. With an
+// archive channel configured, the "DM" is a message in that channel or one
+// of its threads.
+type Bookmark struct {
+	UserID      string `json:"user_id"`
+	GuildID     string `json:"guild_id"`
+	ChannelID   string `json:"channel_id"`
+	MessageID   string `json:"message_id"`
+	DMChannelID string `json:"dm_channel_id"`
+	DMMessageID string `json:"dm_message_id"`
+	Content     string `json:"content"`
+	// Transcript is the speech-to-text of the message's voice or audio attachments.
+	Transcript string     `json:"transcript,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	EditedAt   *time.Time `json:"edited_at,omitempty"`
+	// SourceDeleted is set once the bookmarked message has been deleted.
+	SourceDeleted bool `json:"source_deleted,omitempty"`
+	// Archived is set when the attachments were re-uploaded to the DM.
+	Archived bool `json:"archived,omitempty"`
+
+	// Pending is set while a bookmark awaits the user's next digest; it has no DM of its own.
+	Pending bool `json:"pending,omitempty"`
+
+	Pinned bool           `json:"pinned,omitempty"`
+	Status BookmarkStatus `json:"status,omitempty"`
+	Tags   []string       `json:"tags,omitempty"`
+	// Note is the user's own annotation of the bookmark.
+	Note string `json:"note,omitempty"`
+	// Orphaned is set once the bot left the source guild or the user was
+	// banned from it, see the guild retention policy.
+	Orphaned bool `json:"orphaned,omitempty"`
+	// UserInstall is set when the bookmark was made through a user install
+	// in a guild the bot isn't in, so retention doesn't apply to it.
+	UserInstall bool `json:"user_install,omitempty"`
+	// ShortID identifies the bookmark among the user's in commands, e.g.
+	// bk-7F3K. Add assigns one if it's empty.
+	ShortID string `json:"short_id,omitempty"`
+	// ContentHash identifies the message's content and attachments, so
+	// reposts of it can be recognized.
+	ContentHash string `json:"content_hash,omitempty"`
+	// Reposts are the other messages with the same content merged into
+	// this bookmark.
+	Reposts []Source `json:"reposts,omitempty"`
+	// Compact is set while the DM shows the compact embed, until expanded.
+	Compact bool `json:"compact,omitempty"`
+	// Translation is the message text translated into the user's language.
+	Translation string `json:"translation,omitempty"`
+	// RemindAt is when the user asked to be reminded of the bookmark; it's
+	// cleared once the reminder is sent.
+	RemindAt *time.Time `json:"remind_at,omitempty"`
+	// History holds the earlier versions of the content and note, see AddVersion.
+	History []Version `json:"history,omitempty"`
+	// EncryptionKey is the ID of the key the text was encrypted with, empty
+	// if it's stored in plain text. SealedContentHash then holds ContentHash
+	// encrypted, while ContentHash is keyed, see EncryptedStore.
+	EncryptionKey     string `json:"encryption_key,omitempty"`
+	SealedContentHash string `json:"sealed_content_hash,omitempty"`
+}
+
+// Source is a message a bookmark was made from.
+type Source struct {
+	GuildID   string `json:"guild_id"`
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id"`
+}
+
+// HasDM reports whether the bookmark was delivered as its own DM, rather
+// than in a digest.
+func (b Bookmark) HasDM() bool {
+	return b.DMMessageID != ""
+}
+
+// BookmarkStore is implemented by every storage backend.
+type BookmarkStore interface {
+	Add(b Bookmark) error
+	BySource(channelID, messageID string) []Bookmark
+	Has(userID, channelID, messageID string) bool
+	Get(userID, channelID, messageID string) (Bookmark, bool)
+	ForUser(userID string) []Bookmark
+	All() []Bookmark
+	Count() int
+	ByDM(dmChannelID, dmMessageID string) (Bookmark, bool)
+	Update(b Bookmark) error
+	Replace(dmChannelID, dmMessageID string, b Bookmark) error
+	Remove(dmChannelID, dmMessageID string) error
+	RemoveIf(match func(Bookmark) bool) ([]Bookmark, error)
+
+	MarkProcessed(userID, channelID, messageID string) error
+	Processed(userID, channelID, messageID string) bool
+	// Claim and Release let one of several instances sharing the store
+	// handle an event at a time.
+	Claim(key string, now time.Time) (bool, error)
+	Release(key string) error
+
+	Settings(userID string) UserSettings
+	SetSettings(userID string, settings UserSettings) error
+	AllSettings() map[string]UserSettings
+	Pending(userID string) []Bookmark
+	// Reminders returns the user's bookmarks with a reminder, soonest first;
+	// DueReminders returns every user's reminders due at or before now.
+	Reminders(userID string) []Bookmark
+	DueReminders(now time.Time) []Bookmark
+	MarkDigested(userID string, at time.Time) error
+	ArchiveThread(userID, key string) (string, bool)
+	SetArchiveThread(userID, key, threadID string) error
+	GuildSettings(guildID string) GuildSettings
+	SetGuildSettings(guildID string, settings GuildSettings) error
+	// Collection finds a collection by owner and name; Collections returns the ones a
+	// user owns, was shared or subscribes to.
+	Collection(ownerID, name string) (Collection, bool)
+	SetCollection(c Collection) error
+	DeleteCollection(ownerID, name string) error
+	Collections(userID string) []Collection
+
+	AddAudit(e AuditEntry) error
+	AuditLog(f AuditFilter) ([]AuditEntry, error)
+
+	// ForgetUser removes everything stored about a user.
+	ForgetUser(userID string) ([]Bookmark, error)
+
+	// Trash, Untrash and PurgeTrash keep deleted bookmarks restorable for a while.
+	Trash(dmChannelID, dmMessageID string, at time.Time) (Bookmark, bool, error)
+	Untrash(userID, channelID, messageID string) (Bookmark, bool, error)
+	// Retrash puts a bookmark taken out with Untrash back, deleted at at,
+	// when it couldn't be stored again.
+	Retrash(b Bookmark, at time.Time) error
+	PurgeTrash(before time.Time) (int, error)
+	// UpdateTrash replaces each deleted bookmark update returns true for
+	// with the bookmark it returns, and returns how many changed.
+	UpdateTrash(update func(Bookmark) (Bookmark, bool)) (int, error)
+
+	// NewShortID returns a short ID none of the user's bookmarks has yet;
+	// ByShortID finds the bookmark with one, normalized by NormalizeShortID.
+	NewShortID(userID string) string
+	ByShortID(userID, shortID string) (Bookmark, bool)
+	// ByContentHash returns the user's bookmarks with the given content hash.
+	ByContentHash(userID, hash string) []Bookmark
+
+	// Snapshot copies the whole store for a backup; Restore replaces the
+	// whole store with a snapshot.
+	Snapshot() (Snapshot, error)
+	Restore(s Snapshot) error
+
+	Close() error
+}
+
+var _ BookmarkStore = (*Store)(nil)
+
+// Store is a JSON file backed BookmarkStore. Every mutation is flushed to disk.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	bookmarks []Bookmark
+	// processed records handled 🔖 reaction events, keyed by processedKey.
+	processed map[string]time.Time
+	// claims are the events claimed by this process, see Claim.
+	claims map[string]time.Time
+	users  map[string]UserSettings
+	// threads maps threadKey to archive channel thread IDs.
+	threads map[string]string
+	guilds  map[string]GuildSettings
+	// collections are keyed by owner and normalized name, see collectionKey.
+	collections map[string]Collection
+	trash       []trashedBookmark
+	audit       []AuditEntry
+}
+
+// storeFile is the on-disk layout of the store.
+type storeFile struct {
+	Bookmarks   []Bookmark               `json:"bookmarks"`
+	Processed   map[string]time.Time     `json:"processed,omitempty"`
+	Users       map[string]UserSettings  `json:"users,omitempty"`
+	Threads     map[string]string        `json:"threads,omitempty"`
+	Guilds      map[string]GuildSettings `json:"guilds,omitempty"`
+	Collections map[string]Collection    `json:"collections,omitempty"`
+	Trash       []trashedBookmark        `json:"trash,omitempty"`
+	Audit       []AuditEntry             `json:"audit,omitempty"`
+}
+
+// Open loads the store at path, starting empty if the file doesn't exist yet.
+func Open(path string) (*Store, error) {
+	st := &Store{path: path, processed: map[string]time.Time{}, users: map[string]UserSettings{}, threads: map[string]string{}, guilds: map[string]GuildSettings{}, collections: map[string]Collection{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Stores written by older versions are a bare array of bookmarks.
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(data, &st.bookmarks); err != nil {
+			return nil, err
+		}
+		if st.assignShortIDs() {
+			return st, st.flush()
+		}
+		return st, nil
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	st.bookmarks = file.Bookmarks
+	if file.Processed != nil {
+		st.processed = file.Processed
+	}
+	if file.Users != nil {
+		st.users = file.Users
+	}
+	if file.Threads != nil {
+		st.threads = file.Threads
+	}
+	if file.Guilds != nil {
+		st.guilds = file.Guilds
+	}
+	if file.Collections != nil {
+		// Files written before names were unique per owner key them by name.
+		st.collections = keyCollections(slices.Collect(maps.Values(file.Collections)))
+	}
+	st.trash = file.Trash
+	st.audit = file.Audit
+
+	if st.assignShortIDs() {
+		if err := st.flush(); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// Close is a no-op, every mutation is already on disk.
+func (st *Store) Close() error {
+	return nil
+}
+
+func (st *Store) Add(b Bookmark) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if b.ShortID == "" {
+		b.ShortID = st.newShortID(b.UserID)
+	}
+	st.bookmarks = append(st.bookmarks, b)
+	if err := st.flush(); err != nil {
+		// The caller gives up on the bookmark; don't persist it with a later flush.
+		st.bookmarks = st.bookmarks[:len(st.bookmarks)-1]
+		return err
+	}
+	return nil
+}
+
+// BySource returns every bookmark of the given source message.
+func (st *Store) BySource(channelID, messageID string) []Bookmark {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var found []Bookmark
+	for _, b := range st.bookmarks {
+		if b.ChannelID == channelID && b.MessageID == messageID {
+			found = append(found, b)
+		}
+	}
+	return found
+}
+
+// Has reports whether the user already bookmarked the given message.
+func (st *Store) Has(userID, channelID, messageID string) bool {
+	_, ok := st.Get(userID, channelID, messageID)
+	return ok
+}
+
+// Get returns the user's bookmark of the given message.
+func (st *Store) Get(userID, channelID, messageID string) (Bookmark, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	i := st.indexBySource(userID, channelID, messageID)
+	if i == -1 {
+		return Bookmark{}, false
+	}
+	return st.bookmarks[i], true
+}
+
+// ForUser returns every bookmark of the user, oldest first.
+func (st *Store) ForUser(userID string) []Bookmark {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var found []Bookmark
+	for _, b := range st.bookmarks {
+		if b.UserID == userID {
+			found = append(found, b)
+		}
+	}
+	return found
+}
+
+// All returns a copy of every stored bookmark, oldest first.
+func (st *Store) All() []Bookmark {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return append([]Bookmark(nil), st.bookmarks...)
+}
+
+// Count returns the number of stored bookmarks.
+func (st *Store) Count() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return len(st.bookmarks)
+}
+
+// ByDM returns the bookmark delivered as the given DM message.
+func (st *Store) ByDM(dmChannelID, dmMessageID string) (Bookmark, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if i := st.indexByDM(dmChannelID, dmMessageID); i != -1 {
+		return st.bookmarks[i], true
+	}
+	return Bookmark{}, false
+}
+
+// Update replaces the user's stored bookmark of b's source message.
+func (st *Store) Update(b Bookmark) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	i := st.indexBySource(b.UserID, b.ChannelID, b.MessageID)
+	if i == -1 {
+		return os.ErrNotExist
+	}
+	st.bookmarks[i] = b
+	return st.flush()
+}
+
+// Replace swaps the bookmark delivered as the given DM message for b, which
+// may point to a different DM message.
+func (st *Store) Replace(dmChannelID, dmMessageID string, b Bookmark) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	i := st.indexByDM(dmChannelID, dmMessageID)
+	if i == -1 {
+		return os.ErrNotExist
+	}
+	st.bookmarks[i] = b
+	return st.flush()
+}
+
+func (st *Store) Remove(dmChannelID, dmMessageID string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	i := st.indexByDM(dmChannelID, dmMessageID)
+	if i == -1 {
+		return nil
+	}
+	st.bookmarks = append(st.bookmarks[:i], st.bookmarks[i+1:]...)
+	return st.flush()
+}
+
+// RemoveIf removes every bookmark for which match returns true and returns
+// the removed bookmarks.
+func (st *Store) RemoveIf(match func(Bookmark) bool) ([]Bookmark, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var removed []Bookmark
+	// kept is a copy, so the bookmarks stay as they were if the flush fails.
+	kept := make([]Bookmark, 0, len(st.bookmarks))
+	for _, b := range st.bookmarks {
+		if match(b) {
+			removed = append(removed, b)
+		} else {
+			kept = append(kept, b)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	prev := st.bookmarks
+	st.bookmarks = kept
+	if err := st.flush(); err != nil {
+		st.bookmarks = prev
+		return nil, err
+	}
+	return removed, nil
+}
+
+// MarkProcessed records that a user's 🔖 reaction on a message was handled,
+// forgetting events older than PROCESSED_RETENTION.
+func (st *Store) MarkProcessed(userID, channelID, messageID string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	for key, at := range st.processed {
+		if now.Sub(at) > PROCESSED_RETENTION {
+			delete(st.processed, key)
+		}
+	}
+
+	st.processed[processedKey(userID, channelID, messageID)] = now
+	return st.flush()
+}
+
+// Processed reports whether a user's 🔖 reaction on a message was handled.
+func (st *Store) Processed(userID, channelID, messageID string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	_, ok := st.processed[processedKey(userID, channelID, messageID)]
+	return ok
+}
+
+func processedKey(userID, channelID, messageID string) string {
+	return userID + ":" + channelID + ":" + messageID
+}
+
+func (st *Store) indexBySource(userID, channelID, messageID string) int {
+	for i, b := range st.bookmarks {
+		if b.UserID == userID && b.ChannelID == channelID && b.MessageID == messageID {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexByDM never matches digest bookmarks, which have no DM message.
+func (st *Store) indexByDM(dmChannelID, dmMessageID string) int {
+	if dmMessageID == "" {
+		return -1
+	}
+	for i, b := range st.bookmarks {
+		if b.DMChannelID == dmChannelID && b.DMMessageID == dmMessageID {
+			return i
+		}
+	}
+	return -1
+}
+
+// flush atomically rewrites the store file. Callers must hold st.mu.
+func (st *Store) flush() error {
+	data, err := json.MarshalIndent(storeFile{
+		Bookmarks:   st.bookmarks,
+		Processed:   st.processed,
+		Users:       st.users,
+		Threads:     st.threads,
+		Guilds:      st.guilds,
+		Collections: st.collections,
+		Trash:       st.trash,
+		Audit:       st.audit,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(st.path), ".bookmarks-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), st.path)
+}
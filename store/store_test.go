@@ -0,0 +1,147 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStorePersists(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b testBackend) {
+		location := b.location(t)
+		st := b.openStore(t, location)
+		bm := Bookmark{
+			UserID:      "user",
+			GuildID:     "guild",
+			ChannelID:   "channel",
+			MessageID:   "message",
+			DMChannelID: "dm",
+			DMMessageID: "dm-message",
+			CreatedAt:   time.Now(),
+		}
+		if err := st.Add(bm); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		if err := st.MarkProcessed("user", "channel", "message"); err != nil {
+			t.Fatalf("MarkProcessed: %v", err)
+		}
+
+		st = b.openStore(t, location)
+		if !st.Has("user", "channel", "message") {
+			t.Error("bookmark was not persisted")
+		}
+		if !st.Processed("user", "channel", "message") {
+			t.Error("processed reaction was not persisted")
+		}
+		if got := st.BySource("channel", "message"); len(got) != 1 {
+			t.Errorf("BySource returned %d bookmarks, want 1", len(got))
+		}
+
+		if err := st.Remove("dm", "dm-message"); err != nil {
+			t.Fatalf("Remove: %v", err)
+		}
+		if _, ok := st.ByDM("dm", "dm-message"); ok {
+			t.Error("bookmark still stored after Remove")
+		}
+	})
+}
+
+func TestStoreReadsLegacyArray(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	legacy := `[{"user_id": "user", "channel_id": "channel", "message_id": "message", "dm_channel_id": "dm", "dm_message_id": "dm-message"}]`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !st.Has("user", "channel", "message") {
+		t.Error("legacy bookmark was not loaded")
+	}
+}
+
+func TestStoreAddRollsBackFailedFlush(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "store")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "bookmarks.json")
+	st, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	// Without its directory the store file can't be written.
+	os.RemoveAll(dir)
+	if err := st.Add(Bookmark{UserID: "user", ChannelID: "channel", MessageID: "failed"}); err == nil {
+		t.Fatal("Add succeeded without a directory to write to")
+	}
+	if st.Has("user", "channel", "failed") {
+		t.Error("bookmark kept after its flush failed")
+	}
+
+	os.Mkdir(dir, 0o700)
+	if err := st.Add(Bookmark{UserID: "user", ChannelID: "channel", MessageID: "stored"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if st, _ := Open(path); st.Has("user", "channel", "failed") || !st.Has("user", "channel", "stored") {
+		t.Error("failed bookmark persisted by a later flush")
+	}
+}
+
+func TestMarkDigestedKeepsOtherSettings(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b testBackend) {
+		st := b.newStore(t)
+		now := time.Now().Truncate(time.Second)
+		st.Add(Bookmark{UserID: "user", ChannelID: "channel", MessageID: "message", CreatedAt: now.Add(-time.Hour), Pending: true})
+		if err := st.SetSettings("user", UserSettings{Delivery: DeliveryDaily, Language: "de"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := st.MarkDigested("user", now); err != nil {
+			t.Fatalf("MarkDigested: %v", err)
+		}
+		settings := st.Settings("user")
+		if settings.Delivery != DeliveryDaily || settings.Language != "de" || !settings.LastDigest.Equal(now) {
+			t.Errorf("settings = %+v after MarkDigested", settings)
+		}
+		if pending := st.Pending("user"); len(pending) != 0 {
+			t.Errorf("%d bookmarks still pending", len(pending))
+		}
+
+		// Users without settings get a row for the digest.
+		if err := st.MarkDigested("new-user", now); err != nil {
+			t.Fatalf("MarkDigested of a user without settings: %v", err)
+		}
+		if got := st.Settings("new-user").LastDigest; !got.Equal(now) {
+			t.Errorf("LastDigest = %v, want %v", got, now)
+		}
+	})
+}
+
+func TestStoreRemoveIfAndRestoreRollBackFailedFlush(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "store")
+	os.Mkdir(dir, 0o700)
+	st, err := Open(filepath.Join(dir, "bookmarks.json"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	st.Add(Bookmark{UserID: "user", ChannelID: "channel", MessageID: "message"})
+
+	os.RemoveAll(dir)
+	if removed, err := st.RemoveIf(func(Bookmark) bool { return true }); err == nil || len(removed) != 0 {
+		t.Errorf("RemoveIf = %d, %v, want an error and nothing removed", len(removed), err)
+	}
+	if !st.Has("user", "channel", "message") {
+		t.Error("bookmark removed from memory although its flush failed")
+	}
+	if err := st.Restore(Snapshot{Version: SNAPSHOT_VERSION}); err == nil {
+		t.Error("Restore succeeded without a directory to write to")
+	}
+	if !st.Has("user", "channel", "message") {
+		t.Error("store replaced in memory although the restore failed")
+	}
+}
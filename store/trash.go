@@ -0,0 +1,215 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// trashedBookmark is a deleted bookmark that can still be restored.
+type trashedBookmark struct {
+	Bookmark
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// Trash removes the bookmark delivered as the given DM message, keeping it
+// restorable with Untrash until PurgeTrash drops it.
+func (st *Store) Trash(dmChannelID, dmMessageID string, at time.Time) (Bookmark, bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	i := st.indexByDM(dmChannelID, dmMessageID)
+	if i == -1 {
+		return Bookmark{}, false, nil
+	}
+	b := st.bookmarks[i]
+	st.bookmarks = append(st.bookmarks[:i], st.bookmarks[i+1:]...)
+
+	st.trash = st.removeTrashed(b.UserID, b.ChannelID, b.MessageID)
+	st.trash = append(st.trash, trashedBookmark{Bookmark: b, DeletedAt: at})
+	return b, true, st.flush()
+}
+
+// Untrash takes the user's deleted bookmark of a message back out of the
+// trash. The caller adds it again once it is delivered anew.
+func (st *Store) Untrash(userID, channelID, messageID string) (Bookmark, bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for _, t := range st.trash {
+		if t.UserID == userID && t.ChannelID == channelID && t.MessageID == messageID {
+			st.trash = st.removeTrashed(userID, channelID, messageID)
+			return t.Bookmark, true, st.flush()
+		}
+	}
+	return Bookmark{}, false, nil
+}
+
+func (st *Store) Retrash(b Bookmark, at time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.trash = st.removeTrashed(b.UserID, b.ChannelID, b.MessageID)
+	st.trash = append(st.trash, trashedBookmark{Bookmark: b, DeletedAt: at})
+	return st.flush()
+}
+
+// PurgeTrash drops bookmarks deleted before the given time for good.
+func (st *Store) PurgeTrash(before time.Time) (int, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	kept := st.trash[:0]
+	for _, t := range st.trash {
+		if !t.DeletedAt.Before(before) {
+			kept = append(kept, t)
+		}
+	}
+	purged := len(st.trash) - len(kept)
+	if purged == 0 {
+		return 0, nil
+	}
+	st.trash = kept
+	return purged, st.flush()
+}
+
+func (st *Store) UpdateTrash(update func(Bookmark) (Bookmark, bool)) (int, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	changed := 0
+	for i, t := range st.trash {
+		if b, ok := update(t.Bookmark); ok {
+			st.trash[i].Bookmark = b
+			changed++
+		}
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+	return changed, st.flush()
+}
+
+// removeTrashed returns the trash without the given bookmark. Callers must hold st.mu.
+func (st *Store) removeTrashed(userID, channelID, messageID string) []trashedBookmark {
+	kept := st.trash[:0]
+	for _, t := range st.trash {
+		if t.UserID != userID || t.ChannelID != channelID || t.MessageID != messageID {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+func (st *SQLStore) Trash(dmChannelID, dmMessageID string, at time.Time) (Bookmark, bool, error) {
+	if dmMessageID == "" {
+		return Bookmark{}, false, nil
+	}
+	found, err := st.query(`WHERE dm_channel_id = ? AND dm_message_id = ?`, dmChannelID, dmMessageID)
+	if err != nil || len(found) == 0 {
+		return Bookmark{}, false, err
+	}
+	b := found[0]
+
+	args, err := bookmarkArgs(b)
+	if err != nil {
+		return Bookmark{}, false, err
+	}
+
+	tx, err := st.db.Begin()
+	if err != nil {
+		return Bookmark{}, false, err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM deleted_bookmarks WHERE user_id = ? AND channel_id = ? AND message_id = ?`,
+		`DELETE FROM bookmarks WHERE user_id = ? AND channel_id = ? AND message_id = ?`,
+	} {
+		if _, err := tx.Exec(st.rebind(query), b.UserID, b.ChannelID, b.MessageID); err != nil {
+			return Bookmark{}, false, err
+		}
+	}
+	_, err = tx.Exec(st.rebind(`INSERT INTO deleted_bookmarks (`+bookmarkColumns+`, deleted_at) VALUES (`+bookmarkValues+`, ?)`),
+		append(args, at)...)
+	if err != nil {
+		return Bookmark{}, false, fmt.Errorf("trashing bookmark: %w", err)
+	}
+	return b, true, tx.Commit()
+}
+
+func (st *SQLStore) Untrash(userID, channelID, messageID string) (Bookmark, bool, error) {
+	found, err := st.queryFrom("deleted_bookmarks", `WHERE user_id = ? AND channel_id = ? AND message_id = ?`, userID, channelID, messageID)
+	if err != nil || len(found) == 0 {
+		return Bookmark{}, false, err
+	}
+
+	res, err := st.db.Exec(st.rebind(`DELETE FROM deleted_bookmarks WHERE user_id = ? AND channel_id = ? AND message_id = ?`),
+		userID, channelID, messageID)
+	if err != nil {
+		return Bookmark{}, false, err
+	}
+	// Another instance may have restored or purged it meanwhile.
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return Bookmark{}, false, nil
+	}
+	return found[0], true, nil
+}
+
+func (st *SQLStore) Retrash(b Bookmark, at time.Time) error {
+	args, err := bookmarkArgs(b)
+	if err != nil {
+		return err
+	}
+
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(st.rebind(`DELETE FROM deleted_bookmarks WHERE user_id = ? AND channel_id = ? AND message_id = ?`), b.UserID, b.ChannelID, b.MessageID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(st.rebind(`INSERT INTO deleted_bookmarks (`+bookmarkColumns+`, deleted_at) VALUES (`+bookmarkValues+`, ?)`),
+		append(args, at)...)
+	if err != nil {
+		return fmt.Errorf("trashing bookmark: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (st *SQLStore) PurgeTrash(before time.Time) (int, error) {
+	res, err := st.db.Exec(st.rebind(`DELETE FROM deleted_bookmarks WHERE deleted_at < ?`), before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (st *SQLStore) UpdateTrash(update func(Bookmark) (Bookmark, bool)) (int, error) {
+	trashed, err := st.queryFrom("deleted_bookmarks", ``)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, t := range trashed {
+		b, ok := update(t)
+		if !ok {
+			continue
+		}
+		// Restored or purged meanwhile by another instance, it's skipped.
+		err := st.replaceIn("deleted_bookmarks", b, `user_id = ? AND channel_id = ? AND message_id = ?`, t.UserID, t.ChannelID, t.MessageID)
+		if errors.Is(err, errBookmarkNotFound) {
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrashAndUntrash(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b testBackend) {
+		location := b.location(t)
+		st := b.openStore(t, location)
+		st.Add(Bookmark{UserID: "user", ChannelID: "channel", MessageID: "message", DMChannelID: "dm", DMMessageID: "dm-message", Pinned: true})
+
+		deletedAt := time.Now()
+		bm, ok, err := st.Trash("dm", "dm-message", deletedAt)
+		if err != nil || !ok || bm.MessageID != "message" {
+			t.Fatalf("Trash = %+v, %t, %v", bm, ok, err)
+		}
+		if st.Has("user", "channel", "message") {
+			t.Error("trashed bookmark is still listed")
+		}
+
+		// The trash survives a restart.
+		st = b.openStore(t, location)
+		bm, ok, err = st.Untrash("user", "channel", "message")
+		if err != nil || !ok || !bm.Pinned {
+			t.Fatalf("Untrash = %+v, %t, %v", bm, ok, err)
+		}
+		if _, ok, _ := st.Untrash("user", "channel", "message"); ok {
+			t.Error("bookmark restored twice")
+		}
+
+		// A bookmark that couldn't be stored again goes back in the trash.
+		if err := st.Retrash(bm, time.Now()); err != nil {
+			t.Fatalf("Retrash: %v", err)
+		}
+		if bm, ok, err := st.Untrash("user", "channel", "message"); err != nil || !ok || !bm.Pinned {
+			t.Errorf("Untrash after Retrash = %+v, %t, %v", bm, ok, err)
+		}
+	})
+}
+
+func TestPurgeTrash(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b testBackend) {
+		st := b.newStore(t)
+		now := time.Now()
+		st.Add(Bookmark{UserID: "user", ChannelID: "channel", MessageID: "old", DMChannelID: "dm", DMMessageID: "dm-old"})
+		st.Add(Bookmark{UserID: "user", ChannelID: "channel", MessageID: "new", DMChannelID: "dm", DMMessageID: "dm-new"})
+		st.Trash("dm", "dm-old", now.Add(-time.Hour))
+		st.Trash("dm", "dm-new", now)
+
+		purged, err := st.PurgeTrash(now.Add(-time.Minute))
+		if err != nil || purged != 1 {
+			t.Fatalf("PurgeTrash = %d, %v, want 1", purged, err)
+		}
+		if _, ok, _ := st.Untrash("user", "channel", "old"); ok {
+			t.Error("purged bookmark was restored")
+		}
+		if _, ok, _ := st.Untrash("user", "channel", "new"); !ok {
+			t.Error("recently deleted bookmark was purged")
+		}
+	})
+}
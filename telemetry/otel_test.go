@@ -0,0 +1,70 @@
+//go:build otel
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestEnableExportsSpans(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", srv.URL)
+
+	ctx := context.Background()
+	shutdown, err := Enable(ctx)
+	if err != nil {
+		t.Fatalf("Enable = %v", err)
+	}
+	spanCtx, span := Start(ctx, "test", "key", "value")
+	if spanCtx == ctx {
+		t.Error("span didn't add itself to the context")
+	}
+	span.End(errors.New("failed"))
+
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) == 0 || paths[0] != "/v1/traces" {
+		t.Errorf("exporter requests = %q, want the span sent to /v1/traces", paths)
+	}
+
+	if spanCtx, _ := Start(ctx, "after shutdown"); spanCtx != ctx {
+		t.Error("span started after shutdown changed the context")
+	}
+}
+
+func TestStartWhileEnabling(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://127.0.0.1:1")
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 100; n++ {
+			_, span := Start(ctx, "concurrent")
+			span.End(nil)
+		}
+	}()
+	shutdown, err := Enable(ctx)
+	if err != nil {
+		t.Fatalf("Enable = %v", err)
+	}
+	wg.Wait()
+	shutdown(context.Background())
+}
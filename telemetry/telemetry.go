@@ -0,0 +1,75 @@
+// Package telemetry traces the bookmark pipeline. Spans are exported with
+// OpenTelemetry from binaries built with -tags otel once Enable is called;
+// otherwise they do nothing.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// SERVICE_NAME identifies the bot in traces, unless OTEL_SERVICE_NAME is set.
+const SERVICE_NAME = "discord-bookmarker"
+
+// ErrUnavailable is returned by Enable in binaries built without -tags otel.
+var ErrUnavailable = errors.New("tracing is not compiled in (is the binary built with -tags otel?)")
+
+// Span is a timed stage of the pipeline.
+type Span interface {
+	// End finishes the span, marking it failed if err isn't nil.
+	End(err error)
+}
+
+type tracer interface {
+	start(ctx context.Context, name string, attrs []string) (context.Context, Span)
+}
+
+type noopTracer struct{}
+
+func (noopTracer) start(ctx context.Context, _ string, _ []string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(error) {}
+
+// activeTracer boxes a tracer so implementations of different types can be
+// swapped atomically.
+type activeTracer struct {
+	tracer
+}
+
+var (
+	// current is the tracer spans start with, nil until Enable.
+	current atomic.Pointer[activeTracer]
+	// enable is replaced by the OpenTelemetry exporter when compiled in.
+	enable = func(ctx context.Context) (tracer, func(context.Context) error, error) {
+		return nil, nil, ErrUnavailable
+	}
+)
+
+// Enable starts exporting spans over OTLP, configured by the standard
+// OTEL_EXPORTER_OTLP_* environment variables. The returned function
+// flushes pending spans and stops exporting.
+func Enable(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	t, stop, err := enable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current.Store(&activeTracer{t})
+	return func(ctx context.Context) error {
+		current.Store(nil)
+		return stop(ctx)
+	}, nil
+}
+
+// Start begins a span named name as a child of the span in ctx, if any.
+// attrs are key and value pairs describing it, e.g. "user.id", "123".
+func Start(ctx context.Context, name string, attrs ...string) (context.Context, Span) {
+	if t := current.Load(); t != nil {
+		return t.start(ctx, name, attrs)
+	}
+	return noopTracer{}.start(ctx, name, attrs)
+}
@@ -0,0 +1,22 @@
+//go:build !otel
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDisabledByDefault(t *testing.T) {
+	ctx := context.Background()
+	spanCtx, span := Start(ctx, "test", "key", "value")
+	if spanCtx != ctx {
+		t.Error("no-op span changed the context")
+	}
+	span.End(errors.New("failed"))
+
+	if _, err := Enable(ctx); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Enable = %v, want ErrUnavailable without -tags otel", err)
+	}
+}
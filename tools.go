@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/anonmiraj/discord-bookmarker/bot"
+	"github.com/anonmiraj/discord-bookmarker/config"
+	"github.com/anonmiraj/discord-bookmarker/store"
+)
+
+// The operational commands log to stderr, whatever the log settings are, so
+// their output can be redirected on its own.
+var toolLogger = log.New(os.Stderr, "", log.Ldate|log.Ltime)
+
+// TOOL_AUDIT_ACTOR is the actor of audit log entries the operational commands record.
+const TOOL_AUDIT_ACTOR = "cli"
+
+// migrateStore applies pending migrations, which opening a SQL store does.
+func migrateStore(cfg config.Config, args []string) error {
+	if cfg.StoreDriver == config.STORE_JSON {
+		toolLogger.Printf("The %s store has no migrations", cfg.StoreDriver)
+		return nil
+	}
+
+	st, err := openStore(cfg, toolLogger)
+	if err != nil {
+		return fmt.Errorf("opening %s bookmark store: %w", cfg.StoreDriver, err)
+	}
+	toolLogger.Printf("The %s store is up to date", cfg.StoreDriver)
+	return st.Close()
+}
+
+func registerCommands(cfg config.Config, args []string) error {
+	dg, err := newSession(cfg)
+	if err != nil {
+		return err
+	}
+
+	app, err := dg.Application("@me")
+	if err != nil {
+		return fmt.Errorf("getting application info: %w", err)
+	}
+	n, err := bot.RegisterCommands(dg, app.ID)
+	if err != nil {
+		return fmt.Errorf("registering application commands: %w", err)
+	}
+	toolLogger.Printf("Registered %d application command(s) for %s", n, app.Name)
+	return nil
+}
+
+// exportUser prints every bookmark of a user as a JSON array.
+func exportUser(cfg config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: export-user [flags] <user-id>")
+	}
+
+	st, err := openStore(cfg, toolLogger)
+	if err != nil {
+		return fmt.Errorf("opening %s bookmark store: %w", cfg.StoreDriver, err)
+	}
+	defer st.Close()
+
+	bookmarks := st.ForUser(args[0])
+	if bookmarks == nil {
+		bookmarks = []store.Bookmark{}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(bookmarks); err != nil {
+		return err
+	}
+	auditTool(st, store.AuditEntry{Action: store.AuditExport, UserID: args[0], Detail: fmt.Sprintf("exported %d bookmark(s)", len(bookmarks))})
+	toolLogger.Printf("Exported %d bookmark(s) of user %s", len(bookmarks), args[0])
+	return nil
+}
+
+// backupStore writes a backup of the whole store. Encrypted bookmarks stay
+// encrypted, unless -decrypt follows the file name.
+func backupStore(cfg config.Config, args []string) error {
+	const usage = "usage: backup [flags] <file> [-decrypt]"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	decrypt := fs.Bool("decrypt", false, "write the bookmarks decrypted, to restore them without the encryption key")
+	if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 {
+		return errors.New(usage)
+	}
+
+	st, err := openStore(cfg, toolLogger)
+	if err != nil {
+		return fmt.Errorf("opening %s bookmark store: %w", cfg.StoreDriver, err)
+	}
+	defer st.Close()
+
+	snapshot, err := st.Snapshot()
+	if enc, ok := st.(*store.EncryptedStore); ok && *decrypt {
+		snapshot, err = enc.DecryptedSnapshot()
+	}
+	if err != nil {
+		return fmt.Errorf("reading store: %w", err)
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	if err := store.WriteSnapshot(f, snapshot); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	auditTool(st, store.AuditEntry{Action: store.AuditExport, Detail: fmt.Sprintf("backup of %d bookmark(s)", len(snapshot.Bookmarks))})
+	toolLogger.Printf("Wrote backup of %d bookmark(s) to %s", len(snapshot.Bookmarks), args[0])
+	return nil
+}
+
+func restoreStore(cfg config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: restore [flags] <file>")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	snapshot, err := store.ReadSnapshot(f)
+	if err != nil {
+		return err
+	}
+
+	st, err := openStore(cfg, toolLogger)
+	if err != nil {
+		return fmt.Errorf("opening %s bookmark store: %w", cfg.StoreDriver, err)
+	}
+	defer st.Close()
+
+	if _, ok := st.(*store.EncryptedStore); !ok && snapshot.Encrypted() {
+		return errors.New("the backup is encrypted, set the ENCRYPTION_KEY it was made with to restore it")
+	}
+	if err := st.Restore(snapshot); err != nil {
+		return fmt.Errorf("restoring backup: %w", err)
+	}
+	auditTool(st, store.AuditEntry{Action: store.AuditImport, Detail: fmt.Sprintf("restored %d bookmark(s)", len(snapshot.Bookmarks))})
+	toolLogger.Printf("Restored %d bookmark(s) from %s", len(snapshot.Bookmarks), args[0])
+	return nil
+}
+
+// rotateKey re-encrypts every bookmark with ENCRYPTION_KEY, decrypting
+// with it or ENCRYPTION_OLD_KEYS. It also encrypts bookmarks stored before
+// encryption was enabled.
+func rotateKey(cfg config.Config, args []string) error {
+	if cfg.EncryptionKey == "" {
+		return errors.New("ENCRYPTION_KEY is not set")
+	}
+
+	st, err := openStore(cfg, toolLogger)
+	if err != nil {
+		return fmt.Errorf("opening %s bookmark store: %w", cfg.StoreDriver, err)
+	}
+	defer st.Close()
+
+	n, skipped, err := st.(*store.EncryptedStore).Reencrypt()
+	if err != nil {
+		return fmt.Errorf("re-encrypting bookmarks: %w", err)
+	}
+	auditTool(st, store.AuditEntry{Action: store.AuditRekey, Detail: fmt.Sprintf("re-encrypted %d bookmark(s), skipped %d", n, skipped)})
+	if skipped > 0 {
+		return fmt.Errorf("re-encrypted %d bookmark(s), but %d couldn't be decrypted with any key; keep the old keys until they are", n, skipped)
+	}
+	toolLogger.Printf("Re-encrypted %d bookmark(s); the old keys can be removed", n)
+	return nil
+}
+
+// auditTool records an action of a command line tool in the audit log.
+func auditTool(st store.BookmarkStore, e store.AuditEntry) {
+	e.At, e.ActorID = time.Now(), TOOL_AUDIT_ACTOR
+	if err := st.AddAudit(e); err != nil {
+		toolLogger.Printf("Error recording %s action in the audit log: %v", e.Action, err)
+	}
+}
@@ -0,0 +1,55 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// DEEPL_FREE_URL is the translate endpoint of DeepL's free API; paid plans
+// use https://api.deepl.com/v2/translate.
+const DEEPL_FREE_URL = "https://api-free.deepl.com/v2/translate"
+
+// deeplTargets are the regional variants DeepL requires for some target
+// languages.
+var deeplTargets = map[string]string{
+	"en": "EN-US",
+	"pt": "PT-BR",
+	"zh": "ZH-HANS",
+}
+
+// DeepL calls DeepL's /v2/translate endpoint.
+type DeepL struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+func (t *DeepL) Translate(ctx context.Context, text, target string) (Translation, error) {
+	targetLang, ok := deeplTargets[strings.ToLower(target)]
+	if !ok {
+		targetLang = strings.ToUpper(target)
+	}
+	body := map[string]any{
+		"text":        []string{text},
+		"target_lang": targetLang,
+	}
+	header := http.Header{}
+	header.Set("Authorization", "DeepL-Auth-Key "+t.APIKey)
+
+	var result struct {
+		Translations []struct {
+			DetectedSourceLanguage string `json:"detected_source_language"`
+			Text                   string `json:"text"`
+		} `json:"translations"`
+	}
+	if err := postJSON(ctx, t.Client, t.URL, header, body, &result); err != nil {
+		return Translation{}, err
+	}
+	if len(result.Translations) == 0 {
+		return Translation{}, errors.New("no translation in response")
+	}
+	translated := result.Translations[0]
+	return Translation{Text: translated.Text, SourceLanguage: strings.ToLower(translated.DetectedSourceLanguage)}, nil
+}
@@ -0,0 +1,38 @@
+package translate
+
+import (
+	"context"
+	"net/http"
+)
+
+// LibreTranslate calls the /translate endpoint of a LibreTranslate server,
+// e.g. https://libretranslate.com/translate or a self-hosted one.
+type LibreTranslate struct {
+	URL string
+	// APIKey is only needed by servers that require one.
+	APIKey string
+	Client *http.Client
+}
+
+func (t *LibreTranslate) Translate(ctx context.Context, text, target string) (Translation, error) {
+	body := map[string]string{
+		"q":      text,
+		"source": "auto",
+		"target": target,
+		"format": "text",
+	}
+	if t.APIKey != "" {
+		body["api_key"] = t.APIKey
+	}
+
+	var result struct {
+		TranslatedText   string `json:"translatedText"`
+		DetectedLanguage struct {
+			Language string `json:"language"`
+		} `json:"detectedLanguage"`
+	}
+	if err := postJSON(ctx, t.Client, t.URL, http.Header{}, body, &result); err != nil {
+		return Translation{}, err
+	}
+	return Translation{Text: result.TranslatedText, SourceLanguage: result.DetectedLanguage.Language}, nil
+}
@@ -0,0 +1,91 @@
+// Package translate translates message text through a translation service.
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// TRANSLATE_TIMEOUT bounds a single translation request.
+	TRANSLATE_TIMEOUT = 30 * time.Second
+
+	PROVIDER_LIBRETRANSLATE = "libretranslate"
+	PROVIDER_DEEPL          = "deepl"
+)
+
+// Translation is text translated from the language the service detected.
+type Translation struct {
+	Text string
+	// SourceLanguage is the detected language code of the original, e.g. "de".
+	SourceLanguage string
+}
+
+// Translator translates text into the language with the given code, e.g. "en".
+type Translator interface {
+	Translate(ctx context.Context, text, target string) (Translation, error)
+}
+
+// New returns the translator of a provider, PROVIDER_LIBRETRANSLATE or
+// PROVIDER_DEEPL. url may be empty for DeepL's free API.
+func New(provider, url, apiKey string) (Translator, error) {
+	client := &http.Client{Timeout: TRANSLATE_TIMEOUT}
+	switch provider {
+	case PROVIDER_LIBRETRANSLATE:
+		if url == "" {
+			return nil, fmt.Errorf("%s needs the URL of its /translate endpoint", provider)
+		}
+		return &LibreTranslate{URL: url, APIKey: apiKey, Client: client}, nil
+	case PROVIDER_DEEPL:
+		if url == "" {
+			url = DEEPL_FREE_URL
+		}
+		return &DeepL{URL: url, APIKey: apiKey, Client: client}, nil
+	}
+	return nil, fmt.Errorf("unknown translation provider %q, expected %s or %s", provider, PROVIDER_LIBRETRANSLATE, PROVIDER_DEEPL)
+}
+
+// SameLanguage reports whether two language codes name the same language,
+// ignoring case and regional variants like the "US" of "en-US".
+func SameLanguage(a, b string) bool {
+	base := func(code string) string {
+		code, _, _ = strings.Cut(code, "-")
+		return strings.ToLower(code)
+	}
+	return base(a) == base(b)
+}
+
+// postJSON posts body as JSON and decodes the JSON response into result.
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decoding translation: %w", err)
+	}
+	return nil
+}
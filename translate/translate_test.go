@@ -0,0 +1,84 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLibreTranslate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["q"] != "Hallo Welt" || req["source"] != "auto" || req["target"] != "en" || req["api_key"] != "key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"translatedText": "Hello world", "detectedLanguage": {"confidence": 90, "language": "de"}}`))
+	}))
+	defer srv.Close()
+
+	translator, err := New(PROVIDER_LIBRETRANSLATE, srv.URL, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := translator.Translate(context.Background(), "Hallo Welt", "en")
+	if err != nil || got != (Translation{Text: "Hello world", SourceLanguage: "de"}) {
+		t.Errorf("Translate = %+v, %v", got, err)
+	}
+}
+
+func TestDeepL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Text       []string `json:"text"`
+			TargetLang string   `json:"target_lang"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.Header.Get("Authorization") != "DeepL-Auth-Key key" || len(req.Text) != 1 || req.TargetLang != "EN-US" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"translations": [{"detected_source_language": "DE", "text": "Hello world"}]}`))
+	}))
+	defer srv.Close()
+
+	translator, err := New(PROVIDER_DEEPL, srv.URL, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := translator.Translate(context.Background(), "Hallo Welt", "en")
+	if err != nil || got != (Translation{Text: "Hello world", SourceLanguage: "de"}) {
+		t.Errorf("Translate = %+v, %v", got, err)
+	}
+}
+
+func TestTranslateReportsErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", 456)
+	}))
+	defer srv.Close()
+
+	translator, _ := New(PROVIDER_DEEPL, srv.URL, "key")
+	if _, err := translator.Translate(context.Background(), "Hallo", "en"); err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("Translate error = %v, want the status and message", err)
+	}
+}
+
+func TestNewRejectsUnknownProviders(t *testing.T) {
+	if _, err := New("babelfish", "", ""); err == nil {
+		t.Error("unknown provider accepted")
+	}
+	if _, err := New(PROVIDER_LIBRETRANSLATE, "", ""); err == nil {
+		t.Error("LibreTranslate accepted without a URL")
+	}
+}
+
+func TestSameLanguage(t *testing.T) {
+	if !SameLanguage("EN-US", "en") || SameLanguage("pt", "es") {
+		t.Error("SameLanguage compares wrongly")
+	}
+}
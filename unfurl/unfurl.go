@@ -0,0 +1,157 @@
+// Package unfurl reads the OpenGraph preview of a link: its title,
+// description and image.
+package unfurl
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// UNFURL_TIMEOUT bounds fetching a page.
+	UNFURL_TIMEOUT = 5 * time.Second
+	// UNFURL_MAX_BYTES caps how much of a page is read; the metadata is in
+	// its head.
+	UNFURL_MAX_BYTES = 512 << 10
+)
+
+// Preview describes a linked page.
+type Preview struct {
+	URL         string
+	SiteName    string
+	Title       string
+	Description string
+	// Image is an absolute http(s) URL.
+	Image string
+}
+
+// IsZero reports whether nothing worth showing was found.
+func (p Preview) IsZero() bool {
+	return p.Title == "" && p.Description == "" && p.Image == ""
+}
+
+// LinkOnly returns the URL if content is nothing but a single http(s) link,
+// optionally in <> to suppress Discord's own preview.
+func LinkOnly(content string) (string, bool) {
+	link := strings.TrimSpace(content)
+	if strings.HasPrefix(link, "<") && strings.HasSuffix(link, ">") {
+		link = link[1 : len(link)-1]
+	}
+	if link == "" || strings.ContainsAny(link, " \t\n<>") {
+		return "", false
+	}
+
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return link, true
+}
+
+// Fetch downloads the page at link with client and parses its preview.
+// Only the first UNFURL_MAX_BYTES of HTML pages are read.
+func Fetch(ctx context.Context, client *http.Client, link string) (Preview, error) {
+	ctx, cancel := context.WithTimeout(ctx, UNFURL_TIMEOUT)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", "discord-bookmarker (link preview)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Preview{}, fmt.Errorf("not an HTML page: %q", mediaType)
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, UNFURL_MAX_BYTES))
+	if err != nil {
+		return Preview{}, err
+	}
+	// Relative image URLs resolve against the page after redirects.
+	return Parse(page, resp.Request.URL), nil
+}
+
+var (
+	metaTag   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attribute = regexp.MustCompile(`(?s)([\w:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	titleTag  = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// Parse reads the OpenGraph tags of page, falling back to its <title> and
+// meta description. page is the URL it was read from.
+func Parse(page []byte, pageURL *url.URL) Preview {
+	meta := map[string]string{}
+	for _, tag := range metaTag.FindAll(page, -1) {
+		attrs := map[string]string{}
+		for _, m := range attribute.FindAllSubmatch(tag, -1) {
+			attrs[strings.ToLower(string(m[1]))] = string(m[2]) + string(m[3]) + string(m[4])
+		}
+
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(key)
+		if _, seen := meta[key]; key != "" && !seen {
+			meta[key] = clean(attrs["content"])
+		}
+	}
+
+	p := Preview{
+		URL:         pageURL.String(),
+		SiteName:    meta["og:site_name"],
+		Title:       first(meta["og:title"], meta["twitter:title"]),
+		Description: first(meta["og:description"], meta["twitter:description"], meta["description"]),
+	}
+	if p.Title == "" {
+		if m := titleTag.FindSubmatch(page); m != nil {
+			p.Title = clean(string(m[1]))
+		}
+	}
+	if canonical, err := pageURL.Parse(meta["og:url"]); meta["og:url"] != "" && err == nil && isHTTP(canonical) {
+		p.URL = canonical.String()
+	}
+	if image := first(meta["og:image:secure_url"], meta["og:image"], meta["twitter:image"]); image != "" {
+		if u, err := pageURL.Parse(image); err == nil && isHTTP(u) {
+			p.Image = u.String()
+		}
+	}
+	return p
+}
+
+// clean unescapes HTML entities and collapses whitespace.
+func clean(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func isHTTP(u *url.URL) bool {
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,82 @@
+package unfurl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestLinkOnly(t *testing.T) {
+	for content, want := range map[string]string{
+		"https://example.com/a?b=c":   "https://example.com/a?b=c",
+		"  <https://example.com/>\n ": "https://example.com/",
+		"look https://example.com":    "",
+		"ftp://example.com":           "",
+		"example.com":                 "",
+		"":                            "",
+	} {
+		got, ok := LinkOnly(content)
+		if got != want || ok != (want != "") {
+			t.Errorf("LinkOnly(%q) = %q, %t, want %q", content, got, ok, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	page := `<html><head>
+		<title>Fallback</title>
+		<meta property="og:site_name" content="Example">
+		<meta content='A &amp; B' property='og:title'>
+		<META NAME="description" CONTENT="Plain   description">
+		<meta property="og:image" content="/cover.png" />
+	</head></html>`
+	base, _ := url.Parse("https://example.com/post/1")
+
+	p := Parse([]byte(page), base)
+	want := Preview{
+		URL:         "https://example.com/post/1",
+		SiteName:    "Example",
+		Title:       "A & B",
+		Description: "Plain description",
+		Image:       "https://example.com/cover.png",
+	}
+	if p != want {
+		t.Errorf("Parse = %+v, want %+v", p, want)
+	}
+
+	p = Parse([]byte(`<title> Only a title </title><meta property="og:image" content="javascript:alert(1)">`), base)
+	if p.Title != "Only a title" || p.Image != "" {
+		t.Errorf("Parse without OpenGraph = %+v", p)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<meta property="og:title" content="Page">`))
+		case "/huge":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(strings.Repeat(" ", UNFURL_MAX_BYTES)))
+			w.Write([]byte(`<meta property="og:title" content="Too far">`))
+		default:
+			w.Header().Set("Content-Type", "application/zip")
+		}
+	}))
+	defer server.Close()
+
+	p, err := Fetch(context.Background(), server.Client(), server.URL+"/page")
+	if err != nil || p.Title != "Page" {
+		t.Errorf("Fetch = %+v, %v", p, err)
+	}
+	if p, err := Fetch(context.Background(), server.Client(), server.URL+"/huge"); err != nil || !p.IsZero() {
+		t.Errorf("Fetch read past the size cap: %+v, %v", p, err)
+	}
+	if _, err := Fetch(context.Background(), server.Client(), server.URL+"/file.zip"); err == nil {
+		t.Error("Fetch accepted a non-HTML response")
+	}
+}